@@ -10,13 +10,18 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"sigs.k8s.io/cli-utils/cmd/flagutils"
 	"sigs.k8s.io/cli-utils/pkg/apply"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/rules"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 	"sigs.k8s.io/cli-utils/pkg/printers"
 )
@@ -42,6 +47,15 @@ func GetApplyRunner(factory cmdutil.Factory, invFactory inventory.InventoryClien
 		"If true, overwrite applied fields on server if field manager conflict.")
 	cmd.Flags().StringVar(&r.serverSideOptions.FieldManager, "field-manager", common.DefaultFieldManager,
 		"The client owner of the fields being applied on the server-side.")
+	cmd.Flags().BoolVar(&r.serverSideOptions.MigrateFromClientSide, "migrate-from-client-side", false,
+		"If true, move the fields owned by client-side apply to the server-side field manager and remove the "+
+			"last-applied-configuration annotation before applying. Only used with --server-side.")
+
+	cmd.Flags().BoolVar(&r.forceApply, "force-apply", false,
+		"If true, apply all objects, including those that are unchanged since they were last applied. "+
+			"Use it to revert out-of-band changes to fields the objects don't set.")
+	cmd.Flags().BoolVar(&r.recreateOnImmutable, "recreate-on-immutable", false,
+		"If true, delete and re-create objects that fail to apply because an immutable field was changed.")
 
 	cmd.Flags().StringVar(&r.output, "output", printers.DefaultPrinter(),
 		fmt.Sprintf("Output format, must be one of %s", strings.Join(printers.SupportedPrinters(), ",")))
@@ -49,12 +63,22 @@ func GetApplyRunner(factory cmdutil.Factory, invFactory inventory.InventoryClien
 		"Polling period for resource statuses.")
 	cmd.Flags().DurationVar(&r.reconcileTimeout, "reconcile-timeout", time.Duration(0),
 		"Timeout threshold for waiting for all resources to reach the Current status.")
+	cmd.Flags().BoolVar(&r.reconcileFailFast, "reconcile-fail-fast", false,
+		"If true, stop waiting for resources to reach the Current status as soon as any resource reaches the Failed status.")
+	cmd.Flags().BoolVar(&r.failOnReconcileTimeout, "fail-on-reconcile-timeout", false,
+		"If true, exit with an error if resources don't reach their wait condition before their reconcile timeout.")
+	cmd.Flags().StringVar(&r.statusRules, flagutils.StatusRulesFlag, "", flagutils.StatusRulesUsage)
 	cmd.Flags().BoolVar(&r.noPrune, "no-prune", r.noPrune,
 		"If true, do not prune previously applied objects.")
 	cmd.Flags().StringVar(&r.prunePropagationPolicy, "prune-propagation-policy",
 		"Background", "Propagation policy for pruning")
 	cmd.Flags().DurationVar(&r.pruneTimeout, "prune-timeout", time.Duration(0),
 		"Timeout threshold for waiting for all pruned resources to be deleted")
+	cmd.Flags().IntVar(&r.maxPruneCount, "max-prune", 0,
+		"If greater than zero, fail without applying or pruning anything if more than this many objects would be pruned.")
+	cmd.Flags().IntVar(&r.maxPrunePercent, "max-prune-percent", 0,
+		"If greater than zero, fail without applying or pruning anything if more than this percentage of the "+
+			"inventory objects would be pruned.")
 	cmd.Flags().StringVar(&r.inventoryPolicy, flagutils.InventoryPolicyFlag, flagutils.InventoryPolicyStrict,
 		"It determines the behavior when the resources don't belong to current inventory. Available options "+
 			fmt.Sprintf("%q, %q and %q.", flagutils.InventoryPolicyStrict, flagutils.InventoryPolicyAdopt, flagutils.InventoryPolicyForceAdopt))
@@ -62,6 +86,26 @@ func GetApplyRunner(factory cmdutil.Factory, invFactory inventory.InventoryClien
 		"How long to wait before exiting")
 	cmd.Flags().BoolVar(&r.printStatusEvents, "status-events", false,
 		"Print status events (always enabled for table output)")
+	cmd.Flags().IntVar(&r.historyLimit, "history-limit", 0,
+		"If greater than zero, record the applied objects in the inventory history, "+
+			"keeping this many revisions. See the history and rollback commands.")
+	cmd.Flags().IntVar(&r.applyConcurrency, "apply-concurrency", 1,
+		"Maximum number of resources in the same apply group that are applied in parallel.")
+	cmd.Flags().BoolVar(&r.dagScheduling, "dag-scheduling", false,
+		"If true, apply each resource as soon as the resources it depends on are reconciled, "+
+			"instead of waiting for all resources applied before it.")
+	cmd.Flags().DurationVar(&r.externalDepTimeout, "external-dependency-timeout", apply.DefaultExternalDependencyTimeout,
+		"Timeout threshold for waiting for the resources referenced by depends-on annotations "+
+			"that are not applied with the package to reach the Current status.")
+	cmd.Flags().IntVar(&r.retryAttempts, flagutils.RetryAttemptsFlag, 1, flagutils.RetryAttemptsUsage)
+	cmd.Flags().DurationVar(&r.retryBackoff, flagutils.RetryBackoffFlag, time.Second, flagutils.RetryBackoffUsage)
+	cmd.Flags().BoolVar(&r.inventoryLock, flagutils.InventoryLockFlag, true, flagutils.InventoryLockUsage)
+	cmd.Flags().DurationVar(&r.lockWait, flagutils.LockWaitFlag, 0, flagutils.LockWaitUsage)
+	cmd.Flags().DurationVar(&r.lockTTL, flagutils.LockTTLFlag, lock.DefaultTTL, flagutils.LockTTLUsage)
+	cmd.Flags().BoolVar(&r.forceUnlock, flagutils.ForceUnlockFlag, false, flagutils.ForceUnlockUsage)
+	cmd.Flags().StringVar(&r.planFile, "plan", "",
+		"If set, apply the plan in this file, created by preview --out-plan, instead of a "+
+			"package. Refuses to apply if the inventory changed since the plan was created.")
 
 	r.Command = cmd
 	return r
@@ -80,15 +124,33 @@ type ApplyRunner struct {
 	loader     manifestreader.ManifestLoader
 
 	serverSideOptions      common.ServerSideOptions
+	forceApply             bool
+	recreateOnImmutable    bool
 	output                 string
 	period                 time.Duration
 	reconcileTimeout       time.Duration
+	reconcileFailFast      bool
+	failOnReconcileTimeout bool
+	statusRules            string
 	noPrune                bool
 	prunePropagationPolicy string
 	pruneTimeout           time.Duration
+	maxPruneCount          int
+	maxPrunePercent        int
 	inventoryPolicy        string
 	timeout                time.Duration
 	printStatusEvents      bool
+	planFile               string
+	historyLimit           int
+	retryAttempts          int
+	retryBackoff           time.Duration
+	applyConcurrency       int
+	dagScheduling          bool
+	externalDepTimeout     time.Duration
+	inventoryLock          bool
+	lockWait               time.Duration
+	lockTTL                time.Duration
+	forceUnlock            bool
 }
 
 func (r *ApplyRunner) RunE(cmd *cobra.Command, args []string) error {
@@ -108,27 +170,48 @@ func (r *ApplyRunner) RunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-
-	// TODO: Fix DemandOneDirectory to no longer return FileNameFlags
-	// since we are no longer using them.
-	_, err = common.DemandOneDirectory(args)
-	if err != nil {
-		return err
+	if r.maxPruneCount < 0 {
+		return fmt.Errorf("--max-prune must not be negative, got %d", r.maxPruneCount)
 	}
-	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
+	if r.maxPrunePercent < 0 || r.maxPrunePercent > 100 {
+		return fmt.Errorf("--max-prune-percent must be between 0 and 100, got %d", r.maxPrunePercent)
+	}
+	retryPolicy, err := flagutils.ConvertRetryPolicy(r.retryAttempts, r.retryBackoff)
 	if err != nil {
 		return err
 	}
-	objs, err := reader.Read()
+	lockOptions, err := flagutils.ConvertInventoryLock(r.inventoryLock, r.lockWait, r.lockTTL, r.forceUnlock)
 	if err != nil {
 		return err
 	}
+	if r.applyConcurrency < 1 {
+		return fmt.Errorf("--apply-concurrency must be at least 1, got %d", r.applyConcurrency)
+	}
 
-	invObj, objs, err := inventory.SplitUnstructureds(objs)
-	if err != nil {
-		return err
+	// A plan is applied exactly as it was computed, so the package isn't
+	// read: the objects and the inventory are taken from the plan.
+	var plan *apply.Plan
+	var inv inventory.InventoryInfo
+	var objs []*unstructured.Unstructured
+	if r.planFile != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("a package can't be passed with --plan, got %v", args)
+		}
+		plan, err = apply.ReadPlan(r.planFile)
+		if err != nil {
+			return err
+		}
+		invObj, err := plan.Inventory.Object()
+		if err != nil {
+			return err
+		}
+		inv = resourcegroup.WrapInventoryInfoObj(invObj)
+	} else {
+		inv, objs, err = r.readPackage(cmd, args)
+		if err != nil {
+			return err
+		}
 	}
-	inv := inventory.WrapInventoryInfoObj(invObj)
 
 	invClient, err := r.invFactory.NewInventoryClient(r.factory)
 	if err != nil {
@@ -147,22 +230,73 @@ func (r *ApplyRunner) RunE(cmd *cobra.Command, args []string) error {
 		r.printStatusEvents = true
 	}
 
-	ch := a.Run(ctx, inv, objs, apply.Options{
-		ServerSideOptions: r.serverSideOptions,
-		PollInterval:      r.period,
-		ReconcileTimeout:  r.reconcileTimeout,
+	options := apply.Options{
+		ServerSideOptions:      r.serverSideOptions,
+		ForceApply:             r.forceApply,
+		RecreateOnImmutable:    r.recreateOnImmutable,
+		PollInterval:           r.period,
+		ReconcileTimeout:       r.reconcileTimeout,
+		ReconcileFailFast:      r.reconcileFailFast,
+		FailOnReconcileTimeout: r.failOnReconcileTimeout,
 		// If we are not waiting for status, tell the applier to not
 		// emit the events.
-		EmitStatusEvents:       r.printStatusEvents,
-		NoPrune:                r.noPrune,
-		DryRunStrategy:         common.DryRunNone,
-		PrunePropagationPolicy: prunePropPolicy,
-		PruneTimeout:           r.pruneTimeout,
-		InventoryPolicy:        inventoryPolicy,
-	})
+		EmitStatusEvents:          r.printStatusEvents,
+		NoPrune:                   r.noPrune,
+		DryRunStrategy:            common.DryRunNone,
+		PrunePropagationPolicy:    prunePropPolicy,
+		PruneTimeout:              r.pruneTimeout,
+		MaxPruneCount:             r.maxPruneCount,
+		MaxPrunePercent:           r.maxPrunePercent,
+		InventoryPolicy:           inventoryPolicy,
+		HistoryLimit:              r.historyLimit,
+		RetryPolicy:               retryPolicy,
+		InventoryLock:             lockOptions,
+		ApplyConcurrency:          r.applyConcurrency,
+		DAGScheduling:             r.dagScheduling,
+		ExternalDependencyTimeout: r.externalDepTimeout,
+	}
+	if r.statusRules != "" {
+		rs, err := rules.ReadFile(r.statusRules)
+		if err != nil {
+			return err
+		}
+		options.CustomStatusReadersFactoryFunc = rs.StatusReadersFactoryFunc()
+	}
+	var ch <-chan event.Event
+	if plan != nil {
+		ch = a.ApplyPlan(ctx, inv, plan, options)
+	} else {
+		ch = a.Run(ctx, inv, objs, options)
+	}
 
 	// The printer will print updates from the channel. It will block
 	// until the channel is closed.
 	printer := printers.GetPrinter(r.output, r.ioStreams)
 	return printer.Print(ch, common.DryRunNone, r.printStatusEvents)
 }
+
+// readPackage reads the objects of the package in the directory passed as
+// argument, or from stdin, and splits out the inventory object.
+func (r *ApplyRunner) readPackage(cmd *cobra.Command, args []string) (inventory.InventoryInfo,
+	[]*unstructured.Unstructured, error) {
+	// TODO: Fix DemandOneDirectory to no longer return FileNameFlags
+	// since we are no longer using them.
+	_, err := common.DemandOneDirectory(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
+	if err != nil {
+		return nil, nil, err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invObj, objs, err := inventory.SplitUnstructureds(objs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resourcegroup.WrapInventoryInfoObj(invObj), objs, nil
+}
@@ -17,6 +17,9 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/rules"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 	"sigs.k8s.io/cli-utils/pkg/printers"
 )
@@ -44,12 +47,19 @@ func GetDestroyRunner(factory cmdutil.Factory, invFactory inventory.InventoryCli
 			fmt.Sprintf("%q, %q and %q.", flagutils.InventoryPolicyStrict, flagutils.InventoryPolicyAdopt, flagutils.InventoryPolicyForceAdopt))
 	cmd.Flags().DurationVar(&r.deleteTimeout, "delete-timeout", time.Duration(0),
 		"Timeout threshold for waiting for all deleted resources to complete deletion")
+	cmd.Flags().StringVar(&r.statusRules, flagutils.StatusRulesFlag, "", flagutils.StatusRulesUsage)
 	cmd.Flags().StringVar(&r.deletePropagationPolicy, "delete-propagation-policy",
 		"Background", "Propagation policy for deletion")
 	cmd.Flags().DurationVar(&r.timeout, "timeout", 0,
 		"How long to wait before exiting")
 	cmd.Flags().BoolVar(&r.printStatusEvents, "status-events", false,
 		"Print status events (always enabled for table output)")
+	cmd.Flags().IntVar(&r.retryAttempts, flagutils.RetryAttemptsFlag, 1, flagutils.RetryAttemptsUsage)
+	cmd.Flags().DurationVar(&r.retryBackoff, flagutils.RetryBackoffFlag, time.Second, flagutils.RetryBackoffUsage)
+	cmd.Flags().BoolVar(&r.inventoryLock, flagutils.InventoryLockFlag, true, flagutils.InventoryLockUsage)
+	cmd.Flags().DurationVar(&r.lockWait, flagutils.LockWaitFlag, 0, flagutils.LockWaitUsage)
+	cmd.Flags().DurationVar(&r.lockTTL, flagutils.LockTTLFlag, lock.DefaultTTL, flagutils.LockTTLUsage)
+	cmd.Flags().BoolVar(&r.forceUnlock, flagutils.ForceUnlockFlag, false, flagutils.ForceUnlockUsage)
 
 	r.Command = cmd
 	return r
@@ -71,10 +81,17 @@ type DestroyRunner struct {
 
 	output                  string
 	deleteTimeout           time.Duration
+	statusRules             string
 	deletePropagationPolicy string
 	inventoryPolicy         string
 	timeout                 time.Duration
 	printStatusEvents       bool
+	retryAttempts           int
+	retryBackoff            time.Duration
+	inventoryLock           bool
+	lockWait                time.Duration
+	lockTTL                 time.Duration
+	forceUnlock             bool
 }
 
 func (r *DestroyRunner) RunE(cmd *cobra.Command, args []string) error {
@@ -94,6 +111,14 @@ func (r *DestroyRunner) RunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	retryPolicy, err := flagutils.ConvertRetryPolicy(r.retryAttempts, r.retryBackoff)
+	if err != nil {
+		return err
+	}
+	lockOptions, err := flagutils.ConvertInventoryLock(r.inventoryLock, r.lockWait, r.lockTTL, r.forceUnlock)
+	if err != nil {
+		return err
+	}
 	// Retrieve the inventory object.
 	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
 	if err != nil {
@@ -107,7 +132,7 @@ func (r *DestroyRunner) RunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	inv := inventory.WrapInventoryInfoObj(invObj)
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
 
 	invClient, err := r.invFactory.NewInventoryClient(r.factory)
 	if err != nil {
@@ -123,14 +148,25 @@ func (r *DestroyRunner) RunE(cmd *cobra.Command, args []string) error {
 		r.printStatusEvents = true
 	}
 
-	// Run the destroyer. It will return a channel where we can receive updates
-	// to keep track of progress and any issues.
-	ch := d.Run(ctx, inv, apply.DestroyerOptions{
+	options := apply.DestroyerOptions{
 		DeleteTimeout:           r.deleteTimeout,
 		DeletePropagationPolicy: deletePropPolicy,
 		InventoryPolicy:         inventoryPolicy,
 		EmitStatusEvents:        r.printStatusEvents,
-	})
+		RetryPolicy:             retryPolicy,
+		InventoryLock:           lockOptions,
+	}
+	if r.statusRules != "" {
+		rs, err := rules.ReadFile(r.statusRules)
+		if err != nil {
+			return err
+		}
+		options.CustomStatusReadersFactoryFunc = rs.StatusReadersFactoryFunc()
+	}
+
+	// Run the destroyer. It will return a channel where we can receive updates
+	// to keep track of progress and any issues.
+	ch := d.Run(ctx, inv, options)
 
 	// The printer will print updates from the channel. It will block
 	// until the channel is closed.
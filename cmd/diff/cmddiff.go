@@ -4,39 +4,109 @@
 package diff
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/klog/v2"
+	kubectlapply "k8s.io/kubectl/pkg/cmd/apply"
 	"k8s.io/kubectl/pkg/cmd/diff"
 	"k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
 	"k8s.io/kubectl/pkg/util/i18n"
+	utilexec "k8s.io/utils/exec"
+	"sigs.k8s.io/cli-utils/cmd/flagutils"
+	"sigs.k8s.io/cli-utils/pkg/apply"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/apply/filter"
 	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
 const tmpDirPrefix = "diff-cmd"
 
 // NewCmdDiff returns cobra command to implement client-side diff of package
+// directory, like DiffCommand, with the ResourceGroup inventory client
+// factory and the default manifest loader.
+//
+// Deprecated: NewCmdDiff is kept for compatibility. Use DiffCommand instead.
+func NewCmdDiff(f util.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return DiffCommand(f, resourcegroup.ClusterInventoryClientFactory{}, manifestreader.NewManifestLoader(f), ioStreams)
+}
+
+// DiffCommand returns cobra command to implement client-side diff of package
 // directory. For each local config file, get the resource in the cluster
 // and diff the local config resource against the resource in the cluster.
-func NewCmdDiff(f util.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+// The objects in the inventory that are not in the package are shown as
+// deletions, unless they would not be pruned by apply.
+func DiffCommand(f util.Factory, invFactory inventory.InventoryClientFactory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *cobra.Command {
 	options := diff.NewDiffOptions(ioStreams)
+	var noPrune bool
+	var inventoryPolicy string
 	cmd := &cobra.Command{
 		Use:                   "diff (DIRECTORY | STDIN)",
 		DisableFlagsInUseLine: true,
 		Short:                 i18n.T("Diff local config against cluster applied version"),
 		Args:                  cobra.MaximumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			cleanupFunc, err := Initialize(options, f, args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			invPolicy, err := flagutils.ConvertInventoryPolicy(inventoryPolicy)
+			if err != nil {
+				return err
+			}
+			// Stdin is read by both the diff and the manifest reader, so
+			// it is buffered.
+			var data []byte
+			if len(args) == 0 {
+				data, err = ioutil.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+			}
+
+			cleanupFunc, err := initialize(options, f, args, bytes.NewReader(data))
 			defer cleanupFunc()
-			util.CheckErr(err)
-			util.CheckErr(options.Run())
+			if err != nil {
+				return err
+			}
+
+			var pruneObjs object.UnstructuredSet
+			if !noPrune {
+				pruneObjs, err = pruneObjects(f, invFactory, loader, bytes.NewReader(data), args, invPolicy,
+					ioStreams.ErrOut)
+				if err != nil {
+					return err
+				}
+			}
+
+			return run(options, pruneObjs)
 		},
 	}
 
+	cmd.Flags().BoolVar(&options.ServerSideApply, "server-side", false,
+		"If true, diff against the result of a server-side apply instead of a client-side apply.")
+	cmd.Flags().BoolVar(&options.ForceConflicts, "force-conflicts", false,
+		"If true, overwrite applied fields on server if field manager conflict.")
+	cmd.Flags().StringVar(&options.FieldManager, "field-manager", common.DefaultFieldManager,
+		"The client owner of the fields being applied on the server-side.")
+	cmd.Flags().BoolVar(&noPrune, "no-prune", false,
+		"If true, do not show the previously applied objects that would be pruned as deletions.")
+	cmd.Flags().StringVar(&inventoryPolicy, flagutils.InventoryPolicyFlag, flagutils.InventoryPolicyStrict,
+		"It determines the behavior when the resources don't belong to current inventory. Available options "+
+			fmt.Sprintf("%q, %q and %q.", flagutils.InventoryPolicyStrict, flagutils.InventoryPolicyAdopt, flagutils.InventoryPolicyForceAdopt))
+
 	return cmd
 }
 
@@ -45,6 +115,12 @@ func NewCmdDiff(f util.Factory, ioStreams genericclioptions.IOStreams) *cobra.Co
 // error if there is an error filling in the options or if there
 // is not one argument that is a directory.
 func Initialize(o *diff.DiffOptions, f util.Factory, args []string) (func(), error) {
+	return initialize(o, f, args, os.Stdin)
+}
+
+// initialize is Initialize, reading the package from the passed reader
+// if there are no args.
+func initialize(o *diff.DiffOptions, f util.Factory, args []string, in io.Reader) (func(), error) {
 	cleanupFunc := func() {}
 	// Validate the only argument is a (package) directory path.
 	filenameFlags, err := common.DemandOneDirectory(args)
@@ -62,7 +138,7 @@ func Initialize(o *diff.DiffOptions, f util.Factory, args []string) (func(), err
 		}
 		filenameFlags.Filenames = &[]string{tmpDir}
 		klog.V(6).Infof("stdin diff command temp dir: %s", tmpDir)
-		if err := common.FilterInputFile(os.Stdin, tmpDir); err != nil {
+		if err := common.FilterInputFile(in, tmpDir); err != nil {
 			return cleanupFunc, err
 		}
 	} else {
@@ -99,13 +175,226 @@ func Initialize(o *diff.DiffOptions, f util.Factory, args []string) (func(), err
 
 	o.Builder = f.NewBuilder()
 
-	// We don't support server-side apply diffing yet.
-	o.ServerSideApply = false
-	o.ForceConflicts = false
+	// Conflicts can only be forced with server-side apply.
+	if !o.ServerSideApply {
+		o.ForceConflicts = false
+	}
 
 	return cleanupFunc, nil
 }
 
+// pruneObjects returns the objects in the cluster inventory that apply
+// would prune. The objects that the prune filters of the applier would
+// retain are not returned, but reported to the passed writer together with
+// the reason.
+func pruneObjects(f util.Factory, invFactory inventory.InventoryClientFactory, loader manifestreader.ManifestLoader,
+	in io.Reader, args []string, invPolicy inventory.InventoryPolicy, w io.Writer) (object.UnstructuredSet, error) {
+	reader, err := loader.ManifestReader(in, flagutils.PathFromArgs(args))
+	if err != nil {
+		return nil, err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	invObj, objs, err := inventory.SplitUnstructureds(objs)
+	if err != nil {
+		return nil, err
+	}
+	if invObj == nil {
+		// Without an inventory, there is nothing apply would prune.
+		_, _ = fmt.Fprintln(w, "no inventory object template found: the objects apply would prune are not shown")
+		return nil, nil
+	}
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
+
+	invClient, err := invFactory.NewInventoryClient(f)
+	if err != nil {
+		return nil, err
+	}
+	applier, err := apply.NewApplier(f, invClient)
+	if err != nil {
+		return nil, err
+	}
+	pruneObjs, pruneFilters, err := applier.PruneObjects(inv, objs, apply.Options{
+		InventoryPolicy: invPolicy,
+		DryRunStrategy:  common.DryRunNone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filterPruneObjects(pruneObjs, pruneFilters, w)
+}
+
+// filterPruneObjects returns the objects that pass all the filters. The
+// objects that are filtered are reported as retained to the writer.
+func filterPruneObjects(pruneObjs object.UnstructuredSet, pruneFilters []filter.ValidationFilter,
+	w io.Writer) (object.UnstructuredSet, error) {
+	var deleted object.UnstructuredSet
+	for _, obj := range pruneObjs {
+		retained := false
+		for _, pruneFilter := range pruneFilters {
+			filtered, reason, err := pruneFilter.Filter(obj)
+			if err != nil {
+				return nil, err
+			}
+			if filtered {
+				id := object.UnstructuredToObjMetaOrDie(obj)
+				_, _ = fmt.Fprintf(w, "%s/%s retained: %s\n", strings.ToLower(id.GroupKind.String()), id.Name, reason)
+				retained = true
+				break
+			}
+		}
+		if !retained {
+			deleted = append(deleted, obj)
+		}
+	}
+	return deleted, nil
+}
+
+// maxRetries is how many times the diff of an object is retried when the
+// object changes while it is diffed, like DiffOptions.Run.
+const maxRetries = 4
+
+// run diffs the objects of the package like DiffOptions.Run, and shows
+// the prune objects as deletions. The field ownership conflicts of the
+// server-side apply dry-run of an object don't stop the diff of the other
+// objects: they are returned together as an ApplyConflictsError, after
+// the diff of the other objects is shown.
+func run(o *diff.DiffOptions, pruneObjs object.UnstructuredSet) error {
+	differ, err := diff.NewDiffer("LIVE", "MERGED")
+	if err != nil {
+		return err
+	}
+	defer differ.TearDown()
+
+	printer := diff.Printer{}
+	r := o.Builder.
+		Unstructured().
+		NamespaceParam(o.CmdNamespace).DefaultNamespace().
+		FilenameParam(o.EnforceNamespace, &o.FilenameOptions).
+		LabelSelectorParam(o.Selector).
+		Flatten().
+		Do()
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	conflictsErr := &applyerror.ApplyConflictsError{}
+	err = r.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		// The object of the info is replaced by the live object, which
+		// may not exist, so the id is taken first.
+		id, err := object.InfoToObjMeta(info)
+		if err != nil {
+			return err
+		}
+		err = diffObject(o, differ, printer, info)
+		if conflictErr, ok := applyerror.AsApplyConflictError(err); ok {
+			klog.V(4).Infof("server-side apply conflicts (object: %q): %s", id, conflictErr.Summary())
+			conflictsErr.Add(id, conflictErr)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := printPruneObjects(differ, pruneObjs, printer); err != nil {
+		return err
+	}
+
+	err = differ.Run(o.Diff)
+	if len(conflictsErr.Objects) > 0 && (err == nil || isDiffFound(err)) {
+		return conflictsErr
+	}
+	return err
+}
+
+// diffObject adds the live and merged versions of the object to the
+// differ, like DiffOptions.Run. The diff is retried if the object changed
+// while it was diffed.
+func diffObject(o *diff.DiffOptions, differ *diff.Differ, printer diff.Printer, info *resource.Info) error {
+	if err := o.DryRunVerifier.HasSupport(info.Mapping.GroupVersionKind); err != nil {
+		return err
+	}
+
+	local := info.Object.DeepCopyObject()
+	var err error
+	for i := 1; i <= maxRetries; i++ {
+		if err = info.Get(); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			info.Object = nil
+		}
+
+		force := i == maxRetries
+		if force {
+			klog.Warningf("Object (%v: %v) keeps changing, diffing without lock",
+				info.Mapping.GroupVersionKind, info.Name)
+		}
+		obj := diff.InfoObject{
+			LocalObj:        local,
+			Info:            info,
+			Encoder:         scheme.DefaultJSONEncoder(),
+			OpenAPI:         o.OpenAPISchema,
+			Force:           force,
+			ServerSideApply: o.ServerSideApply,
+			FieldManager:    o.FieldManager,
+			ForceConflicts:  o.ForceConflicts,
+			IOStreams:       o.Diff.IOStreams,
+		}
+		err = differ.Diff(obj, printer)
+		// Field ownership conflicts don't change by retrying.
+		if _, ok := applyerror.AsApplyConflictError(err); ok || !apierrors.IsConflict(err) {
+			break
+		}
+	}
+
+	kubectlapply.WarnIfDeleting(info.Object, o.Diff.ErrOut)
+	return err
+}
+
+// printPruneObjects adds the prune objects to the live version of the
+// differ only, so they are shown as deletions.
+func printPruneObjects(differ *diff.Differ, pruneObjs object.UnstructuredSet, printer diff.Printer) error {
+	for _, obj := range pruneObjs {
+		live := obj.DeepCopy()
+		if live.GetAPIVersion() == "v1" && live.GetKind() == "Secret" {
+			m, err := diff.NewMasker(live, nil)
+			if err != nil {
+				return err
+			}
+			live = m.From().(*unstructured.Unstructured)
+		}
+		if err := differ.From.Print(pruneObjName(live), live, printer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDiffFound returns true if the error is the exit status of a diff
+// program that found differences.
+func isDiffFound(err error) bool {
+	var exitErr utilexec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitStatus() == 1
+}
+
+// pruneObjName returns the file name of a pruned object in the diff,
+// using the same format as diff.InfoObject.
+func pruneObjName(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	group := ""
+	if gvk.Group != "" {
+		group = fmt.Sprintf("%v.", gvk.Group)
+	}
+	return group + fmt.Sprintf("%v.%v.%v.%v", gvk.Version, gvk.Kind, obj.GetNamespace(), obj.GetName())
+}
+
 func createTempDir() (string, error) {
 	// Create a temporary file with the passed prefix in
 	// the default temporary directory.
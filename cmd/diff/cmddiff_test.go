@@ -0,0 +1,323 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest/fake"
+	"k8s.io/kubectl/pkg/cmd/diff"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/apply/filter"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+var configMapYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+  namespace: default
+data:
+  key: value
+`
+
+var deploymentYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: default
+`
+
+var podYAML = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: bar
+  namespace: default
+`
+
+func TestFilterPruneObjects(t *testing.T) {
+	deployment := testutil.Unstructured(t, deploymentYAML)
+	pod := testutil.Unstructured(t, podYAML,
+		testutil.AddAnnotation(t, common.OnRemoveAnnotation, common.OnRemoveKeep))
+
+	var out bytes.Buffer
+	deleted, err := filterPruneObjects(object.UnstructuredSet{deployment, pod},
+		[]filter.ValidationFilter{filter.PreventRemoveFilter{}}, &out)
+	require.NoError(t, err)
+
+	assert.Equal(t, object.UnstructuredSet{deployment}, deleted)
+	assert.Equal(t, "pod/bar retained: annotation prevents deletion "+
+		"(annotation: \"cli-utils.sigs.k8s.io/on-remove\", value: \"keep\")\n", out.String())
+}
+
+func TestPruneObjName(t *testing.T) {
+	assert.Equal(t, "apps.v1.Deployment.default.foo", pruneObjName(testutil.Unstructured(t, deploymentYAML)))
+	assert.Equal(t, "v1.Pod.default.bar", pruneObjName(testutil.Unstructured(t, podYAML)))
+}
+
+func TestDiffPruneObjects(t *testing.T) {
+	deployment := testutil.Unstructured(t, deploymentYAML)
+	var fromFiles, toFiles []string
+	fakeExec := &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(cmd string, args ...string) exec.Cmd {
+				fakeCmd := &testingexec.FakeCmd{
+					RunScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							// The diff program finds the pruned
+							// deployment in the live version only.
+							from, to := args[len(args)-2], args[len(args)-1]
+							fromFiles = readDirNames(t, from)
+							toFiles = readDirNames(t, to)
+							return nil, nil, &testingexec.FakeExitError{Status: 1}
+						},
+					},
+				}
+				return testingexec.InitFakeCmd(fakeCmd, cmd, args...)
+			},
+		},
+	}
+	program := &diff.DiffProgram{
+		Exec:      fakeExec,
+		IOStreams: genericclioptions.NewTestIOStreamsDiscard(),
+	}
+
+	differ, err := diff.NewDiffer("LIVE", "MERGED")
+	require.NoError(t, err)
+	defer differ.TearDown()
+	require.NoError(t, printPruneObjects(differ, object.UnstructuredSet{deployment}, diff.Printer{}))
+	err = differ.Run(program)
+	assert.True(t, isDiffFound(err), "expected diff exit status 1, got %v", err)
+	assert.Equal(t, []string{"apps.v1.Deployment.default.foo"}, fromFiles)
+	assert.Empty(t, toFiles)
+}
+
+func TestIsDiffFound(t *testing.T) {
+	assert.True(t, isDiffFound(&testingexec.FakeExitError{Status: 1}))
+	assert.False(t, isDiffFound(&testingexec.FakeExitError{Status: 2}))
+	assert.False(t, isDiffFound(fmt.Errorf("failed to run diff")))
+	assert.False(t, isDiffFound(nil))
+}
+
+func readDirNames(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+func TestDiffCommand_NoInventory(t *testing.T) {
+	dir := writePackage(t, map[string]string{"configmap.yaml": configMapYAML})
+	tf := newDiffTestFactory(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/namespaces/default/configmaps/foo":
+			// The config map doesn't exist yet.
+			return statusResponse(t, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "foo"))
+		case req.Method == http.MethodPost && req.URL.Path == "/namespaces/default/configmaps":
+			return bodyResponse(http.StatusCreated, req)
+		}
+		t.Fatalf("unexpected request: %s %s", req.Method, req.URL)
+		return nil, nil
+	})
+	defer tf.Cleanup()
+
+	ioStreams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	cmd := DiffCommand(tf, inventory.ClusterInventoryClientFactory{}, manifestreader.NewManifestLoader(tf), ioStreams)
+	cmd.SetArgs([]string{dir})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+
+	// The new config map is shown, and the prune diff is skipped.
+	assert.True(t, isDiffFound(err), "expected diff exit status 1, got %v", err)
+	assert.Contains(t, out.String(), "v1.ConfigMap.default.foo")
+	assert.Contains(t, errOut.String(), "no inventory object template found")
+}
+
+func TestDiffCommand_ServerSideConflicts(t *testing.T) {
+	barYAML := strings.ReplaceAll(configMapYAML, "name: foo", "name: bar")
+	dir := writePackage(t, map[string]string{
+		"bar.yaml": barYAML,
+		"foo.yaml": configMapYAML,
+	})
+	conflict := apierrors.NewApplyConflict([]metav1.StatusCause{
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "kubectl-edit" using v1`,
+			Field:   ".data.key",
+		},
+	}, "Apply failed with 1 conflict")
+	tf := newDiffTestFactory(t, func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.Method == http.MethodGet:
+			return statusResponse(t, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "foo"))
+		case req.Method == http.MethodPatch && req.URL.Path == "/namespaces/default/configmaps/bar":
+			return bodyResponse(http.StatusOK, req)
+		case req.Method == http.MethodPatch && req.URL.Path == "/namespaces/default/configmaps/foo":
+			return statusResponse(t, conflict)
+		}
+		t.Fatalf("unexpected request: %s %s", req.Method, req.URL)
+		return nil, nil
+	})
+	defer tf.Cleanup()
+
+	ioStreams, _, out, _ := genericclioptions.NewTestIOStreams()
+	cmd := DiffCommand(tf, inventory.ClusterInventoryClientFactory{}, manifestreader.NewManifestLoader(tf), ioStreams)
+	cmd.SetArgs([]string{dir, "--server-side"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+
+	// The conflict of foo doesn't stop the diff of bar.
+	var conflictsErr *applyerror.ApplyConflictsError
+	require.True(t, errors.As(err, &conflictsErr), "expected ApplyConflictsError, got %v", err)
+	require.Len(t, conflictsErr.Objects, 1)
+	assert.Equal(t, "foo", conflictsErr.Objects[0].Identifier.Name)
+	assert.Equal(t, []applyerror.FieldConflict{
+		{
+			Manager: "kubectl-edit",
+			Field:   ".data.key",
+			Message: `conflict with "kubectl-edit" using v1`,
+		},
+	}, conflictsErr.Objects[0].Err.Conflicts)
+	assert.Contains(t, out.String(), "v1.ConfigMap.default.bar")
+	assert.NotContains(t, out.String(), "v1.ConfigMap.default.foo")
+}
+
+// diffTestFactory is a TestFactory with a discovery client serving an
+// OpenAPI document, which the dry-run verifier of the diff requires.
+type diffTestFactory struct {
+	*cmdtesting.TestFactory
+}
+
+func (f *diffTestFactory) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return &openAPIDiscovery{}, nil
+}
+
+// openAPIDiscovery serves an OpenAPI document where ConfigMaps support
+// dry-run. The other methods are not implemented.
+type openAPIDiscovery struct {
+	discovery.CachedDiscoveryInterface
+}
+
+func (d *openAPIDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	return &openapi_v2.Document{
+		Paths: &openapi_v2.Paths{
+			Path: []*openapi_v2.NamedPathItem{
+				{
+					Name: "/api/v1/namespaces/{namespace}/configmaps/{name}",
+					Value: &openapi_v2.PathItem{
+						Patch: &openapi_v2.Operation{
+							VendorExtension: []*openapi_v2.NamedAny{
+								{
+									Name: "x-kubernetes-group-version-kind",
+									Value: &openapi_v2.Any{
+										Yaml: "group: \"\"\nkind: ConfigMap\nversion: v1\n",
+									},
+								},
+							},
+							Parameters: []*openapi_v2.ParametersItem{
+								{
+									Oneof: &openapi_v2.ParametersItem_Parameter{
+										Parameter: &openapi_v2.Parameter{
+											Oneof: &openapi_v2.Parameter_NonBodyParameter{
+												NonBodyParameter: &openapi_v2.NonBodyParameter{
+													Oneof: &openapi_v2.NonBodyParameter_QueryParameterSubSchema{
+														QueryParameterSubSchema: &openapi_v2.QueryParameterSubSchema{
+															Name: "dryRun",
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// newDiffTestFactory returns a diffTestFactory with a REST client that
+// handles the requests with the passed function.
+func newDiffTestFactory(t *testing.T, handle func(*http.Request) (*http.Response, error)) *diffTestFactory {
+	tf := cmdtesting.NewTestFactory().WithNamespace("default")
+	tf.UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+		Client:               fake.CreateHTTPClient(handle),
+	}
+	return &diffTestFactory{TestFactory: tf}
+}
+
+// writePackage writes the passed files to a new package directory.
+func writePackage(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "diff-test")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(strings.TrimSpace(content)), 0600))
+	}
+	return dir
+}
+
+// bodyResponse returns a response with the body of the request, like a
+// dry-run create or patch of the object.
+func bodyResponse(code int, req *http.Request) (*http.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: code,
+		Header:     cmdtesting.DefaultHeader(),
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+// statusResponse returns a response with the status of the passed error.
+func statusResponse(t *testing.T, err *apierrors.StatusError) (*http.Response, error) {
+	status := err.Status()
+	body, encodeErr := runtime.Encode(scheme.Codecs.LegacyCodec(schema.GroupVersion{Version: "v1"}), &status)
+	require.NoError(t, encodeErr)
+	return &http.Response{
+		StatusCode: int(status.Code),
+		Header:     cmdtesting.DefaultHeader(),
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
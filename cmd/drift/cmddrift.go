@@ -0,0 +1,228 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/cmd/flagutils"
+	"sigs.k8s.io/cli-utils/pkg/apply"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/printers"
+)
+
+// GetDriftRunner creates and returns the DriftRunner which stores the cobra command.
+func GetDriftRunner(factory cmdutil.Factory, invFactory inventory.InventoryClientFactory,
+	loader manifestreader.ManifestLoader, ioStreams genericclioptions.IOStreams) *DriftRunner {
+	r := &DriftRunner{
+		ioStreams:           ioStreams,
+		factory:             factory,
+		invFactory:          invFactory,
+		loader:              loader,
+		detectorFactoryFunc: detectorFactoryFunc,
+	}
+	cmd := &cobra.Command{
+		Use:                   "drift (DIRECTORY | STDIN)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Compare the live objects of the inventory with the local package"),
+		Long: i18n.T("Compare the live objects of the inventory with the local package, without " +
+			"applying anything. Exits with a non-zero status if any object has drifted."),
+		RunE: r.RunE,
+	}
+
+	cmd.Flags().StringVar(&r.output, "output", printers.DefaultPrinter(),
+		fmt.Sprintf("Output format, must be one of %s", strings.Join(printers.SupportedPrinters(), ",")))
+	cmd.Flags().StringVar(&r.fieldManager, "field-manager", common.DefaultFieldManager,
+		"The field manager the objects are applied with.")
+
+	r.Command = cmd
+	return r
+}
+
+// DriftCommand creates the DriftRunner, returning the cobra command associated with it.
+func DriftCommand(f cmdutil.Factory, invFactory inventory.InventoryClientFactory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetDriftRunner(f, invFactory, loader, ioStreams).Command
+}
+
+// DriftRunner encapsulates data necessary to run the drift command.
+type DriftRunner struct {
+	Command    *cobra.Command
+	ioStreams  genericclioptions.IOStreams
+	factory    cmdutil.Factory
+	invFactory inventory.InventoryClientFactory
+	loader     manifestreader.ManifestLoader
+
+	output       string
+	fieldManager string
+
+	detectorFactoryFunc func(cmdutil.Factory, inventory.InventoryClient) (driftDetector, error)
+}
+
+// driftDetector detects the drift of the live objects of an inventory,
+// like the apply.DriftDetector.
+type driftDetector interface {
+	Detect(ctx context.Context, invInfo inventory.InventoryInfo, objs object.UnstructuredSet,
+		options apply.DriftOptions) (*apply.DriftReport, error)
+}
+
+func detectorFactoryFunc(f cmdutil.Factory, invClient inventory.InventoryClient) (driftDetector, error) {
+	return apply.NewDriftDetector(f, invClient)
+}
+
+func (r *DriftRunner) RunE(cmd *cobra.Command, args []string) error {
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	invObj, objs, err := inventory.SplitUnstructureds(objs)
+	if err != nil {
+		return err
+	}
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
+
+	invClient, err := r.invFactory.NewInventoryClient(r.factory)
+	if err != nil {
+		return err
+	}
+	detector, err := r.detectorFactoryFunc(r.factory, invClient)
+	if err != nil {
+		return err
+	}
+	report, err := detector.Detect(cmd.Context(), inv, objs, apply.DriftOptions{
+		FieldManager: r.fieldManager,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch r.output {
+	case printers.TablePrinter:
+		err = printTable(r.ioStreams.Out, report)
+	case printers.JSONPrinter:
+		err = printJSON(r.ioStreams.Out, report)
+	default:
+		err = printEvents(r.ioStreams.Out, report)
+	}
+	if err != nil {
+		return err
+	}
+	if report.HasDrift() {
+		drifted := object.ObjMetadataSet{}
+		for _, d := range report.Drifted() {
+			drifted = append(drifted, d.Identifier)
+		}
+		return &applyerror.DriftDetectedError{Drifted: drifted}
+	}
+	return nil
+}
+
+// printEvents prints one line per object, followed by a summary.
+func printEvents(w io.Writer, report *apply.DriftReport) error {
+	for _, d := range report.Objects {
+		id := fmt.Sprintf("%s/%s", strings.ToLower(d.Identifier.GroupKind.String()), d.Identifier.Name)
+		var err error
+		switch d.Type {
+		case apply.DriftModified:
+			_, err = fmt.Fprintf(w, "%s modified: %s\n", id, strings.Join(d.Fields, ", "))
+		case apply.DriftMissing:
+			_, err = fmt.Fprintf(w, "%s missing\n", id)
+		case apply.DriftExtra:
+			_, err = fmt.Fprintf(w, "%s extra: in the inventory but not in the package\n", id)
+		default:
+			_, err = fmt.Fprintf(w, "%s in sync\n", id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	counts := countDrift(report)
+	_, err := fmt.Fprintf(w, "%d resource(s) checked: %d in sync, %d modified, %d missing, %d extra\n",
+		len(report.Objects), counts[apply.DriftNone], counts[apply.DriftModified],
+		counts[apply.DriftMissing], counts[apply.DriftExtra])
+	return err
+}
+
+// printJSON prints one JSON object per line for every object, followed
+// by a summary, like the json printer does for apply events.
+func printJSON(w io.Writer, report *apply.DriftReport) error {
+	for _, d := range report.Objects {
+		content := map[string]interface{}{
+			"group":     d.Identifier.GroupKind.Group,
+			"kind":      d.Identifier.GroupKind.Kind,
+			"namespace": d.Identifier.Namespace,
+			"name":      d.Identifier.Name,
+			"drift":     string(d.Type),
+		}
+		if len(d.Fields) > 0 {
+			content["fields"] = d.Fields
+		}
+		if err := printJSONEvent(w, "resourceDrift", content); err != nil {
+			return err
+		}
+	}
+	counts := countDrift(report)
+	return printJSONEvent(w, "completed", map[string]interface{}{
+		"count":         len(report.Objects),
+		"inSyncCount":   counts[apply.DriftNone],
+		"modifiedCount": counts[apply.DriftModified],
+		"missingCount":  counts[apply.DriftMissing],
+		"extraCount":    counts[apply.DriftExtra],
+	})
+}
+
+func printJSONEvent(w io.Writer, eventType string, content map[string]interface{}) error {
+	m := map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"type":      "drift",
+		"eventType": eventType,
+	}
+	for key, val := range content {
+		m[key] = val
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, string(b)+"\n")
+	return err
+}
+
+// printTable prints a table with a row for every object.
+func printTable(w io.Writer, report *apply.DriftReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tRESOURCE\tDRIFT\tFIELDS")
+	for _, d := range report.Objects {
+		_, _ = fmt.Fprintf(tw, "%s\t%s/%s\t%s\t%s\n", d.Identifier.Namespace, d.Identifier.GroupKind.Kind,
+			d.Identifier.Name, d.Type, strings.Join(d.Fields, ","))
+	}
+	return tw.Flush()
+}
+
+func countDrift(report *apply.DriftReport) map[apply.DriftType]int {
+	counts := make(map[apply.DriftType]int)
+	for _, d := range report.Objects {
+		counts[d.Type]++
+	}
+	return counts
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package drift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/apply"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	clierrors "sigs.k8s.io/cli-utils/pkg/errors"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/printers"
+)
+
+var (
+	inventoryTemplate = `
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  labels:
+    cli-utils.sigs.k8s.io/inventory-id: test
+  name: foo
+  namespace: default
+`
+	depObject = object.ObjMetadata{
+		Name:      "foo",
+		Namespace: "default",
+		GroupKind: schema.GroupKind{
+			Group: "apps",
+			Kind:  "Deployment",
+		},
+	}
+
+	stsObject = object.ObjMetadata{
+		Name:      "bar",
+		Namespace: "default",
+		GroupKind: schema.GroupKind{
+			Group: "apps",
+			Kind:  "StatefulSet",
+		},
+	}
+
+	cmObject = object.ObjMetadata{
+		Name:      "baz",
+		Namespace: "default",
+		GroupKind: schema.GroupKind{
+			Kind: "ConfigMap",
+		},
+	}
+
+	svcObject = object.ObjMetadata{
+		Name:      "qux",
+		Namespace: "default",
+		GroupKind: schema.GroupKind{
+			Kind: "Service",
+		},
+	}
+
+	driftReport = &apply.DriftReport{
+		Objects: []apply.ObjectDrift{
+			{Identifier: depObject, Type: apply.DriftModified, Fields: []string{"spec.replicas", "spec.template"}},
+			{Identifier: stsObject, Type: apply.DriftNone},
+			{Identifier: cmObject, Type: apply.DriftMissing},
+			{Identifier: svcObject, Type: apply.DriftExtra},
+		},
+	}
+
+	inSyncReport = &apply.DriftReport{
+		Objects: []apply.ObjectDrift{
+			{Identifier: depObject, Type: apply.DriftNone},
+			{Identifier: stsObject, Type: apply.DriftNone},
+		},
+	}
+)
+
+func TestDriftCommand(t *testing.T) {
+	testCases := map[string]struct {
+		report          *apply.DriftReport
+		output          string
+		expectedDrifted object.ObjMetadataSet
+		expectedOutput  string
+	}{
+		"events output with drift": {
+			report:          driftReport,
+			output:          printers.EventsPrinter,
+			expectedDrifted: object.ObjMetadataSet{depObject, cmObject, svcObject},
+			expectedOutput: `
+deployment.apps/foo modified: spec.replicas, spec.template
+statefulset.apps/bar in sync
+configmap/baz missing
+service/qux extra: in the inventory but not in the package
+4 resource(s) checked: 1 in sync, 1 modified, 1 missing, 1 extra
+`,
+		},
+		"events output without drift": {
+			report: inSyncReport,
+			output: printers.EventsPrinter,
+			expectedOutput: `
+deployment.apps/foo in sync
+statefulset.apps/bar in sync
+2 resource(s) checked: 2 in sync, 0 modified, 0 missing, 0 extra
+`,
+		},
+		"table output with drift": {
+			report:          driftReport,
+			output:          printers.TablePrinter,
+			expectedDrifted: object.ObjMetadataSet{depObject, cmObject, svcObject},
+			expectedOutput: `
+NAMESPACE  RESOURCE         DRIFT     FIELDS
+default    Deployment/foo   Modified  spec.replicas,spec.template
+default    StatefulSet/bar  InSync
+default    ConfigMap/baz    Missing
+default    Service/qux      Extra
+`,
+		},
+		"json output with drift": {
+			report:          driftReport,
+			output:          printers.JSONPrinter,
+			expectedDrifted: object.ObjMetadataSet{depObject, cmObject, svcObject},
+			expectedOutput: `
+{"drift":"Modified","eventType":"resourceDrift","fields":["spec.replicas","spec.template"],"group":"apps","kind":"Deployment","name":"foo","namespace":"default","type":"drift"}
+{"drift":"InSync","eventType":"resourceDrift","group":"apps","kind":"StatefulSet","name":"bar","namespace":"default","type":"drift"}
+{"drift":"Missing","eventType":"resourceDrift","group":"","kind":"ConfigMap","name":"baz","namespace":"default","type":"drift"}
+{"drift":"Extra","eventType":"resourceDrift","group":"","kind":"Service","name":"qux","namespace":"default","type":"drift"}
+{"count":4,"eventType":"completed","extraCount":1,"inSyncCount":1,"missingCount":1,"modifiedCount":1,"type":"drift"}
+`,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := runDrift(t, tc.report, tc.output, &buf)
+
+			output := buf.String()
+			switch tc.output {
+			case printers.JSONPrinter:
+				output = withoutTimestamps(t, output)
+			case printers.TablePrinter:
+				output = withoutTrailingSpaces(output)
+			}
+			assert.Equal(t, strings.TrimLeft(tc.expectedOutput, "\n"), output)
+
+			if tc.expectedDrifted == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var driftErr *applyerror.DriftDetectedError
+			require.True(t, errors.As(err, &driftErr), "expected a DriftDetectedError, got %v", err)
+			assert.Equal(t, tc.expectedDrifted, driftErr.Drifted)
+		})
+	}
+}
+
+// TestDriftCommand_ExitCode runs the drift command in a subprocess, since
+// the error handling of the commands exits the process.
+func TestDriftCommand_ExitCode(t *testing.T) {
+	if os.Getenv("DRIFT_EXIT_CODE_TEST") == "1" {
+		err := runDrift(t, driftReport, printers.EventsPrinter, ioutil.Discard)
+		clierrors.CheckErr(ioutil.Discard, err, "kapply")
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDriftCommand_ExitCode$")
+	cmd.Env = append(os.Environ(), "DRIFT_EXIT_CODE_TEST=1")
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "expected the command to exit with an error, got %v", err)
+	assert.Equal(t, clierrors.DriftDetectedErrorExitCode, exitErr.ExitCode())
+}
+
+// runDrift runs the drift command with a detector returning the passed
+// report, writing the output to out.
+func runDrift(t *testing.T, report *apply.DriftReport, output string, out io.Writer) error {
+	tf := cmdtesting.NewTestFactory().WithNamespace("default")
+	defer tf.Cleanup()
+
+	runner := &DriftRunner{
+		ioStreams:  genericclioptions.IOStreams{Out: out},
+		factory:    tf,
+		invFactory: inventory.FakeInventoryClientFactory(nil),
+		loader:     manifestreader.NewFakeLoader(tf, nil),
+		detectorFactoryFunc: func(cmdutil.Factory, inventory.InventoryClient) (driftDetector, error) {
+			return &fakeDetector{report: report}, nil
+		},
+		output: output,
+	}
+	cmd := &cobra.Command{
+		RunE:          runner.RunE,
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	cmd.SetIn(strings.NewReader(inventoryTemplate))
+	cmd.SetArgs([]string{})
+	return cmd.Execute()
+}
+
+// withoutTimestamps removes the timestamps from the passed JSON lines,
+// which are re-encoded with sorted keys.
+func withoutTimestamps(t *testing.T, output string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		m := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		assert.NotEmpty(t, m["timestamp"])
+		delete(m, "timestamp")
+		b, err := json.Marshal(m)
+		require.NoError(t, err)
+		lines = append(lines, string(b))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// withoutTrailingSpaces removes the padding of the last table column.
+func withoutTrailingSpaces(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+type fakeDetector struct {
+	report *apply.DriftReport
+}
+
+func (d *fakeDetector) Detect(context.Context, inventory.InventoryInfo, object.UnstructuredSet,
+	apply.DriftOptions) (*apply.DriftReport, error) {
+	return d.report, nil
+}
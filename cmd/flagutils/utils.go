@@ -5,9 +5,12 @@ package flagutils
 
 import (
 	"fmt"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
 )
 
 const (
@@ -15,6 +18,20 @@ const (
 	InventoryPolicyStrict     = "strict"
 	InventoryPolicyAdopt      = "adopt"
 	InventoryPolicyForceAdopt = "force-adopt"
+	StatusRulesFlag           = "status-rules"
+	StatusRulesUsage          = "Path to a YAML or JSON file with readiness rules for custom resource types."
+	RetryAttemptsFlag         = "retry-attempts"
+	RetryAttemptsUsage        = "Maximum number of attempts for resources that fail with a transient error, like a conflict. 1 disables retries."
+	RetryBackoffFlag          = "retry-backoff"
+	RetryBackoffUsage         = "Delay before the first retry of a failed resource. The delay is doubled for every following retry."
+	InventoryLockFlag         = "inventory-lock"
+	InventoryLockUsage        = "If true, lock the inventory so that concurrent runs for the same inventory fail or wait. The inventory is not locked, with a warning, if Leases in its namespace are forbidden."
+	LockWaitFlag              = "lock-wait"
+	LockWaitUsage             = "How long to wait for an inventory lock held by another run. 0 fails immediately."
+	LockTTLFlag               = "lock-ttl"
+	LockTTLUsage              = "How long the inventory lock is held if it is not renewed, e.g. because the run was killed."
+	ForceUnlockFlag           = "force-unlock"
+	ForceUnlockUsage          = "If true, release the inventory lock before taking it, even if it is held by another run."
 )
 
 // ConvertPropagationPolicy converts a propagationPolicy described as a
@@ -47,6 +64,44 @@ func ConvertInventoryPolicy(policy string) (inventory.InventoryPolicy, error) {
 	}
 }
 
+// ConvertRetryPolicy converts the values of the retry flags to a retry
+// policy that is passed into the Applier or Destroyer.
+func ConvertRetryPolicy(attempts int, backoff time.Duration) (retry.Policy, error) {
+	if attempts < 1 {
+		return retry.Policy{}, fmt.Errorf("--%s must be at least 1, got %d", RetryAttemptsFlag, attempts)
+	}
+	if backoff < 0 {
+		return retry.Policy{}, fmt.Errorf("--%s must not be negative, got %s", RetryBackoffFlag, backoff)
+	}
+	return retry.Policy{
+		MaxAttempts: attempts,
+		Backoff:     backoff,
+	}, nil
+}
+
+// ConvertInventoryLock converts the values of the inventory lock flags to
+// the lock options that are passed into the Applier, Destroyer or Migrator.
+// Returns nil if locking is disabled.
+func ConvertInventoryLock(enabled bool, wait, ttl time.Duration, forceUnlock bool) (*lock.Options, error) {
+	if !enabled {
+		if forceUnlock {
+			return nil, fmt.Errorf("--%s requires --%s", ForceUnlockFlag, InventoryLockFlag)
+		}
+		return nil, nil
+	}
+	if wait < 0 {
+		return nil, fmt.Errorf("--%s must not be negative, got %s", LockWaitFlag, wait)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("--%s must be positive, got %s", LockTTLFlag, ttl)
+	}
+	return &lock.Options{
+		TTL:         ttl,
+		Wait:        wait,
+		ForceUnlock: forceUnlock,
+	}, nil
+}
+
 // PathFromArgs returns the path which is a positional arg from args list
 // returns "-" if there is length of args is 0, which implies no path is provided
 func PathFromArgs(args []string) string {
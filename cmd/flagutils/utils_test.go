@@ -6,8 +6,11 @@ package flagutils
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
 )
 
 func TestConvertInventoryPolicy(t *testing.T) {
@@ -50,3 +53,58 @@ func TestConvertInventoryPolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertInventoryLock(t *testing.T) {
+	testCases := map[string]struct {
+		enabled     bool
+		wait        time.Duration
+		ttl         time.Duration
+		forceUnlock bool
+		expected    *lock.Options
+		isError     bool
+	}{
+		"disabled": {
+			enabled: false,
+			ttl:     time.Minute,
+		},
+		"force unlock requires locking": {
+			enabled:     false,
+			ttl:         time.Minute,
+			forceUnlock: true,
+			isError:     true,
+		},
+		"enabled": {
+			enabled:     true,
+			wait:        time.Second,
+			ttl:         time.Minute,
+			forceUnlock: true,
+			expected: &lock.Options{
+				TTL:         time.Minute,
+				Wait:        time.Second,
+				ForceUnlock: true,
+			},
+		},
+		"negative wait": {
+			enabled: true,
+			wait:    -time.Second,
+			ttl:     time.Minute,
+			isError: true,
+		},
+		"zero ttl": {
+			enabled: true,
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			o, err := ConvertInventoryLock(tc.enabled, tc.wait, tc.ttl, tc.forceUnlock)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, o)
+		})
+	}
+}
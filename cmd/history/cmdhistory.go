@@ -0,0 +1,89 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/cmd/flagutils"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/history"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+)
+
+// GetHistoryRunner creates and returns the HistoryRunner which stores the cobra command.
+func GetHistoryRunner(factory cmdutil.Factory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *HistoryRunner {
+	r := &HistoryRunner{
+		ioStreams: ioStreams,
+		factory:   factory,
+		loader:    loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "history (DIRECTORY | STDIN)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("List the revisions recorded in the inventory history"),
+		RunE:                  r.RunE,
+	}
+
+	r.Command = cmd
+	return r
+}
+
+// HistoryCommand creates the HistoryRunner, returning the cobra command associated with it.
+func HistoryCommand(f cmdutil.Factory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetHistoryRunner(f, loader, ioStreams).Command
+}
+
+// HistoryRunner encapsulates data necessary to run the history command.
+type HistoryRunner struct {
+	Command   *cobra.Command
+	ioStreams genericclioptions.IOStreams
+	factory   cmdutil.Factory
+	loader    manifestreader.ManifestLoader
+}
+
+func (r *HistoryRunner) RunE(cmd *cobra.Command, args []string) error {
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	invObj, _, err := inventory.SplitUnstructureds(objs)
+	if err != nil {
+		return err
+	}
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
+
+	historyClient, err := history.NewClient(r.factory)
+	if err != nil {
+		return err
+	}
+	revisions, err := historyClient.List(cmd.Context(), inv)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		_, _ = fmt.Fprint(r.ioStreams.Out, "no revisions found in the inventory history\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(r.ioStreams.Out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "REVISION\tTIMESTAMP\tOBJECTS")
+	for _, rev := range revisions {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%d\n", rev.Number, rev.Timestamp.Format(time.RFC3339), len(rev.Objects))
+	}
+	return w.Flush()
+}
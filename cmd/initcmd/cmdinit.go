@@ -24,7 +24,7 @@ func GetInitRunner(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *In
 	cmd := &cobra.Command{
 		Use:                   "init DIRECTORY",
 		DisableFlagsInUseLine: true,
-		Short:                 i18n.T("Create a prune manifest ConfigMap as a inventory object"),
+		Short:                 i18n.T("Create a prune manifest ConfigMap or ResourceGroup as a inventory object"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			err := io.Complete(args)
 			if err != nil {
@@ -34,6 +34,8 @@ func GetInitRunner(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *In
 		},
 	}
 	cmd.Flags().StringVarP(&io.InventoryID, "inventory-id", "i", "", "Identifier for group of applied resources. Must be composed of valid label characters.")
+	cmd.Flags().StringVar(&io.InventoryKind, "inventory-kind", config.ConfigMapInventoryKind,
+		"Kind of the inventory object: ConfigMap or ResourceGroup. For a ResourceGroup, the CRD is written to resourcegroup-crd.yaml, and must be installed before applying.")
 	i := &InitRunner{
 		Command:     cmd,
 		InitOptions: io,
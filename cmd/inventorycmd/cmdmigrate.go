@@ -0,0 +1,164 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventorycmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/cmd/flagutils"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/inventory/migrate"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+)
+
+// InventoryCommand returns the cobra command grouping the inventory commands.
+func InventoryCommand(f cmdutil.Factory, invFactory inventory.InventoryClientFactory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: i18n.T("Manage inventory objects"),
+	}
+	cmd.AddCommand(MigrateCommand(f, invFactory, loader, ioStreams))
+	return cmd
+}
+
+// GetMigrateRunner creates and returns the MigrateRunner which stores the cobra command.
+func GetMigrateRunner(factory cmdutil.Factory, invFactory inventory.InventoryClientFactory,
+	loader manifestreader.ManifestLoader, ioStreams genericclioptions.IOStreams) *MigrateRunner {
+	r := &MigrateRunner{
+		ioStreams:  ioStreams,
+		factory:    factory,
+		invFactory: invFactory,
+		loader:     loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "migrate OLD_INVENTORY NEW_INVENTORY",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Move the inventory of a package to another inventory object"),
+		Long: i18n.T(`Move the inventory of a package to another inventory object, e.g. from a
+ConfigMap to a ResourceGroup, or to another inventory id.
+
+OLD_INVENTORY and NEW_INVENTORY are the files or directories holding the
+old and new inventory object templates. The objects of the old inventory
+are stored in the new one, the live objects are annotated with the new
+inventory id, and the old inventory is deleted last. If both templates
+are the same object with another inventory id, the inventory object is
+relabeled with the new id instead. A migration which failed part way is
+resumed by running the command again.`),
+		Args: cobra.ExactArgs(2),
+		RunE: r.RunE,
+	}
+	cmd.Flags().BoolVar(&r.dryRun, "dry-run", false, "If true, only print what would be migrated, without changing anything.")
+	cmd.Flags().BoolVar(&r.inventoryLock, flagutils.InventoryLockFlag, true, flagutils.InventoryLockUsage)
+	cmd.Flags().DurationVar(&r.lockWait, flagutils.LockWaitFlag, 0, flagutils.LockWaitUsage)
+	cmd.Flags().DurationVar(&r.lockTTL, flagutils.LockTTLFlag, lock.DefaultTTL, flagutils.LockTTLUsage)
+	cmd.Flags().BoolVar(&r.forceUnlock, flagutils.ForceUnlockFlag, false, flagutils.ForceUnlockUsage)
+
+	r.Command = cmd
+	return r
+}
+
+// MigrateCommand creates the MigrateRunner, returning the cobra command associated with it.
+func MigrateCommand(f cmdutil.Factory, invFactory inventory.InventoryClientFactory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetMigrateRunner(f, invFactory, loader, ioStreams).Command
+}
+
+// MigrateRunner encapsulates data necessary to run the migrate command.
+type MigrateRunner struct {
+	Command    *cobra.Command
+	ioStreams  genericclioptions.IOStreams
+	factory    cmdutil.Factory
+	invFactory inventory.InventoryClientFactory
+	loader     manifestreader.ManifestLoader
+
+	dryRun        bool
+	inventoryLock bool
+	lockWait      time.Duration
+	lockTTL       time.Duration
+	forceUnlock   bool
+}
+
+func (r *MigrateRunner) RunE(cmd *cobra.Command, args []string) error {
+	lockOptions, err := flagutils.ConvertInventoryLock(r.inventoryLock, r.lockWait, r.lockTTL, r.forceUnlock)
+	if err != nil {
+		return err
+	}
+	from, err := r.readInventory(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	to, err := r.readInventory(cmd, args[1])
+	if err != nil {
+		return err
+	}
+
+	invClient, err := r.invFactory.NewInventoryClient(r.factory)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrate.NewMigrator(r.factory, invClient)
+	if err != nil {
+		return err
+	}
+	dryRunStrategy := common.DryRunNone
+	if r.dryRun {
+		dryRunStrategy = common.DryRunClient
+	}
+	result, err := migrator.Migrate(cmd.Context(), from, to, migrate.Options{
+		DryRunStrategy: dryRunStrategy,
+		InventoryLock:  lockOptions,
+	})
+	if err != nil {
+		return err
+	}
+
+	suffix := ""
+	if r.dryRun {
+		suffix = " (dry-run)"
+	}
+	out := r.ioStreams.Out
+	_, _ = fmt.Fprintf(out, "%d objects stored in inventory %s/%s%s\n", len(result.Objects), to.Namespace(), to.Name(), suffix)
+	for _, id := range result.Annotated {
+		_, _ = fmt.Fprintf(out, "%s annotated with inventory id %s%s\n", id, to.ID(), suffix)
+	}
+	for _, id := range result.Skipped {
+		_, _ = fmt.Fprintf(out, "%s skipped: not found, or not owned by inventory id %s\n", id, from.ID())
+	}
+	if result.Relabeled {
+		_, _ = fmt.Fprintf(out, "inventory %s/%s relabeled with inventory id %s%s\n", to.Namespace(), to.Name(), to.ID(), suffix)
+	}
+	if result.Deleted {
+		_, _ = fmt.Fprintf(out, "inventory %s/%s deleted%s\n", from.Namespace(), from.Name(), suffix)
+	}
+	return nil
+}
+
+// readInventory reads the inventory object template in the passed path.
+func (r *MigrateRunner) readInventory(cmd *cobra.Command, path string) (inventory.InventoryInfo, error) {
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), path)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	invObj, _, err := inventory.SplitUnstructureds(objs)
+	if err != nil {
+		return nil, err
+	}
+	if invObj == nil {
+		return nil, inventory.NoInventoryObjError{}
+	}
+	return resourcegroup.WrapInventoryInfoObj(invObj), nil
+}
@@ -15,11 +15,15 @@ import (
 	"sigs.k8s.io/cli-utils/cmd/apply"
 	"sigs.k8s.io/cli-utils/cmd/destroy"
 	"sigs.k8s.io/cli-utils/cmd/diff"
+	"sigs.k8s.io/cli-utils/cmd/drift"
+	"sigs.k8s.io/cli-utils/cmd/history"
 	"sigs.k8s.io/cli-utils/cmd/initcmd"
+	"sigs.k8s.io/cli-utils/cmd/inventorycmd"
 	"sigs.k8s.io/cli-utils/cmd/preview"
+	"sigs.k8s.io/cli-utils/cmd/rollback"
 	"sigs.k8s.io/cli-utils/cmd/status"
 	"sigs.k8s.io/cli-utils/pkg/errors"
-	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 	"sigs.k8s.io/cli-utils/pkg/util/factory"
 
@@ -56,23 +60,31 @@ func main() {
 		ErrOut: os.Stderr,
 	}
 
-	names := []string{"init", "apply", "preview", "diff", "destroy", "status"}
+	names := []string{"init", "apply", "preview", "diff", "destroy", "status", "history", "rollback", "drift", "inventory"}
 	initCmd := initcmd.NewCmdInit(f, ioStreams)
 	updateHelp(names, initCmd)
 	loader := manifestreader.NewManifestLoader(f)
-	invFactory := inventory.ClusterInventoryClientFactory{}
+	invFactory := resourcegroup.ClusterInventoryClientFactory{}
 	applyCmd := apply.ApplyCommand(f, invFactory, loader, ioStreams)
 	updateHelp(names, applyCmd)
 	previewCmd := preview.PreviewCommand(f, invFactory, loader, ioStreams)
 	updateHelp(names, previewCmd)
-	diffCmd := diff.NewCmdDiff(f, ioStreams)
+	diffCmd := diff.DiffCommand(f, invFactory, loader, ioStreams)
 	updateHelp(names, diffCmd)
 	destroyCmd := destroy.DestroyCommand(f, invFactory, loader, ioStreams)
 	updateHelp(names, destroyCmd)
 	statusCmd := status.StatusCommand(f, invFactory, loader)
 	updateHelp(names, statusCmd)
+	historyCmd := history.HistoryCommand(f, loader, ioStreams)
+	updateHelp(names, historyCmd)
+	rollbackCmd := rollback.RollbackCommand(f, invFactory, loader, ioStreams)
+	updateHelp(names, rollbackCmd)
+	driftCmd := drift.DriftCommand(f, invFactory, loader, ioStreams)
+	updateHelp(names, driftCmd)
+	inventoryCmd := inventorycmd.InventoryCommand(f, invFactory, loader, ioStreams)
+	updateHelp(names, inventoryCmd)
 
-	cmd.AddCommand(initCmd, applyCmd, diffCmd, destroyCmd, previewCmd, statusCmd)
+	cmd.AddCommand(initCmd, applyCmd, diffCmd, destroyCmd, previewCmd, statusCmd, historyCmd, rollbackCmd, driftCmd, inventoryCmd)
 
 	logs.InitLogs()
 	defer logs.FlushLogs()
@@ -18,6 +18,7 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 	"sigs.k8s.io/cli-utils/pkg/printers"
 )
@@ -59,6 +60,9 @@ func GetPreviewRunner(factory cmdutil.Factory, invFactory inventory.InventoryCli
 			fmt.Sprintf("%q, %q and %q.", flagutils.InventoryPolicyStrict, flagutils.InventoryPolicyAdopt, flagutils.InventoryPolicyForceAdopt))
 	cmd.Flags().DurationVar(&r.timeout, "timeout", 0,
 		"How long to wait before exiting")
+	cmd.Flags().StringVar(&r.outPlan, "out-plan", "",
+		"If set, write the plan to this file, so it can be applied later with apply --plan. "+
+			"The plan is written as JSON if the file has the .json extension, and as YAML otherwise.")
 
 	r.Command = cmd
 	return r
@@ -82,6 +86,7 @@ type PreviewRunner struct {
 	output            string
 	inventoryPolicy   string
 	timeout           time.Duration
+	outPlan           string
 }
 
 // RunE is the function run from the cobra command.
@@ -105,6 +110,9 @@ func (r *PreviewRunner) RunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if previewDestroy && r.outPlan != "" {
+		return fmt.Errorf("--out-plan can't be used with --destroy")
+	}
 
 	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
 	if err != nil {
@@ -119,7 +127,7 @@ func (r *PreviewRunner) RunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	inv := inventory.WrapInventoryInfoObj(invObj)
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
 
 	invClient, err := r.invFactory.NewInventoryClient(r.factory)
 	if err != nil {
@@ -138,15 +146,26 @@ func (r *PreviewRunner) RunE(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		// Run the applier. It will return a channel where we can receive updates
-		// to keep track of progress and any issues.
-		ch = a.Run(ctx, inv, objs, apply.Options{
+		options := apply.Options{
 			EmitStatusEvents:  false,
 			NoPrune:           noPrune,
 			DryRunStrategy:    drs,
 			ServerSideOptions: r.serverSideOptions,
 			InventoryPolicy:   inventoryPolicy,
-		})
+		}
+		if r.outPlan != "" {
+			plan, err := a.Plan(inv, objs, options)
+			if err != nil {
+				return err
+			}
+			if err := apply.WritePlan(r.outPlan, plan); err != nil {
+				return err
+			}
+		}
+
+		// Run the applier. It will return a channel where we can receive updates
+		// to keep track of progress and any issues.
+		ch = a.Run(ctx, inv, objs, options)
 	} else {
 		d, err := apply.NewDestroyer(r.factory, invClient)
 		if err != nil {
@@ -0,0 +1,190 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package rollback
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"sigs.k8s.io/cli-utils/cmd/flagutils"
+	"sigs.k8s.io/cli-utils/pkg/apply"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/history"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/manifestreader"
+	"sigs.k8s.io/cli-utils/pkg/printers"
+)
+
+// GetRollbackRunner creates and returns the RollbackRunner which stores the cobra command.
+func GetRollbackRunner(factory cmdutil.Factory, invFactory inventory.InventoryClientFactory,
+	loader manifestreader.ManifestLoader, ioStreams genericclioptions.IOStreams) *RollbackRunner {
+	r := &RollbackRunner{
+		ioStreams:  ioStreams,
+		factory:    factory,
+		invFactory: invFactory,
+		loader:     loader,
+	}
+	cmd := &cobra.Command{
+		Use:                   "rollback (DIRECTORY | STDIN) --to-revision N",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Apply the objects of a revision from the inventory history"),
+		RunE:                  r.RunE,
+	}
+
+	cmd.Flags().IntVar(&r.toRevision, "to-revision", 0,
+		"The revision from the inventory history to roll back to. Required.")
+	cmd.Flags().StringVar(&r.output, "output", printers.DefaultPrinter(),
+		fmt.Sprintf("Output format, must be one of %s", strings.Join(printers.SupportedPrinters(), ",")))
+	cmd.Flags().DurationVar(&r.period, "poll-period", 2*time.Second,
+		"Polling period for resource statuses.")
+	cmd.Flags().DurationVar(&r.reconcileTimeout, "reconcile-timeout", time.Duration(0),
+		"Timeout threshold for waiting for all resources to reach the Current status.")
+	cmd.Flags().BoolVar(&r.noPrune, "no-prune", r.noPrune,
+		"If true, do not prune objects that are not in the revision.")
+	cmd.Flags().StringVar(&r.prunePropagationPolicy, "prune-propagation-policy",
+		"Background", "Propagation policy for pruning")
+	cmd.Flags().DurationVar(&r.pruneTimeout, "prune-timeout", time.Duration(0),
+		"Timeout threshold for waiting for all pruned resources to be deleted")
+	cmd.Flags().StringVar(&r.inventoryPolicy, flagutils.InventoryPolicyFlag, flagutils.InventoryPolicyStrict,
+		"It determines the behavior when the resources don't belong to current inventory. Available options "+
+			fmt.Sprintf("%q, %q and %q.", flagutils.InventoryPolicyStrict, flagutils.InventoryPolicyAdopt, flagutils.InventoryPolicyForceAdopt))
+	cmd.Flags().DurationVar(&r.timeout, "timeout", 0,
+		"How long to wait before exiting")
+	cmd.Flags().BoolVar(&r.printStatusEvents, "status-events", false,
+		"Print status events (always enabled for table output)")
+	cmd.Flags().IntVar(&r.historyLimit, "history-limit", 0,
+		"If greater than zero, record the rolled back objects as a new revision in the inventory history, "+
+			"keeping this many revisions.")
+	cmd.Flags().BoolVar(&r.inventoryLock, flagutils.InventoryLockFlag, true, flagutils.InventoryLockUsage)
+	cmd.Flags().DurationVar(&r.lockWait, flagutils.LockWaitFlag, 0, flagutils.LockWaitUsage)
+	cmd.Flags().DurationVar(&r.lockTTL, flagutils.LockTTLFlag, lock.DefaultTTL, flagutils.LockTTLUsage)
+	cmd.Flags().BoolVar(&r.forceUnlock, flagutils.ForceUnlockFlag, false, flagutils.ForceUnlockUsage)
+
+	r.Command = cmd
+	return r
+}
+
+// RollbackCommand creates the RollbackRunner, returning the cobra command associated with it.
+func RollbackCommand(f cmdutil.Factory, invFactory inventory.InventoryClientFactory, loader manifestreader.ManifestLoader,
+	ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return GetRollbackRunner(f, invFactory, loader, ioStreams).Command
+}
+
+// RollbackRunner encapsulates data necessary to run the rollback command.
+type RollbackRunner struct {
+	Command    *cobra.Command
+	ioStreams  genericclioptions.IOStreams
+	factory    cmdutil.Factory
+	invFactory inventory.InventoryClientFactory
+	loader     manifestreader.ManifestLoader
+
+	toRevision             int
+	output                 string
+	period                 time.Duration
+	reconcileTimeout       time.Duration
+	noPrune                bool
+	prunePropagationPolicy string
+	pruneTimeout           time.Duration
+	inventoryPolicy        string
+	timeout                time.Duration
+	printStatusEvents      bool
+	historyLimit           int
+	inventoryLock          bool
+	lockWait               time.Duration
+	lockTTL                time.Duration
+	forceUnlock            bool
+}
+
+func (r *RollbackRunner) RunE(cmd *cobra.Command, args []string) error {
+	if r.toRevision < 1 {
+		return fmt.Errorf("--to-revision must be set to a revision from the inventory history")
+	}
+	ctx := cmd.Context()
+	// If specified, cancel with timeout.
+	if r.timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	prunePropPolicy, err := flagutils.ConvertPropagationPolicy(r.prunePropagationPolicy)
+	if err != nil {
+		return err
+	}
+	inventoryPolicy, err := flagutils.ConvertInventoryPolicy(r.inventoryPolicy)
+	if err != nil {
+		return err
+	}
+	lockOptions, err := flagutils.ConvertInventoryLock(r.inventoryLock, r.lockWait, r.lockTTL, r.forceUnlock)
+	if err != nil {
+		return err
+	}
+
+	// Only the inventory object is read from the package. The objects to
+	// apply come from the revision.
+	reader, err := r.loader.ManifestReader(cmd.InOrStdin(), flagutils.PathFromArgs(args))
+	if err != nil {
+		return err
+	}
+	objs, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	invObj, _, err := inventory.SplitUnstructureds(objs)
+	if err != nil {
+		return err
+	}
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
+
+	historyClient, err := history.NewClient(r.factory)
+	if err != nil {
+		return err
+	}
+	rev, err := historyClient.Get(ctx, inv, r.toRevision)
+	if err != nil {
+		return err
+	}
+
+	invClient, err := r.invFactory.NewInventoryClient(r.factory)
+	if err != nil {
+		return err
+	}
+	a, err := apply.NewApplier(r.factory, invClient)
+	if err != nil {
+		return err
+	}
+
+	// Always enable status events for the table printer
+	if r.output == printers.TablePrinter {
+		r.printStatusEvents = true
+	}
+
+	// Run the applier with the objects from the revision. Objects that
+	// are not in the revision are pruned, like with apply.
+	ch := a.Run(ctx, inv, rev.Objects, apply.Options{
+		PollInterval:           r.period,
+		ReconcileTimeout:       r.reconcileTimeout,
+		EmitStatusEvents:       r.printStatusEvents,
+		NoPrune:                r.noPrune,
+		DryRunStrategy:         common.DryRunNone,
+		PrunePropagationPolicy: prunePropPolicy,
+		PruneTimeout:           r.pruneTimeout,
+		InventoryPolicy:        inventoryPolicy,
+		HistoryLimit:           r.historyLimit,
+		InventoryLock:          lockOptions,
+	})
+
+	// The printer will print updates from the channel. It will block
+	// until the channel is closed.
+	printer := printers.GetPrinter(r.output, r.ioStreams)
+	return printer.Print(ch, common.DryRunNone, r.printStatusEvents)
+}
@@ -16,10 +16,12 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply/poller"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/aggregator"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/collector"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/rules"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 	"sigs.k8s.io/cli-utils/pkg/util/factory"
@@ -43,6 +45,7 @@ func GetStatusRunner(factory cmdutil.Factory, invFactory inventory.InventoryClie
 	c.Flags().StringVar(&r.output, "output", "events", "Output format.")
 	c.Flags().DurationVar(&r.timeout, "timeout", 0,
 		"How long to wait before exiting")
+	c.Flags().StringVar(&r.statusRules, flagutils.StatusRulesFlag, "", flagutils.StatusRulesUsage)
 
 	r.Command = c
 	return r
@@ -60,10 +63,11 @@ type StatusRunner struct {
 	invFactory inventory.InventoryClientFactory
 	loader     manifestreader.ManifestLoader
 
-	period    time.Duration
-	pollUntil string
-	timeout   time.Duration
-	output    string
+	period      time.Duration
+	pollUntil   string
+	timeout     time.Duration
+	output      string
+	statusRules string
 
 	pollerFactoryFunc func(cmdutil.Factory) (poller.Poller, error)
 }
@@ -90,7 +94,7 @@ func (r *StatusRunner) runE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	inv := inventory.WrapInventoryInfoObj(invObj)
+	inv := resourcegroup.WrapInventoryInfoObj(invObj)
 
 	invClient, err := r.invFactory.NewInventoryClient(r.factory)
 	if err != nil {
@@ -153,10 +157,19 @@ func (r *StatusRunner) runE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unknown value for pollUntil: %q", r.pollUntil)
 	}
 
-	eventChannel := statusPoller.Poll(ctx, identifiers, polling.Options{
+	pollingOptions := polling.Options{
 		PollInterval: r.period,
 		UseCache:     true,
-	})
+	}
+	if r.statusRules != "" {
+		rs, err := rules.ReadFile(r.statusRules)
+		if err != nil {
+			return err
+		}
+		pollingOptions.CustomStatusReadersFactoryFunc = rs.StatusReadersFactoryFunc()
+	}
+
+	eventChannel := statusPoller.Poll(ctx, identifiers, pollingOptions)
 
 	return printer.Print(eventChannel, identifiers, cancelFunc)
 }
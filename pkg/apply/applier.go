@@ -9,7 +9,9 @@ import (
 	"sort"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
@@ -20,11 +22,16 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply/mutator"
 	"sigs.k8s.io/cli-utils/pkg/apply/poller"
 	"sigs.k8s.io/cli-utils/pkg/apply/prune"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/solver"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/history"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
 	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/object/graph"
 	"sigs.k8s.io/cli-utils/pkg/ordering"
 	statusfactory "sigs.k8s.io/cli-utils/pkg/util/factory"
 )
@@ -104,9 +111,15 @@ func (a *Applier) prepareObjects(localInv inventory.InventoryInfo, localObjs obj
 			}
 		}
 	}
-	pruneObjs, err := a.pruner.GetPruneObjs(localInv, localObjs, prune.Options{
+	pruneOpts := prune.Options{
 		DryRunStrategy: o.DryRunStrategy,
-	})
+	}
+	// The prune limits only matter if objects will actually be pruned.
+	if !o.NoPrune {
+		pruneOpts.MaxPruneCount = o.MaxPruneCount
+		pruneOpts.MaxPrunePercent = o.MaxPrunePercent
+	}
+	pruneObjs, err := a.pruner.GetPruneObjs(localInv, localObjs, pruneOpts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -118,10 +131,10 @@ func (a *Applier) prepareObjects(localInv inventory.InventoryInfo, localObjs obj
 // on progress and any errors are reported back on the event channel.
 // Cancelling the operation or setting timeout on how long to Wait
 // for it complete can be done with the passed in context.
-// Note: There isn't currently any way to interrupt the operation
-// before all the given resources have been applied to the cluster. Any
-// cancellation or timeout will only affect how long we Wait for the
-// resources to become current.
+// If the context is cancelled, the apply and prune tasks stop between
+// objects and send skipped events for the objects they never reached.
+// The inventory is still updated, so it only reflects the objects that
+// were actually applied or pruned.
 func (a *Applier) Run(ctx context.Context, invInfo inventory.InventoryInfo, objects object.UnstructuredSet, options Options) <-chan event.Event {
 	klog.V(4).Infof("apply run for %d objects", len(objects))
 	eventChannel := make(chan event.Event)
@@ -129,25 +142,21 @@ func (a *Applier) Run(ctx context.Context, invInfo inventory.InventoryInfo, obje
 	go func() {
 		defer close(eventChannel)
 
-		client, err := a.factory.DynamicClient()
-		if err != nil {
-			handleError(eventChannel, err)
-			return
-		}
-		mapper, err := a.factory.ToRESTMapper()
-		if err != nil {
+		// Validate the resources to make sure we catch those problems early
+		// before anything has been updated in the cluster.
+		if err := a.validateObjects(objects); err != nil {
 			handleError(eventChannel, err)
 			return
 		}
 
-		// Validate the resources to make sure we catch those problems early
-		// before anything has been updated in the cluster.
-		if err := (&object.Validator{
-			Mapper: mapper,
-		}).Validate(objects); err != nil {
+		// Lock the inventory before it is read, and release it after
+		// the inventory has been updated.
+		ctx, unlock, err := lockInventory(ctx, a.factory, invInfo, options.InventoryLock, options.DryRunStrategy)
+		if err != nil {
 			handleError(eventChannel, err)
 			return
 		}
+		defer unlock()
 
 		applyObjs, pruneObjs, err := a.prepareObjects(invInfo, objects, options)
 		if err != nil {
@@ -156,92 +165,239 @@ func (a *Applier) Run(ctx context.Context, invInfo inventory.InventoryInfo, obje
 		}
 		klog.V(4).Infof("calculated %d apply objs; %d prune objs", len(applyObjs), len(pruneObjs))
 
-		// Fetch the queue (channel) of tasks that should be executed.
-		klog.V(4).Infoln("applier building task queue...")
-		taskBuilder := &solver.TaskQueueBuilder{
-			Pruner:     a.pruner,
-			Factory:    a.factory,
-			InfoHelper: a.infoHelper,
-			Mapper:     mapper,
-			InvClient:  a.invClient,
-			Destroy:    false,
-		}
-		opts := solver.Options{
-			ServerSideOptions:      options.ServerSideOptions,
-			ReconcileTimeout:       options.ReconcileTimeout,
-			Prune:                  !options.NoPrune,
-			DryRunStrategy:         options.DryRunStrategy,
-			PrunePropagationPolicy: options.PrunePropagationPolicy,
-			PruneTimeout:           options.PruneTimeout,
-			InventoryPolicy:        options.InventoryPolicy,
-		}
-		// Build list of apply validation filters.
-		applyFilters := []filter.ValidationFilter{}
-		if options.InventoryPolicy != inventory.AdoptAll {
-			applyFilters = append(applyFilters, filter.InventoryPolicyApplyFilter{
-				Client:    client,
-				Mapper:    mapper,
-				Inv:       invInfo,
-				InvPolicy: options.InventoryPolicy,
-			})
-		}
-
-		// Build list of prune validation filters.
-		pruneFilters := []filter.ValidationFilter{
-			filter.PreventRemoveFilter{},
-			filter.InventoryPolicyFilter{
-				Inv:       invInfo,
-				InvPolicy: options.InventoryPolicy,
-			},
-			filter.LocalNamespacesFilter{
-				LocalNamespaces: localNamespaces(invInfo, object.UnstructuredsToObjMetasOrDie(objects)),
-			},
-		}
-		// Build list of apply mutators.
-		// Share a thread-safe cache with the status poller.
-		resourceCache := cache.NewResourceCacheMap()
-		applyMutators := []mutator.Interface{
-			&mutator.ApplyTimeMutator{
-				Client:        client,
-				Mapper:        mapper,
-				ResourceCache: resourceCache,
-			},
-		}
-		// Build the task queue by appending tasks in the proper order.
-		taskQueue, err := taskBuilder.
-			AppendInvAddTask(invInfo, applyObjs, options.DryRunStrategy).
-			AppendApplyWaitTasks(applyObjs, applyFilters, applyMutators, opts).
-			AppendPruneWaitTasks(pruneObjs, pruneFilters, opts).
-			AppendInvSetTask(invInfo, options.DryRunStrategy).
-			Build()
+		q, err := a.buildTaskQueue(invInfo, applyObjs, pruneObjs, options)
 		if err != nil {
 			handleError(eventChannel, err)
 			return
 		}
-		// Send event to inform the caller about the resources that
-		// will be applied/pruned.
-		eventChannel <- event.Event{
-			Type: event.InitType,
-			InitEvent: event.InitEvent{
-				ActionGroups: taskQueue.ToActionGroups(),
-			},
-		}
-		// Create a new TaskStatusRunner to execute the taskQueue.
-		klog.V(4).Infoln("applier building TaskStatusRunner...")
-		allIds := object.UnstructuredsToObjMetasOrDie(append(applyObjs, pruneObjs...))
-		runner := taskrunner.NewTaskStatusRunner(allIds, a.StatusPoller, resourceCache)
-		klog.V(4).Infoln("applier running TaskStatusRunner...")
-		err = runner.Run(ctx, taskQueue.ToChannel(), eventChannel, taskrunner.Options{
-			PollInterval:     options.PollInterval,
-			UseCache:         true,
-			EmitStatusEvents: options.EmitStatusEvents,
+		a.runTaskQueue(ctx, q, eventChannel, options)
+	}()
+	return eventChannel
+}
+
+// applierTaskQueue is the task queue built by the Applier, together
+// with the inputs needed to execute it.
+type applierTaskQueue struct {
+	invInfo       inventory.InventoryInfo
+	taskQueue     *solver.TaskQueue
+	applyObjs     object.UnstructuredSet
+	pruneObjs     object.UnstructuredSet
+	externalIds   object.ObjMetadataSet
+	applyFilters  []filter.ValidationFilter
+	pruneFilters  []filter.ValidationFilter
+	resourceCache cache.ResourceCache
+}
+
+// validateObjects validates the objects to apply, so that problems are
+// caught before anything has been updated in the cluster.
+func (a *Applier) validateObjects(objects object.UnstructuredSet) error {
+	mapper, err := a.factory.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	return (&object.Validator{
+		Mapper: mapper,
+	}).Validate(objects)
+}
+
+// buildTaskQueue builds the queue of tasks that apply applyObjs, prune
+// pruneObjs and update the inventory.
+func (a *Applier) buildTaskQueue(invInfo inventory.InventoryInfo, applyObjs, pruneObjs object.UnstructuredSet,
+	options Options) (*applierTaskQueue, error) {
+	client, err := a.factory.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := a.factory.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the queue (channel) of tasks that should be executed.
+	klog.V(4).Infoln("applier building task queue...")
+	taskBuilder := &solver.TaskQueueBuilder{
+		Pruner:     a.pruner,
+		Factory:    a.factory,
+		InfoHelper: a.infoHelper,
+		Mapper:     mapper,
+		InvClient:  a.invClient,
+		Client:     client,
+		Destroy:    false,
+	}
+	opts := solver.Options{
+		ServerSideOptions:         options.ServerSideOptions,
+		ReconcileTimeout:          options.ReconcileTimeout,
+		ReconcileFailFast:         options.ReconcileFailFast,
+		FailOnReconcileTimeout:    options.FailOnReconcileTimeout,
+		ForceApply:                options.ForceApply,
+		RecreateOnImmutable:       options.RecreateOnImmutable,
+		Prune:                     !options.NoPrune,
+		DryRunStrategy:            options.DryRunStrategy,
+		PrunePropagationPolicy:    options.PrunePropagationPolicy,
+		PruneTimeout:              options.PruneTimeout,
+		InventoryPolicy:           options.InventoryPolicy,
+		RetryPolicy:               options.RetryPolicy,
+		ApplyConcurrency:          options.ApplyConcurrency,
+		DAGScheduling:             options.DAGScheduling,
+		ExternalDependencyTimeout: options.ExternalDependencyTimeout,
+	}
+	// Build list of apply validation filters.
+	applyFilters := []filter.ValidationFilter{}
+	if options.InventoryPolicy != inventory.AdoptAll {
+		applyFilters = append(applyFilters, filter.InventoryPolicyApplyFilter{
+			Client:    client,
+			Mapper:    mapper,
+			Inv:       invInfo,
+			InvPolicy: options.InventoryPolicy,
 		})
-		if err != nil {
-			handleError(eventChannel, err)
+	}
+
+	pruneFilters, err := a.pruneFilters(invInfo, applyObjs, pruneObjs, options)
+	if err != nil {
+		return nil, err
+	}
+	// Build list of apply mutators.
+	// Share a thread-safe cache with the status poller.
+	resourceCache := cache.NewResourceCacheMap()
+	applyMutators := []mutator.Interface{
+		&mutator.ApplyTimeMutator{
+			Client:        client,
+			Mapper:        mapper,
+			ResourceCache: resourceCache,
+		},
+		mutator.IgnoreFieldsMutator{
+			ServerSideApply: options.ServerSideOptions.ServerSideApply,
+		},
+	}
+	// Build the task queue by appending tasks in the proper order.
+	taskQueue, err := taskBuilder.
+		AppendInvAddTask(invInfo, applyObjs, options.DryRunStrategy).
+		AppendApplyWaitTasks(applyObjs, applyFilters, applyMutators, opts).
+		AppendPruneWaitTasks(pruneObjs, pruneFilters, opts).
+		AppendInvSetTask(invInfo, options.DryRunStrategy).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	// The external dependencies are not applied, but they are polled
+	// so that the objects depending on them can wait for them.
+	externalIds := object.ObjMetadataSet{}
+	if !options.DryRunStrategy.ClientOrServerDryRun() {
+		for _, deps := range graph.ExternalDependencies(applyObjs) {
+			externalIds = externalIds.Union(deps)
+		}
+	}
+	return &applierTaskQueue{
+		invInfo:       invInfo,
+		taskQueue:     taskQueue,
+		applyObjs:     applyObjs,
+		pruneObjs:     pruneObjs,
+		externalIds:   externalIds,
+		applyFilters:  applyFilters,
+		pruneFilters:  pruneFilters,
+		resourceCache: resourceCache,
+	}, nil
+}
+
+// pruneFilters returns the validation filters for the objects to prune.
+func (a *Applier) pruneFilters(invInfo inventory.InventoryInfo, applyObjs, pruneObjs object.UnstructuredSet,
+	options Options) ([]filter.ValidationFilter, error) {
+	pruneFilters := []filter.ValidationFilter{
+		filter.PreventRemoveFilter{},
+		filter.InventoryPolicyFilter{
+			Inv:       invInfo,
+			InvPolicy: options.InventoryPolicy,
+		},
+		filter.LocalNamespacesFilter{
+			LocalNamespaces: localNamespaces(invInfo, object.UnstructuredsToObjMetasOrDie(applyObjs)),
+		},
+	}
+	uidFilter, err := a.pruner.GetInventoryUIDFilter(invInfo, pruneObjs, prune.Options{
+		DryRunStrategy: options.DryRunStrategy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(pruneFilters, uidFilter), nil
+}
+
+// PruneObjects returns the objects that Run would prune when applying the
+// passed objects, together with the validation filters Run checks before
+// pruning each of them. The prune limits of the options are not checked.
+func (a *Applier) PruneObjects(invInfo inventory.InventoryInfo, objects object.UnstructuredSet,
+	options Options) (object.UnstructuredSet, []filter.ValidationFilter, error) {
+	options.MaxPruneCount = 0
+	options.MaxPrunePercent = 0
+	applyObjs, pruneObjs, err := a.prepareObjects(invInfo, objects, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	pruneFilters, err := a.pruneFilters(invInfo, applyObjs, pruneObjs, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pruneObjs, pruneFilters, nil
+}
+
+// runTaskQueue sends the InitEvent and executes the task queue, sending
+// progress and errors on the eventChannel. If the history is enabled,
+// the applied objects are recorded as a new revision afterwards, unless
+// some of them failed to apply.
+func (a *Applier) runTaskQueue(ctx context.Context, q *applierTaskQueue, eventChannel chan event.Event,
+	options Options) {
+	// Send event to inform the caller about the resources that
+	// will be applied/pruned.
+	eventChannel <- event.Event{
+		Type: event.InitType,
+		InitEvent: event.InitEvent{
+			ActionGroups: q.taskQueue.ToActionGroups(),
+		},
+	}
+	// Create a new TaskStatusRunner to execute the taskQueue.
+	klog.V(4).Infoln("applier building TaskStatusRunner...")
+	allIds := object.UnstructuredsToObjMetasOrDie(append(q.applyObjs, q.pruneObjs...))
+	allIds = object.ObjMetadataSet(allIds).Union(q.externalIds)
+	runner := taskrunner.NewTaskStatusRunner(allIds, a.StatusPoller, q.resourceCache)
+	klog.V(4).Infoln("applier running TaskStatusRunner...")
+	err := runner.Run(ctx, q.taskQueue.ToChannel(), eventChannel, taskrunner.Options{
+		PollInterval:                   options.PollInterval,
+		UseCache:                       true,
+		EmitStatusEvents:               options.EmitStatusEvents,
+		CustomStatusReadersFactoryFunc: options.CustomStatusReadersFactoryFunc,
+	})
+	if err != nil {
+		handleError(eventChannel, err)
+		return
+	}
+	if options.HistoryLimit > 0 && !options.DryRunStrategy.ClientOrServerDryRun() && ctx.Err() == nil {
+		if failed := runner.FailedApplies(); len(failed) > 0 {
+			klog.V(4).Infof("not recording inventory history: %d objects failed to apply", len(failed))
 			return
 		}
-	}()
-	return eventChannel
+		if err := a.recordHistory(ctx, q.invInfo, q.applyObjs, options.HistoryLimit); err != nil {
+			handleError(eventChannel, fmt.Errorf("failed to record inventory history: %w", err))
+		}
+	}
+}
+
+// recordHistory stores the applied objects as a new revision in the
+// history of the inventory, owned by the inventory object in the cluster.
+func (a *Applier) recordHistory(ctx context.Context, invInfo inventory.InventoryInfo,
+	applyObjs object.UnstructuredSet, limit int) error {
+	historyClient, err := history.NewClient(a.factory)
+	if err != nil {
+		return err
+	}
+	clusterInv, err := a.invClient.GetClusterInventoryInfo(invInfo, common.DryRunNone)
+	if err != nil {
+		return err
+	}
+	number, err := historyClient.Record(ctx, invInfo, clusterInv, applyObjs, limit)
+	if err != nil {
+		return err
+	}
+	klog.V(4).Infof("recorded inventory history revision %d", number)
+	return nil
 }
 
 type Options struct {
@@ -253,6 +409,38 @@ type Options struct {
 	// how long to wait.
 	ReconcileTimeout time.Duration
 
+	// FailOnReconcileTimeout defines whether the applier reports
+	// applied resources that don't reconcile before their timeout as
+	// an error. If so, a taskrunner.TimeoutError is sent on the event
+	// channel once all the tasks have finished. Otherwise only the
+	// ReconcileTimeout wait events are sent.
+	FailOnReconcileTimeout bool
+
+	// ReconcileFailFast defines whether waiting for the applied resources
+	// to be reconciled should end as soon as any of them reaches the
+	// Failed status, instead of waiting until the ReconcileTimeout.
+	// The remaining apply and prune tasks are then skipped, like on
+	// cancellation, and a taskrunner.ReconcileFailedError with the
+	// failed resources is sent on the event channel.
+	ReconcileFailFast bool
+
+	// ForceApply defines whether objects should be applied even if
+	// they are unchanged since they were last applied. Objects are
+	// unchanged if their content and the values of their fields on the
+	// live objects didn't change. Out-of-band changes the comparison
+	// doesn't detect, e.g. to fields the objects don't set, are only
+	// reverted with ForceApply.
+	ForceApply bool
+
+	// RecreateOnImmutable defines whether objects that fail to apply
+	// because an immutable field was changed should be deleted and
+	// created again. Individual objects can opt in with the
+	// replace-on-immutable annotation. Objects are deleted with the
+	// PrunePropagationPolicy, and fail to apply if they are not deleted
+	// within the PruneTimeout, or task.DefaultReplaceTimeout if it is not
+	// set.
+	RecreateOnImmutable bool
+
 	// PollInterval defines how often we should poll for the status
 	// of resources.
 	PollInterval time.Duration
@@ -279,10 +467,66 @@ type Options struct {
 	// wait.
 	PruneTimeout time.Duration
 
+	// MaxPruneCount defines the maximum number of objects that may be
+	// pruned. If more objects would be pruned, the run fails with a
+	// prune.PruneLimitExceededError before anything is applied or
+	// pruned. There is no limit if this is zero.
+	MaxPruneCount int
+
+	// MaxPrunePercent defines the maximum percentage of the objects in
+	// the inventory that may be pruned, like MaxPruneCount. There is no
+	// limit if this is zero.
+	MaxPrunePercent int
+
 	// InventoryPolicy defines the inventory policy of apply.
 	InventoryPolicy inventory.InventoryPolicy
+
+	// CustomStatusReadersFactoryFunc provides the StatusPoller with
+	// StatusReaders for custom resource types, e.g. from status rules.
+	CustomStatusReadersFactoryFunc func(engine.ClusterReader, meta.RESTMapper) map[schema.GroupKind]engine.StatusReader
+
+	// HistoryLimit defines whether the applied objects should be recorded
+	// in the revision history of the inventory after a successful apply,
+	// and if so, how many revisions should be kept. The history is
+	// disabled if this is zero.
+	HistoryLimit int
+
+	// RetryPolicy defines how applies and prunes that fail with a
+	// transient error, like a conflict or throttling, are retried.
+	// Objects are only reported as failed once the attempts are used
+	// up. Retries are disabled by default.
+	RetryPolicy retry.Policy
+
+	// ApplyConcurrency defines the maximum number of objects that are
+	// applied in parallel within an apply group. The events of every
+	// object are still sent in the order of the objects. The objects are
+	// applied one at a time if this is less than two.
+	ApplyConcurrency int
+
+	// DAGScheduling defines whether each object is applied as soon as
+	// the objects it depends on are reconciled, instead of waiting for
+	// all objects of the previous level of the dependency graph. The
+	// objects are applied and waited on by a single action group.
+	DAGScheduling bool
+
+	// ExternalDependencyTimeout defines how long to wait for the
+	// external dependencies of the applied objects to become Current.
+	// External dependencies are objects referenced by the depends-on
+	// annotation that are not applied with them. Objects whose external
+	// dependencies are not Current in time fail to apply. Defaults to
+	// DefaultExternalDependencyTimeout.
+	ExternalDependencyTimeout time.Duration
+
+	// InventoryLock defines how the inventory is locked, so that
+	// concurrent applies and destroys of the same inventory don't race.
+	// The inventory is not locked if this is nil, or with dry-run.
+	InventoryLock *lock.Options
 }
 
+// DefaultExternalDependencyTimeout is the default time to wait for the
+// external dependencies of the applied objects.
+const DefaultExternalDependencyTimeout = time.Minute
+
 // setDefaults set the options to the default values if they
 // have not been provided.
 func setDefaults(o *Options) {
@@ -292,6 +536,9 @@ func setDefaults(o *Options) {
 	if o.PrunePropagationPolicy == "" {
 		o.PrunePropagationPolicy = metav1.DeletePropagationBackground
 	}
+	if o.ExternalDependencyTimeout == time.Duration(0) {
+		o.ExternalDependencyTimeout = DefaultExternalDependencyTimeout
+	}
 }
 
 func handleError(eventChannel chan event.Event, err error) {
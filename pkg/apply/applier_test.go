@@ -5,8 +5,8 @@ package apply
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -16,6 +16,7 @@ import (
 	"k8s.io/kubectl/pkg/scheme"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
 	pollevent "sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -1254,8 +1255,10 @@ func TestApplier(t *testing.T) {
 				}
 			}
 
-			// sort to allow comparison of multiple apply/prune tasks in the same task group
-			sort.Sort(testutil.GroupedEventsByID(receivedEvents))
+			// The objects of a task group are applied and pruned one at a
+			// time, in the order set by pkg/ordering, and the fake poller
+			// sends the status events in order, so the events are compared
+			// in the order they were received.
 
 			// Validate the rest of the events
 			testutil.AssertEqual(t, tc.expectedEvents, receivedEvents,
@@ -1429,25 +1432,26 @@ func TestApplierCancel(t *testing.T) {
 						Type:      event.Finished, // TODO: add Cancelled event type
 					},
 				},
-				// TODO: Update the inventory after cancellation
-				// {
-				// 	// InvSetTask start
-				// 	EventType: event.ActionGroupType,
-				// 	ActionGroupEvent: &testutil.ExpActionGroupEvent{
-				// 		Action:    event.InventoryAction,
-				// 		GroupName: "inventory-set-0",
-				// 		Type:      event.Started,
-				// 	},
-				// },
-				// {
-				// 	// InvSetTask finished
-				// 	EventType: event.ActionGroupType,
-				// 	ActionGroupEvent: &testutil.ExpActionGroupEvent{
-				// 		Action:    event.InventoryAction,
-				// 		GroupName: "inventory-set-0",
-				// 		Type:      event.Finished,
-				// 	},
-				// },
+				// The inventory is still updated after cancellation, to record
+				// which objects were actually applied.
+				{
+					// InvSetTask start
+					EventType: event.ActionGroupType,
+					ActionGroupEvent: &testutil.ExpActionGroupEvent{
+						Action:    event.InventoryAction,
+						GroupName: "inventory-set-0",
+						Type:      event.Started,
+					},
+				},
+				{
+					// InvSetTask finished
+					EventType: event.ActionGroupType,
+					ActionGroupEvent: &testutil.ExpActionGroupEvent{
+						Action:    event.InventoryAction,
+						GroupName: "inventory-set-0",
+						Type:      event.Finished,
+					},
+				},
 				{
 					// Error
 					EventType: event.ErrorType,
@@ -1707,6 +1711,40 @@ func TestApplierCancel(t *testing.T) {
 	}
 }
 
+func TestApplierInventoryLocked(t *testing.T) {
+	invInfo := inventoryInfo{
+		name:      "abc-123",
+		namespace: "default",
+		id:        "test",
+	}
+	resources := object.UnstructuredSet{
+		testutil.Unstructured(t, resources["deployment"]),
+	}
+	applier := newTestApplier(t, invInfo, resources, object.UnstructuredSet{}, newFakePoller(nil))
+
+	// Another apply of the same inventory holds the lock.
+	dynamicClient, err := applier.factory.DynamicClient()
+	require.NoError(t, err)
+	lockClient := &lock.Client{DynamicClient: dynamicClient}
+	held, err := lockClient.Acquire(context.Background(), invInfo.toWrapped(), lock.Options{Holder: "other"})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, held.Release(context.Background()))
+	}()
+
+	var events []event.Event
+	for e := range applier.Run(context.Background(), invInfo.toWrapped(), resources, Options{
+		InventoryLock: &lock.Options{},
+	}) {
+		events = append(events, e)
+	}
+	require.Equal(t, 1, len(events))
+	assert.Equal(t, event.ErrorType, events[0].Type)
+	var heldErr *lock.LockHeldError
+	require.True(t, errors.As(events[0].ErrorEvent.Err, &heldErr), "expected LockHeldError, got %v", events[0].ErrorEvent.Err)
+	assert.Equal(t, "other", heldErr.Holder)
+}
+
 func TestReadAndPrepareObjectsNilInv(t *testing.T) {
 	applier := Applier{}
 	_, _, err := applier.prepareObjects(nil, object.UnstructuredSet{}, Options{})
@@ -1728,6 +1766,8 @@ func TestReadAndPrepareObjects(t *testing.T) {
 		invInfo inventoryInfo
 		// resources input to applier
 		resources object.UnstructuredSet
+		// options input to applier
+		options Options
 		// expected objects to apply
 		applyObjs object.UnstructuredSet
 		// expected objects to prune
@@ -1804,6 +1844,35 @@ func TestReadAndPrepareObjects(t *testing.T) {
 			applyObjs: object.UnstructuredSet{obj1, obj2, clusterScopedObj},
 			pruneObjs: object.UnstructuredSet{},
 		},
+		"prune exceeds max prune percent": {
+			clusterObjs: object.UnstructuredSet{obj2},
+			invInfo: inventoryInfo{
+				name:      inventory.Name(),
+				namespace: inventory.Namespace(),
+				id:        inventory.ID(),
+				set: object.ObjMetadataSet{
+					object.UnstructuredToObjMetaOrDie(obj2),
+				},
+			},
+			resources: object.UnstructuredSet{obj1},
+			options:   Options{MaxPrunePercent: 50},
+			isError:   true,
+		},
+		"max prune percent ignored without prune": {
+			clusterObjs: object.UnstructuredSet{obj2},
+			invInfo: inventoryInfo{
+				name:      inventory.Name(),
+				namespace: inventory.Namespace(),
+				id:        inventory.ID(),
+				set: object.ObjMetadataSet{
+					object.UnstructuredToObjMetaOrDie(obj2),
+				},
+			},
+			resources: object.UnstructuredSet{obj1},
+			options:   Options{NoPrune: true, MaxPrunePercent: 50},
+			applyObjs: object.UnstructuredSet{obj1},
+			pruneObjs: object.UnstructuredSet{obj2},
+		},
 	}
 
 	for name, tc := range testCases {
@@ -1816,7 +1885,7 @@ func TestReadAndPrepareObjects(t *testing.T) {
 				newFakePoller([]pollevent.Event{}),
 			)
 
-			applyObjs, pruneObjs, err := applier.prepareObjects(tc.invInfo.toWrapped(), tc.resources, Options{})
+			applyObjs, pruneObjs, err := applier.prepareObjects(tc.invInfo.toWrapped(), tc.resources, tc.options)
 			if tc.isError {
 				assert.Error(t, err)
 				return
@@ -74,6 +74,12 @@ func newTestApplier(
 	}
 
 	invClient := newTestInventory(t, tf, infoHelper)
+	if p, ok := statusPoller.(*fakePoller); ok {
+		invClient = &pollerSyncInventoryClient{
+			InventoryClient: invClient,
+			poller:          p,
+		}
+	}
 
 	applier, err := NewApplier(tf, invClient)
 	require.NoError(t, err)
@@ -407,6 +413,7 @@ func (n *nsHandler) handle(t *testing.T, req *http.Request) (*http.Response, boo
 
 type fakePoller struct {
 	start  chan struct{}
+	sent   chan struct{}
 	events []pollevent.Event
 }
 
@@ -414,6 +421,7 @@ func newFakePoller(statusEvents []pollevent.Event) *fakePoller {
 	return &fakePoller{
 		events: statusEvents,
 		start:  make(chan struct{}),
+		sent:   make(chan struct{}),
 	}
 }
 
@@ -422,21 +430,60 @@ func (f *fakePoller) Start() {
 	close(f.start)
 }
 
+// WaitSent blocks until all the events have been sent, if the poller
+// has been started.
+func (f *fakePoller) WaitSent() {
+	select {
+	case <-f.start:
+		<-f.sent
+	default:
+	}
+}
+
 func (f *fakePoller) Poll(ctx context.Context, _ object.ObjMetadataSet, _ polling.Options) <-chan pollevent.Event {
 	eventChannel := make(chan pollevent.Event)
 	go func() {
 		defer close(eventChannel)
-		// wait until started to send the events
-		<-f.start
-		for _, f := range f.events {
-			eventChannel <- f
-		}
+		f.send(ctx, eventChannel)
 		// wait until cancelled to close the event channel and exit
 		<-ctx.Done()
 	}()
 	return eventChannel
 }
 
+// send sends the events once the poller has been started, unless the
+// context is cancelled first.
+func (f *fakePoller) send(ctx context.Context, eventChannel chan<- pollevent.Event) {
+	defer close(f.sent)
+	// wait until started to send the events
+	select {
+	case <-f.start:
+	case <-ctx.Done():
+		return
+	}
+	for _, e := range f.events {
+		select {
+		case eventChannel <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pollerSyncInventoryClient is an InventoryClient that waits for the
+// fakePoller to send all of its status events before the inventory is
+// set, so the run can't finish before they are received, even when the
+// wait tasks don't need them.
+type pollerSyncInventoryClient struct {
+	inventory.InventoryClient
+	poller *fakePoller
+}
+
+func (c *pollerSyncInventoryClient) ReplaceEntries(inv inventory.InventoryInfo, entries inventory.Entries, dryRun common.DryRunStrategy) error {
+	c.poller.WaitSent()
+	return c.InventoryClient.ReplaceEntries(inv, entries, dryRun)
+}
+
 type fakeInfoHelper struct {
 	factory *cmdtesting.TestFactory
 }
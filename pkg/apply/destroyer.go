@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/klog/v2"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"sigs.k8s.io/cli-utils/pkg/apply/cache"
@@ -16,10 +18,13 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply/filter"
 	"sigs.k8s.io/cli-utils/pkg/apply/poller"
 	"sigs.k8s.io/cli-utils/pkg/apply/prune"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/solver"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
 	"sigs.k8s.io/cli-utils/pkg/object"
 	statusfactory "sigs.k8s.io/cli-utils/pkg/util/factory"
 )
@@ -80,6 +85,19 @@ type DestroyerOptions struct {
 	// PollInterval defines how often we should poll for the status
 	// of resources.
 	PollInterval time.Duration
+
+	// CustomStatusReadersFactoryFunc provides the StatusPoller with
+	// StatusReaders for custom resource types, e.g. from status rules.
+	CustomStatusReadersFactoryFunc func(engine.ClusterReader, meta.RESTMapper) map[schema.GroupKind]engine.StatusReader
+
+	// RetryPolicy defines how deletes that fail with a transient error
+	// are retried. Retries are disabled by default.
+	RetryPolicy retry.Policy
+
+	// InventoryLock defines how the inventory is locked, so that
+	// concurrent applies and destroys of the same inventory don't race.
+	// The inventory is not locked if this is nil, or with dry-run.
+	InventoryLock *lock.Options
 }
 
 func setDestroyerDefaults(o *DestroyerOptions) {
@@ -99,6 +117,14 @@ func (d *Destroyer) Run(ctx context.Context, inv inventory.InventoryInfo, option
 	setDestroyerDefaults(&options)
 	go func() {
 		defer close(eventChannel)
+		// Lock the inventory before it is read, and release it after
+		// the inventory has been deleted.
+		ctx, unlock, err := lockInventory(ctx, d.factory, inv, options.InventoryLock, options.DryRunStrategy)
+		if err != nil {
+			handleError(eventChannel, err)
+			return
+		}
+		defer unlock()
 		// Retrieve the objects to be deleted from the cluster. Second parameter is empty
 		// because no local objects returns all inventory objects for deletion.
 		emptyLocalObjs := object.UnstructuredSet{}
@@ -127,6 +153,14 @@ func (d *Destroyer) Run(ctx context.Context, inv inventory.InventoryInfo, option
 			PruneTimeout:           options.DeleteTimeout,
 			DryRunStrategy:         options.DryRunStrategy,
 			PrunePropagationPolicy: options.DeletePropagationPolicy,
+			RetryPolicy:            options.RetryPolicy,
+		}
+		uidFilter, err := d.pruner.GetInventoryUIDFilter(inv, deleteObjs, prune.Options{
+			DryRunStrategy: options.DryRunStrategy,
+		})
+		if err != nil {
+			handleError(eventChannel, err)
+			return
 		}
 		deleteFilters := []filter.ValidationFilter{
 			filter.PreventRemoveFilter{},
@@ -134,6 +168,7 @@ func (d *Destroyer) Run(ctx context.Context, inv inventory.InventoryInfo, option
 				Inv:       inv,
 				InvPolicy: options.InventoryPolicy,
 			},
+			uidFilter,
 		}
 		// Build the ordered set of tasks to execute.
 		taskQueue, err := taskBuilder.
@@ -160,9 +195,10 @@ func (d *Destroyer) Run(ctx context.Context, inv inventory.InventoryInfo, option
 		klog.V(4).Infoln("destroyer running TaskStatusRunner...")
 		// TODO(seans): Make the poll interval configurable like the applier.
 		err = runner.Run(ctx, taskQueue.ToChannel(), eventChannel, taskrunner.Options{
-			UseCache:         true,
-			PollInterval:     options.PollInterval,
-			EmitStatusEvents: options.EmitStatusEvents,
+			UseCache:                       true,
+			PollInterval:                   options.PollInterval,
+			EmitStatusEvents:               options.EmitStatusEvents,
+			CustomStatusReadersFactoryFunc: options.CustomStatusReadersFactoryFunc,
 		})
 		if err != nil {
 			handleError(eventChannel, err)
@@ -5,13 +5,16 @@ package apply
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
 	pollevent "sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -153,6 +156,25 @@ func TestDestroyerCancel(t *testing.T) {
 				},
 				// Inventory cannot be deleted, because the objects still exist,
 				// even tho they've been deleted (ex: blocked by finalizer).
+				// The DeleteInvTask runs, but keeps the inventory.
+				{
+					// DeleteInvTask start
+					EventType: event.ActionGroupType,
+					ActionGroupEvent: &testutil.ExpActionGroupEvent{
+						Action:    event.InventoryAction,
+						GroupName: "delete-inventory-0",
+						Type:      event.Started,
+					},
+				},
+				{
+					// DeleteInvTask finished
+					EventType: event.ActionGroupType,
+					ActionGroupEvent: &testutil.ExpActionGroupEvent{
+						Action:    event.InventoryAction,
+						GroupName: "delete-inventory-0",
+						Type:      event.Finished,
+					},
+				},
 				{
 					// Error
 					EventType: event.ErrorType,
@@ -389,3 +411,34 @@ func TestDestroyerCancel(t *testing.T) {
 		})
 	}
 }
+
+func TestDestroyerInventoryLocked(t *testing.T) {
+	invInfo := inventoryInfo{
+		name:      "abc-123",
+		namespace: "default",
+		id:        "test",
+	}
+	destroyer := newTestDestroyer(t, invInfo, object.UnstructuredSet{}, newFakePoller(nil))
+
+	// An apply of the same inventory holds the lock.
+	dynamicClient, err := destroyer.factory.DynamicClient()
+	require.NoError(t, err)
+	lockClient := &lock.Client{DynamicClient: dynamicClient}
+	held, err := lockClient.Acquire(context.Background(), invInfo.toWrapped(), lock.Options{Holder: "other"})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, held.Release(context.Background()))
+	}()
+
+	var events []event.Event
+	for e := range destroyer.Run(context.Background(), invInfo.toWrapped(), DestroyerOptions{
+		InventoryLock: &lock.Options{},
+	}) {
+		events = append(events, e)
+	}
+	require.Equal(t, 1, len(events))
+	assert.Equal(t, event.ErrorType, events[0].Type)
+	var heldErr *lock.LockHeldError
+	require.True(t, errors.As(events[0].ErrorEvent.Err, &heldErr), "expected LockHeldError, got %v", events[0].ErrorEvent.Err)
+	assert.Equal(t, "other", heldErr.Holder)
+}
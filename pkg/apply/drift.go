@@ -0,0 +1,261 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/ordering"
+)
+
+// DriftType describes how a live object differs from the local package.
+type DriftType string
+
+const (
+	// DriftNone means the live object matches the local object.
+	DriftNone DriftType = "InSync"
+	// DriftModified means fields of the live object differ from the
+	// local object.
+	DriftModified DriftType = "Modified"
+	// DriftMissing means the local object does not exist in the cluster.
+	DriftMissing DriftType = "Missing"
+	// DriftExtra means the object is in the inventory, but not in the
+	// local package.
+	DriftExtra DriftType = "Extra"
+)
+
+// ObjectDrift is the drift of a single object.
+type ObjectDrift struct {
+	Identifier object.ObjMetadata
+	Type       DriftType
+	// Fields are the paths of the modified fields, like spec.replicas.
+	// Only set for DriftModified.
+	Fields []string
+}
+
+// DriftReport is the result of a drift detection.
+type DriftReport struct {
+	// Objects holds the drift of the local objects, in apply order,
+	// followed by the extra objects in the inventory.
+	Objects []ObjectDrift
+}
+
+// HasDrift returns true if any object has drifted.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Drifted()) > 0
+}
+
+// Drifted returns the objects that have drifted.
+func (r *DriftReport) Drifted() []ObjectDrift {
+	var drifted []ObjectDrift
+	for _, d := range r.Objects {
+		if d.Type != DriftNone {
+			drifted = append(drifted, d)
+		}
+	}
+	return drifted
+}
+
+// DriftOptions defines the parameters of a drift detection.
+type DriftOptions struct {
+	// FieldManager is the field manager used for the server-side apply
+	// dry-run the local objects are compared with. It should be the
+	// field manager the objects are applied with. If this is not
+	// provided, the default field manager is used.
+	FieldManager string
+}
+
+// NewDriftDetector returns a new DriftDetector.
+func NewDriftDetector(factory cmdutil.Factory, invClient inventory.InventoryClient) (*DriftDetector, error) {
+	client, err := factory.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := factory.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return &DriftDetector{
+		invClient: invClient,
+		client:    client,
+		mapper:    mapper,
+	}, nil
+}
+
+// DriftDetector compares the live objects in the cluster with the local
+// objects of a package, without changing anything. Every local object is
+// applied with a server-side apply dry-run, and the result is compared
+// with the live object. The fields that would change are the fields that
+// have drifted from the local package, e.g. because of manual edits.
+type DriftDetector struct {
+	invClient inventory.InventoryClient
+	client    dynamic.Interface
+	mapper    meta.RESTMapper
+}
+
+// Detect returns the drift between the local objects and the live objects
+// of the inventory.
+func (d *DriftDetector) Detect(ctx context.Context, invInfo inventory.InventoryInfo, objs object.UnstructuredSet,
+	options DriftOptions) (*DriftReport, error) {
+	if invInfo == nil {
+		return nil, fmt.Errorf("the local inventory can't be nil")
+	}
+	if err := inventory.ValidateNoInventory(objs); err != nil {
+		return nil, err
+	}
+	if options.FieldManager == "" {
+		options.FieldManager = common.DefaultFieldManager
+	}
+	invIDs, err := d.invClient.GetClusterObjs(invInfo, common.DryRunNone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare copies of the objects as they would be applied, so the
+	// inventory annotation added by the applier does not show up as
+	// drift. The apply hash annotation is not compared.
+	localObjs := make(object.UnstructuredSet, 0, len(objs))
+	for _, obj := range objs {
+		obj = obj.DeepCopy()
+		inventory.AddInventoryIDAnnotation(obj, invInfo)
+		localObjs = append(localObjs, obj)
+	}
+	sort.Sort(ordering.SortableUnstructureds(localObjs))
+
+	report := &DriftReport{}
+	for _, obj := range localObjs {
+		drift, err := d.detectObject(ctx, obj, options)
+		if err != nil {
+			return nil, err
+		}
+		report.Objects = append(report.Objects, drift)
+	}
+	for _, id := range invIDs.Diff(object.UnstructuredsToObjMetasOrDie(localObjs)) {
+		report.Objects = append(report.Objects, ObjectDrift{
+			Identifier: id,
+			Type:       DriftExtra,
+		})
+	}
+	return report, nil
+}
+
+// detectObject returns the drift of a single local object.
+func (d *DriftDetector) detectObject(ctx context.Context, obj *unstructured.Unstructured,
+	options DriftOptions) (ObjectDrift, error) {
+	id := object.UnstructuredToObjMetaOrDie(obj)
+	drift := ObjectDrift{
+		Identifier: id,
+		Type:       DriftNone,
+	}
+	mapping, err := d.mapper.RESTMapping(id.GroupKind, obj.GroupVersionKind().Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			drift.Type = DriftMissing
+			return drift, nil
+		}
+		return drift, err
+	}
+	client := d.client.Resource(mapping.Resource).Namespace(id.Namespace)
+
+	live, err := client.Get(ctx, id.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			drift.Type = DriftMissing
+			return drift, nil
+		}
+		return drift, err
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return drift, err
+	}
+	// The dry-run does not force conflicts: a field owned by another
+	// field manager with a different value has drifted too.
+	force := false
+	applied, err := client.Patch(ctx, id.Name, types.ApplyPatchType, data, metav1.PatchOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: options.FieldManager,
+		Force:        &force,
+	})
+	if conflictErr, ok := applyerror.AsApplyConflictError(err); ok {
+		for _, c := range conflictErr.Conflicts {
+			drift.Fields = append(drift.Fields, strings.TrimPrefix(c.Field, "."))
+		}
+		sort.Strings(drift.Fields)
+		klog.V(4).Infof("drift detected in %s: conflicts in %v", id, drift.Fields)
+		drift.Type = DriftModified
+		return drift, nil
+	}
+	if err != nil {
+		return drift, fmt.Errorf("server-side apply dry-run of %s failed: %w", id, err)
+	}
+
+	drift.Fields = diffFields("", live.Object, applied.Object)
+	if len(drift.Fields) > 0 {
+		klog.V(4).Infof("drift detected in %s: %v", id, drift.Fields)
+		drift.Type = DriftModified
+	}
+	return drift, nil
+}
+
+// ignoredDriftFields are the fields that change on every apply, so they
+// are not compared.
+var ignoredDriftFields = map[string]bool{
+	"metadata.managedFields":                             true,
+	"metadata.resourceVersion":                           true,
+	"metadata.generation":                                true,
+	"metadata.annotations." + common.ApplyHashAnnotation: true,
+	"status": true,
+}
+
+// diffFields returns the sorted paths of the fields that differ between
+// the live and the applied object. Lists are compared as a whole.
+func diffFields(prefix string, live, applied map[string]interface{}) []string {
+	var fields []string
+	keys := make(map[string]bool)
+	for k := range live {
+		keys[k] = true
+	}
+	for k := range applied {
+		keys[k] = true
+	}
+	for k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if ignoredDriftFields[path] {
+			continue
+		}
+		liveMap, liveIsMap := live[k].(map[string]interface{})
+		appliedMap, appliedIsMap := applied[k].(map[string]interface{})
+		if liveIsMap && appliedIsMap {
+			fields = append(fields, diffFields(path, liveMap, appliedMap)...)
+			continue
+		}
+		if !reflect.DeepEqual(live[k], applied[k]) {
+			fields = append(fields, path)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/object/applyhash"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+func TestDriftDetector(t *testing.T) {
+	invInfo := inventoryInfo{
+		name:      "abc-123",
+		namespace: "test-namespace",
+		id:        "test",
+	}
+	deployment := testutil.Unstructured(t, resources["deployment"])
+	secret := testutil.Unstructured(t, resources["secret"])
+	obj1 := testutil.Unstructured(t, resources["obj1"])
+	obj2 := testutil.Unstructured(t, resources["obj2"])
+
+	// The live objects were applied from the local objects, after which
+	// the deployment was scaled manually.
+	liveDeployment := prepareLiveObject(t, deployment, invInfo)
+	require.NoError(t, unstructured.SetNestedField(liveDeployment.Object, int64(3), "spec", "replicas"))
+	// The apply hash of the live secret is outdated, which is no drift.
+	liveSecret := prepareLiveObject(t, secret, invInfo)
+	liveSecret.SetAnnotations(map[string]string{
+		inventory.OwningInventoryKey: invInfo.id,
+		common.ApplyHashAnnotation:   "outdated",
+	})
+
+	fakeClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme, liveDeployment, liveSecret)
+	fakeClient.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		// Emulate a server-side apply dry-run by merging the applied
+		// configuration into the live object.
+		patchAction := action.(clienttesting.PatchAction)
+		live, err := fakeClient.Tracker().Get(patchAction.GetResource(), patchAction.GetNamespace(),
+			patchAction.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		liveMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+		if err != nil {
+			return true, nil, err
+		}
+		patch := &unstructured.Unstructured{}
+		if err := patch.UnmarshalJSON(patchAction.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		mergeMaps(liveMap, patch.Object)
+		return true, &unstructured.Unstructured{Object: liveMap}, nil
+	})
+
+	detector := &DriftDetector{
+		invClient: inventory.NewFakeInventoryClient(object.ObjMetadataSet{
+			object.UnstructuredToObjMetaOrDie(deployment),
+			object.UnstructuredToObjMetaOrDie(secret),
+			object.UnstructuredToObjMetaOrDie(obj2),
+		}),
+		client: fakeClient,
+		mapper: testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme,
+			scheme.Scheme.PrioritizedVersionsAllGroups()...),
+	}
+
+	report, err := detector.Detect(context.Background(), invInfo.toWrapped(),
+		object.UnstructuredSet{deployment, secret, obj1}, DriftOptions{})
+	require.NoError(t, err)
+
+	byID := make(map[object.ObjMetadata]ObjectDrift)
+	for _, d := range report.Objects {
+		byID[d.Identifier] = d
+	}
+	assert.Len(t, report.Objects, 4)
+	assert.Equal(t, ObjectDrift{
+		Identifier: object.UnstructuredToObjMetaOrDie(deployment),
+		Type:       DriftModified,
+		Fields:     []string{"spec.replicas"},
+	}, byID[object.UnstructuredToObjMetaOrDie(deployment)])
+	assert.Equal(t, DriftNone, byID[object.UnstructuredToObjMetaOrDie(secret)].Type)
+	assert.Equal(t, DriftMissing, byID[object.UnstructuredToObjMetaOrDie(obj1)].Type)
+	assert.Equal(t, DriftExtra, byID[object.UnstructuredToObjMetaOrDie(obj2)].Type)
+	assert.True(t, report.HasDrift())
+	assert.Len(t, report.Drifted(), 3)
+
+	// The local objects are not changed.
+	assert.Empty(t, deployment.GetAnnotations())
+}
+
+func TestDriftDetector_Conflict(t *testing.T) {
+	invInfo := inventoryInfo{
+		name:      "abc-123",
+		namespace: "test-namespace",
+		id:        "test",
+	}
+	deployment := testutil.Unstructured(t, resources["deployment"])
+	liveDeployment := prepareLiveObject(t, deployment, invInfo)
+
+	fakeClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme, liveDeployment)
+	fakeClient.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		// Another field manager changed the replicas, so the dry-run
+		// conflicts.
+		return true, nil, &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status: metav1.StatusFailure,
+			Code:   http.StatusConflict,
+			Reason: metav1.StatusReasonConflict,
+			Details: &metav1.StatusDetails{
+				Causes: []metav1.StatusCause{
+					{
+						Type:    metav1.CauseTypeFieldManagerConflict,
+						Message: `conflict with "kubectl-edit" using apps/v1`,
+						Field:   ".spec.replicas",
+					},
+				},
+			},
+		}}
+	})
+
+	detector := &DriftDetector{
+		invClient: inventory.NewFakeInventoryClient(object.ObjMetadataSet{
+			object.UnstructuredToObjMetaOrDie(deployment),
+		}),
+		client: fakeClient,
+		mapper: testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme,
+			scheme.Scheme.PrioritizedVersionsAllGroups()...),
+	}
+
+	report, err := detector.Detect(context.Background(), invInfo.toWrapped(),
+		object.UnstructuredSet{deployment}, DriftOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []ObjectDrift{
+		{
+			Identifier: object.UnstructuredToObjMetaOrDie(deployment),
+			Type:       DriftModified,
+			Fields:     []string{"spec.replicas"},
+		},
+	}, report.Objects)
+}
+
+func TestDiffFields(t *testing.T) {
+	testCases := map[string]struct {
+		live     map[string]interface{}
+		applied  map[string]interface{}
+		expected []string
+	}{
+		"equal": {
+			live:     map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			applied:  map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1)}},
+			expected: nil,
+		},
+		"modified, added and removed fields": {
+			live: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"replicas": int64(1),
+					"paused":   true,
+				},
+			},
+			applied: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"replicas": int64(2),
+					"selector": map[string]interface{}{},
+				},
+			},
+			expected: []string{"spec.paused", "spec.replicas", "spec.selector"},
+		},
+		"lists are compared as a whole": {
+			live: map[string]interface{}{
+				"spec": map[string]interface{}{"args": []interface{}{"a", "b"}},
+			},
+			applied: map[string]interface{}{
+				"spec": map[string]interface{}{"args": []interface{}{"a"}},
+			},
+			expected: []string{"spec.args"},
+		},
+		"ignored fields": {
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"resourceVersion": "1",
+					"generation":      int64(1),
+					"annotations": map[string]interface{}{
+						common.ApplyHashAnnotation: "1",
+					},
+				},
+				"status": map[string]interface{}{"ready": true},
+			},
+			applied: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"resourceVersion": "2",
+					"generation":      int64(2),
+					"annotations":     map[string]interface{}{},
+				},
+			},
+			expected: nil,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.expected, diffFields("", tc.live, tc.applied))
+		})
+	}
+}
+
+// prepareLiveObject returns a copy of the object with the annotations
+// the applier adds to applied objects.
+func prepareLiveObject(t *testing.T, obj *unstructured.Unstructured, invInfo inventoryInfo) *unstructured.Unstructured {
+	live := obj.DeepCopy()
+	inventory.AddInventoryIDAnnotation(live, invInfo.toWrapped())
+	live, _, err := applyhash.Set(live)
+	require.NoError(t, err)
+	return live
+}
+
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
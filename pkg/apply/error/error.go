@@ -2,6 +2,20 @@
 // SPDX-License-Identifier: Apache-2.0
 package error
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/object/dependson"
+)
+
 type UnknownTypeError struct {
 	err error
 }
@@ -37,3 +51,160 @@ func (e *InitializeApplyOptionError) Error() string {
 func NewInitializeApplyOptionError(err error) *InitializeApplyOptionError {
 	return &InitializeApplyOptionError{err: err}
 }
+
+// FieldConflict is a conflict over the ownership of a field, reported by
+// the server when a server-side apply would change a field that is
+// owned by another field manager.
+type FieldConflict struct {
+	// Manager is the field manager that owns the field.
+	Manager string
+	// Field is the path of the field, like .spec.replicas.
+	Field string
+	// Message is the message of the conflict, as returned by the server.
+	Message string
+}
+
+// ApplyConflictError is a server-side apply error caused by field
+// ownership conflicts.
+type ApplyConflictError struct {
+	Conflicts []FieldConflict
+	err       error
+}
+
+func (e *ApplyConflictError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ApplyConflictError) Unwrap() error {
+	return e.err
+}
+
+// Summary returns the conflicting fields and their managers on a single
+// line, like: .spec.replicas (kube-controller-manager)
+func (e *ApplyConflictError) Summary() string {
+	fields := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		if c.Manager == "" {
+			fields = append(fields, c.Field)
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s (%s)", c.Field, c.Manager))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// NewApplyConflictError returns an ApplyConflictError for the error with
+// the passed conflicts.
+func NewApplyConflictError(err error, conflicts []FieldConflict) *ApplyConflictError {
+	return &ApplyConflictError{
+		Conflicts: conflicts,
+		err:       err,
+	}
+}
+
+// conflictManagerRegexp extracts the field manager from the message of a
+// conflict cause, like: conflict with "kubectl" using apps/v1
+var conflictManagerRegexp = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// AsApplyConflictError returns an ApplyConflictError with the conflicts
+// in the causes of the passed error, if it is a server-side apply
+// conflict. Returns false otherwise.
+func AsApplyConflictError(err error) (*ApplyConflictError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var conflictErr *ApplyConflictError
+	if errors.As(err, &conflictErr) {
+		return conflictErr, true
+	}
+	var statusErr *apierrors.StatusError
+	if !errors.As(err, &statusErr) || !apierrors.IsConflict(statusErr) {
+		return nil, false
+	}
+	details := statusErr.Status().Details
+	if details == nil {
+		return nil, false
+	}
+	var conflicts []FieldConflict
+	for _, cause := range details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflict := FieldConflict{
+			Field:   cause.Field,
+			Message: cause.Message,
+		}
+		if match := conflictManagerRegexp.FindStringSubmatch(cause.Message); match != nil {
+			conflict.Manager = match[1]
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	if len(conflicts) == 0 {
+		return nil, false
+	}
+	return NewApplyConflictError(err, conflicts), true
+}
+
+// ObjectApplyConflict is the ApplyConflictError of an object.
+type ObjectApplyConflict struct {
+	Identifier object.ObjMetadata
+	Err        *ApplyConflictError
+}
+
+// ApplyConflictsError is the ApplyConflictErrors of multiple objects,
+// returned once all the objects were processed, when the conflicts of an
+// object must not stop the processing of the other objects.
+type ApplyConflictsError struct {
+	Objects []ObjectApplyConflict
+}
+
+func (e *ApplyConflictsError) Error() string {
+	objs := make([]string, 0, len(e.Objects))
+	for _, o := range e.Objects {
+		objs = append(objs, fmt.Sprintf("%s: %s", o.Identifier, o.Err.Summary()))
+	}
+	return fmt.Sprintf("server-side apply conflicts in %d objects: %s", len(e.Objects), strings.Join(objs, "; "))
+}
+
+// Add adds the conflicts of an object.
+func (e *ApplyConflictsError) Add(id object.ObjMetadata, err *ApplyConflictError) {
+	e.Objects = append(e.Objects, ObjectApplyConflict{
+		Identifier: id,
+		Err:        err,
+	})
+}
+
+// DriftDetectedError is returned by commands when the live objects
+// have drifted from the local package.
+type DriftDetectedError struct {
+	// Drifted are the objects that have drifted.
+	Drifted object.ObjMetadataSet
+}
+
+func (e *DriftDetectedError) Error() string {
+	return fmt.Sprintf("drift detected in %d objects", len(e.Drifted))
+}
+
+// ExternalDependencyError is the error of an object that was not applied,
+// because an object it depends on, that is not applied with it, was not
+// reconciled before the timeout.
+type ExternalDependencyError struct {
+	// Dependency is the external dependency.
+	Dependency object.ObjMetadata
+	// Status is the last known status of the dependency.
+	Status status.Status
+	// Timeout is how long the dependency was waited on.
+	Timeout time.Duration
+}
+
+func (e *ExternalDependencyError) Error() string {
+	dep, err := dependson.FormatObjMetadata(e.Dependency)
+	if err != nil {
+		dep = e.Dependency.String()
+	}
+	if e.Status == status.NotFoundStatus {
+		return fmt.Sprintf("external dependency %q not found after %s: it must be applied before its dependents",
+			dep, e.Timeout)
+	}
+	return fmt.Sprintf("external dependency %q not reconciled after %s: status %s", dep, e.Timeout, e.Status)
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package error
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestAsApplyConflictError(t *testing.T) {
+	conflictErr := &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status: metav1.StatusFailure,
+		Code:   409,
+		Reason: metav1.StatusReasonConflict,
+		Details: &metav1.StatusDetails{
+			Causes: []metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "kube-controller-manager" using apps/v1`,
+					Field:   ".spec.replicas",
+				},
+			},
+		},
+	}}
+
+	testCases := map[string]struct {
+		err               error
+		expectedConflicts []FieldConflict
+	}{
+		"nil error": {
+			err: nil,
+		},
+		"other error": {
+			err: fmt.Errorf("this is a test"),
+		},
+		"conflict without field manager causes": {
+			err: apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "foo",
+				fmt.Errorf("the object has been modified")),
+		},
+		"field manager conflict": {
+			err: conflictErr,
+			expectedConflicts: []FieldConflict{
+				{
+					Manager: "kube-controller-manager",
+					Field:   ".spec.replicas",
+					Message: `conflict with "kube-controller-manager" using apps/v1`,
+				},
+			},
+		},
+		"wrapped field manager conflict": {
+			err: fmt.Errorf("apply failed: %w", conflictErr),
+			expectedConflicts: []FieldConflict{
+				{
+					Manager: "kube-controller-manager",
+					Field:   ".spec.replicas",
+					Message: `conflict with "kube-controller-manager" using apps/v1`,
+				},
+			},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			applyConflictErr, ok := AsApplyConflictError(tc.err)
+			if tc.expectedConflicts == nil {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tc.expectedConflicts, applyConflictErr.Conflicts)
+			assert.Equal(t, tc.err.Error(), applyConflictErr.Error())
+		})
+	}
+}
+
+func TestApplyConflictErrorSummary(t *testing.T) {
+	err := NewApplyConflictError(fmt.Errorf("this is a test"), []FieldConflict{
+		{Manager: "kube-controller-manager", Field: ".spec.replicas"},
+		{Field: ".spec.paused"},
+	})
+	assert.Equal(t, ".spec.replicas (kube-controller-manager), .spec.paused", err.Summary())
+	assert.Equal(t, "this is a test", err.Error())
+}
+
+func TestExternalDependencyError(t *testing.T) {
+	secret := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Kind: "Secret"},
+		Namespace: "default",
+		Name:      "foo",
+	}
+
+	testCases := map[string]struct {
+		err      *ExternalDependencyError
+		expected string
+	}{
+		"not found": {
+			err: &ExternalDependencyError{
+				Dependency: secret,
+				Status:     status.NotFoundStatus,
+				Timeout:    time.Minute,
+			},
+			expected: `external dependency "/namespaces/default/Secret/foo" not found after 1m0s: ` +
+				`it must be applied before its dependents`,
+		},
+		"not reconciled": {
+			err: &ExternalDependencyError{
+				Dependency: secret,
+				Status:     status.InProgressStatus,
+				Timeout:    time.Minute,
+			},
+			expected: `external dependency "/namespaces/default/Secret/foo" not reconciled after 1m0s: status InProgress`,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.err.Error())
+		})
+	}
+}
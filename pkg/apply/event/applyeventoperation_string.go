@@ -13,11 +13,13 @@ func _() {
 	_ = x[Created-2]
 	_ = x[Unchanged-3]
 	_ = x[Configured-4]
+	_ = x[ApplySkipped-5]
+	_ = x[Replaced-6]
 }
 
-const _ApplyEventOperation_name = "ApplyUnspecifiedServersideAppliedCreatedUnchangedConfigured"
+const _ApplyEventOperation_name = "ApplyUnspecifiedServersideAppliedCreatedUnchangedConfiguredApplySkippedReplaced"
 
-var _ApplyEventOperation_index = [...]uint8{0, 16, 33, 40, 49, 59}
+var _ApplyEventOperation_index = [...]uint8{0, 16, 33, 40, 49, 59, 71, 79}
 
 func (i ApplyEventOperation) String() string {
 	if i < 0 || i >= ApplyEventOperation(len(_ApplyEventOperation_index)-1) {
@@ -25,6 +25,7 @@ const (
 	PruneType
 	DeleteType
 	WaitType
+	RetryType
 )
 
 // Event is the type of the objects that will be returned through
@@ -65,6 +66,10 @@ type Event struct {
 
 	// WaitEvent contains information about any errors encountered in a WaitTask.
 	WaitEvent WaitEvent
+
+	// RetryEvent contains information about an operation on an object
+	// that failed with a transient error and will be retried.
+	RetryEvent RetryEvent
 }
 
 // String returns a string suitable for logging
@@ -88,6 +93,8 @@ func (e Event) String() string {
 		sb.WriteString(e.DeleteEvent.String())
 	case WaitType:
 		sb.WriteString(e.WaitEvent.String())
+	case RetryType:
+		sb.WriteString(e.RetryEvent.String())
 	}
 	sb.WriteString(" }")
 	return sb.String()
@@ -160,6 +167,7 @@ const (
 	Reconciled
 	ReconcileSkipped // Skipped
 	ReconcileTimeout // Timeout
+	ReconcileFailed  // Failed
 )
 
 type WaitEvent struct {
@@ -203,6 +211,8 @@ const (
 	Created
 	Unchanged
 	Configured
+	ApplySkipped
+	Replaced
 )
 
 type ApplyEvent struct {
@@ -210,13 +220,29 @@ type ApplyEvent struct {
 	Identifier object.ObjMetadata
 	Operation  ApplyEventOperation
 	Resource   *unstructured.Unstructured
-	Error      error
+	// If apply is skipped, this reason string explains why
+	Reason string
+	Error  error
+	// Migration is set if the object was migrated from client-side
+	// apply to server-side apply before it was applied.
+	Migration *ClientSideMigration
+}
+
+// ClientSideMigration describes the migration of an object from
+// client-side apply to server-side apply.
+type ClientSideMigration struct {
+	// FieldManagers are the client-side apply field managers whose
+	// fields were moved to the server-side apply field manager.
+	FieldManagers []string
+	// LastAppliedRemoved is true if the last-applied-configuration
+	// annotation was removed from the object.
+	LastAppliedRemoved bool
 }
 
 // String returns a string suitable for logging
 func (ae ApplyEvent) String() string {
-	return fmt.Sprintf("ApplyEvent{ GroupName: %q, Operation: %q, Identifier: %q, Error: %q }",
-		ae.GroupName, ae.Operation, ae.Identifier, ae.Error)
+	return fmt.Sprintf("ApplyEvent{ GroupName: %q, Operation: %q, Identifier: %q, Reason: %q, Error: %q }",
+		ae.GroupName, ae.Operation, ae.Identifier, ae.Reason, ae.Error)
 }
 
 type StatusEvent struct {
@@ -289,3 +315,24 @@ func (de DeleteEvent) String() string {
 	return fmt.Sprintf("DeleteEvent{ GroupName: %q, Operation: %q, Identifier: %q, Reason: %q, Error: %q }",
 		de.GroupName, de.Operation, de.Identifier, de.Reason, de.Error)
 }
+
+// RetryEvent is sent before an operation on an object that failed with a
+// transient error is retried.
+type RetryEvent struct {
+	GroupName  string
+	Identifier object.ObjMetadata
+	// Action is the operation that is retried.
+	Action ResourceAction
+	// Attempt is the number of the next attempt, starting at 2.
+	Attempt int
+	// MaxAttempts is the maximum number of attempts.
+	MaxAttempts int
+	// Error is the error of the failed attempt.
+	Error error
+}
+
+// String returns a string suitable for logging
+func (re RetryEvent) String() string {
+	return fmt.Sprintf("RetryEvent{ GroupName: %q, Action: %q, Identifier: %q, Attempt: %d, MaxAttempts: %d, Error: %q }",
+		re.GroupName, re.Action, re.Identifier, re.Attempt, re.MaxAttempts, re.Error)
+}
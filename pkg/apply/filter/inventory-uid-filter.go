@@ -0,0 +1,39 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// InventoryUIDFilter implements ValidationFilter interface to determine
+// if an object should not be pruned (deleted) because its UID differs
+// from the UID recorded in the inventory, which means the object was
+// deleted and recreated by someone else since it was applied.
+type InventoryUIDFilter struct {
+	Entries inventory.Entries
+}
+
+// Name returns a filter identifier for logging.
+func (iuf InventoryUIDFilter) Name() string {
+	return "InventoryUIDFilter"
+}
+
+// Filter returns true if the passed object should NOT be pruned (deleted)
+// because its UID differs from the UID recorded in the inventory. Objects
+// without a recorded UID are not filtered. Never returns an error.
+func (iuf InventoryUIDFilter) Filter(obj *unstructured.Unstructured) (bool, string, error) {
+	id := object.UnstructuredToObjMetaOrDie(obj)
+	recordedUID := iuf.Entries[id].UID
+	if recordedUID == "" || obj.GetUID() == "" || obj.GetUID() == recordedUID {
+		return false, "", nil
+	}
+	reason := fmt.Sprintf("resource UID %q differs from the UID recorded in the inventory (UID: %q)",
+		obj.GetUID(), recordedUID)
+	return true, reason, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+func TestInventoryUIDFilter(t *testing.T) {
+	tests := map[string]struct {
+		recordedUID string
+		objUID      string
+		filtered    bool
+	}{
+		"No recorded UID, object is not filtered": {
+			recordedUID: "",
+			objUID:      "foo",
+			filtered:    false,
+		},
+		"Empty object UID, object is not filtered": {
+			recordedUID: "foo",
+			objUID:      "",
+			filtered:    false,
+		},
+		"Object UID matches recorded UID, object is not filtered": {
+			recordedUID: "foo",
+			objUID:      "foo",
+			filtered:    false,
+		},
+		"Object UID differs from recorded UID, object is filtered": {
+			recordedUID: "foo",
+			objUID:      "bar",
+			filtered:    true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter := InventoryUIDFilter{
+				Entries: inventory.Entries{
+					object.UnstructuredToObjMetaOrDie(defaultObj): {UID: types.UID(tc.recordedUID)},
+				},
+			}
+			obj := defaultObj.DeepCopy()
+			obj.SetUID(types.UID(tc.objUID))
+			actual, reason, err := filter.Filter(obj)
+			if err != nil {
+				t.Fatalf("InventoryUIDFilter unexpected error (%s)", err)
+			}
+			if tc.filtered != actual {
+				t.Errorf("InventoryUIDFilter expected filter (%t), got (%t)", tc.filtered, actual)
+			}
+			if tc.filtered && len(reason) == 0 {
+				t.Errorf("InventoryUIDFilter filtered; expected but missing Reason")
+			}
+			if !tc.filtered && len(reason) > 0 {
+				t.Errorf("InventoryUIDFilter not filtered; received unexpected Reason: %s", reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"context"
+
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+)
+
+// lockInventory takes the lock of the inventory, unless the lock options
+// are nil or this is a dry-run. Returns a context that is cancelled if the
+// lock is lost while it is held, and a function that releases the lock.
+func lockInventory(ctx context.Context, factory cmdutil.Factory, invInfo inventory.InventoryInfo,
+	o *lock.Options, dryRun common.DryRunStrategy) (context.Context, func(), error) {
+	if o == nil || dryRun.ClientOrServerDryRun() {
+		return ctx, func() {}, nil
+	}
+	lockClient, err := lock.NewClient(factory)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := lockClient.Acquire(ctx, invInfo, *o)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := l.Context(ctx)
+	return ctx, func() {
+		cancel()
+		// The lock is released even if the context was cancelled. If
+		// releasing fails, the lock expires after its TTL.
+		if err := l.Release(context.Background()); err != nil {
+			klog.Warningf("failed to release the lock of inventory %s/%s: %v",
+				invInfo.Namespace(), invInfo.Name(), err)
+		}
+	}, nil
+}
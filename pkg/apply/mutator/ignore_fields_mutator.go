@@ -0,0 +1,82 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package mutator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// IgnoreFieldsMutator mutates a resource by removing the fields specified
+// by the ignore-fields annotation, so they are not changed by apply.
+//
+// With server-side apply, a field owned only by the applier's field
+// manager, because it was applied before being ignored, is deleted from
+// the live object when it's removed from the applied object. Ignored
+// fields are only kept if they were never applied, or if another field
+// manager, e.g. a controller updating them, also owns them.
+//
+// Implements the Mutator interface
+type IgnoreFieldsMutator struct {
+	// ServerSideApply must be true for the ignore-fields annotation to be
+	// used. A client-side apply would delete the removed fields that are
+	// in the last-applied-configuration annotation of the live object.
+	ServerSideApply bool
+}
+
+// Name returns a mutator identifier for logging.
+func (ifm IgnoreFieldsMutator) Name() string {
+	return "IgnoreFieldsMutator"
+}
+
+// Mutate parses the ignore-fields annotation and removes the fields that
+// match each of the JSONPath expressions from the supplied object.
+// Returns true with a reason, if any field was removed.
+func (ifm IgnoreFieldsMutator) Mutate(_ context.Context, obj *unstructured.Unstructured) (bool, string, error) {
+	paths, err := ReadIgnoreFieldsAnnotation(obj)
+	if err != nil || len(paths) == 0 {
+		return false, "", err
+	}
+	if !ifm.ServerSideApply {
+		return false, "", fmt.Errorf("the %s annotation requires server-side apply",
+			common.IgnoreFieldsAnnotation)
+	}
+
+	var removed []string
+	for _, path := range paths {
+		found, err := jsonpath.Delete(obj.Object, path)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to remove ignored field (%s): %w", path, err)
+		}
+		if found > 0 {
+			klog.V(5).Infof("removed ignored field (%s) from %s/%s", path, obj.GetNamespace(), obj.GetName())
+			removed = append(removed, path)
+		}
+	}
+	if len(removed) == 0 {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("ignored fields removed: %s", strings.Join(removed, ", ")), nil
+}
+
+// ReadIgnoreFieldsAnnotation returns the JSONPath expressions of the
+// ignore-fields annotation of the object, if any.
+func ReadIgnoreFieldsAnnotation(obj *unstructured.Unstructured) ([]string, error) {
+	value, found := obj.GetAnnotations()[common.IgnoreFieldsAnnotation]
+	if !found {
+		return nil, nil
+	}
+	var paths []string
+	if err := yaml.Unmarshal([]byte(value), &paths); err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation: %q: %v", common.IgnoreFieldsAnnotation, value, err)
+	}
+	return paths, nil
+}
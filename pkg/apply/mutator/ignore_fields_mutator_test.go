@@ -0,0 +1,132 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package mutator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ktestutil "sigs.k8s.io/cli-utils/pkg/kstatus/polling/testutil"
+)
+
+var ignoreFieldsDeployment1y = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment-name
+  namespace: deployment-namespace
+  annotations:
+    cli-utils.sigs.k8s.io/ignore-fields: |
+      - $.spec.replicas
+      - $.spec.template.spec.containers[?(@.name=="app")].image
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: example:1.0
+      - name: sidecar
+        image: sidecar:1.0
+`
+
+var ignoreFieldsDeployment2y = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment-name
+  namespace: deployment-namespace
+spec:
+  replicas: 3
+`
+
+var ignoreFieldsDeployment3y = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment-name
+  namespace: deployment-namespace
+  annotations:
+    cli-utils.sigs.k8s.io/ignore-fields: "not a list"
+spec:
+  replicas: 3
+`
+
+func TestIgnoreFieldsMutator(t *testing.T) {
+	testCases := map[string]struct {
+		obj             *unstructured.Unstructured
+		clientSideApply bool
+		expectedMutated bool
+		expectedReason  string
+		expectedErr     bool
+		expectedSpec    map[string]interface{}
+	}{
+		"ignored fields are removed": {
+			obj:             ktestutil.YamlToUnstructured(t, ignoreFieldsDeployment1y),
+			expectedMutated: true,
+			expectedReason: "ignored fields removed: $.spec.replicas, " +
+				`$.spec.template.spec.containers[?(@.name=="app")].image`,
+			expectedSpec: map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "app"},
+							map[string]interface{}{"name": "sidecar", "image": "sidecar:1.0"},
+						},
+					},
+				},
+			},
+		},
+		"no annotation": {
+			obj:             ktestutil.YamlToUnstructured(t, ignoreFieldsDeployment2y),
+			expectedMutated: false,
+			expectedSpec:    map[string]interface{}{"replicas": int64(3)},
+		},
+		"client-side apply is an error": {
+			obj:             ktestutil.YamlToUnstructured(t, ignoreFieldsDeployment1y),
+			clientSideApply: true,
+			expectedErr:     true,
+			expectedSpec: map[string]interface{}{
+				"replicas": int64(3),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "app", "image": "example:1.0"},
+							map[string]interface{}{"name": "sidecar", "image": "sidecar:1.0"},
+						},
+					},
+				},
+			},
+		},
+		"client-side apply without annotation": {
+			obj:             ktestutil.YamlToUnstructured(t, ignoreFieldsDeployment2y),
+			clientSideApply: true,
+			expectedMutated: false,
+			expectedSpec:    map[string]interface{}{"replicas": int64(3)},
+		},
+		"invalid annotation": {
+			obj:          ktestutil.YamlToUnstructured(t, ignoreFieldsDeployment3y),
+			expectedErr:  true,
+			expectedSpec: map[string]interface{}{"replicas": int64(3)},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ifm := IgnoreFieldsMutator{ServerSideApply: !tc.clientSideApply}
+			mutated, reason, err := ifm.Mutate(context.TODO(), tc.obj)
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedMutated, mutated)
+			assert.Equal(t, tc.expectedReason, reason)
+			assert.Equal(t, tc.expectedSpec, tc.obj.Object["spec"])
+		})
+	}
+}
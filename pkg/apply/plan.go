@@ -0,0 +1,357 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/apply/filter"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/yaml"
+)
+
+// Plan is a serializable description of what an apply would do. It holds
+// the ordered action groups, the objects to apply and prune, the verdicts
+// of the validation filters and a snapshot of the inventory it was
+// computed from. A Plan is created with Applier.Plan and executed with
+// Applier.ApplyPlan.
+type Plan struct {
+	// Inventory is the snapshot of the inventory the plan was computed from.
+	Inventory PlanInventory `json:"inventory"`
+
+	// Options are the options that shape the plan. They are used again
+	// when the plan is applied.
+	Options PlanOptions `json:"options"`
+
+	// ActionGroups are the ordered groups of actions the plan performs.
+	ActionGroups []PlanActionGroup `json:"actionGroups"`
+
+	// ApplyObjects are the objects that will be applied.
+	ApplyObjects []*unstructured.Unstructured `json:"applyObjects,omitempty"`
+
+	// PruneObjects are the objects that will be pruned, as they were in
+	// the cluster when the plan was created.
+	PruneObjects []*unstructured.Unstructured `json:"pruneObjects,omitempty"`
+
+	// FilterVerdicts lists the objects that a validation filter would
+	// skip, or failed to validate, when the plan was created.
+	FilterVerdicts []PlanFilterVerdict `json:"filterVerdicts,omitempty"`
+}
+
+// PlanInventory is the inventory snapshot stored in a Plan.
+type PlanInventory struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	ID         string `json:"id,omitempty"`
+	// Objects are the identifiers of the objects stored in the inventory
+	// in the cluster, sorted.
+	Objects []string `json:"objects,omitempty"`
+}
+
+// PlanOptions are the subset of the apply Options that determine the
+// content of a Plan.
+type PlanOptions struct {
+	ServerSideOptions common.ServerSideOptions  `json:"serverSideOptions"`
+	NoPrune           bool                      `json:"noPrune,omitempty"`
+	InventoryPolicy   inventory.InventoryPolicy `json:"inventoryPolicy"`
+}
+
+// PlanActionGroup is the serializable form of an event.ActionGroup.
+type PlanActionGroup struct {
+	Name        string   `json:"name"`
+	Action      string   `json:"action"`
+	Identifiers []string `json:"identifiers,omitempty"`
+}
+
+// PlanFilterVerdict records the result of a validation filter for
+// a single object.
+type PlanFilterVerdict struct {
+	Identifier string `json:"identifier"`
+	Action     string `json:"action"`
+	Filter     string `json:"filter"`
+	Reason     string `json:"reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Object returns the inventory object the plan was created for, without
+// the objects it stores, so the plan can be applied without reading the
+// package again. Returns an error if the plan doesn't record the kind of
+// the inventory object.
+func (pi PlanInventory) Object() (*unstructured.Unstructured, error) {
+	if pi.APIVersion == "" || pi.Kind == "" {
+		return nil, fmt.Errorf("plan doesn't record the kind of inventory %s/%s", pi.Namespace, pi.Name)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(pi.APIVersion)
+	obj.SetKind(pi.Kind)
+	obj.SetName(pi.Name)
+	obj.SetNamespace(pi.Namespace)
+	if pi.ID != "" {
+		obj.SetLabels(map[string]string{common.InventoryLabel: pi.ID})
+	}
+	return obj, nil
+}
+
+// StalePlanError is returned when a plan can't be applied because the
+// live state no longer matches the state the plan was computed from.
+type StalePlanError struct {
+	Reason string
+}
+
+func (e StalePlanError) Error() string {
+	return fmt.Sprintf("plan is stale: %s", e.Reason)
+}
+
+// Plan computes what Run would do with the same arguments, without
+// changing anything in the cluster. The DryRunStrategy in the options
+// is ignored.
+func (a *Applier) Plan(invInfo inventory.InventoryInfo, objects object.UnstructuredSet, options Options) (*Plan, error) {
+	setDefaults(&options)
+	options.DryRunStrategy = common.DryRunNone
+	if err := a.validateObjects(objects); err != nil {
+		return nil, err
+	}
+	applyObjs, pruneObjs, err := a.prepareObjects(invInfo, objects, options)
+	if err != nil {
+		return nil, err
+	}
+	invIDs, err := a.invClient.GetClusterObjs(invInfo, common.DryRunNone)
+	if err != nil {
+		return nil, err
+	}
+	invGVK, err := inventoryGVK(invInfo)
+	if err != nil {
+		return nil, err
+	}
+	q, err := a.buildTaskQueue(invInfo, applyObjs, pruneObjs, options)
+	if err != nil {
+		return nil, err
+	}
+	if options.NoPrune {
+		pruneObjs = nil
+	}
+	verdicts := filterVerdicts(event.ApplyAction, applyObjs, q.applyFilters)
+	if !options.NoPrune {
+		verdicts = append(verdicts, filterVerdicts(event.PruneAction, pruneObjs, q.pruneFilters)...)
+	}
+	return &Plan{
+		Inventory: PlanInventory{
+			APIVersion: invGVK.GroupVersion().String(),
+			Kind:       invGVK.Kind,
+			Name:       invInfo.Name(),
+			Namespace:  invInfo.Namespace(),
+			ID:         invInfo.ID(),
+			Objects:    idStrings(invIDs),
+		},
+		Options: PlanOptions{
+			ServerSideOptions: options.ServerSideOptions,
+			NoPrune:           options.NoPrune,
+			InventoryPolicy:   options.InventoryPolicy,
+		},
+		ActionGroups:   planActionGroups(q.taskQueue.ToActionGroups()),
+		ApplyObjects:   applyObjs,
+		PruneObjects:   pruneObjs,
+		FilterVerdicts: verdicts,
+	}, nil
+}
+
+// ApplyPlan executes a plan created by Plan. Before anything is changed
+// in the cluster, it verifies that the inventory matches the inventory
+// snapshot of the plan, and that the plan would still result in the same
+// action groups. If not, a StalePlanError is sent on the event channel.
+// The ServerSideOptions, NoPrune and InventoryPolicy options are taken
+// from the plan. Dry-run is not supported.
+func (a *Applier) ApplyPlan(ctx context.Context, invInfo inventory.InventoryInfo, plan *Plan, options Options) <-chan event.Event {
+	klog.V(4).Infof("apply plan for %d objects", len(plan.ApplyObjects))
+	eventChannel := make(chan event.Event)
+	setDefaults(&options)
+	options.ServerSideOptions = plan.Options.ServerSideOptions
+	options.NoPrune = plan.Options.NoPrune
+	options.InventoryPolicy = plan.Options.InventoryPolicy
+	go func() {
+		defer close(eventChannel)
+
+		if options.DryRunStrategy.ClientOrServerDryRun() {
+			handleError(eventChannel, fmt.Errorf("a plan can't be applied with dry-run"))
+			return
+		}
+		ctx, unlock, err := lockInventory(ctx, a.factory, invInfo, options.InventoryLock, options.DryRunStrategy)
+		if err != nil {
+			handleError(eventChannel, err)
+			return
+		}
+		defer unlock()
+		if err := a.verifyPlanInventory(invInfo, plan); err != nil {
+			handleError(eventChannel, err)
+			return
+		}
+		objects := object.UnstructuredSet(plan.ApplyObjects)
+		if err := a.validateObjects(objects); err != nil {
+			handleError(eventChannel, err)
+			return
+		}
+		applyObjs, pruneObjs, err := a.prepareObjects(invInfo, objects, options)
+		if err != nil {
+			handleError(eventChannel, err)
+			return
+		}
+		q, err := a.buildTaskQueue(invInfo, applyObjs, pruneObjs, options)
+		if err != nil {
+			handleError(eventChannel, err)
+			return
+		}
+		actionGroups := planActionGroups(q.taskQueue.ToActionGroups())
+		if !reflect.DeepEqual(actionGroups, plan.ActionGroups) {
+			handleError(eventChannel, StalePlanError{
+				Reason: "the action groups computed from the cluster differ from the plan",
+			})
+			return
+		}
+		a.runTaskQueue(ctx, q, eventChannel, options)
+	}()
+	return eventChannel
+}
+
+// verifyPlanInventory returns a StalePlanError if the passed inventory
+// or the objects it stores in the cluster differ from the inventory
+// snapshot in the plan.
+func (a *Applier) verifyPlanInventory(invInfo inventory.InventoryInfo, plan *Plan) error {
+	if invInfo == nil {
+		return fmt.Errorf("the local inventory can't be nil")
+	}
+	if invInfo.Name() != plan.Inventory.Name || invInfo.Namespace() != plan.Inventory.Namespace ||
+		invInfo.ID() != plan.Inventory.ID {
+		return StalePlanError{
+			Reason: fmt.Sprintf("plan was created for inventory %s/%s (id: %q), not %s/%s (id: %q)",
+				plan.Inventory.Namespace, plan.Inventory.Name, plan.Inventory.ID,
+				invInfo.Namespace(), invInfo.Name(), invInfo.ID()),
+		}
+	}
+	invIDs, err := a.invClient.GetClusterObjs(invInfo, common.DryRunNone)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(idStrings(invIDs), plan.Inventory.Objects) {
+		return StalePlanError{
+			Reason: "the inventory in the cluster changed since the plan was created",
+		}
+	}
+	return nil
+}
+
+// inventoryGVK returns the kind of the object wrapped by the inventory,
+// or an empty kind if the inventory doesn't wrap an object.
+func inventoryGVK(invInfo inventory.InventoryInfo) (schema.GroupVersionKind, error) {
+	inv, ok := invInfo.(inventory.Inventory)
+	if !ok {
+		return schema.GroupVersionKind{}, nil
+	}
+	obj, err := inv.GetObject()
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	return obj.GroupVersionKind(), nil
+}
+
+// filterVerdicts runs the filters against the objects, the same way the
+// apply and prune tasks do, and returns the verdicts for the objects
+// that would be skipped or fail validation.
+func filterVerdicts(action event.ResourceAction, objs object.UnstructuredSet,
+	filters []filter.ValidationFilter) []PlanFilterVerdict {
+	var verdicts []PlanFilterVerdict
+	for _, obj := range objs {
+		id := object.UnstructuredToObjMetaOrDie(obj)
+		for _, f := range filters {
+			filtered, reason, err := f.Filter(obj)
+			if err != nil {
+				verdicts = append(verdicts, PlanFilterVerdict{
+					Identifier: id.String(),
+					Action:     action.String(),
+					Filter:     f.Name(),
+					Error:      err.Error(),
+				})
+				break
+			}
+			if filtered {
+				verdicts = append(verdicts, PlanFilterVerdict{
+					Identifier: id.String(),
+					Action:     action.String(),
+					Filter:     f.Name(),
+					Reason:     reason,
+				})
+				break
+			}
+		}
+	}
+	return verdicts
+}
+
+func planActionGroups(actionGroups []event.ActionGroup) []PlanActionGroup {
+	planGroups := make([]PlanActionGroup, 0, len(actionGroups))
+	for _, ag := range actionGroups {
+		planGroups = append(planGroups, PlanActionGroup{
+			Name:        ag.Name,
+			Action:      ag.Action.String(),
+			Identifiers: idStrings(ag.Identifiers),
+		})
+	}
+	return planGroups
+}
+
+// idStrings returns the string form of the ids, sorted.
+func idStrings(ids object.ObjMetadataSet) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	strs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strs = append(strs, id.String())
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// WritePlan writes the plan to the file at path. The plan is written as
+// JSON if the file has the ".json" extension, and as YAML otherwise.
+func WritePlan(path string, plan *Plan) error {
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(plan, "", "  ")
+	} else {
+		data, err = yaml.Marshal(plan)
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// ReadPlan reads a plan written by WritePlan, in either JSON or YAML.
+func ReadPlan(path string) (*Plan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{}
+	if err := yaml.Unmarshal(data, plan); err != nil {
+		return nil, fmt.Errorf("failed to read plan %q: %w", path, err)
+	}
+	return plan, nil
+}
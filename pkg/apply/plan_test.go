@@ -0,0 +1,170 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+func TestPlan(t *testing.T) {
+	obj1 := testutil.Unstructured(t, resources["obj1"])
+	obj2 := testutil.Unstructured(t, resources["obj2"])
+	invInfo := inventoryInfo{
+		name:      "abc-123",
+		namespace: "test-namespace",
+		id:        "test",
+		set: object.ObjMetadataSet{
+			object.UnstructuredToObjMetaOrDie(obj2),
+		},
+	}
+	applier := newTestApplier(t, invInfo, object.UnstructuredSet{obj1}, object.UnstructuredSet{obj2},
+		newFakePoller(nil))
+
+	plan, err := applier.Plan(invInfo.toWrapped(), object.UnstructuredSet{obj1}, Options{
+		InventoryPolicy: inventory.AdoptIfNoInventory,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, PlanInventory{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       "abc-123",
+		Namespace:  "test-namespace",
+		ID:         "test",
+		Objects:    []string{object.UnstructuredToObjMetaOrDie(obj2).String()},
+	}, plan.Inventory)
+	invObj, err := plan.Inventory.Object()
+	require.NoError(t, err)
+	assert.Equal(t, inventory.InvInfoToConfigMap(invInfo.toWrapped()), invObj)
+	assert.Equal(t, inventory.AdoptIfNoInventory, plan.Options.InventoryPolicy)
+	assert.Equal(t, object.UnstructuredsToObjMetasOrDie(plan.ApplyObjects),
+		[]object.ObjMetadata{object.UnstructuredToObjMetaOrDie(obj1)})
+	assert.Equal(t, object.UnstructuredsToObjMetasOrDie(plan.PruneObjects),
+		[]object.ObjMetadata{object.UnstructuredToObjMetaOrDie(obj2)})
+
+	var actions []string
+	for _, ag := range plan.ActionGroups {
+		actions = append(actions, ag.Action)
+	}
+	assert.Equal(t, []string{
+		event.InventoryAction.String(),
+		event.ApplyAction.String(),
+		event.WaitAction.String(),
+		event.PruneAction.String(),
+		event.WaitAction.String(),
+		event.InventoryAction.String(),
+	}, actions)
+}
+
+func TestWriteAndReadPlan(t *testing.T) {
+	plan := &Plan{
+		Inventory: PlanInventory{
+			Name:      "abc-123",
+			Namespace: "test-namespace",
+			ID:        "test",
+			Objects:   []string{"test-namespace_obj2__Pod"},
+		},
+		Options: PlanOptions{
+			NoPrune:         true,
+			InventoryPolicy: inventory.AdoptAll,
+		},
+		ActionGroups: []PlanActionGroup{
+			{
+				Name:        "apply-0",
+				Action:      event.ApplyAction.String(),
+				Identifiers: []string{"test-namespace_obj1__Pod"},
+			},
+		},
+		ApplyObjects: []*unstructured.Unstructured{
+			testutil.Unstructured(t, resources["obj1"]),
+		},
+		FilterVerdicts: []PlanFilterVerdict{
+			{
+				Identifier: "test-namespace_obj1__Pod",
+				Action:     event.ApplyAction.String(),
+				Filter:     "InventoryPolicyApplyFilter",
+				Reason:     "inventory policy prevented apply",
+			},
+		},
+	}
+
+	for _, name := range []string{"plan.json", "plan.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, WritePlan(path, plan))
+			readPlan, err := ReadPlan(path)
+			require.NoError(t, err)
+			assert.Equal(t, plan, readPlan)
+		})
+	}
+}
+
+func TestApplyPlanStale(t *testing.T) {
+	obj1 := testutil.Unstructured(t, resources["obj1"])
+	obj2 := testutil.Unstructured(t, resources["obj2"])
+
+	testCases := map[string]struct {
+		// inventory when the plan is created
+		planInvInfo inventoryInfo
+		// inventory when the plan is applied
+		invInfo inventoryInfo
+	}{
+		"object added to inventory": {
+			planInvInfo: inventoryInfo{
+				name:      "abc-123",
+				namespace: "test-namespace",
+				id:        "test",
+			},
+			invInfo: inventoryInfo{
+				name:      "abc-123",
+				namespace: "test-namespace",
+				id:        "test",
+				set: object.ObjMetadataSet{
+					object.UnstructuredToObjMetaOrDie(obj2),
+				},
+			},
+		},
+		"different inventory id": {
+			planInvInfo: inventoryInfo{
+				name:      "abc-123",
+				namespace: "test-namespace",
+				id:        "test",
+			},
+			invInfo: inventoryInfo{
+				name:      "abc-123",
+				namespace: "test-namespace",
+				id:        "other",
+			},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			planApplier := newTestApplier(t, tc.planInvInfo, object.UnstructuredSet{obj1},
+				object.UnstructuredSet{}, newFakePoller(nil))
+			plan, err := planApplier.Plan(tc.planInvInfo.toWrapped(), object.UnstructuredSet{obj1}, Options{})
+			require.NoError(t, err)
+
+			applier := newTestApplier(t, tc.invInfo, object.UnstructuredSet{obj1},
+				object.UnstructuredSet{obj2}, newFakePoller(nil))
+			var events []event.Event
+			for e := range applier.ApplyPlan(context.TODO(), tc.invInfo.toWrapped(), plan, Options{}) {
+				events = append(events, e)
+			}
+			require.Len(t, events, 1)
+			assert.Equal(t, event.ErrorType, events[0].Type)
+			assert.IsType(t, StalePlanError{}, events[0].ErrorEvent.Err)
+		})
+	}
+}
@@ -16,6 +16,7 @@ type EventFactory interface {
 	CreateSuccessEvent(obj *unstructured.Unstructured) event.Event
 	CreateSkippedEvent(obj *unstructured.Unstructured, reason string) event.Event
 	CreateFailedEvent(id object.ObjMetadata, err error) event.Event
+	CreateRetryEvent(id object.ObjMetadata, attempt, maxAttempts int, err error) event.Event
 }
 
 // CreateEventFactory returns the correct concrete version of
@@ -73,6 +74,20 @@ func (pef PruneEventFactory) CreateFailedEvent(id object.ObjMetadata, err error)
 	}
 }
 
+func (pef PruneEventFactory) CreateRetryEvent(id object.ObjMetadata, attempt, maxAttempts int, err error) event.Event {
+	return event.Event{
+		Type: event.RetryType,
+		RetryEvent: event.RetryEvent{
+			GroupName:   pef.groupName,
+			Identifier:  id,
+			Action:      event.PruneAction,
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Error:       err,
+		},
+	}
+}
+
 // DeleteEventFactory implements EventFactory interface as a concrete
 // representation of for delete events.
 type DeleteEventFactory struct {
@@ -114,3 +129,17 @@ func (def DeleteEventFactory) CreateFailedEvent(id object.ObjMetadata, err error
 		},
 	}
 }
+
+func (def DeleteEventFactory) CreateRetryEvent(id object.ObjMetadata, attempt, maxAttempts int, err error) event.Event {
+	return event.Event{
+		Type: event.RetryType,
+		RetryEvent: event.RetryEvent{
+			GroupName:   def.groupName,
+			Identifier:  id,
+			Action:      event.DeleteAction,
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Error:       err,
+		},
+	}
+}
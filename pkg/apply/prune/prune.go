@@ -13,7 +13,9 @@ package prune
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -23,6 +25,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/cmd/util"
 	"sigs.k8s.io/cli-utils/pkg/apply/filter"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
@@ -69,6 +72,46 @@ type Options struct {
 	// True if we are destroying, which deletes the inventory object
 	// as well (possibly) the inventory namespace.
 	Destroy bool
+
+	// MaxPruneCount is the maximum number of objects GetPruneObjs may
+	// return. Zero means no limit.
+	MaxPruneCount int
+
+	// MaxPrunePercent is the maximum percentage of the objects in the
+	// inventory GetPruneObjs may return. Zero means no limit.
+	MaxPrunePercent int
+
+	// RetryPolicy defines how deletes that fail with a transient error
+	// are retried. Retries are disabled by default.
+	RetryPolicy retry.Policy
+}
+
+// PruneLimitExceededError is returned by GetPruneObjs when the set of
+// prune objects exceeds the MaxPruneCount or MaxPrunePercent limits.
+type PruneLimitExceededError struct {
+	// Objects are the objects that would have been pruned.
+	Objects object.ObjMetadataSet
+	// InventorySize is the number of objects in the inventory.
+	InventorySize int
+	MaxCount      int
+	MaxPercent    int
+}
+
+func (e *PruneLimitExceededError) Error() string {
+	return fmt.Sprintf("refusing to prune %d of %d inventory objects: exceeds the prune limit (%s)",
+		len(e.Objects), e.InventorySize, e.Limit())
+}
+
+// Limit returns a description of the configured prune limits.
+func (e *PruneLimitExceededError) Limit() string {
+	var limits []string
+	if e.MaxCount > 0 {
+		limits = append(limits, fmt.Sprintf("max %d objects", e.MaxCount))
+	}
+	if e.MaxPercent > 0 {
+		limits = append(limits, fmt.Sprintf("max %d%% of the inventory", e.MaxPercent))
+	}
+	return strings.Join(limits, ", ")
 }
 
 // Prune deletes the set of passed objects. A prune skip/failure is
@@ -94,9 +137,21 @@ func (p *Pruner) Prune(
 	opts Options,
 ) error {
 	eventFactory := CreateEventFactory(opts.Destroy, taskName)
+	ctx := taskContext.Context()
 	// Iterate through objects to prune (delete). If an object is not pruned
 	// and we need to keep it in the inventory, we must capture the prune failure.
-	for _, obj := range objs {
+	for i, obj := range objs {
+		// Stop pruning objects if the task runner was cancelled. The
+		// remaining objects are skipped, so they are kept in the inventory.
+		if err := ctx.Err(); err != nil {
+			klog.V(4).Infof("prune interrupted (task: %q): %v", taskName, err)
+			for _, skippedObj := range objs[i:] {
+				taskContext.SendEvent(eventFactory.CreateSkippedEvent(skippedObj,
+					fmt.Sprintf("prune interrupted: %v", err)))
+				taskContext.AddSkippedDelete(object.UnstructuredToObjMetaOrDie(skippedObj))
+			}
+			break
+		}
 		id := object.UnstructuredToObjMetaOrDie(obj)
 		klog.V(5).Infof("evaluating prune filters (object: %q)", id)
 		// Check filters to see if we're prevented from pruning/deleting object.
@@ -145,8 +200,21 @@ func (p *Pruner) Prune(
 		// Filters passed--actually delete object if not dry run.
 		if !opts.DryRunStrategy.ClientOrServerDryRun() {
 			klog.V(4).Infof("deleting object (object: %q)", id)
-			err := p.deleteObject(id, metav1.DeleteOptions{
-				PropagationPolicy: &opts.PropagationPolicy,
+			attempts := 0
+			err := opts.RetryPolicy.Do(ctx, func() error {
+				attempts++
+				err := p.deleteObject(id, metav1.DeleteOptions{
+					PropagationPolicy: &opts.PropagationPolicy,
+				})
+				// A failed attempt may still have deleted the object,
+				// so the object not being found on a retry is a success.
+				if attempts > 1 && apierrors.IsNotFound(err) {
+					klog.V(4).Infof("object not found on delete retry (object: %q)", id)
+					return nil
+				}
+				return err
+			}, func(attempt int, err error) {
+				taskContext.SendEvent(eventFactory.CreateRetryEvent(id, attempt, opts.RetryPolicy.MaxAttempts, err))
 			})
 			if err != nil {
 				if klog.V(4).Enabled() {
@@ -218,9 +286,48 @@ func (p *Pruner) GetPruneObjs(
 		objs = append(objs, pruneObj)
 	}
 	sort.Sort(sort.Reverse(ordering.SortableUnstructureds(objs)))
+	if exceedsPruneLimits(len(objs), len(invIDs), opts) {
+		return nil, &PruneLimitExceededError{
+			Objects:       object.UnstructuredsToObjMetasOrDie(objs),
+			InventorySize: len(invIDs),
+			MaxCount:      opts.MaxPruneCount,
+			MaxPercent:    opts.MaxPrunePercent,
+		}
+	}
 	return objs, nil
 }
 
+// GetInventoryUIDFilter returns the prune filter which refuses to delete
+// the passed prune objects whose UID differs from the UID recorded in the
+// inventory. The inventory is only read if there are objects to prune.
+func (p *Pruner) GetInventoryUIDFilter(
+	inv inventory.InventoryInfo,
+	pruneObjs object.UnstructuredSet,
+	opts Options,
+) (filter.InventoryUIDFilter, error) {
+	if len(pruneObjs) == 0 {
+		return filter.InventoryUIDFilter{}, nil
+	}
+	entries, err := p.InvClient.GetClusterEntries(inv, opts.DryRunStrategy)
+	if err != nil {
+		return filter.InventoryUIDFilter{}, err
+	}
+	return filter.InventoryUIDFilter{Entries: entries}, nil
+}
+
+// exceedsPruneLimits returns true if pruning count objects out of an
+// inventory with invSize objects exceeds the limits in the options.
+func exceedsPruneLimits(count, invSize int, opts Options) bool {
+	if count == 0 {
+		return false
+	}
+	if opts.MaxPruneCount > 0 && count > opts.MaxPruneCount {
+		return true
+	}
+	// Compare count/invSize > percent/100 without rounding.
+	return opts.MaxPrunePercent > 0 && count*100 > opts.MaxPrunePercent*invSize
+}
+
 func (p *Pruner) getObject(id object.ObjMetadata) (*unstructured.Unstructured, error) {
 	namespacedClient, err := p.namespacedClient(id)
 	if err != nil {
@@ -466,7 +466,7 @@ func TestPrune(t *testing.T) {
 			// the events that can be put on it.
 			eventChannel := make(chan event.Event, len(tc.pruneObjs)+1)
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 			err = func() error {
 				defer close(eventChannel)
 				// Run the prune and validate.
@@ -546,7 +546,7 @@ func TestPruneDeletionPrevention(t *testing.T) {
 			// the events that can be put on it.
 			eventChannel := make(chan event.Event, 2)
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 			err = func() error {
 				defer close(eventChannel)
 				// Run the prune and validate.
@@ -642,7 +642,7 @@ func TestPruneWithErrors(t *testing.T) {
 			// the events that can be put on it.
 			eventChannel := make(chan event.Event, len(tc.pruneObjs))
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 			err = func() error {
 				defer close(eventChannel)
 				var opts Options
@@ -747,6 +747,64 @@ func TestGetPruneObjs(t *testing.T) {
 	}
 }
 
+func TestGetPruneObjs_Limits(t *testing.T) {
+	tests := map[string]struct {
+		localObjs     []*unstructured.Unstructured
+		opts          Options
+		expectedError bool
+	}{
+		"no limits": {
+			localObjs: []*unstructured.Unstructured{},
+			opts:      Options{},
+		},
+		"count within limit": {
+			localObjs: []*unstructured.Unstructured{pdb},
+			opts:      Options{MaxPruneCount: 2},
+		},
+		"count exceeds limit": {
+			localObjs:     []*unstructured.Unstructured{},
+			opts:          Options{MaxPruneCount: 2},
+			expectedError: true,
+		},
+		"percent within limit": {
+			localObjs: []*unstructured.Unstructured{pod, pdb},
+			opts:      Options{MaxPrunePercent: 34},
+		},
+		"percent exceeds limit": {
+			localObjs:     []*unstructured.Unstructured{pdb},
+			opts:          Options{MaxPrunePercent: 50},
+			expectedError: true,
+		},
+		"nothing to prune never exceeds limit": {
+			localObjs: []*unstructured.Unstructured{pod, pdb, namespace},
+			opts:      Options{MaxPruneCount: 1, MaxPrunePercent: 1},
+		},
+	}
+	prevInventory := []*unstructured.Unstructured{pod, pdb, namespace}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			po := Pruner{
+				InvClient: inventory.NewFakeInventoryClient(object.UnstructuredsToObjMetasOrDie(prevInventory)),
+				Client:    fake.NewSimpleDynamicClient(scheme.Scheme, pod, pdb, namespace),
+				Mapper: testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme,
+					scheme.Scheme.PrioritizedVersionsAllGroups()...),
+			}
+			currentInventory := createInventoryInfo(prevInventory...)
+			actualObjs, err := po.GetPruneObjs(currentInventory, tc.localObjs, tc.opts)
+			if !tc.expectedError {
+				require.NoError(t, err)
+				assert.Len(t, actualObjs, len(prevInventory)-len(tc.localObjs))
+				return
+			}
+			require.Error(t, err)
+			limitErr, ok := err.(*PruneLimitExceededError)
+			require.True(t, ok)
+			assert.Equal(t, len(prevInventory), limitErr.InventorySize)
+			assert.Len(t, limitErr.Objects, len(prevInventory)-len(tc.localObjs))
+		})
+	}
+}
+
 func TestGetObject_NoMatchError(t *testing.T) {
 	po := Pruner{
 		Client: fake.NewSimpleDynamicClient(scheme.Scheme, pod, namespace),
@@ -852,7 +910,7 @@ func TestPrune_PropagationPolicy(t *testing.T) {
 
 			eventChannel := make(chan event.Event, 1)
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 			err := po.Prune([]*unstructured.Unstructured{pdb}, []filter.ValidationFilter{}, taskContext, "test-0", Options{
 				PropagationPolicy: tc.propagationPolicy,
 			})
@@ -863,6 +921,239 @@ func TestPrune_PropagationPolicy(t *testing.T) {
 	}
 }
 
+func TestPrune_Cancelled(t *testing.T) {
+	pruneObjs := []*unstructured.Unstructured{pod, pdb}
+	pruneIds, err := object.UnstructuredsToObjMetas(pruneObjs)
+	require.NoError(t, err)
+
+	captureClient := &optionsCaptureNamespaceClient{}
+	po := Pruner{
+		InvClient: inventory.NewFakeInventoryClient(pruneIds),
+		Client: &fakeDynamicClient{
+			resourceInterface: captureClient,
+		},
+		Mapper: testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme,
+			scheme.Scheme.PrioritizedVersionsAllGroups()...),
+	}
+
+	// Cancel the context before pruning, so no object is deleted.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	eventChannel := make(chan event.Event, len(pruneObjs))
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := taskrunner.NewTaskContext(ctx, eventChannel, resourceCache)
+	err = po.Prune(pruneObjs, []filter.ValidationFilter{}, taskContext, "test-0", defaultOptions)
+	close(eventChannel)
+	require.NoError(t, err)
+	assert.Nil(t, captureClient.options.PropagationPolicy, "Prune() should NOT delete objects")
+
+	var actualEvents []event.Event
+	for e := range eventChannel {
+		actualEvents = append(actualEvents, e)
+	}
+	require.Equal(t, len(pruneIds), len(actualEvents))
+	for i, e := range actualEvents {
+		assert.Equal(t, event.PruneType, e.Type)
+		assert.Equal(t, event.PruneSkipped, e.PruneEvent.Operation)
+		assert.Equal(t, pruneIds[i], e.PruneEvent.Identifier)
+		assert.Equal(t, "prune interrupted: context canceled", e.PruneEvent.Reason)
+	}
+	for _, id := range pruneIds {
+		assert.Truef(t, taskContext.IsSkippedDelete(id), "Prune() should mark object as skipped: %s", id)
+		assert.Falsef(t, taskContext.IsFailedDelete(id), "Prune() should NOT mark object as failed: %s", id)
+	}
+}
+
+func TestPrune_InventoryUIDMismatch(t *testing.T) {
+	// The pod was deleted and recreated by someone else since it was applied.
+	recreatedPod := pod.DeepCopy()
+	recreatedPod.SetUID("new-uid")
+	pruneObjs := []*unstructured.Unstructured{recreatedPod, pdb}
+	pruneIds, err := object.UnstructuredsToObjMetas(pruneObjs)
+	require.NoError(t, err)
+
+	invClient := inventory.NewFakeInventoryClient(pruneIds)
+	invClient.Entries[pruneIds[0]] = inventory.Entry{UID: "old-uid"}
+	po := Pruner{
+		InvClient: invClient,
+		Client: &fakeDynamicClient{
+			resourceInterface: &optionsCaptureNamespaceClient{},
+		},
+		Mapper: testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme,
+			scheme.Scheme.PrioritizedVersionsAllGroups()...),
+	}
+	uidFilter, err := po.GetInventoryUIDFilter(createInventoryInfo(pruneObjs...), pruneObjs, defaultOptions)
+	require.NoError(t, err)
+
+	eventChannel := make(chan event.Event, len(pruneObjs))
+	taskContext := taskrunner.NewTaskContext(context.Background(), eventChannel, cache.NewResourceCacheMap())
+	err = po.Prune(pruneObjs, []filter.ValidationFilter{uidFilter}, taskContext, "test-0", defaultOptions)
+	close(eventChannel)
+	require.NoError(t, err)
+
+	var actualEvents []event.Event
+	for e := range eventChannel {
+		actualEvents = append(actualEvents, e)
+	}
+	require.Equal(t, len(pruneIds), len(actualEvents))
+	assert.Equal(t, event.PruneSkipped, actualEvents[0].PruneEvent.Operation)
+	assert.Equal(t, event.Pruned, actualEvents[1].PruneEvent.Operation)
+	assert.True(t, taskContext.IsSkippedDelete(pruneIds[0]), "Prune() should NOT delete the recreated object")
+	assert.False(t, taskContext.IsSkippedDelete(pruneIds[1]), "Prune() should delete the object without a recorded UID")
+}
+
+// flakyNamespaceClient fails the first deletes with a conflict.
+type flakyNamespaceClient struct {
+	dynamic.ResourceInterface
+	failures int
+	deletes  int
+	// notFound makes the deletes after the failures return NotFound,
+	// like when a failed delete still deleted the object.
+	notFound bool
+}
+
+var _ dynamic.ResourceInterface = &flakyNamespaceClient{}
+
+func (c *flakyNamespaceClient) Delete(_ context.Context, name string, _ metav1.DeleteOptions, _ ...string) error {
+	c.deletes++
+	if c.deletes <= c.failures {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "poddisruptionbudgets"}, name, fmt.Errorf("conflict"))
+	}
+	if c.notFound {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "poddisruptionbudgets"}, name)
+	}
+	return nil
+}
+
+func TestPrune_Retry(t *testing.T) {
+	pdbID := object.UnstructuredToObjMetaOrDie(pdb)
+	testCases := map[string]struct {
+		failures        int
+		notFound        bool
+		expectedDeletes int
+		expectedEvents  []testutil.ExpEvent
+		expectedFailed  bool
+	}{
+		"delete not found on retry succeeds": {
+			failures:        1,
+			notFound:        true,
+			expectedDeletes: 2,
+			expectedEvents: []testutil.ExpEvent{
+				{
+					EventType: event.RetryType,
+					RetryEvent: &testutil.ExpRetryEvent{
+						Action:     event.PruneAction,
+						Identifier: pdbID,
+						Attempt:    2,
+						Error:      fmt.Errorf("conflict"),
+					},
+				},
+				{
+					EventType: event.PruneType,
+					PruneEvent: &testutil.ExpPruneEvent{
+						Operation:  event.Pruned,
+						Identifier: pdbID,
+					},
+				},
+			},
+		},
+		"delete succeeds after retries": {
+			failures:        2,
+			expectedDeletes: 3,
+			expectedEvents: []testutil.ExpEvent{
+				{
+					EventType: event.RetryType,
+					RetryEvent: &testutil.ExpRetryEvent{
+						Action:     event.PruneAction,
+						Identifier: pdbID,
+						Attempt:    2,
+						Error:      fmt.Errorf("conflict"),
+					},
+				},
+				{
+					EventType: event.RetryType,
+					RetryEvent: &testutil.ExpRetryEvent{
+						Action:     event.PruneAction,
+						Identifier: pdbID,
+						Attempt:    3,
+						Error:      fmt.Errorf("conflict"),
+					},
+				},
+				{
+					EventType: event.PruneType,
+					PruneEvent: &testutil.ExpPruneEvent{
+						Operation:  event.Pruned,
+						Identifier: pdbID,
+					},
+				},
+			},
+		},
+		"delete fails after retries are used up": {
+			failures:        5,
+			expectedDeletes: 3,
+			expectedEvents: []testutil.ExpEvent{
+				{
+					EventType: event.RetryType,
+					RetryEvent: &testutil.ExpRetryEvent{
+						Action:     event.PruneAction,
+						Identifier: pdbID,
+						Attempt:    2,
+						Error:      fmt.Errorf("conflict"),
+					},
+				},
+				{
+					EventType: event.RetryType,
+					RetryEvent: &testutil.ExpRetryEvent{
+						Action:     event.PruneAction,
+						Identifier: pdbID,
+						Attempt:    3,
+						Error:      fmt.Errorf("conflict"),
+					},
+				},
+				{
+					EventType: event.PruneType,
+					PruneEvent: &testutil.ExpPruneEvent{
+						Identifier: pdbID,
+						Error:      fmt.Errorf("conflict"),
+					},
+				},
+			},
+			expectedFailed: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			flakyClient := &flakyNamespaceClient{failures: tc.failures, notFound: tc.notFound}
+			po := Pruner{
+				InvClient: inventory.NewFakeInventoryClient(object.ObjMetadataSet{pdbID}),
+				Client: &fakeDynamicClient{
+					resourceInterface: flakyClient,
+				},
+				Mapper: testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme,
+					scheme.Scheme.PrioritizedVersionsAllGroups()...),
+			}
+
+			eventChannel := make(chan event.Event, 10)
+			resourceCache := cache.NewResourceCacheMap()
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+			opts := defaultOptions
+			opts.RetryPolicy.MaxAttempts = 3
+			err := po.Prune([]*unstructured.Unstructured{pdb}, []filter.ValidationFilter{}, taskContext, "test-0", opts)
+			close(eventChannel)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDeletes, flakyClient.deletes)
+
+			var actualEvents []event.Event
+			for e := range eventChannel {
+				actualEvents = append(actualEvents, e)
+			}
+			assert.Len(t, actualEvents, len(tc.expectedEvents))
+			assert.NoError(t, testutil.VerifyEvents(tc.expectedEvents, actualEvents))
+			assert.Equal(t, tc.expectedFailed, taskContext.IsFailedDelete(pdbID))
+		})
+	}
+}
+
 type fakeDynamicClient struct {
 	resourceInterface dynamic.ResourceInterface
 }
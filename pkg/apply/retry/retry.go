@@ -0,0 +1,122 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package retry retries operations on objects that fail with transient
+// errors, like conflicts, throttling and webhook timeouts.
+package retry
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+)
+
+// Policy defines how operations that fail with a retryable error are
+// retried. The zero value disables retries.
+type Policy struct {
+	// MaxAttempts is the maximum number of attempts, including the
+	// first one. Retries are disabled if this is less than two.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. The delay is doubled
+	// for every following retry, up to MaxBackoff.
+	Backoff time.Duration
+
+	// MaxBackoff is the maximum delay between two attempts. There is no
+	// maximum if this is zero.
+	MaxBackoff time.Duration
+
+	// Retryable returns true if an operation that failed with the error
+	// should be retried. If this is not provided, IsRetryable is used.
+	Retryable func(error) bool
+}
+
+// Enabled returns true if the policy retries failed operations.
+func (p Policy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Delay returns the delay before the passed attempt, where the second
+// attempt is the first retry.
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 2; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return delay
+}
+
+// Do calls fn until it succeeds, it fails with an error that is not
+// retryable, the attempts are used up or the context is cancelled.
+// Before every retry, onRetry is called with the number of the next
+// attempt and the error of the previous one. Returns the last error.
+func (p Policy) Do(ctx context.Context, fn func() error, onRetry func(attempt int, err error)) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	err := fn()
+	for attempt := 2; err != nil && attempt <= p.MaxAttempts && retryable(err); attempt++ {
+		// No retry is announced once the context is cancelled.
+		if ctx.Err() != nil {
+			return err
+		}
+		klog.V(4).Infof("retrying (%d/%d) after error: %v", attempt, p.MaxAttempts, err)
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(p.Delay(attempt)):
+		}
+		err = fn()
+	}
+	return err
+}
+
+// IsRetryable returns true if the error is transient, so the operation
+// may succeed if it is retried: conflicts, throttling, server timeouts,
+// internal errors like failed webhook calls, an unavailable API server
+// and etcd leader changes. Server-side apply conflicts with other field
+// managers are not transient.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := applyerror.AsApplyConflictError(err); ok {
+		return false
+	}
+	if apierrors.IsConflict(err) || apierrors.IsTooManyRequests(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) || apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) {
+		return true
+	}
+	// kubectl does not always wrap the API error, so the message is
+	// checked as well.
+	msg := err.Error()
+	for _, transient := range transientMessages {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// transientMessages are parts of the messages of transient errors.
+var transientMessages = []string{
+	"etcdserver: leader changed",
+	"etcdserver: request timed out",
+	"the object has been modified; please apply your changes to the latest version and try again",
+	"Too many requests",
+	"context deadline exceeded",
+}
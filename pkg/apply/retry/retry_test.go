@@ -0,0 +1,191 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var deploymentGR = schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+func TestPolicyDo(t *testing.T) {
+	transientErr := apierrors.NewConflict(deploymentGR, "foo", errors.New("conflict"))
+	permanentErr := apierrors.NewForbidden(deploymentGR, "foo", errors.New("forbidden"))
+
+	testCases := map[string]struct {
+		policy           Policy
+		errs             []error
+		expectedErr      error
+		expectedAttempts int
+		expectedRetries  []int
+	}{
+		"success on first attempt": {
+			policy:           Policy{MaxAttempts: 3},
+			errs:             []error{nil},
+			expectedAttempts: 1,
+		},
+		"success after retries": {
+			policy:           Policy{MaxAttempts: 3},
+			errs:             []error{transientErr, transientErr, nil},
+			expectedAttempts: 3,
+			expectedRetries:  []int{2, 3},
+		},
+		"attempts used up": {
+			policy:           Policy{MaxAttempts: 2},
+			errs:             []error{transientErr, transientErr, nil},
+			expectedErr:      transientErr,
+			expectedAttempts: 2,
+			expectedRetries:  []int{2},
+		},
+		"error not retryable": {
+			policy:           Policy{MaxAttempts: 3},
+			errs:             []error{permanentErr, nil},
+			expectedErr:      permanentErr,
+			expectedAttempts: 1,
+		},
+		"custom classifier": {
+			policy: Policy{
+				MaxAttempts: 3,
+				Retryable:   func(err error) bool { return apierrors.IsForbidden(err) },
+			},
+			errs:             []error{permanentErr, nil},
+			expectedAttempts: 2,
+			expectedRetries:  []int{2},
+		},
+		"retries disabled": {
+			policy:           Policy{},
+			errs:             []error{transientErr, nil},
+			expectedErr:      transientErr,
+			expectedAttempts: 1,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			attempts := 0
+			var retries []int
+			err := tc.policy.Do(context.Background(), func() error {
+				err := tc.errs[attempts]
+				attempts++
+				return err
+			}, func(attempt int, err error) {
+				retries = append(retries, attempt)
+			})
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+			assert.Equal(t, tc.expectedRetries, retries)
+		})
+	}
+}
+
+func TestPolicyDo_Cancelled(t *testing.T) {
+	transientErr := apierrors.NewTooManyRequests("throttled", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{MaxAttempts: 5, Backoff: time.Hour}
+
+	attempts := 0
+	err := policy.Do(ctx, func() error {
+		attempts++
+		return transientErr
+	}, func(int, error) {
+		cancel()
+	})
+	assert.Equal(t, transientErr, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestPolicyDo_CancelledBeforeRetry(t *testing.T) {
+	transientErr := apierrors.NewTooManyRequests("throttled", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{MaxAttempts: 5}
+
+	attempts := 0
+	var retries []int
+	err := policy.Do(ctx, func() error {
+		attempts++
+		cancel()
+		return transientErr
+	}, func(attempt int, _ error) {
+		retries = append(retries, attempt)
+	})
+	assert.Equal(t, transientErr, err)
+	assert.Equal(t, 1, attempts)
+	assert.Empty(t, retries)
+}
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.Delay(2))
+	assert.Equal(t, 2*time.Second, policy.Delay(3))
+	assert.Equal(t, 4*time.Second, policy.Delay(4))
+	assert.Equal(t, 5*time.Second, policy.Delay(5))
+	assert.Equal(t, 5*time.Second, policy.Delay(50))
+}
+
+func TestIsRetryable(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"nil": {
+			err:      nil,
+			expected: false,
+		},
+		"conflict": {
+			err:      apierrors.NewConflict(deploymentGR, "foo", errors.New("conflict")),
+			expected: true,
+		},
+		"too many requests": {
+			err:      apierrors.NewTooManyRequests("throttled", 1),
+			expected: true,
+		},
+		"webhook timeout": {
+			err: apierrors.NewInternalError(errors.New(`failed calling webhook "validate.example.com": ` +
+				`context deadline exceeded`)),
+			expected: true,
+		},
+		"etcd leader change wrapped by kubectl": {
+			err:      fmt.Errorf("error when applying patch: etcdserver: leader changed"),
+			expected: true,
+		},
+		"server-side apply conflict": {
+			err: &apierrors.StatusError{ErrStatus: metav1.Status{
+				Status: metav1.StatusFailure,
+				Code:   409,
+				Reason: metav1.StatusReasonConflict,
+				Details: &metav1.StatusDetails{
+					Causes: []metav1.StatusCause{{
+						Type:    metav1.CauseTypeFieldManagerConflict,
+						Message: `conflict with "kube-controller-manager"`,
+						Field:   ".spec.replicas",
+					}},
+				},
+			}},
+			expected: false,
+		},
+		"not found": {
+			err:      apierrors.NewNotFound(deploymentGR, "foo"),
+			expected: false,
+		},
+		"invalid": {
+			err:      apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "foo", nil),
+			expected: false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsRetryable(tc.err))
+		})
+	}
+}
@@ -20,6 +20,8 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/cmd/util"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
@@ -27,12 +29,14 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply/info"
 	"sigs.k8s.io/cli-utils/pkg/apply/mutator"
 	"sigs.k8s.io/cli-utils/pkg/apply/prune"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/task"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
 	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/cli-utils/pkg/object/graph"
+	"sigs.k8s.io/cli-utils/pkg/object/reconcile"
 )
 
 type TaskQueueBuilder struct {
@@ -41,6 +45,8 @@ type TaskQueueBuilder struct {
 	Factory    util.Factory
 	Mapper     meta.RESTMapper
 	InvClient  inventory.InventoryClient
+	// Client is passed to the apply tasks to look up live objects.
+	Client dynamic.Interface
 	// True if we are destroying, which deletes the inventory object
 	// as well (possibly) the inventory namespace.
 	Destroy bool
@@ -50,6 +56,7 @@ type TaskQueueBuilder struct {
 	deleteInvCounter int
 	applyCounter     int
 	waitCounter      int
+	externalCounter  int
 	pruneCounter     int
 	tasks            []taskrunner.Task
 	err              error
@@ -83,11 +90,27 @@ func (tq *TaskQueue) ToActionGroups() []event.ActionGroup {
 type Options struct {
 	ServerSideOptions      common.ServerSideOptions
 	ReconcileTimeout       time.Duration
+	ReconcileFailFast      bool
+	ForceApply             bool
+	RecreateOnImmutable    bool
 	Prune                  bool
 	DryRunStrategy         common.DryRunStrategy
 	PrunePropagationPolicy metav1.DeletionPropagation
 	PruneTimeout           time.Duration
 	InventoryPolicy        inventory.InventoryPolicy
+	RetryPolicy            retry.Policy
+	ApplyConcurrency       int
+	// DAGScheduling defines whether the objects are applied by a single
+	// task as soon as the objects they depend on are reconciled, instead
+	// of by apply and wait tasks for every level of the dependency graph.
+	DAGScheduling bool
+	// ExternalDependencyTimeout is how long to wait for the objects that
+	// applied objects depend on, but that are not applied with them.
+	ExternalDependencyTimeout time.Duration
+	// FailOnReconcileTimeout defines whether the wait tasks of the
+	// applied objects return a TimeoutError when objects don't
+	// reconcile before their timeout.
+	FailOnReconcileTimeout bool
 }
 
 // Build returns the queue of tasks that have been created.
@@ -153,34 +176,127 @@ func (t *TaskQueueBuilder) AppendDeleteInvTask(inv inventory.InventoryInfo, dryR
 func (t *TaskQueueBuilder) AppendApplyTask(applyObjs object.UnstructuredSet,
 	applyFilters []filter.ValidationFilter, applyMutators []mutator.Interface, o Options) *TaskQueueBuilder {
 	klog.V(2).Infof("adding apply task (%d objects)", len(applyObjs))
-	t.tasks = append(t.tasks, &task.ApplyTask{
-		TaskName:          fmt.Sprintf("apply-%d", t.applyCounter),
-		Objects:           applyObjs,
-		Filters:           applyFilters,
-		Mutators:          applyMutators,
-		ServerSideOptions: o.ServerSideOptions,
-		DryRunStrategy:    o.DryRunStrategy,
-		InfoHelper:        t.InfoHelper,
-		Factory:           t.Factory,
-		Mapper:            t.Mapper,
+	t.tasks = append(t.tasks, t.newApplyTask(fmt.Sprintf("apply-%d", t.applyCounter),
+		applyObjs, applyFilters, applyMutators, o))
+	t.applyCounter += 1
+	return t
+}
+
+// AppendApplyDAGTask appends a task to the task queue that applies each of
+// the passed objects as soon as the objects it depends on are reconciled,
+// instead of the apply and wait tasks for every level of the dependency
+// graph. Returns a pointer to the Builder to chain function calls.
+func (t *TaskQueueBuilder) AppendApplyDAGTask(applyObjs object.UnstructuredSet,
+	applyFilters []filter.ValidationFilter, applyMutators []mutator.Interface, o Options) *TaskQueueBuilder {
+	deps, err := graph.Dependencies(applyObjs)
+	if err != nil {
+		t.err = err
+		return t
+	}
+	// Schedule the objects in the same order as the apply tasks would
+	// apply them.
+	applySets, err := graph.SortObjs(applyObjs)
+	if err != nil {
+		t.err = err
+		return t
+	}
+	var sortedObjs object.UnstructuredSet
+	for _, applySet := range applySets {
+		sortedObjs = append(sortedObjs, applySet...)
+	}
+	waits := map[object.ObjMetadata]task.ObjectWait{}
+	var externalDeps map[object.ObjMetadata]object.ObjMetadataSet
+	// dry-run skips waiting
+	if !o.DryRunStrategy.ClientOrServerDryRun() {
+		externalDeps = graph.ExternalDependencies(applyObjs)
+		for _, obj := range sortedObjs {
+			id := object.UnstructuredToObjMetaOrDie(obj)
+			group, _, wait, err := readWaitGroup(obj, o)
+			if err != nil {
+				t.err = fmt.Errorf("object %s: %w", id, err)
+				return t
+			}
+			if wait {
+				waits[id] = task.ObjectWait{Condition: group.condition, Timeout: group.timeout}
+			}
+		}
+	}
+	klog.V(2).Infof("adding dag apply task (%d objects)", len(sortedObjs))
+	t.tasks = append(t.tasks, &task.DAGApplyTask{
+		ApplyTask: *t.newApplyTask(fmt.Sprintf("apply-dag-%d", t.applyCounter),
+			sortedObjs, applyFilters, applyMutators, o),
+		Dependencies:         deps,
+		Waits:                waits,
+		ExternalDependencies: externalDeps,
+		ExternalTimeout:      o.ExternalDependencyTimeout,
+		Condition:            applyWaitCondition(o),
+		Timeout:              o.ReconcileTimeout,
+		FailOnTimeout:        o.FailOnReconcileTimeout,
 	})
 	t.applyCounter += 1
 	return t
 }
 
+// newApplyTask returns a task to apply the passed objects.
+func (t *TaskQueueBuilder) newApplyTask(name string, applyObjs object.UnstructuredSet,
+	applyFilters []filter.ValidationFilter, applyMutators []mutator.Interface, o Options) *task.ApplyTask {
+	return &task.ApplyTask{
+		TaskName:               name,
+		Objects:                applyObjs,
+		Filters:                applyFilters,
+		Mutators:               applyMutators,
+		ServerSideOptions:      o.ServerSideOptions,
+		DryRunStrategy:         o.DryRunStrategy,
+		InfoHelper:             t.InfoHelper,
+		Factory:                t.Factory,
+		Mapper:                 t.Mapper,
+		Client:                 t.Client,
+		ForceApply:             o.ForceApply,
+		RecreateOnImmutable:    o.RecreateOnImmutable,
+		PrunePropagationPolicy: o.PrunePropagationPolicy,
+		ReplaceTimeout:         o.PruneTimeout,
+		RetryPolicy:            o.RetryPolicy,
+		Concurrency:            o.ApplyConcurrency,
+	}
+}
+
 // AppendInvAddTask appends a task to wait on the passed objects to the task queue.
 // Returns a pointer to the Builder to chain function calls.
 func (t *TaskQueueBuilder) AppendWaitTask(waitIds object.ObjMetadataSet, condition taskrunner.Condition,
 	waitTimeout time.Duration) *TaskQueueBuilder {
+	t.appendWaitTask(waitIds, condition, waitTimeout)
+	return t
+}
+
+// appendWaitTask appends a task to wait on the passed objects to the task
+// queue, and returns it.
+func (t *TaskQueueBuilder) appendWaitTask(waitIds object.ObjMetadataSet, condition taskrunner.Condition,
+	waitTimeout time.Duration) *taskrunner.WaitTask {
 	klog.V(2).Infoln("adding wait task")
-	t.tasks = append(t.tasks, taskrunner.NewWaitTask(
+	waitTask := taskrunner.NewWaitTask(
 		fmt.Sprintf("wait-%d", t.waitCounter),
 		waitIds,
 		condition,
 		waitTimeout,
+		t.Mapper)
+	t.tasks = append(t.tasks, waitTask)
+	t.waitCounter += 1
+	return waitTask
+}
+
+// AppendExternalWaitTask appends a task to wait on the passed external
+// dependencies to the task queue. Returns a pointer to the Builder to
+// chain function calls.
+func (t *TaskQueueBuilder) AppendExternalWaitTask(externalIds object.ObjMetadataSet,
+	waitTimeout time.Duration) *TaskQueueBuilder {
+	klog.V(2).Infof("adding external wait task (%d objects)", len(externalIds))
+	t.tasks = append(t.tasks, taskrunner.NewExternalWaitTask(
+		fmt.Sprintf("wait-external-%d", t.externalCounter),
+		externalIds,
+		waitTimeout,
 		t.Mapper),
 	)
-	t.waitCounter += 1
+	t.externalCounter += 1
 	return t
 }
 
@@ -198,6 +314,7 @@ func (t *TaskQueueBuilder) AppendPruneTask(pruneObjs object.UnstructuredSet,
 			PropagationPolicy: o.PrunePropagationPolicy,
 			DryRunStrategy:    o.DryRunStrategy,
 			Destroy:           t.Destroy,
+			RetryPolicy:       o.RetryPolicy,
 		},
 	)
 	t.pruneCounter += 1
@@ -206,26 +323,126 @@ func (t *TaskQueueBuilder) AppendPruneTask(pruneObjs object.UnstructuredSet,
 
 // AppendApplyWaitTasks adds apply and wait tasks to the task queue,
 // depending on build variables (like dry-run) and resource types
-// (like CRD's). Returns a pointer to the Builder to chain function calls.
+// (like CRD's). If DAGScheduling is set, a single DAG apply task is added
+// instead. Returns a pointer to the Builder to chain function calls.
 func (t *TaskQueueBuilder) AppendApplyWaitTasks(applyObjs object.UnstructuredSet,
 	applyFilters []filter.ValidationFilter, applyMutators []mutator.Interface, o Options) *TaskQueueBuilder {
+	if o.DAGScheduling {
+		return t.AppendApplyDAGTask(applyObjs, applyFilters, applyMutators, o)
+	}
 	// Use the "depends-on" annotation to create a graph, ands sort the
 	// objects to apply into sets using a topological sort.
 	applySets, err := graph.SortObjs(applyObjs)
 	if err != nil {
 		t.err = err
 	}
+	externalDeps := graph.ExternalDependencies(applyObjs)
+	waitedExternal := object.ObjMetadataSet{}
 	for _, applySet := range applySets {
+		// Wait for the external dependencies of the objects before
+		// applying them. dry-run skips wait tasks.
+		if !o.DryRunStrategy.ClientOrServerDryRun() {
+			var externalIds object.ObjMetadataSet
+			for _, id := range object.UnstructuredsToObjMetasOrDie(applySet) {
+				for _, dep := range externalDeps[id] {
+					if !waitedExternal.Contains(dep) {
+						externalIds = append(externalIds, dep)
+						waitedExternal = append(waitedExternal, dep)
+					}
+				}
+			}
+			if len(externalIds) > 0 {
+				t.AppendExternalWaitTask(externalIds, o.ExternalDependencyTimeout)
+			}
+		}
 		t.AppendApplyTask(applySet, applyFilters, applyMutators, o)
 		// dry-run skips wait tasks
 		if !o.DryRunStrategy.ClientOrServerDryRun() {
-			applyIds := object.UnstructuredsToObjMetasOrDie(applySet)
-			t.AppendWaitTask(applyIds, taskrunner.AllCurrent, o.ReconcileTimeout)
+			t.appendApplyWaitTask(applySet, o)
 		}
 	}
 	return t
 }
 
+// waitGroup is the wait condition and timeout of an applied object.
+type waitGroup struct {
+	condition taskrunner.Condition
+	timeout   time.Duration
+}
+
+// appendApplyWaitTask adds a wait task for the passed applied objects.
+// Every object is waited on with the condition and timeout set by the
+// wait-for and reconcile-timeout annotations, if it has them. Objects
+// with the wait-for annotation set to "none" are not waited on.
+func (t *TaskQueueBuilder) appendApplyWaitTask(applyObjs object.UnstructuredSet, o Options) {
+	condition := applyWaitCondition(o)
+	var waitIds object.ObjMetadataSet
+	objectConditions := map[object.ObjMetadata]taskrunner.Condition{}
+	objectTimeouts := map[object.ObjMetadata]time.Duration{}
+	for _, obj := range applyObjs {
+		id := object.UnstructuredToObjMetaOrDie(obj)
+		group, annotated, wait, err := readWaitGroup(obj, o)
+		if err != nil {
+			t.err = fmt.Errorf("object %s: %w", id, err)
+			return
+		}
+		if !wait {
+			continue
+		}
+		waitIds = append(waitIds, id)
+		if group.condition != condition {
+			objectConditions[id] = group.condition
+		}
+		if annotated {
+			objectTimeouts[id] = group.timeout
+		}
+	}
+	if len(waitIds) == 0 {
+		return
+	}
+	waitTask := t.appendWaitTask(waitIds, condition, o.ReconcileTimeout)
+	waitTask.ObjectConditions = objectConditions
+	waitTask.ObjectTimeouts = objectTimeouts
+	waitTask.FailOnTimeout = o.FailOnReconcileTimeout
+}
+
+// applyWaitCondition returns the condition the applied objects are
+// waited on for, unless they set one with the wait-for annotation.
+func applyWaitCondition(o Options) taskrunner.Condition {
+	if o.ReconcileFailFast {
+		return taskrunner.AllCurrentOrAnyFailed
+	}
+	return taskrunner.AllCurrent
+}
+
+// readWaitGroup returns the wait condition and timeout of the applied
+// object, as set by the wait-for and reconcile-timeout annotations, and
+// whether the timeout was set with the annotation. Returns false if the
+// object is not waited on.
+func readWaitGroup(obj *unstructured.Unstructured, o Options) (waitGroup, bool, bool, error) {
+	waitFor, err := reconcile.ReadWaitForAnnotation(obj)
+	if err != nil {
+		return waitGroup{}, false, false, err
+	}
+	var condition taskrunner.Condition
+	switch waitFor {
+	case reconcile.WaitForNone:
+		return waitGroup{}, false, false, nil
+	case reconcile.WaitForDeleted:
+		condition = taskrunner.AllNotFound
+	default:
+		condition = applyWaitCondition(o)
+	}
+	timeout, found, err := reconcile.ReadTimeoutAnnotation(obj)
+	if err != nil {
+		return waitGroup{}, false, false, err
+	}
+	if !found {
+		timeout = o.ReconcileTimeout
+	}
+	return waitGroup{condition: condition, timeout: timeout}, found, true, nil
+}
+
 // AppendPruneWaitTasks adds prune and wait tasks to the task queue
 // based on build variables (like dry-run). Returns a pointer to the
 // Builder to chain function calls.
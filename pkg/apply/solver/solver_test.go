@@ -18,6 +18,7 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
 	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/object/reconcile"
 	"sigs.k8s.io/cli-utils/pkg/testutil"
 )
 
@@ -161,6 +162,58 @@ func TestTaskQueueBuilder_AppendApplyWaitTasks(t *testing.T) {
 			},
 			isError: false,
 		},
+		"external dependency is waited on before apply": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+			},
+			options: Options{
+				ExternalDependencyTimeout: time.Minute,
+			},
+			expectedTasks: []taskrunner.Task{
+				taskrunner.NewExternalWaitTask(
+					"wait-external-0",
+					object.ObjMetadataSet{
+						testutil.ToIdentifier(t, resources["secret"]),
+					},
+					time.Minute,
+					testutil.NewFakeRESTMapper(),
+				),
+				&task.ApplyTask{
+					TaskName: "apply-0",
+					Objects: []*unstructured.Unstructured{
+						testutil.Unstructured(t, resources["deployment"]),
+					},
+				},
+				taskrunner.NewWaitTask(
+					"wait-0",
+					object.ObjMetadataSet{
+						testutil.ToIdentifier(t, resources["deployment"]),
+					},
+					taskrunner.AllCurrent, 1*time.Second,
+					testutil.NewFakeRESTMapper(),
+				),
+			},
+			isError: false,
+		},
+		"dry-run does not wait for external dependency": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+			},
+			options: Options{
+				DryRunStrategy: common.DryRunClient,
+			},
+			expectedTasks: []taskrunner.Task{
+				&task.ApplyTask{
+					TaskName: "apply-0",
+					Objects: []*unstructured.Unstructured{
+						testutil.Unstructured(t, resources["deployment"]),
+					},
+				},
+			},
+			isError: false,
+		},
 		"multiple resources with reconcile timeout": {
 			applyObjs: []*unstructured.Unstructured{
 				testutil.Unstructured(t, resources["deployment"]),
@@ -189,6 +242,35 @@ func TestTaskQueueBuilder_AppendApplyWaitTasks(t *testing.T) {
 			},
 			isError: false,
 		},
+		"multiple resources with reconcile fail fast": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"]),
+				testutil.Unstructured(t, resources["secret"]),
+			},
+			options: Options{
+				ReconcileTimeout:  time.Minute,
+				ReconcileFailFast: true,
+			},
+			expectedTasks: []taskrunner.Task{
+				&task.ApplyTask{
+					TaskName: "apply-0",
+					Objects: []*unstructured.Unstructured{
+						testutil.Unstructured(t, resources["deployment"]),
+						testutil.Unstructured(t, resources["secret"]),
+					},
+				},
+				taskrunner.NewWaitTask(
+					"wait-0",
+					object.ObjMetadataSet{
+						testutil.ToIdentifier(t, resources["deployment"]),
+						testutil.ToIdentifier(t, resources["secret"]),
+					},
+					taskrunner.AllCurrentOrAnyFailed, 1*time.Second,
+					testutil.NewFakeRESTMapper(),
+				),
+			},
+			isError: false,
+		},
 		"multiple resources with reconcile timeout and dryrun": {
 			applyObjs: []*unstructured.Unstructured{
 				testutil.Unstructured(t, resources["deployment"]),
@@ -428,8 +510,187 @@ func TestTaskQueueBuilder_AppendApplyWaitTasks(t *testing.T) {
 						t.Errorf("expected wait ids (%v), got (%v)",
 							expTsk.Ids, actWaitTask.Ids)
 					}
-					assert.Equal(t, taskrunner.AllCurrent, actWaitTask.Condition)
+					assert.Equal(t, expTsk.Condition, actWaitTask.Condition)
+					assert.Equal(t, expTsk.External, actWaitTask.External)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskQueueBuilder_AppendApplyWaitTasks_WaitAnnotations(t *testing.T) {
+	type expectedWait struct {
+		ids              object.ObjMetadataSet
+		condition        taskrunner.Condition
+		timeout          time.Duration
+		objectConditions map[object.ObjMetadata]taskrunner.Condition
+		objectTimeouts   map[object.ObjMetadata]time.Duration
+		failOnTimeout    bool
+	}
+	testCases := map[string]struct {
+		applyObjs     []*unstructured.Unstructured
+		options       Options
+		expectedWaits []expectedWait
+		isError       bool
+	}{
+		"per-object reconcile timeout shares the wait task": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddAnnotation(t, reconcile.TimeoutAnnotation, "20m")),
+				testutil.Unstructured(t, resources["secret"]),
+			},
+			options: Options{
+				ReconcileTimeout: time.Minute,
+			},
+			expectedWaits: []expectedWait{
+				{
+					ids: object.ObjMetadataSet{
+						testutil.ToIdentifier(t, resources["deployment"]),
+						testutil.ToIdentifier(t, resources["secret"]),
+					},
+					condition:        taskrunner.AllCurrent,
+					timeout:          time.Minute,
+					objectConditions: map[object.ObjMetadata]taskrunner.Condition{},
+					objectTimeouts: map[object.ObjMetadata]time.Duration{
+						testutil.ToIdentifier(t, resources["deployment"]): 20 * time.Minute,
+					},
+				},
+			},
+		},
+		"same reconcile timeout shares the wait task": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddAnnotation(t, reconcile.TimeoutAnnotation, "1m")),
+				testutil.Unstructured(t, resources["secret"]),
+			},
+			options: Options{
+				ReconcileTimeout: time.Minute,
+			},
+			expectedWaits: []expectedWait{
+				{
+					ids: object.ObjMetadataSet{
+						testutil.ToIdentifier(t, resources["deployment"]),
+						testutil.ToIdentifier(t, resources["secret"]),
+					},
+					condition:        taskrunner.AllCurrent,
+					timeout:          time.Minute,
+					objectConditions: map[object.ObjMetadata]taskrunner.Condition{},
+					objectTimeouts: map[object.ObjMetadata]time.Duration{
+						testutil.ToIdentifier(t, resources["deployment"]): time.Minute,
+					},
+				},
+			},
+		},
+		"wait-for none and deleted": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddAnnotation(t, reconcile.WaitForAnnotation, "none")),
+				testutil.Unstructured(t, resources["secret"],
+					testutil.AddAnnotation(t, reconcile.WaitForAnnotation, "deleted")),
+				testutil.Unstructured(t, resources["pod"]),
+			},
+			options: Options{
+				ReconcileTimeout:  time.Minute,
+				ReconcileFailFast: true,
+			},
+			expectedWaits: []expectedWait{
+				{
+					ids: object.ObjMetadataSet{
+						testutil.ToIdentifier(t, resources["secret"]),
+						testutil.ToIdentifier(t, resources["pod"]),
+					},
+					condition: taskrunner.AllCurrentOrAnyFailed,
+					timeout:   time.Minute,
+					objectConditions: map[object.ObjMetadata]taskrunner.Condition{
+						testutil.ToIdentifier(t, resources["secret"]): taskrunner.AllNotFound,
+					},
+					objectTimeouts: map[object.ObjMetadata]time.Duration{},
+				},
+			},
+		},
+		"wait-for none on all objects adds no wait task": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddAnnotation(t, reconcile.WaitForAnnotation, "none")),
+			},
+			options: Options{
+				ReconcileTimeout: time.Minute,
+			},
+		},
+		"fail on reconcile timeout": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"]),
+			},
+			options: Options{
+				ReconcileTimeout:       time.Minute,
+				FailOnReconcileTimeout: true,
+			},
+			expectedWaits: []expectedWait{
+				{
+					ids:              object.ObjMetadataSet{testutil.ToIdentifier(t, resources["deployment"])},
+					condition:        taskrunner.AllCurrent,
+					timeout:          time.Minute,
+					objectConditions: map[object.ObjMetadata]taskrunner.Condition{},
+					objectTimeouts:   map[object.ObjMetadata]time.Duration{},
+					failOnTimeout:    true,
+				},
+			},
+		},
+		"invalid wait-for annotation returns error": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddAnnotation(t, reconcile.WaitForAnnotation, "ready")),
+			},
+			isError: true,
+		},
+		"invalid reconcile-timeout annotation returns error": {
+			applyObjs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddAnnotation(t, reconcile.TimeoutAnnotation, "forever")),
+			},
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			applyIds := object.UnstructuredsToObjMetasOrDie(tc.applyObjs)
+			fakeInvClient := inventory.NewFakeInventoryClient(applyIds)
+			tqb := TaskQueueBuilder{
+				Pruner:    pruner,
+				Mapper:    testutil.NewFakeRESTMapper(),
+				InvClient: fakeInvClient,
+			}
+			tq, err := tqb.AppendApplyWaitTasks(
+				tc.applyObjs,
+				[]filter.ValidationFilter{},
+				[]mutator.Interface{},
+				tc.options,
+			).Build()
+			if tc.isError {
+				assert.NotNil(t, err, "expected error, but received none")
+				return
+			}
+			assert.Nil(t, err, "unexpected error received")
+			var waitTasks []*taskrunner.WaitTask
+			for _, tsk := range tq.tasks {
+				if waitTask, ok := tsk.(*taskrunner.WaitTask); ok {
+					waitTasks = append(waitTasks, waitTask)
+				}
+			}
+			if !assert.Equal(t, len(tc.expectedWaits), len(waitTasks)) {
+				return
+			}
+			for i, expWait := range tc.expectedWaits {
+				waitTask := waitTasks[i]
+				if !expWait.ids.Equal(waitTask.Ids) {
+					t.Errorf("expected wait ids (%v), got (%v)", expWait.ids, waitTask.Ids)
 				}
+				assert.Equal(t, expWait.condition, waitTask.Condition)
+				assert.Equal(t, expWait.timeout, waitTask.Timeout)
+				assert.Equal(t, expWait.objectConditions, waitTask.ObjectConditions)
+				assert.Equal(t, expWait.objectTimeouts, waitTask.ObjectTimeouts)
+				assert.Equal(t, expWait.failOnTimeout, waitTask.FailOnTimeout)
 			}
 		})
 	}
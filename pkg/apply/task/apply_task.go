@@ -5,27 +5,39 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/cmd/apply"
 	cmddelete "k8s.io/kubectl/pkg/cmd/delete"
 	"k8s.io/kubectl/pkg/cmd/util"
+	kubectlutil "k8s.io/kubectl/pkg/util"
 	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/apply/filter"
 	"sigs.k8s.io/cli-utils/pkg/apply/info"
 	"sigs.k8s.io/cli-utils/pkg/apply/mutator"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/object/applyhash"
+	"sigs.k8s.io/cli-utils/pkg/object/dependson"
 )
 
 // applyOptions defines the two key functions on the ApplyOptions
@@ -54,6 +66,37 @@ type ApplyTask struct {
 	Mutators          []mutator.Interface
 	DryRunStrategy    common.DryRunStrategy
 	ServerSideOptions common.ServerSideOptions
+	// Client is used to look up the live objects, so objects that are
+	// unchanged since they were last applied can be skipped, unless
+	// ForceApply is true. An object is unchanged if its apply hash
+	// annotation matches the live object, and its fields have the same
+	// values on the live object. Changes made out of band to fields the
+	// object doesn't set are not detected, so they are only reverted with
+	// ForceApply. Objects with fields the server normalizes, like
+	// quantities, are always applied. Existing objects with the create-only apply
+	// policy are always skipped. If nil, all objects are applied. It is
+	// also used to migrate objects from client-side apply.
+	Client     dynamic.Interface
+	ForceApply bool
+	// RecreateOnImmutable defines whether objects that fail to apply
+	// because an immutable field was changed are deleted and created
+	// again. Objects with the replace-on-immutable annotation are always
+	// replaced. Requires the Client.
+	RecreateOnImmutable bool
+	// PrunePropagationPolicy is the deletion propagation policy used to
+	// delete objects that are replaced.
+	PrunePropagationPolicy metav1.DeletionPropagation
+	// ReplaceTimeout is how long to wait for an object that is replaced
+	// to be deleted, before the object fails to apply. Defaults to
+	// DefaultReplaceTimeout.
+	ReplaceTimeout time.Duration
+	// RetryPolicy defines how applies that fail with a transient error
+	// are retried. Retries are disabled by default.
+	RetryPolicy retry.Policy
+	// Concurrency is the maximum number of objects that are applied in
+	// parallel. The objects are applied one at a time if this is less
+	// than two.
+	Concurrency int
 }
 
 // applyOptionsFactoryFunc is a factory function for creating a new
@@ -82,111 +125,344 @@ func (a *ApplyTask) Identifiers() object.ObjMetadataSet {
 // the desired state of a resource is changed.
 func (a *ApplyTask) Start(taskContext *taskrunner.TaskContext) {
 	go func() {
-		// TODO: pipe Context through TaskContext
-		ctx := context.TODO()
+		ctx := taskContext.Context()
 		objects := a.Objects
 		klog.V(2).Infof("apply task starting (name: %q, objects: %d)",
 			a.Name(), len(objects))
-		// Create a new instance of the applyOptions interface and use it
-		// to apply the objects.
-		ao, err := applyOptionsFactoryFunc(a.Name(), taskContext.EventChannel(),
-			a.ServerSideOptions, a.DryRunStrategy, a.Factory)
-		if err != nil {
-			if klog.V(4).Enabled() {
-				klog.Errorf("error creating ApplyOptions (%s)--returning", err)
+		// Create the workers, each with a new instance of the
+		// applyOptions interface to apply the objects.
+		concurrency := a.concurrency()
+		workers := make([]*applyWorker, 0, concurrency)
+		for i := 0; i < concurrency; i++ {
+			w, err := a.newApplyWorker(taskContext, concurrency > 1)
+			if err != nil {
+				if klog.V(4).Enabled() {
+					klog.Errorf("error creating ApplyOptions (%s)--returning", err)
+				}
+				a.sendBatchApplyEvents(taskContext, objects, err)
+				a.sendTaskResult(taskContext)
+				return
 			}
-			a.sendBatchApplyEvents(taskContext, objects, err)
+			workers = append(workers, w)
+		}
+		if concurrency > 1 {
+			a.applyConcurrently(taskContext, workers, objects)
 			a.sendTaskResult(taskContext)
 			return
 		}
-		for _, obj := range objects {
-			// Set the client and mapping fields on the provided
-			// info so they can be applied to the cluster.
-			info, err := a.InfoHelper.BuildInfo(obj)
-			// BuildInfo strips path annotations.
-			// Use modified object for filters, mutations, and events.
-			obj = info.Object.(*unstructured.Unstructured)
-			id := object.UnstructuredToObjMetaOrDie(obj)
-			if err != nil {
-				if klog.V(4).Enabled() {
-					klog.Errorf("unable to convert obj to info for %s/%s (%s)--continue",
-						obj.GetNamespace(), obj.GetName(), err)
-				}
-				taskContext.SendEvent(a.createApplyFailedEvent(
-					id,
-					applyerror.NewUnknownTypeError(err),
-				))
-				taskContext.AddFailedApply(id)
-				continue
+		for i, obj := range objects {
+			// Stop applying objects if the task runner was cancelled.
+			// The remaining objects are skipped, so they are retained
+			// in the inventory if they were already in it.
+			if err := ctx.Err(); err != nil {
+				klog.V(4).Infof("apply task interrupted (name: %q): %v", a.Name(), err)
+				a.sendBatchSkippedEvents(taskContext, objects[i:], err)
+				break
 			}
+			a.applyObject(taskContext, workers[0], obj, taskContext.SendEvent)
+		}
+		a.sendTaskResult(taskContext)
+	}()
+}
 
-			// Check filters to see if we're prevented from applying.
-			var filtered bool
-			var filterErr error
-			for _, filter := range a.Filters {
-				klog.V(6).Infof("apply filter %s: %s", filter.Name(), id)
-				var reason string
-				filtered, reason, filterErr = filter.Filter(obj)
-				if filterErr != nil {
-					if klog.V(5).Enabled() {
-						klog.Errorf("error during %s, (%s): %s", filter.Name(), id, filterErr)
-					}
-					taskContext.SendEvent(a.createApplyFailedEvent(id, filterErr))
-					taskContext.AddFailedApply(id)
-					break
+// applyConcurrently applies the objects with the workers in parallel.
+// The events of every object are buffered and sent in the order of the
+// objects, so the output does not depend on the order in which the
+// applies complete.
+func (a *ApplyTask) applyConcurrently(taskContext *taskrunner.TaskContext, workers []*applyWorker,
+	objects object.UnstructuredSet) {
+	ctx := taskContext.Context()
+	objEvents := make([][]event.Event, len(objects))
+	done := make([]chan struct{}, len(objects))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range objects {
+			indexes <- i
+		}
+	}()
+	for _, w := range workers {
+		go func(w *applyWorker) {
+			for i := range indexes {
+				i := i
+				send := func(e event.Event) {
+					objEvents[i] = append(objEvents[i], e)
 				}
-				if filtered {
-					klog.V(4).Infof("apply filtered (filter: %q, resource: %q, reason: %q)", filter.Name(), id, reason)
-					taskContext.SendEvent(a.createApplyEvent(id, event.Unchanged, obj))
+				obj := objects[i]
+				// Objects that were not started before the task runner
+				// was cancelled are skipped.
+				if err := ctx.Err(); err != nil {
+					id := object.UnstructuredToObjMetaOrDie(obj)
+					send(a.createApplySkippedEvent(id, obj, fmt.Sprintf("apply interrupted: %v", err)))
 					taskContext.AddSkippedApply(id)
-					break
+				} else {
+					a.applyObject(taskContext, w, obj, send)
 				}
+				close(done[i])
 			}
-			if filtered || filterErr != nil {
-				continue
-			}
+		}(w)
+	}
+	for i := range objects {
+		<-done[i]
+		for _, e := range objEvents[i] {
+			taskContext.SendEvent(e)
+		}
+	}
+}
 
-			// Execute mutators, if any apply
-			err = a.mutate(ctx, obj)
-			if err != nil {
-				if klog.V(5).Enabled() {
-					klog.Errorf("error mutating: %w", err)
-				}
-				taskContext.SendEvent(a.createApplyFailedEvent(id, err))
-				taskContext.AddFailedApply(id)
-				continue
-			}
+// applyObject applies a single object with the worker, and sends the
+// events of the object with the send function.
+func (a *ApplyTask) applyObject(taskContext *taskrunner.TaskContext, w *applyWorker,
+	obj *unstructured.Unstructured, send func(event.Event)) {
+	ctx := taskContext.Context()
+	// Set the client and mapping fields on the provided
+	// info so they can be applied to the cluster.
+	info, err := a.InfoHelper.BuildInfo(obj)
+	// BuildInfo strips path annotations.
+	// Use modified object for filters, mutations, and events.
+	obj = info.Object.(*unstructured.Unstructured)
+	id := object.UnstructuredToObjMetaOrDie(obj)
+	if err != nil {
+		if klog.V(4).Enabled() {
+			klog.Errorf("unable to convert obj to info for %s/%s (%s)--continue",
+				obj.GetNamespace(), obj.GetName(), err)
+		}
+		send(a.createApplyFailedEvent(
+			id,
+			applyerror.NewUnknownTypeError(err),
+		))
+		taskContext.AddFailedApply(id)
+		return
+	}
+
+	// Objects are not applied if an external dependency was not
+	// reconciled before the timeout.
+	if depErr := externalDependencyError(taskContext, obj); depErr != nil {
+		klog.V(4).Infof("apply failed (resource: %q): %v", id, depErr)
+		send(a.createApplyFailedEvent(id, depErr))
+		taskContext.AddFailedApply(id)
+		return
+	}
 
-			// Apply the object
-			ao.SetObjects([]*resource.Info{info})
-			klog.V(5).Infof("applying %s/%s...", info.Namespace, info.Name)
-			err = ao.Run()
-			if err != nil && a.ServerSideOptions.ServerSideApply && isAPIService(obj) && isStreamError(err) {
-				// Server-side Apply doesn't work with APIService before k8s 1.21
-				// https://github.com/kubernetes/kubernetes/issues/89264
-				// Thus APIService is handled specially using client-side apply.
-				err = a.clientSideApply(info, taskContext.EventChannel())
+	// Check filters to see if we're prevented from applying.
+	for _, filter := range a.Filters {
+		klog.V(6).Infof("apply filter %s: %s", filter.Name(), id)
+		filtered, reason, filterErr := filter.Filter(obj)
+		if filterErr != nil {
+			if klog.V(5).Enabled() {
+				klog.Errorf("error during %s, (%s): %s", filter.Name(), id, filterErr)
 			}
+			send(a.createApplyFailedEvent(id, filterErr))
+			taskContext.AddFailedApply(id)
+			return
+		}
+		if filtered {
+			klog.V(4).Infof("apply filtered (filter: %q, resource: %q, reason: %q)", filter.Name(), id, reason)
+			send(a.createApplyEvent(id, event.Unchanged, obj))
+			taskContext.AddSkippedApply(id)
+			return
+		}
+	}
+
+	// Execute mutators, if any apply
+	err = a.mutate(ctx, obj)
+	if err != nil {
+		if klog.V(5).Enabled() {
+			klog.Errorf("error mutating: %w", err)
+		}
+		send(a.createApplyFailedEvent(id, err))
+		taskContext.AddFailedApply(id)
+		return
+	}
+
+	// Objects with the create-only apply policy are skipped if
+	// they already exist.
+	if common.CreateOnly(obj.GetAnnotations()) && a.Client != nil {
+		live, err := a.liveObject(ctx, id)
+		if err != nil {
+			send(a.createApplyFailedEvent(id, applyerror.NewApplyRunError(err)))
+			taskContext.AddFailedApply(id)
+			return
+		}
+		if live != nil {
+			klog.V(4).Infof("apply skipped, create-only object exists: %s", id)
+			send(a.createApplyEvent(id, event.Unchanged, live))
+			taskContext.AddUnchangedApply(id, live.GetUID(), live.GetGeneration())
+			return
+		}
+	}
+
+	// Record the content hash of the object, and skip the object
+	// if the live object still carries the same hash.
+	obj, hash, err := applyhash.Set(obj)
+	if err != nil {
+		send(a.createApplyFailedEvent(id, err))
+		taskContext.AddFailedApply(id)
+		return
+	}
+	info.Object = obj
+	// Migrate the object from client-side apply before the first
+	// server-side apply. Migrated objects are always applied.
+	var migration *event.ClientSideMigration
+	if a.migrateFromClientSideEnabled() {
+		migration, err = a.migrateFromClientSide(ctx, id)
+		if err != nil {
+			send(a.createApplyFailedEvent(id, applyerror.NewApplyRunError(
+				fmt.Errorf("failed to migrate from client-side apply: %w", err))))
+			taskContext.AddFailedApply(id)
+			return
+		}
+		if migration != nil {
+			klog.V(4).Infof("migrated from client-side apply: %s (field managers: %v)", id,
+				migration.FieldManagers)
+		}
+	}
+	if migration == nil {
+		if live := a.unchangedLiveObject(ctx, obj, hash); live != nil {
+			klog.V(4).Infof("apply skipped, object unchanged since last apply: %s", id)
+			send(a.createApplyEvent(id, event.Unchanged, live))
+			taskContext.AddUnchangedApply(id, live.GetUID(), live.GetGeneration())
+			return
+		}
+	}
+
+	// Objects with the force-conflicts annotation are applied
+	// with ForceConflicts, using separate ApplyOptions.
+	objAo := w.ao
+	if a.ServerSideOptions.ServerSideApply && !a.ServerSideOptions.ForceConflicts && forceConflicts(obj) {
+		if w.forceAo == nil {
+			forceOptions := a.ServerSideOptions
+			forceOptions.ForceConflicts = true
+			w.forceAo, err = applyOptionsFactoryFunc(a.Name(), w.events,
+				forceOptions, a.DryRunStrategy, a.Factory)
 			if err != nil {
-				if klog.V(4).Enabled() {
-					klog.Errorf("error applying (%s/%s) %s", info.Namespace, info.Name, err)
-				}
-				taskContext.SendEvent(a.createApplyFailedEvent(
+				send(a.createApplyFailedEvent(
 					id,
-					applyerror.NewApplyRunError(err),
+					applyerror.NewInitializeApplyOptionError(err),
 				))
 				taskContext.AddFailedApply(id)
-			} else if info.Object != nil {
-				acc, err := meta.Accessor(info.Object)
-				if err == nil {
-					uid := acc.GetUID()
-					gen := acc.GetGeneration()
-					taskContext.AddSuccessfulApply(id, uid, gen)
-				}
+				return
 			}
 		}
-		a.sendTaskResult(taskContext)
-	}()
+		klog.V(4).Infof("applying with force-conflicts: %s", id)
+		objAo = w.forceAo
+	}
+
+	// kubectl drops the causes of the error of a failed server-side
+	// apply, so the object is applied with a client that records the
+	// conflicts returned by the server.
+	if w.conflicts != nil {
+		client, err := w.conflicts.client(a.Factory, info.Mapping)
+		if err != nil {
+			send(a.createApplyFailedEvent(id, applyerror.NewApplyRunError(err)))
+			taskContext.AddFailedApply(id)
+			return
+		}
+		info.Client = client
+		w.conflicts.reset()
+	}
+
+	// Apply the object
+	objAo.SetObjects([]*resource.Info{info})
+	klog.V(5).Infof("applying %s/%s...", info.Namespace, info.Name)
+	// Applies that fail with a transient error are retried, and
+	// only recorded as failed once the attempts are used up.
+	err = a.RetryPolicy.Do(ctx, func() error {
+		err := a.runApply(w, objAo, send, migration)
+		if err != nil && a.ServerSideOptions.ServerSideApply && isAPIService(obj) && isStreamError(err) {
+			// Server-side Apply doesn't work with APIService before k8s 1.21
+			// https://github.com/kubernetes/kubernetes/issues/89264
+			// Thus APIService is handled specially using client-side apply.
+			err = a.clientSideApply(w, info, send)
+		}
+		return err
+	}, func(attempt int, err error) {
+		send(a.createRetryEvent(id, attempt, err))
+	})
+	if err != nil && a.replaceOnImmutable(obj) && isImmutableFieldError(err) {
+		klog.V(4).Infof("immutable field changed, replacing %s: %s", id, err)
+		replacedObj, replaceErr := a.replace(ctx, id, obj)
+		if replaceErr == nil {
+			send(a.createApplyEvent(id, event.Replaced, replacedObj))
+			taskContext.AddSuccessfulApply(id, replacedObj.GetUID(), replacedObj.GetGeneration())
+			return
+		}
+		err = fmt.Errorf("failed to replace object after immutable field change: %w", replaceErr)
+	}
+	if err != nil {
+		if klog.V(4).Enabled() {
+			klog.Errorf("error applying (%s/%s) %s", info.Namespace, info.Name, err)
+		}
+		send(a.createApplyFailedEvent(
+			id,
+			a.applyError(w, err),
+		))
+		taskContext.AddFailedApply(id)
+	} else if info.Object != nil {
+		acc, err := meta.Accessor(info.Object)
+		if err == nil {
+			uid := acc.GetUID()
+			gen := acc.GetGeneration()
+			taskContext.AddSuccessfulApply(id, uid, gen)
+		}
+	}
+}
+
+// applyWorker applies objects one at a time. ApplyOptions are not safe
+// for concurrent use, so every worker has its own.
+type applyWorker struct {
+	// events is the channel the ApplyOptions send the apply events to.
+	events chan event.Event
+	// forward is true if the apply events are forwarded from the events
+	// channel, instead of being sent to the task context directly.
+	forward bool
+	ao      applyOptions
+	// forceAo applies the objects with the force-conflicts
+	// annotation. It is created when it is first needed.
+	forceAo applyOptions
+	// conflicts records the conflicts of server-side applies. It is
+	// nil for client-side applies.
+	conflicts *conflictRecorder
+}
+
+// newApplyWorker returns a new worker. When objects are applied
+// concurrently or migrated from client-side apply, the apply events are
+// sent through a separate channel, so they can be buffered per object or
+// the migration of an object can be added to its apply event.
+func (a *ApplyTask) newApplyWorker(taskContext *taskrunner.TaskContext, concurrent bool) (*applyWorker, error) {
+	w := &applyWorker{
+		events: taskContext.EventChannel(),
+	}
+	if concurrent || a.migrateFromClientSideEnabled() {
+		w.events = make(chan event.Event)
+		w.forward = true
+	}
+	ao, err := applyOptionsFactoryFunc(a.Name(), w.events,
+		a.ServerSideOptions, a.DryRunStrategy, a.Factory)
+	if err != nil {
+		return nil, err
+	}
+	w.ao = ao
+	if a.ServerSideOptions.ServerSideApply && a.Factory != nil {
+		w.conflicts = &conflictRecorder{}
+	}
+	return w, nil
+}
+
+// concurrency returns the number of objects that are applied in
+// parallel, which is at least one and at most the number of objects.
+func (a *ApplyTask) concurrency() int {
+	concurrency := a.Concurrency
+	if concurrency > len(a.Objects) {
+		concurrency = len(a.Objects)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return concurrency
 }
 
 func newApplyOptions(taskName string, eventChannel chan event.Event, serverSideOptions common.ServerSideOptions,
@@ -259,6 +535,199 @@ func (a *ApplyTask) mutate(ctx context.Context, obj *unstructured.Unstructured)
 	return nil
 }
 
+// unchangedLiveObject returns the live object if it carries the passed
+// apply hash, which means the object is unchanged since it was last
+// applied, and the fields of the object still have the same values on the
+// live object, which means they were not changed out of band. Returns nil
+// if the object should be applied.
+func (a *ApplyTask) unchangedLiveObject(ctx context.Context, obj *unstructured.Unstructured, hash string) *unstructured.Unstructured {
+	if a.ForceApply || a.Client == nil {
+		return nil
+	}
+	id := object.UnstructuredToObjMetaOrDie(obj)
+	live, err := a.liveObject(ctx, id)
+	if err != nil {
+		klog.V(4).Infof("unable to get live object %s, applying: %s", id, err)
+		return nil
+	}
+	if live == nil || live.GetDeletionTimestamp() != nil || live.GetAnnotations()[common.ApplyHashAnnotation] != hash {
+		return nil
+	}
+	if !applyhash.LiveMatches(obj, live) {
+		klog.V(4).Infof("live object changed since last apply, applying: %s", id)
+		return nil
+	}
+	return live
+}
+
+// replaceOnImmutable returns true if the object should be replaced if
+// applying it fails because an immutable field was changed.
+func (a *ApplyTask) replaceOnImmutable(obj *unstructured.Unstructured) bool {
+	if a.Client == nil {
+		return false
+	}
+	return a.RecreateOnImmutable || obj.GetAnnotations()[common.ReplaceOnImmutableAnnotation] == "true"
+}
+
+// DefaultReplaceTimeout is how long to wait for an object that is
+// replaced to be deleted, if the ReplaceTimeout is not set.
+const DefaultReplaceTimeout = time.Minute
+
+// replacePollInterval is how often a replaced object is looked up while
+// waiting for it to be deleted. Used to allow unit testing.
+var replacePollInterval = time.Second
+
+// replace deletes the object from the cluster, waits until it is deleted,
+// and creates it again. Returns the created object. For dry-runs, nothing
+// is changed and the local object is returned.
+func (a *ApplyTask) replace(ctx context.Context, id object.ObjMetadata,
+	obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if a.DryRunStrategy.ClientOrServerDryRun() {
+		return obj, nil
+	}
+	ri, err := a.resourceInterface(id)
+	if err != nil {
+		return nil, err
+	}
+	policy := a.PrunePropagationPolicy
+	if policy == "" {
+		policy = metav1.DeletePropagationBackground
+	}
+	err = ri.Delete(ctx, id.Name, metav1.DeleteOptions{PropagationPolicy: &policy})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	// Wait until the object is gone, e.g. after its finalizers ran.
+	timeout := a.ReplaceTimeout
+	if timeout <= 0 {
+		timeout = DefaultReplaceTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		_, err = ri.Get(ctx, id.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			return nil, fmt.Errorf("timed out after %s waiting for the object to be deleted", timeout)
+		case <-time.After(replacePollInterval):
+		}
+	}
+
+	obj = obj.DeepCopy()
+	obj.SetResourceVersion("")
+	if a.ServerSideOptions.ServerSideApply {
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		force := true
+		return ri.Patch(ctx, id.Name, types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: a.fieldManager(),
+			Force:        &force,
+		})
+	}
+	// Record the last-applied-configuration, like client-side apply does
+	// when it creates an object.
+	if err := kubectlutil.CreateApplyAnnotation(obj, unstructured.UnstructuredJSONScheme); err != nil {
+		return nil, err
+	}
+	return ri.Create(ctx, obj, metav1.CreateOptions{FieldManager: a.ServerSideOptions.FieldManager})
+}
+
+// fieldManager returns the field manager for server-side apply.
+func (a *ApplyTask) fieldManager() string {
+	if a.ServerSideOptions.FieldManager == "" {
+		return common.DefaultFieldManager
+	}
+	return a.ServerSideOptions.FieldManager
+}
+
+// liveObject returns the object from the cluster, or nil if it does not
+// exist.
+func (a *ApplyTask) liveObject(ctx context.Context, id object.ObjMetadata) (*unstructured.Unstructured, error) {
+	ri, err := a.resourceInterface(id)
+	if err != nil {
+		return nil, err
+	}
+	live, err := ri.Get(ctx, id.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return live, nil
+}
+
+// migrateFromClientSideEnabled returns true if objects should be migrated
+// from client-side apply before they are applied. Migration changes the
+// live objects, so it is disabled for dry-runs.
+func (a *ApplyTask) migrateFromClientSideEnabled() bool {
+	return a.ServerSideOptions.ServerSideApply && a.ServerSideOptions.MigrateFromClientSide &&
+		!a.DryRunStrategy.ClientOrServerDryRun() && a.Client != nil
+}
+
+// runApply runs the ApplyOptions. If the apply events are sent through
+// the separate channel of the worker, they are forwarded with the
+// client-side apply migration of the object added.
+func (a *ApplyTask) runApply(w *applyWorker, ao applyOptions, send func(event.Event),
+	migration *event.ClientSideMigration) error {
+	if !w.forward {
+		return ao.Run()
+	}
+	// The channel is unbuffered, so all events have been forwarded
+	// when Run returns.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ao.Run()
+	}()
+	for {
+		select {
+		case e := <-w.events:
+			e.ApplyEvent.Migration = migration
+			send(e)
+		case err := <-errCh:
+			return err
+		}
+	}
+}
+
+// resourceInterface returns the dynamic client for the object.
+func (a *ApplyTask) resourceInterface(id object.ObjMetadata) (dynamic.ResourceInterface, error) {
+	mapping, err := a.Mapper.RESTMapping(id.GroupKind)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return a.Client.Resource(mapping.Resource).Namespace(id.Namespace), nil
+	}
+	return a.Client.Resource(mapping.Resource), nil
+}
+
+// applyError returns the error for a failed apply. Server-side apply
+// conflicts are returned as an ApplyConflictError with the conflicting
+// fields and their managers, read from the conflict recorded by the
+// worker.
+func (a *ApplyTask) applyError(w *applyWorker, err error) error {
+	if conflictErr, ok := applyerror.AsApplyConflictError(err); ok {
+		return conflictErr
+	}
+	if w.conflicts != nil {
+		if conflictErr, ok := applyerror.AsApplyConflictError(w.conflicts.conflict()); ok {
+			return applyerror.NewApplyConflictError(err, conflictErr.Conflicts)
+		}
+	}
+	return applyerror.NewApplyRunError(err)
+}
+
 // createApplyEvent is a helper function to package an apply event for a single resource.
 func (a *ApplyTask) createApplyEvent(id object.ObjMetadata, operation event.ApplyEventOperation, resource *unstructured.Unstructured) event.Event {
 	return event.Event{
@@ -272,6 +741,19 @@ func (a *ApplyTask) createApplyEvent(id object.ObjMetadata, operation event.Appl
 	}
 }
 
+func (a *ApplyTask) createApplySkippedEvent(id object.ObjMetadata, resource *unstructured.Unstructured, reason string) event.Event {
+	return event.Event{
+		Type: event.ApplyType,
+		ApplyEvent: event.ApplyEvent{
+			GroupName:  a.Name(),
+			Identifier: id,
+			Operation:  event.ApplySkipped,
+			Resource:   resource,
+			Reason:     reason,
+		},
+	}
+}
+
 func (a *ApplyTask) createApplyFailedEvent(id object.ObjMetadata, err error) event.Event {
 	return event.Event{
 		Type: event.ApplyType,
@@ -283,6 +765,20 @@ func (a *ApplyTask) createApplyFailedEvent(id object.ObjMetadata, err error) eve
 	}
 }
 
+func (a *ApplyTask) createRetryEvent(id object.ObjMetadata, attempt int, err error) event.Event {
+	return event.Event{
+		Type: event.RetryType,
+		RetryEvent: event.RetryEvent{
+			GroupName:   a.Name(),
+			Identifier:  id,
+			Action:      event.ApplyAction,
+			Attempt:     attempt,
+			MaxAttempts: a.RetryPolicy.MaxAttempts,
+			Error:       err,
+		},
+	}
+}
+
 // sendBatchApplyEvents is a helper function to send out multiple apply events for
 // a list of resources when failed to initialize the apply process.
 func (a *ApplyTask) sendBatchApplyEvents(
@@ -300,22 +796,91 @@ func (a *ApplyTask) sendBatchApplyEvents(
 	}
 }
 
+// sendBatchSkippedEvents is a helper function to send out multiple skipped
+// apply events for a list of resources that were never reached, because the
+// task runner was cancelled.
+func (a *ApplyTask) sendBatchSkippedEvents(
+	taskContext *taskrunner.TaskContext,
+	objects object.UnstructuredSet,
+	err error,
+) {
+	for _, obj := range objects {
+		id := object.UnstructuredToObjMetaOrDie(obj)
+		taskContext.SendEvent(a.createApplySkippedEvent(id, obj,
+			fmt.Sprintf("apply interrupted: %v", err)))
+		taskContext.AddSkippedApply(id)
+	}
+}
+
+// externalDependencyError returns the error of the first dependency of the
+// object that was waited on as an external dependency and not reconciled.
+// Returns nil if there is none.
+func externalDependencyError(taskContext *taskrunner.TaskContext, obj *unstructured.Unstructured) error {
+	deps, err := dependson.ReadAnnotation(obj)
+	if err != nil {
+		// Invalid annotations are ignored, like when sorting the objects.
+		return nil
+	}
+	for _, dep := range deps {
+		if depErr := taskContext.ExternalDependencyError(dep); depErr != nil {
+			return depErr
+		}
+	}
+	return nil
+}
+
 func isAPIService(obj *unstructured.Unstructured) bool {
 	gk := obj.GroupVersionKind().GroupKind()
 	return gk.Group == "apiregistration.k8s.io" && gk.Kind == "APIService"
 }
 
+// forceConflicts returns true if the object has the force-conflicts
+// annotation set to true.
+func forceConflicts(obj *unstructured.Unstructured) bool {
+	return obj.GetAnnotations()[common.ForceConflictsAnnotation] == "true"
+}
+
+// isImmutableFieldError checks if the error is an invalid error caused by
+// a change of an immutable field, like a Job template or Service clusterIP,
+// or of a StatefulSet field that can't be updated. The causes of the error
+// must all be immutable or forbidden fields.
+func isImmutableFieldError(err error) bool {
+	var status apierrors.APIStatus
+	if !apierrors.IsInvalid(err) || !errors.As(err, &status) {
+		return false
+	}
+	details := status.Status().Details
+	if details == nil || len(details.Causes) == 0 {
+		return false
+	}
+	for _, cause := range details.Causes {
+		if cause.Field == "" {
+			return false
+		}
+		switch cause.Type {
+		case metav1.CauseType(field.ErrorTypeForbidden):
+		case metav1.CauseType(field.ErrorTypeInvalid):
+			if !strings.HasSuffix(cause.Message, apimachineryvalidation.FieldImmutableErrorMsg) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // isStreamError checks if the error is a StreamError. Since kubectl wraps the actual StreamError,
 // we can't check the error type.
 func isStreamError(err error) bool {
 	return strings.Contains(err.Error(), "stream error: stream ID ")
 }
 
-func (a *ApplyTask) clientSideApply(info *resource.Info, eventChannel chan event.Event) error {
-	ao, err := applyOptionsFactoryFunc(a.Name(), eventChannel, common.ServerSideOptions{ServerSideApply: false}, a.DryRunStrategy, a.Factory)
+func (a *ApplyTask) clientSideApply(w *applyWorker, info *resource.Info, send func(event.Event)) error {
+	ao, err := applyOptionsFactoryFunc(a.Name(), w.events, common.ServerSideOptions{ServerSideApply: false}, a.DryRunStrategy, a.Factory)
 	if err != nil {
 		return err
 	}
 	ao.SetObjects([]*resource.Info{info})
-	return ao.Run()
+	return a.runApply(w, ao, send, nil)
 }
@@ -4,23 +4,37 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/scheme"
 	"sigs.k8s.io/cli-utils/pkg/apply/cache"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/object/applyhash"
 	"sigs.k8s.io/cli-utils/pkg/testutil"
 )
 
@@ -81,7 +95,7 @@ func TestApplyTask_BasicAppliedObjects(t *testing.T) {
 			eventChannel := make(chan event.Event)
 			defer close(eventChannel)
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 			objs := toUnstructureds(tc.applied)
 
@@ -172,7 +186,7 @@ func TestApplyTask_FetchGeneration(t *testing.T) {
 			eventChannel := make(chan event.Event)
 			defer close(eventChannel)
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 			objs := toUnstructureds(tc.rss)
 
@@ -284,7 +298,7 @@ func TestApplyTask_DryRun(t *testing.T) {
 			t.Run(tn, func(t *testing.T) {
 				eventChannel := make(chan event.Event)
 				resourceCache := cache.NewResourceCacheMap()
-				taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+				taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 				restMapper := testutil.NewFakeRESTMapper(schema.GroupVersionKind{
 					Group:   "apps",
@@ -431,7 +445,7 @@ func TestApplyTaskWithError(t *testing.T) {
 		t.Run(tn, func(t *testing.T) {
 			eventChannel := make(chan event.Event)
 			resourceCache := cache.NewResourceCacheMap()
-			taskContext := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 			restMapper := testutil.NewFakeRESTMapper(schema.GroupVersionKind{
 				Group:   "apps",
@@ -508,6 +522,842 @@ func TestApplyTaskWithError(t *testing.T) {
 	}
 }
 
+func TestApplyTask_Cancelled(t *testing.T) {
+	objs := toUnstructureds([]resourceInfo{
+		{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       "foo",
+			namespace:  "default",
+			uid:        types.UID("uid-1"),
+		},
+		{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       "bar",
+			namespace:  "default",
+			uid:        types.UID("uid-2"),
+		},
+		{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       "baz",
+			namespace:  "default",
+			uid:        types.UID("uid-3"),
+		},
+	})
+	ids, err := object.UnstructuredsToObjMetas(objs)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := taskrunner.NewTaskContext(ctx, eventChannel, resourceCache)
+
+	// Cancel the context after the first object has been applied.
+	ao := &cancellingApplyOptions{cancel: cancel}
+	oldAO := applyOptionsFactoryFunc
+	applyOptionsFactoryFunc = func(string, chan event.Event, common.ServerSideOptions, common.DryRunStrategy, util.Factory) (applyOptions, error) {
+		return ao, nil
+	}
+	defer func() { applyOptionsFactoryFunc = oldAO }()
+
+	applyTask := &ApplyTask{
+		TaskName: "apply-0",
+		Objects:  objs,
+		Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+			Group:   "apps",
+			Version: "v1",
+			Kind:    "Deployment",
+		}),
+		InfoHelper: &fakeInfoHelper{},
+	}
+
+	var events []event.Event
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for msg := range eventChannel {
+			events = append(events, msg)
+		}
+	}()
+
+	applyTask.Start(taskContext)
+	<-taskContext.TaskChannel()
+	close(eventChannel)
+	wg.Wait()
+
+	assert.Equal(t, 1, len(ao.passedObjects))
+	assert.True(t, taskContext.IsSuccessfulApply(ids[0]))
+
+	require.Equal(t, 2, len(events))
+	for i, e := range events {
+		id := ids[i+1]
+		assert.Equal(t, event.ApplyType, e.Type)
+		assert.Equal(t, event.ApplySkipped, e.ApplyEvent.Operation)
+		assert.Equal(t, id, e.ApplyEvent.Identifier)
+		assert.Equal(t, "apply interrupted: context canceled", e.ApplyEvent.Reason)
+		assert.Truef(t, taskContext.IsSkippedApply(id), "ApplyTask should mark object as skipped: %s", id)
+		assert.Falsef(t, taskContext.IsSuccessfulApply(id), "ApplyTask should NOT mark object as applied: %s", id)
+	}
+}
+
+func TestApplyTask_ExternalDependencyError(t *testing.T) {
+	objs := toUnstructureds([]resourceInfo{
+		{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       "foo",
+			namespace:  "default",
+			uid:        types.UID("uid-1"),
+		},
+		{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       "bar",
+			namespace:  "default",
+			uid:        types.UID("uid-2"),
+		},
+	})
+	ids, err := object.UnstructuredsToObjMetas(objs)
+	require.NoError(t, err)
+	secretID := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Kind: "Secret"},
+		Namespace: "default",
+		Name:      "db",
+	}
+	// foo depends on the secret, which is not applied with it.
+	testutil.AddDependsOn(t, secretID).Mutate(objs[0])
+
+	eventChannel := make(chan event.Event)
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, cache.NewResourceCacheMap())
+	externalErr := &applyerror.ExternalDependencyError{
+		Dependency: secretID,
+		Status:     status.NotFoundStatus,
+		Timeout:    time.Minute,
+	}
+	taskContext.AddExternalDependencyError(secretID, externalErr)
+
+	ao := &fakeApplyOptions{}
+	oldAO := applyOptionsFactoryFunc
+	applyOptionsFactoryFunc = func(string, chan event.Event, common.ServerSideOptions, common.DryRunStrategy, util.Factory) (applyOptions, error) {
+		return ao, nil
+	}
+	defer func() { applyOptionsFactoryFunc = oldAO }()
+
+	applyTask := &ApplyTask{
+		TaskName: "apply-0",
+		Objects:  objs,
+		Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+			Group:   "apps",
+			Version: "v1",
+			Kind:    "Deployment",
+		}),
+		InfoHelper: &fakeInfoHelper{},
+	}
+
+	var events []event.Event
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for msg := range eventChannel {
+			events = append(events, msg)
+		}
+	}()
+
+	applyTask.Start(taskContext)
+	<-taskContext.TaskChannel()
+	close(eventChannel)
+	wg.Wait()
+
+	// Only bar is applied.
+	require.Equal(t, 1, len(ao.passedObjects))
+	assert.Equal(t, "bar", ao.passedObjects[0].Name)
+	assert.True(t, taskContext.IsFailedApply(ids[0]))
+	assert.True(t, taskContext.IsSuccessfulApply(ids[1]))
+
+	require.Equal(t, 1, len(events))
+	assert.Equal(t, event.ApplyType, events[0].Type)
+	assert.Equal(t, ids[0], events[0].ApplyEvent.Identifier)
+	assert.Equal(t, externalErr, events[0].ApplyEvent.Error)
+}
+
+func TestApplyTask_Unchanged(t *testing.T) {
+	testCases := map[string]struct {
+		liveHash        bool
+		liveChanged     bool
+		liveDefaulted   bool
+		changed         bool
+		forceApply      bool
+		expectUnchanged bool
+	}{
+		"live object with same hash is unchanged": {
+			liveHash:        true,
+			expectUnchanged: true,
+		},
+		"live object with same hash and other fields is unchanged": {
+			liveHash:        true,
+			liveDefaulted:   true,
+			expectUnchanged: true,
+		},
+		"live object with same hash changed out of band is applied": {
+			liveHash:        true,
+			liveChanged:     true,
+			expectUnchanged: false,
+		},
+		"live object without hash is applied": {
+			liveHash:        false,
+			expectUnchanged: false,
+		},
+		"changed object is applied": {
+			liveHash:        true,
+			changed:         true,
+			expectUnchanged: false,
+		},
+		"force apply applies unchanged object": {
+			liveHash:        true,
+			forceApply:      true,
+			expectUnchanged: false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			objs := toUnstructureds([]resourceInfo{
+				{
+					group:      "apps",
+					apiVersion: "apps/v1",
+					kind:       "Deployment",
+					name:       "foo",
+					namespace:  "default",
+					uid:        types.UID("uid-1"),
+					generation: int64(3),
+				},
+			})
+			id := object.UnstructuredToObjMetaOrDie(objs[0])
+			objs[0].Object["spec"] = map[string]interface{}{"replicas": int64(1)}
+			live := objs[0].DeepCopy()
+			if tc.liveHash {
+				var err error
+				live, _, err = applyhash.Set(live)
+				require.NoError(t, err)
+			}
+			if tc.liveChanged {
+				// The object was scaled out of band.
+				live.Object["spec"] = map[string]interface{}{"replicas": int64(5)}
+			}
+			if tc.liveDefaulted {
+				// The server set a field that the object doesn't set.
+				live.Object["status"] = map[string]interface{}{"replicas": int64(1)}
+			}
+			if tc.changed {
+				objs[0].Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+			}
+
+			eventChannel := make(chan event.Event)
+			resourceCache := cache.NewResourceCacheMap()
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+			ao := &fakeApplyOptions{}
+			oldAO := applyOptionsFactoryFunc
+			applyOptionsFactoryFunc = func(string, chan event.Event, common.ServerSideOptions, common.DryRunStrategy, util.Factory) (applyOptions, error) {
+				return ao, nil
+			}
+			defer func() { applyOptionsFactoryFunc = oldAO }()
+
+			applyTask := &ApplyTask{
+				TaskName: "apply-0",
+				Objects:  objs,
+				Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+					Group:   "apps",
+					Version: "v1",
+					Kind:    "Deployment",
+				}),
+				InfoHelper: &fakeInfoHelper{},
+				Client:     fake.NewSimpleDynamicClient(scheme.Scheme, live),
+				ForceApply: tc.forceApply,
+			}
+
+			var events []event.Event
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for msg := range eventChannel {
+					events = append(events, msg)
+				}
+			}()
+
+			applyTask.Start(taskContext)
+			<-taskContext.TaskChannel()
+			close(eventChannel)
+			wg.Wait()
+
+			assert.True(t, taskContext.IsSuccessfulApply(id))
+			assert.Equal(t, tc.expectUnchanged, taskContext.IsUnchangedApply(id))
+			if tc.expectUnchanged {
+				assert.Empty(t, ao.passedObjects)
+				require.Equal(t, 1, len(events))
+				assert.Equal(t, event.Unchanged, events[0].ApplyEvent.Operation)
+				assert.Equal(t, id, events[0].ApplyEvent.Identifier)
+				gen, _ := taskContext.AppliedGeneration(id)
+				assert.Equal(t, int64(3), gen)
+			} else {
+				require.Equal(t, 1, len(ao.passedObjects))
+				assert.Empty(t, events)
+				// The hash is recorded on the applied object.
+				applied := ao.passedObjects[0].Object.(*unstructured.Unstructured)
+				assert.NotEmpty(t, applied.GetAnnotations()[common.ApplyHashAnnotation])
+			}
+		})
+	}
+}
+
+func TestApplyTask_CreateOnly(t *testing.T) {
+	testCases := map[string]struct {
+		exists          bool
+		forceApply      bool
+		expectUnchanged bool
+	}{
+		"existing object is not applied": {
+			exists:          true,
+			expectUnchanged: true,
+		},
+		"existing object is not applied with force apply": {
+			exists:          true,
+			forceApply:      true,
+			expectUnchanged: true,
+		},
+		"missing object is applied": {
+			exists:          false,
+			expectUnchanged: false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			objs := toUnstructureds([]resourceInfo{
+				{
+					group:      "apps",
+					apiVersion: "apps/v1",
+					kind:       "Deployment",
+					name:       "foo",
+					namespace:  "default",
+					uid:        types.UID("uid-1"),
+					generation: int64(3),
+				},
+			})
+			id := object.UnstructuredToObjMetaOrDie(objs[0])
+			live := objs[0].DeepCopy()
+			annotations := objs[0].GetAnnotations()
+			annotations[common.ApplyPolicyAnnotation] = common.ApplyPolicyCreateOnly
+			objs[0].SetAnnotations(annotations)
+			objs[0].Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+
+			var client *fake.FakeDynamicClient
+			if tc.exists {
+				client = fake.NewSimpleDynamicClient(scheme.Scheme, live)
+			} else {
+				client = fake.NewSimpleDynamicClient(scheme.Scheme)
+			}
+
+			eventChannel := make(chan event.Event)
+			resourceCache := cache.NewResourceCacheMap()
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+			ao := &fakeApplyOptions{}
+			oldAO := applyOptionsFactoryFunc
+			applyOptionsFactoryFunc = func(string, chan event.Event, common.ServerSideOptions, common.DryRunStrategy, util.Factory) (applyOptions, error) {
+				return ao, nil
+			}
+			defer func() { applyOptionsFactoryFunc = oldAO }()
+
+			applyTask := &ApplyTask{
+				TaskName: "apply-0",
+				Objects:  objs,
+				Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+					Group:   "apps",
+					Version: "v1",
+					Kind:    "Deployment",
+				}),
+				InfoHelper: &fakeInfoHelper{},
+				Client:     client,
+				ForceApply: tc.forceApply,
+			}
+
+			var events []event.Event
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for msg := range eventChannel {
+					events = append(events, msg)
+				}
+			}()
+
+			applyTask.Start(taskContext)
+			<-taskContext.TaskChannel()
+			close(eventChannel)
+			wg.Wait()
+
+			assert.True(t, taskContext.IsSuccessfulApply(id))
+			assert.Equal(t, tc.expectUnchanged, taskContext.IsUnchangedApply(id))
+			if tc.expectUnchanged {
+				assert.Empty(t, ao.passedObjects)
+				require.Equal(t, 1, len(events))
+				assert.Equal(t, event.Unchanged, events[0].ApplyEvent.Operation)
+				assert.Equal(t, id, events[0].ApplyEvent.Identifier)
+			} else {
+				assert.Equal(t, 1, len(ao.passedObjects))
+				assert.Empty(t, events)
+			}
+		})
+	}
+}
+
+func TestApplyTask_Retry(t *testing.T) {
+	conflictErr := apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, "foo",
+		fmt.Errorf("the object has been modified"))
+	forbiddenErr := apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "foo",
+		fmt.Errorf("forbidden"))
+
+	testCases := map[string]struct {
+		applyErr        error
+		failures        int
+		expectedRuns    int
+		expectedRetries []int
+		expectFailed    bool
+	}{
+		"succeeds after retries": {
+			applyErr:        conflictErr,
+			failures:        2,
+			expectedRuns:    3,
+			expectedRetries: []int{2, 3},
+		},
+		"fails after retries are used up": {
+			applyErr:        conflictErr,
+			failures:        5,
+			expectedRuns:    3,
+			expectedRetries: []int{2, 3},
+			expectFailed:    true,
+		},
+		"error not retryable": {
+			applyErr:     forbiddenErr,
+			failures:     1,
+			expectedRuns: 1,
+			expectFailed: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			objs := toUnstructureds([]resourceInfo{
+				{
+					group:      "apps",
+					apiVersion: "apps/v1",
+					kind:       "Deployment",
+					name:       "foo",
+					namespace:  "default",
+					uid:        types.UID("uid-1"),
+					generation: int64(1),
+				},
+			})
+			id := object.UnstructuredToObjMetaOrDie(objs[0])
+
+			eventChannel := make(chan event.Event)
+			resourceCache := cache.NewResourceCacheMap()
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+			ao := &flakyApplyOptions{err: tc.applyErr, failures: tc.failures}
+			oldAO := applyOptionsFactoryFunc
+			applyOptionsFactoryFunc = func(string, chan event.Event, common.ServerSideOptions, common.DryRunStrategy, util.Factory) (applyOptions, error) {
+				return ao, nil
+			}
+			defer func() { applyOptionsFactoryFunc = oldAO }()
+
+			applyTask := &ApplyTask{
+				TaskName: "apply-0",
+				Objects:  objs,
+				Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+					Group:   "apps",
+					Version: "v1",
+					Kind:    "Deployment",
+				}),
+				InfoHelper:  &fakeInfoHelper{},
+				RetryPolicy: retry.Policy{MaxAttempts: 3},
+			}
+
+			var events []event.Event
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for msg := range eventChannel {
+					events = append(events, msg)
+				}
+			}()
+
+			applyTask.Start(taskContext)
+			<-taskContext.TaskChannel()
+			close(eventChannel)
+			wg.Wait()
+
+			assert.Equal(t, tc.expectedRuns, ao.runs)
+			var retries []int
+			for _, e := range events {
+				if e.Type != event.RetryType {
+					continue
+				}
+				assert.Equal(t, id, e.RetryEvent.Identifier)
+				assert.Equal(t, event.ApplyAction, e.RetryEvent.Action)
+				assert.Equal(t, 3, e.RetryEvent.MaxAttempts)
+				assert.Equal(t, tc.applyErr, e.RetryEvent.Error)
+				retries = append(retries, e.RetryEvent.Attempt)
+			}
+			assert.Equal(t, tc.expectedRetries, retries)
+			assert.Equal(t, tc.expectFailed, taskContext.IsFailedApply(id))
+			assert.Equal(t, !tc.expectFailed, taskContext.IsSuccessfulApply(id))
+		})
+	}
+}
+
+func TestApplyTask_Concurrency(t *testing.T) {
+	var rss []resourceInfo
+	for i := 0; i < 6; i++ {
+		rss = append(rss, resourceInfo{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       fmt.Sprintf("deployment-%d", i),
+			namespace:  "default",
+			uid:        types.UID(fmt.Sprintf("uid-%d", i)),
+			generation: int64(1),
+		})
+	}
+	objs := toUnstructureds(rss)
+	ids, err := object.UnstructuredsToObjMetas(objs)
+	require.NoError(t, err)
+
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+	// The first objects take the longest to apply, so the applies
+	// complete in a different order than the objects.
+	tracker := &concurrencyTracker{}
+	oldAO := applyOptionsFactoryFunc
+	applyOptionsFactoryFunc = func(taskName string, ch chan event.Event, _ common.ServerSideOptions, _ common.DryRunStrategy,
+		_ util.Factory) (applyOptions, error) {
+		return &eventApplyOptions{
+			taskName: taskName,
+			ch:       ch,
+			tracker:  tracker,
+			delay: func(name string) time.Duration {
+				var i int
+				_, _ = fmt.Sscanf(name, "deployment-%d", &i)
+				return time.Duration(len(objs)-i) * 10 * time.Millisecond
+			},
+		}, nil
+	}
+	defer func() { applyOptionsFactoryFunc = oldAO }()
+
+	applyTask := &ApplyTask{
+		TaskName: "apply-0",
+		Objects:  objs,
+		Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+			Group:   "apps",
+			Version: "v1",
+			Kind:    "Deployment",
+		}),
+		InfoHelper:  &fakeInfoHelper{},
+		Concurrency: 3,
+	}
+
+	var events []event.Event
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for msg := range eventChannel {
+			events = append(events, msg)
+		}
+	}()
+
+	applyTask.Start(taskContext)
+	<-taskContext.TaskChannel()
+	close(eventChannel)
+	wg.Wait()
+
+	assert.Equal(t, 3, tracker.max)
+	require.Equal(t, len(ids), len(events))
+	for i, e := range events {
+		assert.Equal(t, event.ApplyType, e.Type)
+		assert.Equal(t, event.Configured, e.ApplyEvent.Operation)
+		assert.Equal(t, ids[i], e.ApplyEvent.Identifier)
+		assert.Truef(t, taskContext.IsSuccessfulApply(ids[i]), "ApplyTask should mark object as applied: %s", ids[i])
+	}
+}
+
+func TestApplyTask_ReplaceOnImmutable(t *testing.T) {
+	immutableErr := apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "foo",
+		field.ErrorList{field.Invalid(field.NewPath("spec", "template"), "", "field is immutable")})
+	otherInvalidErr := apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "foo",
+		field.ErrorList{field.Required(field.NewPath("spec", "template"), "")})
+	forbiddenErr := apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "foo",
+		field.ErrorList{field.Forbidden(field.NewPath("spec"), "updates to job spec are forbidden")})
+	mixedErr := apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "foo",
+		field.ErrorList{
+			field.Invalid(field.NewPath("spec", "template"), "", "field is immutable"),
+			field.Required(field.NewPath("spec", "completions"), ""),
+		})
+	oldPollInterval := replacePollInterval
+	replacePollInterval = 10 * time.Millisecond
+	defer func() { replacePollInterval = oldPollInterval }()
+
+	testCases := map[string]struct {
+		applyErr            error
+		annotation          bool
+		recreateOnImmutable bool
+		stuck               bool
+		expectReplaced      bool
+	}{
+		"immutable field change with annotation is replaced": {
+			applyErr:       immutableErr,
+			annotation:     true,
+			expectReplaced: true,
+		},
+		"immutable field change with RecreateOnImmutable is replaced": {
+			applyErr:            immutableErr,
+			recreateOnImmutable: true,
+			expectReplaced:      true,
+		},
+		"immutable field change without opt-in fails": {
+			applyErr:       immutableErr,
+			expectReplaced: false,
+		},
+		"other invalid error fails": {
+			applyErr:            otherInvalidErr,
+			recreateOnImmutable: true,
+			expectReplaced:      false,
+		},
+		"forbidden field change is replaced": {
+			applyErr:            forbiddenErr,
+			recreateOnImmutable: true,
+			expectReplaced:      true,
+		},
+		"immutable field change with other invalid fields fails": {
+			applyErr:            mixedErr,
+			recreateOnImmutable: true,
+			expectReplaced:      false,
+		},
+		"object that is not deleted in time fails": {
+			applyErr:            immutableErr,
+			recreateOnImmutable: true,
+			stuck:               true,
+			expectReplaced:      false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			objs := toUnstructureds([]resourceInfo{
+				{
+					group:      "batch",
+					apiVersion: "batch/v1",
+					kind:       "Job",
+					name:       "foo",
+					namespace:  "default",
+					uid:        types.UID("uid-1"),
+					generation: int64(1),
+				},
+			})
+			id := object.UnstructuredToObjMetaOrDie(objs[0])
+			live := objs[0].DeepCopy()
+			if tc.annotation {
+				annotations := objs[0].GetAnnotations()
+				annotations[common.ReplaceOnImmutableAnnotation] = "true"
+				objs[0].SetAnnotations(annotations)
+			}
+			objs[0].Object["spec"] = map[string]interface{}{"parallelism": int64(2)}
+			client := fake.NewSimpleDynamicClient(scheme.Scheme, live)
+			if tc.stuck {
+				// The object is held by a finalizer, so it is never deleted.
+				client.PrependReactor("delete", "jobs", func(clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, nil
+				})
+			}
+
+			eventChannel := make(chan event.Event)
+			resourceCache := cache.NewResourceCacheMap()
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+			oldAO := applyOptionsFactoryFunc
+			applyOptionsFactoryFunc = func(string, chan event.Event, common.ServerSideOptions, common.DryRunStrategy, util.Factory) (applyOptions, error) {
+				return &errorApplyOptions{err: tc.applyErr}, nil
+			}
+			defer func() { applyOptionsFactoryFunc = oldAO }()
+
+			applyTask := &ApplyTask{
+				TaskName: "apply-0",
+				Objects:  objs,
+				Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+					Group:   "batch",
+					Version: "v1",
+					Kind:    "Job",
+				}),
+				InfoHelper:             &fakeInfoHelper{},
+				Client:                 client,
+				RecreateOnImmutable:    tc.recreateOnImmutable,
+				PrunePropagationPolicy: metav1.DeletePropagationForeground,
+				ReplaceTimeout:         50 * time.Millisecond,
+			}
+
+			var events []event.Event
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for msg := range eventChannel {
+					events = append(events, msg)
+				}
+			}()
+
+			applyTask.Start(taskContext)
+			<-taskContext.TaskChannel()
+			close(eventChannel)
+			wg.Wait()
+
+			require.Equal(t, 1, len(events))
+			assert.Equal(t, id, events[0].ApplyEvent.Identifier)
+			if !tc.expectReplaced {
+				assert.Error(t, events[0].ApplyEvent.Error)
+				assert.True(t, taskContext.IsFailedApply(id))
+				return
+			}
+			assert.NoError(t, events[0].ApplyEvent.Error)
+			assert.Equal(t, event.Replaced, events[0].ApplyEvent.Operation)
+			assert.True(t, taskContext.IsSuccessfulApply(id))
+
+			var deleted bool
+			for _, action := range client.Actions() {
+				if _, ok := action.(clienttesting.DeleteAction); ok {
+					deleted = true
+				}
+			}
+			assert.True(t, deleted)
+
+			// The object was created again from the local object.
+			replaced, err := client.Resource(schema.GroupVersionResource{
+				Group:    "batch",
+				Version:  "v1",
+				Resource: "jobs",
+			}).Namespace("default").Get(context.TODO(), "foo", metav1.GetOptions{})
+			require.NoError(t, err)
+			parallelism, _, err := unstructured.NestedInt64(replaced.Object, "spec", "parallelism")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), parallelism)
+			assert.NotEmpty(t, replaced.GetAnnotations()[corev1.LastAppliedConfigAnnotation])
+		})
+	}
+}
+
+func TestApplyTask_ForceConflictsAnnotation(t *testing.T) {
+	testCases := map[string]struct {
+		serverSideOptions common.ServerSideOptions
+		expectForced      []string
+		expectNotForced   []string
+	}{
+		"server-side apply forces objects with annotation": {
+			serverSideOptions: common.ServerSideOptions{ServerSideApply: true},
+			expectForced:      []string{"forced"},
+			expectNotForced:   []string{"foo"},
+		},
+		"force conflicts forces all objects": {
+			serverSideOptions: common.ServerSideOptions{ServerSideApply: true, ForceConflicts: true},
+			expectForced:      []string{"foo", "forced"},
+		},
+		"annotation is ignored for client-side apply": {
+			serverSideOptions: common.ServerSideOptions{ServerSideApply: false},
+			expectNotForced:   []string{"foo", "forced"},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			objs := toUnstructureds([]resourceInfo{
+				{
+					group:      "apps",
+					apiVersion: "apps/v1",
+					kind:       "Deployment",
+					name:       "foo",
+					namespace:  "default",
+				},
+				{
+					group:      "apps",
+					apiVersion: "apps/v1",
+					kind:       "Deployment",
+					name:       "forced",
+					namespace:  "default",
+				},
+			})
+			annotations := objs[1].GetAnnotations()
+			annotations[common.ForceConflictsAnnotation] = "true"
+			objs[1].SetAnnotations(annotations)
+
+			eventChannel := make(chan event.Event)
+			defer close(eventChannel)
+			resourceCache := cache.NewResourceCacheMap()
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+			forcedAO := &fakeApplyOptions{}
+			notForcedAO := &fakeApplyOptions{}
+			oldAO := applyOptionsFactoryFunc
+			applyOptionsFactoryFunc = func(_ string, _ chan event.Event, opts common.ServerSideOptions,
+				_ common.DryRunStrategy, _ util.Factory) (applyOptions, error) {
+				if opts.ForceConflicts {
+					return forcedAO, nil
+				}
+				return notForcedAO, nil
+			}
+			defer func() { applyOptionsFactoryFunc = oldAO }()
+
+			applyTask := &ApplyTask{
+				TaskName: "apply-0",
+				Objects:  objs,
+				Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+					Group:   "apps",
+					Version: "v1",
+					Kind:    "Deployment",
+				}),
+				InfoHelper:        &fakeInfoHelper{},
+				ServerSideOptions: tc.serverSideOptions,
+			}
+
+			applyTask.Start(taskContext)
+			<-taskContext.TaskChannel()
+
+			assert.Equal(t, tc.expectForced, infoNames(forcedAO.passedObjects))
+			assert.Equal(t, tc.expectNotForced, infoNames(notForcedAO.passedObjects))
+		})
+	}
+}
+
+func infoNames(infos []*resource.Info) []string {
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
 func toUnstructured(obj map[string]interface{}) *unstructured.Unstructured {
 	return &unstructured.Unstructured{
 		Object: obj,
@@ -558,6 +1408,100 @@ func (f *fakeApplyOptions) SetObjects(objects []*resource.Info) {
 	f.objects = objects
 }
 
+// concurrencyTracker records the maximum number of concurrent applies.
+type concurrencyTracker struct {
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyTracker) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+}
+
+func (c *concurrencyTracker) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current--
+}
+
+// eventApplyOptions applies every object after a delay and sends an
+// apply event, like the ApplyOptions do.
+type eventApplyOptions struct {
+	taskName string
+	ch       chan event.Event
+	tracker  *concurrencyTracker
+	delay    func(name string) time.Duration
+	objects  []*resource.Info
+}
+
+func (e *eventApplyOptions) Run() error {
+	e.tracker.start()
+	defer e.tracker.done()
+	for _, info := range e.objects {
+		time.Sleep(e.delay(info.Name))
+		obj := info.Object.(*unstructured.Unstructured)
+		e.ch <- event.Event{
+			Type: event.ApplyType,
+			ApplyEvent: event.ApplyEvent{
+				GroupName:  e.taskName,
+				Identifier: object.UnstructuredToObjMetaOrDie(obj),
+				Operation:  event.Configured,
+				Resource:   obj,
+			},
+		}
+	}
+	return nil
+}
+
+func (e *eventApplyOptions) SetObjects(objects []*resource.Info) {
+	e.objects = objects
+}
+
+// errorApplyOptions fails to apply every object with the error.
+type errorApplyOptions struct {
+	err error
+}
+
+func (e *errorApplyOptions) Run() error {
+	return e.err
+}
+
+func (e *errorApplyOptions) SetObjects([]*resource.Info) {}
+
+// flakyApplyOptions fails the first applies with the error.
+type flakyApplyOptions struct {
+	err      error
+	failures int
+	runs     int
+}
+
+func (f *flakyApplyOptions) Run() error {
+	f.runs++
+	if f.runs <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyApplyOptions) SetObjects([]*resource.Info) {}
+
+// cancellingApplyOptions cancels the context after the first apply.
+type cancellingApplyOptions struct {
+	fakeApplyOptions
+	cancel context.CancelFunc
+}
+
+func (c *cancellingApplyOptions) Run() error {
+	defer c.cancel()
+	return c.fakeApplyOptions.Run()
+}
+
 type fakeInfoHelper struct{}
 
 func (f *fakeInfoHelper) UpdateInfo(*resource.Info) error {
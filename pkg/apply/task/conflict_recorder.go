@@ -0,0 +1,115 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+	"k8s.io/kubectl/pkg/cmd/util"
+)
+
+// conflictRecorder records the last conflict returned by the server to
+// the requests of the clients it wraps. kubectl formats the error of a
+// failed server-side apply with %v, which drops the causes of the
+// original StatusError, so the field conflicts are read from the
+// recorded StatusError instead.
+//
+// A recorder is used by a single apply worker, which applies one object
+// at a time, so it is not safe for concurrent use.
+type conflictRecorder struct {
+	err *apierrors.StatusError
+	// clients are the REST clients recording the conflicts, by group
+	// version. They are created when they are first needed.
+	clients map[schema.GroupVersion]resource.RESTClient
+}
+
+// reset forgets the recorded conflict.
+func (r *conflictRecorder) reset() {
+	r.err = nil
+}
+
+// conflict returns the last recorded conflict, or nil if there is none.
+func (r *conflictRecorder) conflict() error {
+	if r.err == nil {
+		return nil
+	}
+	return r.err
+}
+
+// wrap returns a RoundTripper that records the conflicts returned by rt.
+func (r *conflictRecorder) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &conflictRecordingRoundTripper{
+		recorder: r,
+		delegate: rt,
+	}
+}
+
+// client returns a REST client for the mapping, like the unstructured
+// client of the factory, which records the conflicts. The client of a
+// group version is created once and reused for all its objects.
+func (r *conflictRecorder) client(factory util.Factory, mapping *meta.RESTMapping) (resource.RESTClient, error) {
+	gv := mapping.GroupVersionKind.GroupVersion()
+	if client, found := r.clients[gv]; found {
+		return client, nil
+	}
+	cfg, err := factory.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg = rest.CopyConfig(cfg)
+	if err := rest.SetKubernetesDefaults(cfg); err != nil {
+		return nil, err
+	}
+	cfg.APIPath = "/apis"
+	if mapping.GroupVersionKind.Group == "" {
+		cfg.APIPath = "/api"
+	}
+	cfg.ContentConfig = resource.UnstructuredPlusDefaultContentConfig()
+	cfg.GroupVersion = &gv
+	cfg.WrapTransport = transport.Wrappers(cfg.WrapTransport, r.wrap)
+	client, err := rest.RESTClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if r.clients == nil {
+		r.clients = map[schema.GroupVersion]resource.RESTClient{}
+	}
+	r.clients[gv] = client
+	return client, nil
+}
+
+type conflictRecordingRoundTripper struct {
+	recorder *conflictRecorder
+	delegate http.RoundTripper
+}
+
+func (rt *conflictRecordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusConflict {
+		return resp, err
+	}
+	// The body is read to decode the status, and replaced so the
+	// client can still read it.
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	status := metav1.Status{}
+	if err := json.Unmarshal(body, &status); err == nil && status.Kind == "Status" {
+		rt.recorder.err = &apierrors.StatusError{ErrStatus: status}
+	}
+	return resp, nil
+}
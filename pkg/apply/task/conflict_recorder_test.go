@@ -0,0 +1,86 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+)
+
+func TestConflictRecorder(t *testing.T) {
+	conflict := metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Code:     http.StatusConflict,
+		Reason:   metav1.StatusReasonConflict,
+		Message:  "Apply failed with 1 conflict: conflict with \"kubectl-edit\" using apps/v1: .spec.replicas",
+		Details: &metav1.StatusDetails{
+			Causes: []metav1.StatusCause{
+				{
+					Type:    metav1.CauseTypeFieldManagerConflict,
+					Message: `conflict with "kubectl-edit" using apps/v1`,
+					Field:   ".spec.replicas",
+				},
+			},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(conflict)
+	}))
+	defer server.Close()
+
+	tf := cmdtesting.NewTestFactory()
+	defer tf.Cleanup()
+	tf.ClientConfigVal = &rest.Config{Host: server.URL}
+	mapping := &meta.RESTMapping{
+		Resource:         schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Scope:            meta.RESTScopeNamespace,
+	}
+
+	recorder := &conflictRecorder{}
+	client, err := recorder.client(tf, mapping)
+	require.NoError(t, err)
+	_, err = resource.NewHelper(client, mapping).
+		Patch("default", "foo", types.ApplyPatchType, []byte(`{}`), &metav1.PatchOptions{})
+	require.Error(t, err)
+	// Like kubectl, which formats the error of a server-side apply.
+	err = fmt.Errorf("%v\nPlease review the fields above", err)
+
+	_, ok := applyerror.AsApplyConflictError(err)
+	assert.False(t, ok)
+	conflictErr, ok := applyerror.AsApplyConflictError(recorder.conflict())
+	require.True(t, ok)
+	assert.Equal(t, []applyerror.FieldConflict{
+		{
+			Manager: "kubectl-edit",
+			Field:   ".spec.replicas",
+			Message: `conflict with "kubectl-edit" using apps/v1`,
+		},
+	}, conflictErr.Conflicts)
+
+	recorder.reset()
+	assert.NoError(t, recorder.conflict())
+
+	// The client is reused for the objects of the same group version.
+	sameClient, err := recorder.client(tf, mapping)
+	require.NoError(t, err)
+	assert.Same(t, client, sameClient)
+}
@@ -0,0 +1,457 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// ObjectWait is the condition an applied object must meet to be
+// reconciled, and how long to wait for it.
+type ObjectWait struct {
+	Condition taskrunner.Condition
+	// Timeout is the maximum time to wait for the object after it was
+	// applied. There is no timeout if this is zero.
+	Timeout time.Duration
+}
+
+// dagState is the scheduling state of an object of the DAGApplyTask.
+type dagState int
+
+const (
+	// dagBlocked objects wait for their dependencies.
+	dagBlocked dagState = iota
+	// dagApplying objects are being applied.
+	dagApplying
+	// dagReconciling objects have been applied and wait for their
+	// condition to be met.
+	dagReconciling
+	// dagDone objects are reconciled, or they failed, were skipped or
+	// timed out.
+	dagDone
+)
+
+// DAGApplyTask applies the Objects of the ApplyTask as soon as the
+// objects they depend on are done, instead of applying and waiting for
+// the objects level by level. An object is done when it is reconciled,
+// failed to apply or reconcile, was skipped or timed out. Like the apply
+// and wait tasks, a failed dependency does not prevent its dependents
+// from being applied. The task waits for the applied objects itself, so
+// it replaces both the apply and the wait tasks, and sends the wait
+// events of the objects with its own name as the group name.
+type DAGApplyTask struct {
+	ApplyTask
+
+	// Dependencies maps the objects to the objects they depend on.
+	// Objects without an entry have no dependencies.
+	Dependencies map[object.ObjMetadata]object.ObjMetadataSet
+	// Waits maps the objects to the condition they must meet before
+	// their dependents are applied. Objects without an entry are done
+	// as soon as they are applied.
+	Waits map[object.ObjMetadata]ObjectWait
+	// ExternalDependencies maps the objects to the objects they depend
+	// on that are not applied with them. These are waited on from the
+	// start of the task until they are Current. Objects fail to apply if
+	// an external dependency is not Current before the ExternalTimeout.
+	ExternalDependencies map[object.ObjMetadata]object.ObjMetadataSet
+	// ExternalTimeout is the maximum time to wait for the external
+	// dependencies. There is no timeout if this is zero.
+	ExternalTimeout time.Duration
+	// Condition and Timeout are the wait condition and timeout of the
+	// objects that don't set their own with the wait-for and
+	// reconcile-timeout annotations. They are reported in the errors
+	// of the task.
+	Condition taskrunner.Condition
+	Timeout   time.Duration
+	// FailOnTimeout is true if the task returns a TimeoutError when
+	// objects don't reach their condition before their timeout.
+	// Otherwise only the ReconcileTimeout events are sent.
+	FailOnTimeout bool
+
+	// mu protects the scheduling state below. It is held while objects
+	// are scheduled and events are sent, but not while objects are
+	// applied.
+	mu        sync.Mutex
+	ids       object.ObjMetadataSet
+	externals object.ObjMetadataSet
+	objects   map[object.ObjMetadata]*unstructured.Unstructured
+	states    map[object.ObjMetadata]dagState
+	timers    map[object.ObjMetadata]*time.Timer
+	workers   chan *applyWorker
+	remaining int
+	cancelled bool
+	finished  bool
+	// failed are the objects that reached the Failed status, which
+	// stops the task like a cancellation.
+	failed object.ObjMetadataSet
+	// timedOut are the objects that didn't reach their condition
+	// before their timeout.
+	timedOut []taskrunner.TimedOutResource
+	// ctx is cancelled when the task is cancelled or finished, so
+	// objects waiting for a worker are skipped.
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+}
+
+// Identifiers returns the objects to apply and their external
+// dependencies, so the task receives the status updates of both.
+func (d *DAGApplyTask) Identifiers() object.ObjMetadataSet {
+	return append(d.ApplyTask.Identifiers(), d.externalIDs()...)
+}
+
+// externalIDs returns the external dependencies of the objects.
+func (d *DAGApplyTask) externalIDs() object.ObjMetadataSet {
+	var ids object.ObjMetadataSet
+	for _, id := range object.UnstructuredsToObjMetasOrDie(d.Objects) {
+		for _, dep := range d.ExternalDependencies[id] {
+			if !ids.Contains(dep) {
+				ids = append(ids, dep)
+			}
+		}
+	}
+	return ids
+}
+
+// Start applies the objects without dependencies and returns. The
+// dependents of the objects are applied when the objects are done, after
+// they were applied or after a status update.
+func (d *DAGApplyTask) Start(taskContext *taskrunner.TaskContext) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	klog.V(2).Infof("dag apply task starting (name: %q, objects: %d)",
+		d.Name(), len(d.Objects))
+	d.ctx, d.cancelFunc = context.WithCancel(taskContext.Context())
+	d.ids = object.UnstructuredsToObjMetasOrDie(d.Objects)
+	d.objects = make(map[object.ObjMetadata]*unstructured.Unstructured, len(d.ids))
+	d.states = make(map[object.ObjMetadata]dagState, len(d.ids))
+	d.timers = make(map[object.ObjMetadata]*time.Timer)
+	for i, id := range d.ids {
+		d.objects[id] = d.Objects[i]
+		d.states[id] = dagBlocked
+	}
+	d.externals = d.externalIDs()
+	for _, id := range d.externals {
+		d.states[id] = dagReconciling
+	}
+	d.remaining = len(d.ids) + len(d.externals)
+
+	// Objects in independent branches are applied in parallel, so the
+	// apply events of the workers are always forwarded.
+	concurrency := d.concurrency()
+	d.workers = make(chan *applyWorker, concurrency)
+	for i := 0; i < concurrency; i++ {
+		w, err := d.newApplyWorker(taskContext, true)
+		if err != nil {
+			if klog.V(4).Enabled() {
+				klog.Errorf("error creating ApplyOptions (%s)--returning", err)
+			}
+			d.sendBatchApplyEvents(taskContext, d.Objects, err)
+			d.remaining = 0
+			d.finish(taskContext)
+			return
+		}
+		d.workers <- w
+	}
+
+	// If the task runner was cancelled before this task started, all
+	// objects are skipped.
+	if err := d.ctx.Err(); err != nil {
+		d.cancel(taskContext, err)
+		return
+	}
+	d.waitExternal(taskContext)
+	d.schedule(taskContext)
+	d.finish(taskContext)
+}
+
+// waitExternal starts waiting for the external dependencies. The caller
+// must hold the lock.
+func (d *DAGApplyTask) waitExternal(taskContext *taskrunner.TaskContext) {
+	for _, id := range d.externals {
+		if taskrunner.ObjectReconciled(taskContext, id, taskrunner.AllCurrent) {
+			d.sendWaitEvent(taskContext, id, event.Reconciled)
+			d.done(taskContext, id)
+			continue
+		}
+		d.sendWaitEvent(taskContext, id, event.ReconcilePending)
+		if d.ExternalTimeout > 0 {
+			id := id
+			d.timers[id] = time.AfterFunc(d.ExternalTimeout, func() {
+				d.timeout(taskContext, id)
+			})
+		}
+	}
+}
+
+// schedule starts applying the blocked objects whose dependencies are
+// done. The caller must hold the lock.
+func (d *DAGApplyTask) schedule(taskContext *taskrunner.TaskContext) {
+	if d.cancelled || len(d.failed) > 0 {
+		return
+	}
+	for _, id := range d.ids {
+		if d.states[id] != dagBlocked || !d.dependenciesDone(id) {
+			continue
+		}
+		klog.V(4).Infof("dag apply task scheduling object (name: %q, object: %q)", d.Name(), id)
+		d.states[id] = dagApplying
+		go d.apply(taskContext, id)
+	}
+}
+
+// dependenciesDone returns true if all the dependencies of the object
+// are done. The caller must hold the lock.
+func (d *DAGApplyTask) dependenciesDone(id object.ObjMetadata) bool {
+	for _, deps := range []object.ObjMetadataSet{d.Dependencies[id], d.ExternalDependencies[id]} {
+		for _, dep := range deps {
+			if state, found := d.states[dep]; found && state != dagDone {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// apply applies the object with the next free worker, and then starts
+// waiting for it.
+func (d *DAGApplyTask) apply(taskContext *taskrunner.TaskContext, id object.ObjMetadata) {
+	w := <-d.workers
+	obj := d.objects[id]
+	if err := d.ctx.Err(); err != nil {
+		taskContext.SendEvent(d.createApplySkippedEvent(id, obj, fmt.Sprintf("apply interrupted: %v", err)))
+		taskContext.AddSkippedApply(id)
+	} else {
+		d.applyObject(taskContext, w, obj, taskContext.SendEvent)
+	}
+	d.workers <- w
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.applied(taskContext, id)
+	d.finish(taskContext)
+}
+
+// applied starts waiting for the applied object, or marks it as done if
+// it is not waited on. The caller must hold the lock.
+func (d *DAGApplyTask) applied(taskContext *taskrunner.TaskContext, id object.ObjMetadata) {
+	wait, found := d.Waits[id]
+	switch {
+	case d.cancelled:
+		d.done(taskContext, id)
+	case !found:
+		d.done(taskContext, id)
+	case taskContext.IsFailedApply(id) || taskContext.IsSkippedApply(id):
+		d.sendWaitEvent(taskContext, id, event.ReconcileSkipped)
+		d.done(taskContext, id)
+	case taskrunner.ObjectReconciled(taskContext, id, wait.Condition):
+		d.sendWaitEvent(taskContext, id, event.Reconciled)
+		d.done(taskContext, id)
+	case taskrunner.ObjectFailed(taskContext, id, wait.Condition):
+		d.fail(taskContext, id)
+	default:
+		d.sendWaitEvent(taskContext, id, event.ReconcilePending)
+		d.states[id] = dagReconciling
+		if wait.Timeout > 0 {
+			d.timers[id] = time.AfterFunc(wait.Timeout, func() {
+				d.timeout(taskContext, id)
+			})
+		}
+	}
+}
+
+// StatusUpdate checks whether the object that is waited on is reconciled
+// or failed, and if so, applies its dependents.
+func (d *DAGApplyTask) StatusUpdate(taskContext *taskrunner.TaskContext, id object.ObjMetadata) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.states[id] != dagReconciling {
+		return
+	}
+	// External dependencies are waited on until they are Current.
+	condition := taskrunner.AllCurrent
+	if wait, found := d.Waits[id]; found {
+		condition = wait.Condition
+	}
+	switch {
+	case taskrunner.ObjectReconciled(taskContext, id, condition):
+		d.sendWaitEvent(taskContext, id, event.Reconciled)
+		d.done(taskContext, id)
+	case taskrunner.ObjectFailed(taskContext, id, condition):
+		d.fail(taskContext, id)
+	default:
+		return
+	}
+	d.finish(taskContext)
+}
+
+// fail records that the object reached the Failed status and stops
+// applying and waiting for the remaining objects, like a wait task that
+// fails fast. The caller must hold the lock.
+func (d *DAGApplyTask) fail(taskContext *taskrunner.TaskContext, id object.ObjMetadata) {
+	d.failed = append(d.failed, id)
+	d.sendWaitEvent(taskContext, id, event.ReconcileFailed)
+	klog.V(3).Infof("object failed to reconcile (name: %q, object: %q)", d.Name(), id)
+	d.done(taskContext, id)
+	d.cancel(taskContext, context.Canceled)
+}
+
+// timeout sends the timeout event for the object if it is still waited
+// on, and applies its dependents.
+func (d *DAGApplyTask) timeout(taskContext *taskrunner.TaskContext, id object.ObjMetadata) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.states[id] != dagReconciling || d.cancelled {
+		return
+	}
+	d.sendWaitEvent(taskContext, id, event.ReconcileTimeout)
+	cached := taskContext.ResourceCache().Get(id)
+	if d.externals.Contains(id) {
+		taskContext.AddExternalDependencyError(id, &applyerror.ExternalDependencyError{
+			Dependency: id,
+			Status:     cached.Status,
+			Timeout:    d.ExternalTimeout,
+		})
+	} else {
+		wait := d.Waits[id]
+		resource := taskrunner.TimedOutResource{
+			Identifier: id,
+			Status:     cached.Status,
+			Message:    cached.StatusMessage,
+		}
+		if wait.Timeout != d.Timeout {
+			resource.Timeout = wait.Timeout
+		}
+		if wait.Condition != d.Condition {
+			resource.Condition = wait.Condition
+		}
+		d.timedOut = append(d.timedOut, resource)
+	}
+	d.done(taskContext, id)
+	d.finish(taskContext)
+}
+
+// done marks the object as done and applies the dependents that are no
+// longer blocked. The RESTMapper is reset after CRDs are done, so the
+// custom resources can be applied. The caller must hold the lock.
+func (d *DAGApplyTask) done(taskContext *taskrunner.TaskContext, id object.ObjMetadata) {
+	if timer, found := d.timers[id]; found {
+		timer.Stop()
+		delete(d.timers, id)
+	}
+	d.states[id] = dagDone
+	d.remaining--
+	if obj, found := d.objects[id]; found && object.IsCRD(obj) && !taskContext.IsFailedApply(id) && !taskContext.IsSkippedApply(id) {
+		klog.V(5).Infof("resetting RESTMapper")
+		if err := taskrunner.ResetRESTMapper(d.Mapper); err != nil {
+			if klog.V(4).Enabled() {
+				klog.Errorf("error resetting RESTMapper: %v", err)
+			}
+		}
+	}
+	d.schedule(taskContext)
+}
+
+// Cancel skips the objects that have not been applied yet and stops
+// waiting for the applied objects. Objects that are being applied are
+// completed before the task finishes.
+func (d *DAGApplyTask) Cancel(taskContext *taskrunner.TaskContext) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cancel(taskContext, context.Canceled)
+}
+
+// cancel skips the blocked objects and stops waiting for the reconciling
+// objects. The caller must hold the lock.
+func (d *DAGApplyTask) cancel(taskContext *taskrunner.TaskContext, err error) {
+	if d.cancelled {
+		return
+	}
+	klog.V(4).Infof("dag apply task interrupted (name: %q): %v", d.Name(), err)
+	d.cancelled = true
+	d.cancelFunc()
+	for _, id := range append(append(object.ObjMetadataSet{}, d.ids...), d.externals...) {
+		switch d.states[id] {
+		case dagBlocked:
+			obj := d.objects[id]
+			taskContext.SendEvent(d.createApplySkippedEvent(id, obj, fmt.Sprintf("apply interrupted: %v", err)))
+			taskContext.AddSkippedApply(id)
+			d.done(taskContext, id)
+		case dagReconciling:
+			d.done(taskContext, id)
+		}
+	}
+	d.finish(taskContext)
+}
+
+// finish signals the completion of the task to the task runner when all
+// objects are done. The caller must hold the lock.
+func (d *DAGApplyTask) finish(taskContext *taskrunner.TaskContext) {
+	if d.remaining > 0 || d.finished {
+		return
+	}
+	d.finished = true
+	d.cancelFunc()
+	// The task runner may be waiting for the lock to deliver a status
+	// update, so the result is sent without holding it.
+	go d.sendResult(taskContext, d.resultError())
+}
+
+// resultError returns a ReconcileFailedError with the objects that
+// reached the Failed status. Otherwise, if the task fails on timeout, it
+// returns a TimeoutError with the objects that timed out. Returns nil if
+// neither happened. The caller must hold the lock.
+func (d *DAGApplyTask) resultError() error {
+	if len(d.failed) > 0 {
+		return &taskrunner.ReconcileFailedError{
+			Identifiers: d.failed,
+			Condition:   d.Condition,
+		}
+	}
+	if d.FailOnTimeout && len(d.timedOut) > 0 {
+		var waitIds object.ObjMetadataSet
+		for _, id := range d.ids {
+			if _, found := d.Waits[id]; found {
+				waitIds = append(waitIds, id)
+			}
+		}
+		return &taskrunner.TimeoutError{
+			Identifiers:       waitIds,
+			Timeout:           d.Timeout,
+			Condition:         d.Condition,
+			TimedOutResources: d.timedOut,
+		}
+	}
+	return nil
+}
+
+func (d *DAGApplyTask) sendResult(taskContext *taskrunner.TaskContext, err error) {
+	klog.V(2).Infof("dag apply task completing (name: %q)", d.Name())
+	taskContext.TaskChannel() <- taskrunner.TaskResult{Err: err}
+}
+
+func (d *DAGApplyTask) sendWaitEvent(taskContext *taskrunner.TaskContext, id object.ObjMetadata,
+	op event.WaitEventOperation) {
+	taskContext.SendEvent(event.Event{
+		Type: event.WaitType,
+		WaitEvent: event.WaitEvent{
+			GroupName:  d.Name(),
+			Identifier: id,
+			Operation:  op,
+		},
+	})
+}
@@ -0,0 +1,412 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/apply/cache"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+// newDAGApplyTask returns a task to apply the deployments a, b, c and d,
+// where b depends on a and d depends on c, and all of them are waited on.
+func newDAGApplyTask(t *testing.T) (*DAGApplyTask, map[string]*unstructured.Unstructured) {
+	var rss []resourceInfo
+	for _, name := range []string{"a", "b", "c", "d"} {
+		rss = append(rss, resourceInfo{
+			group:      "apps",
+			apiVersion: "apps/v1",
+			kind:       "Deployment",
+			name:       name,
+			namespace:  "default",
+			uid:        types.UID("uid-" + name),
+			generation: int64(1),
+		})
+	}
+	objs := toUnstructureds(rss)
+	byName := map[string]*unstructured.Unstructured{}
+	waits := map[object.ObjMetadata]ObjectWait{}
+	for _, obj := range objs {
+		byName[obj.GetName()] = obj
+		waits[object.UnstructuredToObjMetaOrDie(obj)] = ObjectWait{Condition: taskrunner.AllCurrent}
+	}
+	id := func(name string) object.ObjMetadata {
+		return object.UnstructuredToObjMetaOrDie(byName[name])
+	}
+
+	oldAO := applyOptionsFactoryFunc
+	applyOptionsFactoryFunc = func(taskName string, ch chan event.Event, _ common.ServerSideOptions, _ common.DryRunStrategy,
+		_ util.Factory) (applyOptions, error) {
+		return &eventApplyOptions{
+			taskName: taskName,
+			ch:       ch,
+			tracker:  &concurrencyTracker{},
+			delay:    func(string) time.Duration { return 0 },
+		}, nil
+	}
+	t.Cleanup(func() { applyOptionsFactoryFunc = oldAO })
+
+	return &DAGApplyTask{
+		ApplyTask: ApplyTask{
+			TaskName: "apply-dag-0",
+			Objects:  objs,
+			Mapper: testutil.NewFakeRESTMapper(schema.GroupVersionKind{
+				Group:   "apps",
+				Version: "v1",
+				Kind:    "Deployment",
+			}),
+			InfoHelper:  &fakeInfoHelper{},
+			Concurrency: 2,
+		},
+		Dependencies: map[object.ObjMetadata]object.ObjMetadataSet{
+			id("b"): {id("a")},
+			id("d"): {id("c")},
+		},
+		Waits: waits,
+	}, byName
+}
+
+// receiveEvents returns the next n events of the channel as strings.
+func receiveEvents(t *testing.T, ch <-chan event.Event, n int) []string {
+	var events []string
+	for i := 0; i < n; i++ {
+		select {
+		case e := <-ch:
+			switch e.Type {
+			case event.ApplyType:
+				events = append(events, fmt.Sprintf("apply %s %s", e.ApplyEvent.Identifier.Name, e.ApplyEvent.Operation))
+			case event.WaitType:
+				events = append(events, fmt.Sprintf("wait %s %s", e.WaitEvent.Identifier.Name, e.WaitEvent.Operation))
+			default:
+				events = append(events, e.String())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events, received: %v", events)
+		}
+	}
+	return events
+}
+
+func TestDAGApplyTask(t *testing.T) {
+	dagTask, objs := newDAGApplyTask(t)
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+	reconcile := func(name string) {
+		id := object.UnstructuredToObjMetaOrDie(objs[name])
+		resourceCache.Put(id, cache.ResourceStatus{
+			Resource: objs[name],
+			Status:   status.CurrentStatus,
+		})
+		dagTask.StatusUpdate(taskContext, id)
+	}
+
+	dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"apply a Configured",
+		"wait a Pending",
+		"apply c Configured",
+		"wait c Pending",
+	}, receiveEvents(t, eventChannel, 4))
+
+	// d is applied as soon as c is reconciled, while a is still pending.
+	// The events are sent during the status update, so it runs in the
+	// background.
+	go reconcile("c")
+	assert.Equal(t, []string{
+		"wait c Reconciled",
+		"apply d Configured",
+		"wait d Pending",
+	}, receiveEvents(t, eventChannel, 3))
+
+	go reconcile("a")
+	assert.Equal(t, []string{
+		"wait a Reconciled",
+		"apply b Configured",
+		"wait b Pending",
+	}, receiveEvents(t, eventChannel, 3))
+
+	go func() {
+		reconcile("d")
+		reconcile("b")
+	}()
+	assert.Equal(t, []string{
+		"wait d Reconciled",
+		"wait b Reconciled",
+	}, receiveEvents(t, eventChannel, 2))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		require.NoError(t, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+	for name, obj := range objs {
+		assert.Truef(t, taskContext.IsSuccessfulApply(object.UnstructuredToObjMetaOrDie(obj)),
+			"DAGApplyTask should mark object as applied: %s", name)
+	}
+}
+
+func TestDAGApplyTask_Timeout(t *testing.T) {
+	dagTask, objs := newDAGApplyTask(t)
+	for id := range dagTask.Waits {
+		dagTask.Waits[id] = ObjectWait{Condition: taskrunner.AllCurrent, Timeout: 10 * time.Millisecond}
+	}
+	eventChannel := make(chan event.Event)
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, cache.NewResourceCacheMap())
+
+	// The dependents of objects that timed out are still applied, like
+	// with the apply and wait tasks.
+	dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"apply a Configured",
+		"wait a Pending",
+		"wait a Timeout",
+		"apply b Configured",
+		"wait b Pending",
+		"wait b Timeout",
+		"apply c Configured",
+		"wait c Pending",
+		"wait c Timeout",
+		"apply d Configured",
+		"wait d Pending",
+		"wait d Timeout",
+	}, receiveEvents(t, eventChannel, 12))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		require.NoError(t, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+	assert.Equal(t, len(objs), len(taskContext.SuccessfulApplies()))
+}
+
+func TestDAGApplyTask_FailOnTimeout(t *testing.T) {
+	dagTask, objs := newDAGApplyTask(t)
+	dagTask.Condition = taskrunner.AllCurrent
+	dagTask.Timeout = time.Minute
+	dagTask.FailOnTimeout = true
+	// only a is waited on, with a shorter timeout
+	a := object.UnstructuredToObjMetaOrDie(objs["a"])
+	dagTask.Waits = map[object.ObjMetadata]ObjectWait{
+		a: {Condition: taskrunner.AllCurrent, Timeout: 10 * time.Millisecond},
+	}
+	eventChannel := make(chan event.Event)
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, cache.NewResourceCacheMap())
+
+	dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"apply a Configured",
+		"wait a Pending",
+		"wait a Timeout",
+		"apply b Configured",
+		"apply c Configured",
+		"apply d Configured",
+	}, receiveEvents(t, eventChannel, 6))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		assert.Equal(t, &taskrunner.TimeoutError{
+			Identifiers: object.ObjMetadataSet{a},
+			Timeout:     time.Minute,
+			Condition:   taskrunner.AllCurrent,
+			TimedOutResources: []taskrunner.TimedOutResource{
+				{
+					Identifier: a,
+					Status:     status.UnknownStatus,
+					Message:    "resource not cached",
+					Timeout:    10 * time.Millisecond,
+				},
+			},
+		}, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+}
+
+func TestDAGApplyTask_FailFast(t *testing.T) {
+	dagTask, objs := newDAGApplyTask(t)
+	dagTask.Condition = taskrunner.AllCurrentOrAnyFailed
+	for id := range dagTask.Waits {
+		dagTask.Waits[id] = ObjectWait{Condition: taskrunner.AllCurrentOrAnyFailed}
+	}
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+	dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"apply a Configured",
+		"wait a Pending",
+		"apply c Configured",
+		"wait c Pending",
+	}, receiveEvents(t, eventChannel, 4))
+
+	// a failed, so the dependents of both a and c are skipped, and c is
+	// no longer waited on.
+	a := object.UnstructuredToObjMetaOrDie(objs["a"])
+	go func() {
+		resourceCache.Put(a, cache.ResourceStatus{
+			Resource: objs["a"],
+			Status:   status.FailedStatus,
+		})
+		dagTask.StatusUpdate(taskContext, a)
+	}()
+	assert.Equal(t, []string{
+		"wait a Failed",
+		"apply b ApplySkipped",
+		"apply d ApplySkipped",
+	}, receiveEvents(t, eventChannel, 3))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		assert.Equal(t, &taskrunner.ReconcileFailedError{
+			Identifiers: object.ObjMetadataSet{a},
+			Condition:   taskrunner.AllCurrentOrAnyFailed,
+		}, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+	for _, name := range []string{"b", "d"} {
+		assert.Truef(t, taskContext.IsSkippedApply(object.UnstructuredToObjMetaOrDie(objs[name])),
+			"DAGApplyTask should skip the dependents after a failure: %s", name)
+	}
+}
+
+func TestDAGApplyTask_Cancel(t *testing.T) {
+	dagTask, objs := newDAGApplyTask(t)
+	eventChannel := make(chan event.Event)
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, cache.NewResourceCacheMap())
+
+	dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"apply a Configured",
+		"wait a Pending",
+		"apply c Configured",
+		"wait c Pending",
+	}, receiveEvents(t, eventChannel, 4))
+
+	// The objects that were not applied yet are skipped.
+	go dagTask.Cancel(taskContext)
+	assert.Equal(t, []string{
+		"apply b ApplySkipped",
+		"apply d ApplySkipped",
+	}, receiveEvents(t, eventChannel, 2))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		require.NoError(t, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+	for _, name := range []string{"b", "d"} {
+		assert.True(t, taskContext.IsSkippedApply(object.UnstructuredToObjMetaOrDie(objs[name])))
+	}
+}
+
+// newExternalDAGApplyTask returns the task of newDAGApplyTask without
+// waits, where a also depends on a secret that is not applied with it.
+func newExternalDAGApplyTask(t *testing.T) (*DAGApplyTask, map[string]*unstructured.Unstructured, object.ObjMetadata) {
+	dagTask, objs := newDAGApplyTask(t)
+	secretID := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Kind: "Secret"},
+		Namespace: "default",
+		Name:      "secret",
+	}
+	testutil.AddDependsOn(t, secretID).Mutate(objs["a"])
+	dagTask.Waits = map[object.ObjMetadata]ObjectWait{}
+	dagTask.ExternalDependencies = map[object.ObjMetadata]object.ObjMetadataSet{
+		object.UnstructuredToObjMetaOrDie(objs["a"]): {secretID},
+	}
+	return dagTask, objs, secretID
+}
+
+func TestDAGApplyTask_ExternalDependency(t *testing.T) {
+	dagTask, objs, secretID := newExternalDAGApplyTask(t)
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+	// The wait events of the external dependencies are sent on start.
+	go dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"wait secret Pending",
+		"apply c Configured",
+		"apply d Configured",
+	}, receiveEvents(t, eventChannel, 3))
+
+	// a is applied once the secret is Current.
+	go func() {
+		resourceCache.Put(secretID, cache.ResourceStatus{
+			Status: status.CurrentStatus,
+		})
+		dagTask.StatusUpdate(taskContext, secretID)
+	}()
+	assert.Equal(t, []string{
+		"wait secret Reconciled",
+		"apply a Configured",
+		"apply b Configured",
+	}, receiveEvents(t, eventChannel, 3))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		require.NoError(t, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+	assert.Equal(t, len(objs), len(taskContext.SuccessfulApplies()))
+}
+
+func TestDAGApplyTask_ExternalDependencyTimeout(t *testing.T) {
+	dagTask, objs, secretID := newExternalDAGApplyTask(t)
+	dagTask.ExternalTimeout = 10 * time.Millisecond
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	resourceCache.Put(secretID, cache.ResourceStatus{
+		Status: status.NotFoundStatus,
+	})
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+
+	// a fails to apply, but b is still applied, like with the
+	// dependents of objects that failed to apply.
+	go dagTask.Start(taskContext)
+	assert.ElementsMatch(t, []string{
+		"wait secret Pending",
+		"apply c Configured",
+		"apply d Configured",
+		"wait secret Timeout",
+		"apply a ApplyUnspecified",
+		"apply b Configured",
+	}, receiveEvents(t, eventChannel, 6))
+
+	select {
+	case result := <-taskContext.TaskChannel():
+		require.NoError(t, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to finish")
+	}
+	assert.True(t, taskContext.IsFailedApply(object.UnstructuredToObjMetaOrDie(objs["a"])))
+	assert.Equal(t, &applyerror.ExternalDependencyError{
+		Dependency: secretID,
+		Status:     status.NotFoundStatus,
+		Timeout:    10 * time.Millisecond,
+	}, taskContext.ExternalDependencyError(secretID))
+}
@@ -40,6 +40,13 @@ func (i *DeleteInvTask) Identifiers() object.ObjMetadataSet {
 func (i *DeleteInvTask) Start(taskContext *taskrunner.TaskContext) {
 	go func() {
 		klog.V(2).Infof("delete inventory task starting (name: %q)", i.Name())
+		// Keep the inventory if the task runner was cancelled, since
+		// the delete tasks may have skipped some of the objects.
+		if err := taskContext.Context().Err(); err != nil {
+			klog.V(4).Infof("delete inventory skipped (name: %q): %v", i.Name(), err)
+			taskContext.TaskChannel() <- taskrunner.TaskResult{}
+			return
+		}
 		err := i.InvClient.DeleteInventoryObj(i.InvInfo, i.DryRun)
 		// Not found is not error, since this means it was already deleted.
 		if apierrors.IsNotFound(err) {
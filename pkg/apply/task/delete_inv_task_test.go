@@ -4,6 +4,7 @@
 package task
 
 import (
+	"context"
 	"testing"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -41,7 +42,7 @@ func TestDeleteInvTask(t *testing.T) {
 			client.Err = tc.err
 			eventChannel := make(chan event.Event)
 			resourceCache := cache.NewResourceCacheMap()
-			context := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 			task := DeleteInvTask{
 				TaskName:  taskName,
@@ -52,8 +53,8 @@ func TestDeleteInvTask(t *testing.T) {
 			if taskName != task.Name() {
 				t.Errorf("expected task name (%s), got (%s)", taskName, task.Name())
 			}
-			task.Start(context)
-			result := <-context.TaskChannel()
+			task.Start(taskContext)
+			result := <-taskContext.TaskChannel()
 			if tc.isError {
 				if tc.err != result.Err {
 					t.Errorf("running DeleteInvTask expected error (%s), got (%s)", tc.err, result.Err)
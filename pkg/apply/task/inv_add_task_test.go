@@ -4,6 +4,7 @@
 package task
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -111,7 +112,7 @@ func TestInvAddTask(t *testing.T) {
 			client := inventory.NewFakeInventoryClient(tc.initialObjs)
 			eventChannel := make(chan event.Event)
 			resourceCache := cache.NewResourceCacheMap()
-			context := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 			task := InvAddTask{
 				TaskName:  taskName,
@@ -128,8 +129,8 @@ func TestInvAddTask(t *testing.T) {
 			if !task.Identifiers().Equal(applyIds) {
 				t.Errorf("expected task ids (%s), got (%s)", applyIds, task.Identifiers())
 			}
-			task.Start(context)
-			result := <-context.TaskChannel()
+			task.Start(taskContext)
+			result := <-taskContext.TaskChannel()
 			if result.Err != nil {
 				t.Errorf("unexpected error running InvAddTask: %s", result.Err)
 			}
@@ -4,11 +4,13 @@
 package task
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
@@ -54,6 +56,10 @@ func (i *InvSetTask) Identifiers() object.ObjMetadataSet {
 // Removed objects:
 // - Deleted resources (successful)
 // - Abandoned resources (successful)
+//
+// The entries of the applied resources record their UID, generation, the
+// time of the last apply, and their last known status. The retained objects keep
+// their stored entries.
 func (i *InvSetTask) Start(taskContext *taskrunner.TaskContext) {
 	go func() {
 		klog.V(2).Infof("inventory set task starting (name: %q)", i.Name())
@@ -106,13 +112,57 @@ func (i *InvSetTask) Start(taskContext *taskrunner.TaskContext) {
 		invObjs = invObjs.Diff(abandonedObjects)
 
 		klog.V(4).Infof("set inventory %d total objects", len(invObjs))
-		err := i.InvClient.Replace(i.InvInfo, invObjs, i.DryRun)
+		entries := inventory.NewEntries(invObjs)
+		err := i.setAppliedEntries(taskContext, entries, appliedObjs.Diff(abandonedObjects))
+		if err == nil {
+			err = i.InvClient.ReplaceEntries(i.InvInfo, entries, i.DryRun)
+		}
 
 		klog.V(2).Infof("inventory set task completing (name: %q)", i.Name())
 		taskContext.TaskChannel() <- taskrunner.TaskResult{Err: err}
 	}()
 }
 
+// setAppliedEntries sets the entries of the passed applied objects. The
+// objects which were not applied, because they were unchanged, keep the
+// time they were last applied from their stored entry.
+func (i *InvSetTask) setAppliedEntries(taskContext *taskrunner.TaskContext, entries inventory.Entries,
+	appliedObjs object.ObjMetadataSet) error {
+	var storedEntries inventory.Entries
+	for _, id := range appliedObjs {
+		if taskContext.IsUnchangedApply(id) {
+			var err error
+			storedEntries, err = i.InvClient.GetClusterEntries(i.InvInfo, i.DryRun)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	now := metav1.Now()
+	for _, id := range appliedObjs {
+		lastApplied := &now
+		if taskContext.IsUnchangedApply(id) {
+			lastApplied = storedEntries[id].LastApplied
+		}
+		entries[id] = appliedEntry(taskContext, id, lastApplied)
+	}
+	return nil
+}
+
+// appliedEntry returns the inventory entry of the passed applied object.
+func appliedEntry(taskContext *taskrunner.TaskContext, id object.ObjMetadata, lastApplied *metav1.Time) inventory.Entry {
+	entry := inventory.Entry{
+		LastApplied: lastApplied,
+	}
+	entry.UID, _ = taskContext.AppliedResourceUID(id)
+	entry.Generation, _ = taskContext.AppliedGeneration(id)
+	if s := taskContext.ResourceCache().Get(id).Status; s != status.UnknownStatus {
+		entry.Status = s
+	}
+	return entry
+}
+
 // Cancel is not supported by the InvSetTask.
 func (i *InvSetTask) Cancel(_ *taskrunner.TaskContext) {}
 
@@ -4,13 +4,20 @@
 package task
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/cli-utils/pkg/apply/cache"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
 	"sigs.k8s.io/cli-utils/pkg/testutil"
 )
@@ -197,7 +204,7 @@ func TestInvSetTask(t *testing.T) {
 			client := inventory.NewFakeInventoryClient(object.ObjMetadataSet{})
 			eventChannel := make(chan event.Event)
 			resourceCache := cache.NewResourceCacheMap()
-			context := taskrunner.NewTaskContext(eventChannel, resourceCache)
+			taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
 
 			task := InvSetTask{
 				TaskName:      taskName,
@@ -206,28 +213,28 @@ func TestInvSetTask(t *testing.T) {
 				PrevInventory: tc.prevInventory,
 			}
 			for _, applyObj := range tc.appliedObjs {
-				context.AddSuccessfulApply(applyObj, "unusued-uid", int64(0))
+				taskContext.AddSuccessfulApply(applyObj, "unusued-uid", int64(0))
 			}
 			for _, applyFailure := range tc.failedApplies {
-				context.AddFailedApply(applyFailure)
+				taskContext.AddFailedApply(applyFailure)
 			}
 			for _, pruneObj := range tc.failedDeletes {
-				context.AddFailedDelete(pruneObj)
+				taskContext.AddFailedDelete(pruneObj)
 			}
 			for _, skippedApply := range tc.skippedApplies {
-				context.AddSkippedApply(skippedApply)
+				taskContext.AddSkippedApply(skippedApply)
 			}
 			for _, skippedDelete := range tc.skippedDeletes {
-				context.AddSkippedDelete(skippedDelete)
+				taskContext.AddSkippedDelete(skippedDelete)
 			}
 			for _, abandonedObj := range tc.abandonedObjs {
-				context.AddAbandonedObject(abandonedObj)
+				taskContext.AddAbandonedObject(abandonedObj)
 			}
 			if taskName != task.Name() {
 				t.Errorf("expected task name (%s), got (%s)", taskName, task.Name())
 			}
-			task.Start(context)
-			result := <-context.TaskChannel()
+			task.Start(taskContext)
+			result := <-taskContext.TaskChannel()
 			if result.Err != nil {
 				t.Errorf("unexpected error running InvAddTask: %s", result.Err)
 			}
@@ -238,3 +245,48 @@ func TestInvSetTask(t *testing.T) {
 		})
 	}
 }
+
+func TestInvSetTask_Entries(t *testing.T) {
+	id1 := object.UnstructuredToObjMetaOrDie(obj1)
+	id2 := object.UnstructuredToObjMetaOrDie(obj2)
+	id3 := object.UnstructuredToObjMetaOrDie(obj3)
+
+	// id2 failed to apply, so it keeps the entry of the previous apply.
+	client := inventory.NewFakeInventoryClient(object.ObjMetadataSet{id1, id2, id3})
+	prevEntry := inventory.Entry{UID: "uid-2", Generation: 1, Status: status.CurrentStatus}
+	client.Entries[id2] = prevEntry
+	// id3 was unchanged, so it keeps the time it was last applied.
+	lastApplied := metav1.NewTime(time.Now().Add(-time.Hour))
+	client.Entries[id3] = inventory.Entry{UID: "uid-3", Generation: 1, LastApplied: &lastApplied}
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	resourceCache.Put(id1, cache.ResourceStatus{Status: status.InProgressStatus})
+	taskContext := taskrunner.NewTaskContext(context.TODO(), eventChannel, resourceCache)
+	taskContext.AddSuccessfulApply(id1, "uid-1", int64(3))
+	taskContext.AddFailedApply(id2)
+	taskContext.AddUnchangedApply(id3, "uid-3", int64(2))
+
+	task := InvSetTask{
+		TaskName:      taskName,
+		InvClient:     client,
+		InvInfo:       nil,
+		PrevInventory: object.ObjMetadataSet{id1, id2, id3},
+	}
+	task.Start(taskContext)
+	result := <-taskContext.TaskChannel()
+	require.NoError(t, result.Err)
+
+	entries, err := client.GetClusterEntries(nil, common.DryRunNone)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	entry := entries[id1]
+	assert.Equal(t, types.UID("uid-1"), entry.UID)
+	assert.Equal(t, int64(3), entry.Generation)
+	assert.Equal(t, status.InProgressStatus, entry.Status)
+	require.NotNil(t, entry.LastApplied)
+	assert.True(t, entry.LastApplied.After(lastApplied.Time))
+	assert.Equal(t, prevEntry, entries[id2])
+	entry = entries[id3]
+	assert.Equal(t, int64(2), entry.Generation)
+	assert.Equal(t, &lastApplied, entry.LastApplied)
+}
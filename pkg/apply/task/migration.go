@@ -0,0 +1,175 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// csaFieldManager is the field manager kubectl uses for client-side apply.
+const csaFieldManager = "kubectl-client-side-apply"
+
+// migrateFromClientSide migrates the live object from client-side apply
+// to server-side apply. The fields owned by the client-side apply field
+// managers are moved to the server-side apply field manager, and the
+// last-applied-configuration annotation is removed. Returns nil if the
+// object does not exist, or if there is nothing to migrate.
+func (a *ApplyTask) migrateFromClientSide(ctx context.Context, id object.ObjMetadata) (*event.ClientSideMigration, error) {
+	ri, err := a.resourceInterface(id)
+	if err != nil {
+		return nil, err
+	}
+	live, err := ri.Get(ctx, id.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	patch, migration, err := clientSideMigrationPatch(live, a.fieldManager())
+	if err != nil || migration == nil {
+		return nil, err
+	}
+	if _, err := ri.Patch(ctx, id.Name, types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+// clientSideMigrationPatch returns the JSON patch that migrates the live
+// object from client-side apply to server-side apply with the passed
+// field manager. The Update entries of the client-side apply field
+// managers are merged into the Apply entry of the field manager. The
+// patch fails if the object was changed since it was read. Returns a nil
+// migration if there is nothing to migrate.
+func clientSideMigrationPatch(live *unstructured.Unstructured, fieldManager string) ([]byte,
+	*event.ClientSideMigration, error) {
+	var entries []metav1.ManagedFieldsEntry
+	fields := make(map[string]interface{})
+	managers := sets.NewString()
+	for _, entry := range live.GetManagedFields() {
+		isCSA := entry.Operation == metav1.ManagedFieldsOperationUpdate &&
+			(entry.Manager == csaFieldManager || entry.Manager == fieldManager)
+		isSSA := entry.Operation == metav1.ManagedFieldsOperationApply && entry.Manager == fieldManager
+		if entry.Subresource != "" || (!isCSA && !isSSA) {
+			entries = append(entries, entry)
+			continue
+		}
+		if isCSA {
+			managers.Insert(entry.Manager)
+		}
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		entryFields := make(map[string]interface{})
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &entryFields); err != nil {
+			return nil, nil, err
+		}
+		unionFields(fields, entryFields)
+	}
+	_, hasLastApplied := live.GetAnnotations()[corev1.LastAppliedConfigAnnotation]
+	if managers.Len() == 0 && !hasLastApplied {
+		return nil, nil, nil
+	}
+
+	removeLastAppliedField(fields)
+	if len(fields) > 0 {
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		now := metav1.Now()
+		entries = append(entries, metav1.ManagedFieldsEntry{
+			Manager:    fieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: live.GetAPIVersion(),
+			Time:       &now,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: raw},
+		})
+	}
+
+	ops := []map[string]interface{}{
+		{"op": "test", "path": "/metadata/resourceVersion", "value": live.GetResourceVersion()},
+	}
+	// The managed fields are only replaced if client-side apply entries
+	// were merged. The server ignores an empty list of managed fields, so
+	// a list with a single empty entry is sent to reset them instead.
+	if managers.Len() > 0 {
+		if len(entries) == 0 {
+			entries = []metav1.ManagedFieldsEntry{{}}
+		}
+		ops = append(ops, map[string]interface{}{
+			"op": "replace", "path": "/metadata/managedFields", "value": entries,
+		})
+	}
+	if hasLastApplied {
+		ops = append(ops, map[string]interface{}{
+			"op":   "remove",
+			"path": "/metadata/annotations/" + escapeJSONPointer(corev1.LastAppliedConfigAnnotation),
+		})
+	}
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return nil, nil, err
+	}
+	migration := &event.ClientSideMigration{
+		LastAppliedRemoved: hasLastApplied,
+	}
+	if managers.Len() > 0 {
+		migration.FieldManagers = managers.List()
+	}
+	return patch, migration, nil
+}
+
+// unionFields merges the src field set into dst. Both are field sets in
+// the FieldsV1 format.
+func unionFields(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			unionFields(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// removeLastAppliedField removes the last-applied-configuration
+// annotation from the field set, since the annotation is removed from
+// the object.
+func removeLastAppliedField(fields map[string]interface{}) {
+	metadata, ok := fields["f:metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	annotations, ok := metadata["f:annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(annotations, "f:"+corev1.LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		delete(metadata, "f:annotations")
+	}
+	if len(metadata) == 0 {
+		delete(fields, "f:metadata")
+	}
+}
+
+// escapeJSONPointer escapes a key for use in a JSON patch path.
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package task
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+)
+
+func TestClientSideMigrationPatch(t *testing.T) {
+	csaEntry := metav1.ManagedFieldsEntry{
+		Manager:    "kubectl-client-side-apply",
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: "apps/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{".":{},` +
+			`"f:kubectl.kubernetes.io/last-applied-configuration":{}}},"f:spec":{"f:replicas":{}}}`)},
+	}
+	ssaEntry := metav1.ManagedFieldsEntry{
+		Manager:    "kubectl",
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: "apps/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:paused":{}}}`)},
+	}
+	controllerEntry := metav1.ManagedFieldsEntry{
+		Manager:    "kube-controller-manager",
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: "apps/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{}}}`)},
+	}
+
+	testCases := map[string]struct {
+		managedFields     []metav1.ManagedFieldsEntry
+		lastApplied       bool
+		expectedMigration *event.ClientSideMigration
+		expectedManagers  []string
+		expectedFields    string
+	}{
+		"nothing to migrate": {
+			managedFields:     []metav1.ManagedFieldsEntry{ssaEntry, controllerEntry},
+			expectedMigration: nil,
+		},
+		"client-side apply fields and annotation are migrated": {
+			managedFields: []metav1.ManagedFieldsEntry{csaEntry, controllerEntry},
+			lastApplied:   true,
+			expectedMigration: &event.ClientSideMigration{
+				FieldManagers:      []string{"kubectl-client-side-apply"},
+				LastAppliedRemoved: true,
+			},
+			expectedManagers: []string{"kube-controller-manager", "kubectl"},
+			expectedFields:   `{"f:metadata":{"f:annotations":{".":{}}},"f:spec":{"f:replicas":{}}}`,
+		},
+		"client-side apply fields are merged with server-side apply fields": {
+			managedFields: []metav1.ManagedFieldsEntry{csaEntry, ssaEntry},
+			expectedMigration: &event.ClientSideMigration{
+				FieldManagers: []string{"kubectl-client-side-apply"},
+			},
+			expectedManagers: []string{"kubectl"},
+			expectedFields:   `{"f:metadata":{"f:annotations":{".":{}}},"f:spec":{"f:paused":{},"f:replicas":{}}}`,
+		},
+		"only the annotation is removed": {
+			managedFields: []metav1.ManagedFieldsEntry{ssaEntry},
+			lastApplied:   true,
+			expectedMigration: &event.ClientSideMigration{
+				LastAppliedRemoved: true,
+			},
+		},
+		"managed fields are reset if no fields are left": {
+			managedFields: []metav1.ManagedFieldsEntry{{
+				Manager:    "kubectl-client-side-apply",
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				APIVersion: "apps/v1",
+				FieldsType: "FieldsV1",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{` +
+					`"f:kubectl.kubernetes.io/last-applied-configuration":{}}}}`)},
+			}},
+			lastApplied: true,
+			expectedMigration: &event.ClientSideMigration{
+				FieldManagers:      []string{"kubectl-client-side-apply"},
+				LastAppliedRemoved: true,
+			},
+			expectedManagers: []string{""},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			live := &unstructured.Unstructured{}
+			live.SetAPIVersion("apps/v1")
+			live.SetKind("Deployment")
+			live.SetName("foo")
+			live.SetResourceVersion("42")
+			live.SetManagedFields(tc.managedFields)
+			if tc.lastApplied {
+				live.SetAnnotations(map[string]string{corev1.LastAppliedConfigAnnotation: "{}"})
+			}
+
+			patch, migration, err := clientSideMigrationPatch(live, "kubectl")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedMigration, migration)
+			if tc.expectedMigration == nil {
+				assert.Nil(t, patch)
+				return
+			}
+
+			var ops []struct {
+				Op    string          `json:"op"`
+				Path  string          `json:"path"`
+				Value json.RawMessage `json:"value"`
+			}
+			require.NoError(t, json.Unmarshal(patch, &ops))
+			assert.Equal(t, "test", ops[0].Op)
+			assert.Equal(t, "/metadata/resourceVersion", ops[0].Path)
+			assert.JSONEq(t, `"42"`, string(ops[0].Value))
+
+			var opPaths []string
+			for _, op := range ops[1:] {
+				opPaths = append(opPaths, op.Op+" "+op.Path)
+			}
+			var expectedPaths []string
+			if tc.expectedManagers != nil {
+				expectedPaths = append(expectedPaths, "replace /metadata/managedFields")
+			}
+			if tc.lastApplied {
+				expectedPaths = append(expectedPaths,
+					"remove /metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration")
+			}
+			assert.Equal(t, expectedPaths, opPaths)
+			if tc.expectedManagers == nil {
+				return
+			}
+
+			var entries []metav1.ManagedFieldsEntry
+			require.NoError(t, json.Unmarshal(ops[1].Value, &entries))
+			var managers []string
+			for _, entry := range entries {
+				managers = append(managers, entry.Manager)
+			}
+			assert.Equal(t, tc.expectedManagers, managers)
+			if tc.expectedFields == "" {
+				return
+			}
+			applyEntry := entries[len(entries)-1]
+			assert.Equal(t, metav1.ManagedFieldsOperationApply, applyEntry.Operation)
+			assert.JSONEq(t, tc.expectedFields, string(applyEntry.FieldsV1.Raw))
+		})
+	}
+}
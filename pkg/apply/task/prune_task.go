@@ -9,6 +9,7 @@ import (
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/apply/filter"
 	"sigs.k8s.io/cli-utils/pkg/apply/prune"
+	"sigs.k8s.io/cli-utils/pkg/apply/retry"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -28,6 +29,9 @@ type PruneTask struct {
 	// True if we are destroying, which deletes the inventory object
 	// as well (possibly) the inventory namespace.
 	Destroy bool
+	// RetryPolicy defines how deletes that fail with a transient error
+	// are retried.
+	RetryPolicy retry.Policy
 }
 
 func (p *PruneTask) Name() string {
@@ -69,6 +73,7 @@ func (p *PruneTask) Start(taskContext *taskrunner.TaskContext) {
 				DryRunStrategy:    p.DryRunStrategy,
 				PropagationPolicy: p.PropagationPolicy,
 				Destroy:           p.Destroy,
+				RetryPolicy:       p.RetryPolicy,
 			},
 		)
 		klog.V(2).Infof("prune task completing (name: %q)", p.Name())
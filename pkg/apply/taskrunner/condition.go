@@ -21,13 +21,19 @@ const (
 	// has reached the NotFound status, i.e. they are all deleted
 	// from the cluster.
 	AllNotFound Condition = "AllNotFound"
+
+	// AllCurrentOrAnyFailed Condition means all the provided resources
+	// has reached the Current status, like AllCurrent, but waiting
+	// ends early as soon as any of the resources reaches the Failed
+	// status.
+	AllCurrentOrAnyFailed Condition = "AllCurrentOrAnyFailed"
 )
 
 // Meets returns true if the provided status meets the condition and
 // false if it does not.
 func (c Condition) Meets(s status.Status) bool {
 	switch c {
-	case AllCurrent:
+	case AllCurrent, AllCurrentOrAnyFailed:
 		return s == status.CurrentStatus
 	case AllNotFound:
 		return s == status.NotFoundStatus
@@ -41,7 +47,7 @@ func (c Condition) Meets(s status.Status) bool {
 // Resources in the cache older that the applied generation are non-matches.
 func conditionMet(taskContext *TaskContext, ids object.ObjMetadataSet, c Condition) bool {
 	switch c {
-	case AllCurrent:
+	case AllCurrent, AllCurrentOrAnyFailed:
 		return allMatchStatus(taskContext, ids, status.CurrentStatus)
 	case AllNotFound:
 		return allMatchStatus(taskContext, ids, status.NotFoundStatus)
@@ -50,6 +56,29 @@ func conditionMet(taskContext *TaskContext, ids object.ObjMetadataSet, c Conditi
 	}
 }
 
+// FailFast returns true if waiting for the condition should end as soon
+// as any of the resources reaches the Failed status.
+func (c Condition) FailFast() bool {
+	return c == AllCurrentOrAnyFailed
+}
+
+// ObjectReconciled returns true if the object meets the condition,
+// according to the ResourceCache. Resources in the cache older than the
+// applied generation are not considered reconciled.
+func ObjectReconciled(taskContext *TaskContext, id object.ObjMetadata, c Condition) bool {
+	return conditionMet(taskContext, object.ObjMetadataSet{id}, c)
+}
+
+// ObjectFailed returns true if the condition fails fast and the object
+// reached the Failed status, according to the ResourceCache. Resources in
+// the cache older than the applied generation are not considered failed.
+func ObjectFailed(taskContext *TaskContext, id object.ObjMetadata, c Condition) bool {
+	if !c.FailFast() {
+		return false
+	}
+	return allMatchStatus(taskContext, object.ObjMetadataSet{id}, status.FailedStatus)
+}
+
 // allMatchStatus checks whether all of the resources provided have the provided status.
 // Resources with older generations are considered non-matching.
 func allMatchStatus(taskContext *TaskContext, ids object.ObjMetadataSet, s status.Status) bool {
@@ -58,6 +87,10 @@ func allMatchStatus(taskContext *TaskContext, ids object.ObjMetadataSet, s statu
 		if cached.Status != s {
 			return false
 		}
+		if s == status.NotFoundStatus {
+			// deleted objects have no generation to compare
+			continue
+		}
 
 		applyGen, _ := taskContext.AppliedGeneration(id) // generation at apply time
 		cachedGen := int64(0)
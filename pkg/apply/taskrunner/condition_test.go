@@ -4,6 +4,7 @@
 package taskrunner
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -154,7 +155,7 @@ func TestCollector_ConditionMet(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			taskContext := NewTaskContext(nil, resourceCache)
+			taskContext := NewTaskContext(context.TODO(), nil, resourceCache)
 
 			if tc.appliedGen != nil {
 				for id, gen := range tc.appliedGen {
@@ -4,6 +4,9 @@
 package taskrunner
 
 import (
+	"context"
+	"sync"
+
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
@@ -13,8 +16,9 @@ import (
 )
 
 // NewTaskContext returns a new TaskContext
-func NewTaskContext(eventChannel chan event.Event, resourceCache cache.ResourceCache) *TaskContext {
+func NewTaskContext(ctx context.Context, eventChannel chan event.Event, resourceCache cache.ResourceCache) *TaskContext {
 	return &TaskContext{
+		ctx:               ctx,
 		taskChannel:       make(chan TaskResult),
 		eventChannel:      eventChannel,
 		resourceCache:     resourceCache,
@@ -24,12 +28,18 @@ func NewTaskContext(eventChannel chan event.Event, resourceCache cache.ResourceC
 		skippedApplies:    make(map[object.ObjMetadata]struct{}),
 		skippedDeletes:    make(map[object.ObjMetadata]struct{}),
 		abandonedObjects:  make(map[object.ObjMetadata]struct{}),
+		externalErrors:    make(map[object.ObjMetadata]error),
 	}
 }
 
 // TaskContext defines a context that is passed between all
-// the tasks that is in a taskqueue.
+// the tasks that is in a taskqueue. It is safe for concurrent use, so
+// tasks may process objects in parallel.
 type TaskContext struct {
+	// mu guards the maps of the objects.
+	mu sync.RWMutex
+
+	ctx               context.Context
 	taskChannel       chan TaskResult
 	eventChannel      chan event.Event
 	resourceCache     cache.ResourceCache
@@ -39,6 +49,13 @@ type TaskContext struct {
 	skippedApplies    map[object.ObjMetadata]struct{}
 	skippedDeletes    map[object.ObjMetadata]struct{}
 	abandonedObjects  map[object.ObjMetadata]struct{}
+	externalErrors    map[object.ObjMetadata]error
+}
+
+// Context returns the context of the task runner. Tasks should stop
+// processing objects as soon as possible after it is cancelled.
+func (tc *TaskContext) Context() context.Context {
+	return tc.ctx
 }
 
 func (tc *TaskContext) TaskChannel() chan TaskResult {
@@ -61,6 +78,8 @@ func (tc *TaskContext) SendEvent(e event.Event) {
 
 // IsSuccessfulApply returns true if the object apply was successful
 func (tc *TaskContext) IsSuccessfulApply(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	_, found := tc.successfulApplies[id]
 	return found
 }
@@ -69,15 +88,41 @@ func (tc *TaskContext) IsSuccessfulApply(id object.ObjMetadata) bool {
 // resource identified by the provided id. Currently, we keep information
 // about the generation of the resource after the apply operation completed.
 func (tc *TaskContext) AddSuccessfulApply(id object.ObjMetadata, uid types.UID, gen int64) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
 	tc.successfulApplies[id] = applyInfo{
 		generation: gen,
 		uid:        uid,
 	}
 }
 
+// AddUnchangedApply updates the context with information about the
+// resource identified by the provided id, like AddSuccessfulApply, for a
+// resource which was not applied because it is unchanged, or because it
+// is create-only and already exists.
+func (tc *TaskContext) AddUnchangedApply(id object.ObjMetadata, uid types.UID, gen int64) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.successfulApplies[id] = applyInfo{
+		generation: gen,
+		uid:        uid,
+		unchanged:  true,
+	}
+}
+
+// IsUnchangedApply returns true if the resource was added with
+// AddUnchangedApply, i.e. it was not applied because it is unchanged.
+func (tc *TaskContext) IsUnchangedApply(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	return tc.successfulApplies[id].unchanged
+}
+
 // SuccessfulApplies returns all the objects (as ObjMetadata) that
 // were added as applied resources to the TaskContext.
 func (tc *TaskContext) SuccessfulApplies() object.ObjMetadataSet {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	all := make(object.ObjMetadataSet, 0, len(tc.successfulApplies))
 	for r := range tc.successfulApplies {
 		all = append(all, r)
@@ -87,6 +132,8 @@ func (tc *TaskContext) SuccessfulApplies() object.ObjMetadataSet {
 
 // AppliedResourceUID looks up the UID of the given resource
 func (tc *TaskContext) AppliedResourceUID(id object.ObjMetadata) (types.UID, bool) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	ai, found := tc.successfulApplies[id]
 	if klog.V(4).Enabled() {
 		if found {
@@ -104,6 +151,8 @@ func (tc *TaskContext) AppliedResourceUID(id object.ObjMetadata) (types.UID, boo
 // AppliedResourceUIDs returns a set with the UIDs of all the
 // successfully applied resources.
 func (tc *TaskContext) AppliedResourceUIDs() sets.String {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	uids := sets.NewString()
 	for _, ai := range tc.successfulApplies {
 		uid := string(ai.uid)
@@ -117,6 +166,8 @@ func (tc *TaskContext) AppliedResourceUIDs() sets.String {
 // AppliedGeneration looks up the generation of the given resource
 // after it was applied.
 func (tc *TaskContext) AppliedGeneration(id object.ObjMetadata) (int64, bool) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	ai, found := tc.successfulApplies[id]
 	if klog.V(4).Enabled() {
 		if found {
@@ -133,84 +184,130 @@ func (tc *TaskContext) AppliedGeneration(id object.ObjMetadata) (int64, bool) {
 
 // IsFailedApply returns true if the object failed to apply
 func (tc *TaskContext) IsFailedApply(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	_, found := tc.failedApplies[id]
 	return found
 }
 
 // AddFailedApply registers that the object failed to apply
 func (tc *TaskContext) AddFailedApply(id object.ObjMetadata) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
 	tc.failedApplies[id] = struct{}{}
 }
 
 // FailedApplies returns all the objects that failed to apply
 func (tc *TaskContext) FailedApplies() object.ObjMetadataSet {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	return object.ObjMetadataSetFromMap(tc.failedApplies)
 }
 
 // IsFailedDelete returns true if the object failed to delete
 func (tc *TaskContext) IsFailedDelete(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	_, found := tc.failedDeletes[id]
 	return found
 }
 
 // AddFailedDelete registers that the object failed to delete
 func (tc *TaskContext) AddFailedDelete(id object.ObjMetadata) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
 	tc.failedDeletes[id] = struct{}{}
 }
 
 // FailedDeletes returns all the objects that failed to delete
 func (tc *TaskContext) FailedDeletes() object.ObjMetadataSet {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	return object.ObjMetadataSetFromMap(tc.failedDeletes)
 }
 
 // IsSkippedApply returns true if the object apply was skipped
 func (tc *TaskContext) IsSkippedApply(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	_, found := tc.skippedApplies[id]
 	return found
 }
 
 // AddSkippedApply registers that the object apply was skipped
 func (tc *TaskContext) AddSkippedApply(id object.ObjMetadata) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
 	tc.skippedApplies[id] = struct{}{}
 }
 
 // SkippedApplies returns all the objects where apply was skipped
 func (tc *TaskContext) SkippedApplies() object.ObjMetadataSet {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	return object.ObjMetadataSetFromMap(tc.skippedApplies)
 }
 
 // IsSkippedDelete returns true if the object delete was skipped
 func (tc *TaskContext) IsSkippedDelete(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	_, found := tc.skippedDeletes[id]
 	return found
 }
 
 // AddSkippedDelete registers that the object delete was skipped
 func (tc *TaskContext) AddSkippedDelete(id object.ObjMetadata) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
 	tc.skippedDeletes[id] = struct{}{}
 }
 
 // SkippedDeletes returns all the objects where deletion was skipped
 func (tc *TaskContext) SkippedDeletes() object.ObjMetadataSet {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	return object.ObjMetadataSetFromMap(tc.skippedDeletes)
 }
 
 // IsAbandonedObject returns true if the object is abandoned
 func (tc *TaskContext) IsAbandonedObject(id object.ObjMetadata) bool {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	_, found := tc.abandonedObjects[id]
 	return found
 }
 
 // AddAbandonedObject registers that the object is abandoned
 func (tc *TaskContext) AddAbandonedObject(id object.ObjMetadata) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
 	tc.abandonedObjects[id] = struct{}{}
 }
 
 // AbandonedObjects returns all the abandoned objects
 func (tc *TaskContext) AbandonedObjects() object.ObjMetadataSet {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
 	return object.ObjMetadataSetFromMap(tc.abandonedObjects)
 }
 
+// AddExternalDependencyError registers that the external dependency was
+// not reconciled, so the objects that depend on it are not applied.
+func (tc *TaskContext) AddExternalDependencyError(id object.ObjMetadata, err error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.externalErrors[id] = err
+}
+
+// ExternalDependencyError returns the error of the external dependency,
+// or nil if it was reconciled or not waited on.
+func (tc *TaskContext) ExternalDependencyError(id object.ObjMetadata) error {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	return tc.externalErrors[id]
+}
+
 // applyInfo captures information about resources that have been
 // applied. This is captured in the TaskContext so other tasks
 // running later might use this information.
@@ -223,4 +320,8 @@ type applyInfo struct {
 
 	// uid captures the uid of the resource that has been applied.
 	uid types.UID
+
+	// unchanged is true if the resource was not applied, because it
+	// was unchanged since it was last applied.
+	unchanged bool
 }
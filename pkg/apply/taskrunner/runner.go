@@ -5,14 +5,18 @@ package taskrunner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/cli-utils/pkg/apply/cache"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/apply/poller"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
 	pollevent "sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -43,6 +47,10 @@ type Options struct {
 	PollInterval     time.Duration
 	UseCache         bool
 	EmitStatusEvents bool
+
+	// CustomStatusReadersFactoryFunc is passed to the statusPoller
+	// to compute the status of custom resource types.
+	CustomStatusReadersFactoryFunc func(engine.ClusterReader, meta.RESTMapper) map[schema.GroupKind]engine.StatusReader
 }
 
 // Run starts the execution of the taskqueue. It will start the
@@ -55,8 +63,9 @@ func (tsr *taskStatusRunner) Run(ctx context.Context, taskQueue chan Task,
 	// causing the poller to be cancelled.
 	statusCtx, cancelFunc := context.WithCancel(context.Background())
 	statusChannel := tsr.statusPoller.Poll(statusCtx, tsr.identifiers, polling.Options{
-		PollInterval: options.PollInterval,
-		UseCache:     options.UseCache,
+		PollInterval:                   options.PollInterval,
+		UseCache:                       options.UseCache,
+		CustomStatusReadersFactoryFunc: options.CustomStatusReadersFactoryFunc,
 	})
 
 	o := baseOptions{
@@ -72,6 +81,12 @@ func (tsr *taskStatusRunner) Run(ctx context.Context, taskQueue chan Task,
 	return err
 }
 
+// FailedApplies returns the objects that failed to apply in the last
+// call to Run.
+func (tsr *taskStatusRunner) FailedApplies() object.ObjMetadataSet {
+	return tsr.baseRunner.failedApplies
+}
+
 // NewTaskRunner returns a new taskRunner. It can process taskqueues
 // that does not contain any wait tasks.
 // TODO: Do we need this abstraction layer now that baseRunner doesn't need a collector?
@@ -117,6 +132,9 @@ func newBaseRunner(cache cache.ResourceCache) *baseRunner {
 // taskStatusRunner.
 type baseRunner struct {
 	cache cache.ResourceCache
+
+	// failedApplies are the objects that failed to apply in the last run.
+	failedApplies object.ObjMetadataSet
 }
 
 type baseOptions struct {
@@ -139,7 +157,15 @@ func (b *baseRunner) run(ctx context.Context, taskQueue chan Task,
 	// taskContext is passed into all tasks when they are started. It
 	// provides access to the eventChannel and the taskChannel, and
 	// also provides a way to pass data between tasks.
-	taskContext := NewTaskContext(eventChannel, b.cache)
+	// The tasks observe the run context through the TaskContext. It is
+	// cancelled with the passed in context, or when a task aborts the
+	// remaining tasks, like a wait task whose resources failed.
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	taskContext := NewTaskContext(runCtx, eventChannel, b.cache)
+	defer func() {
+		b.failedApplies = taskContext.FailedApplies()
+	}()
 
 	// Find and start the first task in the queue.
 	currentTask, done := b.nextTask(taskQueue, taskContext)
@@ -152,9 +178,16 @@ func (b *baseRunner) run(ctx context.Context, taskQueue chan Task,
 	// wait tasks can be interrupted, so for all other tasks we need
 	// to wait for the currently running one to finish before we can
 	// exit.
+	// Cancellation of the context is handled differently, since the
+	// tasks themselves observe the context through the TaskContext.
 	abort := false
 	var abortReason error
 
+	// runErr is the error of a task that aborted the remaining tasks,
+	// or of a wait task that timed out, which is returned once all the
+	// tasks have finished.
+	var runErr error
+
 	// We do this so we can set the doneCh to a nil channel after
 	// it has been closed. This is needed to avoid a busy loop.
 	doneCh := ctx.Done()
@@ -233,24 +266,45 @@ func (b *baseRunner) run(ctx context.Context, taskQueue chan Task,
 				},
 			})
 			if msg.Err != nil {
-				return msg.Err
+				if _, ok := IsReconcileFailedError(msg.Err); ok {
+					// Resources that failed to reconcile abort the remaining
+					// tasks the same way as a cancellation, so the objects
+					// that depend on them are skipped and the inventory
+					// tasks still run.
+					runErr = msg.Err
+					cancelRun()
+				} else if _, ok := IsTimeoutError(msg.Err); ok {
+					// Timeouts don't abort the remaining tasks, but the
+					// first one is returned once they have all finished.
+					if runErr == nil {
+						runErr = msg.Err
+					}
+				} else {
+					return msg.Err
+				}
 			}
 			if abort {
 				return abortReason
 			}
 			currentTask, done = b.nextTask(taskQueue, taskContext)
 			// If there are no more tasks, we are done. So just
-			// return.
+			// return. If the context was cancelled along the way,
+			// the remaining tasks only skipped their objects, so we
+			// report the cancellation to the caller.
 			if done {
-				return nil
+				if runErr != nil {
+					return runErr
+				}
+				return ctx.Err()
 			}
 		// The doneCh will be closed if the passed in context is cancelled.
-		// If so, we just set the abort flag and wait for the currently running
-		// task to complete before we exit.
+		// If so, we cancel the currently running task, but keep processing
+		// the task queue. Since the remaining tasks inherit the cancelled
+		// context, the apply and prune tasks will skip their objects and
+		// the wait tasks will exit immediately. This allows the inventory
+		// tasks to record which objects were actually applied or pruned.
 		case <-doneCh:
 			doneCh = nil // Set doneCh to nil so we don't enter a busy loop.
-			abort = true
-			abortReason = ctx.Err() // always non-nil when doneCh is closed
 			currentTask.Cancel(taskContext)
 		}
 	}
@@ -309,12 +363,25 @@ type TimeoutError struct {
 	TimedOutResources []TimedOutResource
 }
 
+// TimedOutResource is a resource that didn't reach the condition of a
+// WaitTask before it timed out.
 type TimedOutResource struct {
 	Identifier object.ObjMetadata
 
+	// Status is the last known status of the resource.
 	Status status.Status
 
+	// Message is the last known status message of the resource.
 	Message string
+
+	// Timeout is the reconcile timeout budget of the resource, if it
+	// was set for the object with the reconcile-timeout annotation.
+	Timeout time.Duration
+
+	// Condition is the condition the resource was waited on for, if it
+	// was set for the object with the wait-for annotation and differs
+	// from the condition of the WaitTask.
+	Condition Condition
 }
 
 func (te TimeoutError) Error() string {
@@ -327,6 +394,38 @@ func (te TimeoutError) Error() string {
 		te.Timeout.Seconds(), len(te.Identifiers), ids, te.Condition)
 }
 
+// ReconcileFailedError is returned by a wait task that fails fast, when
+// resources it waits for reach the Failed status. The task runner aborts
+// the remaining tasks when it gets this error.
+type ReconcileFailedError struct {
+	// Identifiers contains the identifiers of the resources that reached
+	// the Failed status.
+	Identifiers object.ObjMetadataSet
+
+	// Condition defines the criteria for which the task was waiting.
+	Condition Condition
+}
+
+func (e *ReconcileFailedError) Error() string {
+	ids := []string{}
+	for _, id := range e.Identifiers {
+		ids = append(ids, id.String())
+	}
+	sort.Strings(ids)
+	return fmt.Sprintf("%d resources (%v) failed to reach condition %s",
+		len(e.Identifiers), ids, e.Condition)
+}
+
+// IsReconcileFailedError checks whether a given error is
+// a ReconcileFailedError.
+func IsReconcileFailedError(err error) (*ReconcileFailedError, bool) {
+	var e *ReconcileFailedError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return &ReconcileFailedError{}, false
+}
+
 // IsTimeoutError checks whether a given error is
 // a TimeoutError.
 func IsTimeoutError(err error) (*TimeoutError, bool) {
@@ -46,6 +46,7 @@ func TestBaseRunner(t *testing.T) {
 		statusEvents       []pollevent.Event
 		expectedEventTypes []event.Type
 		expectedWaitEvents []event.WaitEvent
+		expectedError      error
 	}{
 		"wait task runs until condition is met": {
 			tasks: []Task{
@@ -169,8 +170,8 @@ func TestBaseRunner(t *testing.T) {
 		},
 		"wait task times out eventually (InProgress)": {
 			tasks: []Task{
-				NewWaitTask("wait", object.ObjMetadataSet{depID, cmID}, AllCurrent,
-					2*time.Second, testutil.NewFakeRESTMapper()),
+				failOnTimeout(NewWaitTask("wait", object.ObjMetadataSet{depID, cmID}, AllCurrent,
+					2*time.Second, testutil.NewFakeRESTMapper())),
 			},
 			statusEventsDelay: time.Second,
 			statusEvents: []pollevent.Event{
@@ -199,6 +200,17 @@ func TestBaseRunner(t *testing.T) {
 				event.WaitType,   // deployment timeout error
 				event.ActionGroupType,
 			},
+			expectedError: &TimeoutError{
+				Identifiers: object.ObjMetadataSet{depID, cmID},
+				Timeout:     2 * time.Second,
+				Condition:   AllCurrent,
+				TimedOutResources: []TimedOutResource{
+					{
+						Identifier: depID,
+						Status:     status.InProgressStatus,
+					},
+				},
+			},
 			expectedWaitEvents: []event.WaitEvent{
 				{
 					GroupName:  "wait",
@@ -308,9 +320,7 @@ func TestBaseRunner(t *testing.T) {
 			close(eventChannel)
 			wg.Wait()
 
-			if err != nil {
-				t.Errorf("expected no error, but got %v", err)
-			}
+			assert.Equal(t, tc.expectedError, err)
 
 			if want, got := len(tc.expectedEventTypes), len(events); want != got {
 				t.Errorf("expected %d events, but got %d", want, got)
@@ -358,10 +368,11 @@ func TestBaseRunnerCancellation(t *testing.T) {
 				},
 			},
 			contextTimeout: 2 * time.Second,
-			expectedError:  context.Canceled,
+			expectedError:  context.DeadlineExceeded,
 			expectedEventTypes: []event.Type{
 				event.ActionGroupType,
-				event.ApplyType,
+				event.ActionGroupType,
+				event.ActionGroupType,
 				event.ActionGroupType,
 			},
 		},
@@ -377,11 +388,33 @@ func TestBaseRunnerCancellation(t *testing.T) {
 				},
 			},
 			contextTimeout: 2 * time.Second,
-			expectedError:  context.Canceled,
+			expectedError:  context.DeadlineExceeded,
 			expectedEventTypes: []event.Type{
 				event.ActionGroupType,
 				event.WaitType, // pending
 				event.ActionGroupType,
+				event.ActionGroupType,
+				event.ActionGroupType,
+			},
+		},
+		"cancellation before wait task is started": {
+			tasks: []Task{
+				&fakeApplyTask{
+					resultEvent: event.Event{
+						Type: event.ApplyType,
+					},
+					duration: 4 * time.Second,
+				},
+				NewWaitTask("wait", object.ObjMetadataSet{depID}, AllCurrent,
+					20*time.Second, testutil.NewFakeRESTMapper()),
+			},
+			contextTimeout: 2 * time.Second,
+			expectedError:  context.DeadlineExceeded,
+			expectedEventTypes: []event.Type{
+				event.ActionGroupType,
+				event.ActionGroupType,
+				event.ActionGroupType,
+				event.ActionGroupType,
 			},
 		},
 		"error while custom task is running": {
@@ -408,6 +441,47 @@ func TestBaseRunnerCancellation(t *testing.T) {
 				event.ActionGroupType,
 			},
 		},
+		"failed resource while wait task is running": {
+			tasks: []Task{
+				NewWaitTask("wait", object.ObjMetadataSet{depID}, AllCurrentOrAnyFailed,
+					20*time.Second, testutil.NewFakeRESTMapper()),
+				&fakeApplyTask{
+					resultEvent: event.Event{
+						Type: event.ApplyType,
+					},
+					duration: 2 * time.Second,
+				},
+				NewWaitTask("wait-2", object.ObjMetadataSet{cmID}, AllCurrentOrAnyFailed,
+					20*time.Second, testutil.NewFakeRESTMapper()),
+			},
+			statusEventsDelay: 1 * time.Second,
+			statusEvents: []pollevent.Event{
+				{
+					EventType: pollevent.ResourceUpdateEvent,
+					Resource: &pollevent.ResourceStatus{
+						Identifier: depID,
+						Status:     status.FailedStatus,
+					},
+				},
+			},
+			contextTimeout: 30 * time.Second,
+			expectedError: &ReconcileFailedError{
+				Identifiers: object.ObjMetadataSet{depID},
+				Condition:   AllCurrentOrAnyFailed,
+			},
+			// The remaining tasks are aborted: the apply task skips its
+			// objects and the wait task exits immediately.
+			expectedEventTypes: []event.Type{
+				event.ActionGroupType,
+				event.WaitType, // pending
+				event.WaitType, // failed
+				event.ActionGroupType,
+				event.ActionGroupType,
+				event.ActionGroupType,
+				event.ActionGroupType,
+				event.ActionGroupType,
+			},
+		},
 		"error from status poller while wait task is running": {
 			tasks: []Task{
 				NewWaitTask("wait", object.ObjMetadataSet{depID}, AllCurrent,
@@ -487,6 +561,10 @@ func TestBaseRunnerCancellation(t *testing.T) {
 				t.Errorf("expected error %v, but didn't get one", tc.expectedError)
 			}
 
+			if _, ok := IsReconcileFailedError(tc.expectedError); ok {
+				assert.Equal(t, tc.expectedError, err)
+			}
+
 			if want, got := len(tc.expectedEventTypes), len(events); want != got {
 				t.Errorf("expected %d events, but got %d", want, got)
 			}
@@ -522,8 +600,13 @@ func (f *fakeApplyTask) Identifiers() object.ObjMetadataSet {
 
 func (f *fakeApplyTask) Start(taskContext *TaskContext) {
 	go func() {
-		<-time.NewTimer(f.duration).C
-		taskContext.SendEvent(f.resultEvent)
+		select {
+		case <-time.NewTimer(f.duration).C:
+			taskContext.SendEvent(f.resultEvent)
+		case <-taskContext.Context().Done():
+			// Interrupted - skip the result event, like the real tasks
+			// skip the objects they never reached.
+		}
 		taskContext.TaskChannel() <- TaskResult{
 			Err: f.err,
 		}
@@ -533,3 +616,10 @@ func (f *fakeApplyTask) Start(taskContext *TaskContext) {
 func (f *fakeApplyTask) Cancel(_ *TaskContext) {}
 
 func (f *fakeApplyTask) StatusUpdate(_ *TaskContext, _ object.ObjMetadata) {}
+
+// failOnTimeout makes the passed WaitTask return a TimeoutError when it
+// times out.
+func failOnTimeout(task *WaitTask) *WaitTask {
+	task.FailOnTimeout = true
+	return task
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/klog/v2"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/object"
 )
@@ -45,6 +47,21 @@ func NewWaitTask(name string, ids object.ObjMetadataSet, cond Condition, timeout
 	}
 }
 
+// NewExternalWaitTask creates a new wait task that waits until the
+// external dependencies specified by ids are all Current. External
+// dependencies are not applied, so the objects that depend on them
+// fail to apply if they are not Current before the timeout.
+func NewExternalWaitTask(name string, ids object.ObjMetadataSet, timeout time.Duration, mapper meta.RESTMapper) *WaitTask {
+	return &WaitTask{
+		name:      name,
+		Ids:       ids,
+		Condition: AllCurrent,
+		Timeout:   timeout,
+		External:  true,
+		mapper:    mapper,
+	}
+}
+
 // WaitTask is an implementation of the Task interface that is used
 // to wait for a set of resources (identified by a slice of ObjMetadata)
 // will all meet the condition specified. It also specifies a timeout
@@ -62,14 +79,39 @@ type WaitTask struct {
 	// Timeout defines how long we are willing to wait for the condition
 	// to be met.
 	Timeout time.Duration
+	// ObjectConditions are the conditions of the resources that set one
+	// with the wait-for annotation. They are used instead of Condition.
+	ObjectConditions map[object.ObjMetadata]Condition
+	// ObjectTimeouts are the reconcile timeout budgets of the resources
+	// that set one with the reconcile-timeout annotation. They are used
+	// instead of Timeout, and reported in the TimeoutError if the
+	// resources time out.
+	ObjectTimeouts map[object.ObjMetadata]time.Duration
+	// FailOnTimeout is true if the task returns a TimeoutError when
+	// resources don't reach their condition before their timeout.
+	// Otherwise only the ReconcileTimeout events are sent.
+	FailOnTimeout bool
+	// External is true if the resources are external dependencies
+	// of the applied objects. An ExternalDependencyError is recorded
+	// in the TaskContext for every one of them that times out.
+	External bool
 	// mapper is the RESTMapper to update after CRDs have been reconciled
 	mapper meta.RESTMapper
 	// cancelFunc is a function that will cancel the timeout timer
 	// on the task.
 	cancelFunc context.CancelFunc
+	// started is the time the task started, which the timeouts of the
+	// resources count from.
+	started time.Time
 	// pending is the set of resources that we are still waiting for.
 	pending object.ObjMetadataSet
-	// mu protects the pending ObjMetadataSet
+	// failed is the set of resources that reached the Failed status,
+	// if the condition fails fast.
+	failed object.ObjMetadataSet
+	// timedOut are the resources that didn't reach their condition
+	// before their timeout.
+	timedOut []TimedOutResource
+	// mu protects the pending, failed and timedOut resources
 	mu sync.RWMutex
 }
 
@@ -86,23 +128,29 @@ func (w *WaitTask) Identifiers() object.ObjMetadataSet {
 }
 
 // Start kicks off the task. For the wait task, this just means
-// setting up the timeout timer.
+// setting up the timeout timers of the resources.
 func (w *WaitTask) Start(taskContext *TaskContext) {
 	klog.V(2).Infof("wait task starting (name: %q, objects: %d)",
 		w.Name(), len(w.Ids))
 
-	// TODO: inherit context from task runner, passed through the TaskContext
-	ctx := context.Background()
-
-	// use a context wrapper to handle complete/cancel/timeout
-	if w.Timeout > 0 {
-		ctx, w.cancelFunc = context.WithTimeout(ctx, w.Timeout)
-	} else {
-		ctx, w.cancelFunc = context.WithCancel(ctx)
+	// inherit the context from the task runner
+	runnerCtx := taskContext.Context()
+
+	// use a context wrapper to handle complete/cancel. The resources
+	// time out one by one, and the task completes once none of them
+	// are pending anymore.
+	var ctx context.Context
+	ctx, w.cancelFunc = context.WithCancel(runnerCtx)
+	w.started = time.Now()
+
+	// If the task runner was cancelled before this task started,
+	// there is nothing to wait for, because the preceding apply and
+	// prune tasks skipped their objects.
+	if runnerCtx.Err() == nil {
+		w.startInner(taskContext)
+		go w.runTimers(ctx, taskContext)
 	}
 
-	w.startInner(taskContext)
-
 	// A goroutine to handle ending the WaitTask.
 	go func() {
 		// Block until complete/cancel/timeout
@@ -112,19 +160,22 @@ func (w *WaitTask) Start(taskContext *TaskContext) {
 
 		klog.V(2).Infof("wait task completing (name: %q,): %v", w.name, err)
 
-		switch err {
-		case context.Canceled:
-			// happy path - cancelled or completed (not considered an error)
-		case context.DeadlineExceeded:
-			// timed out
-			w.sendTimeoutEvents(taskContext)
+		var result TaskResult
+		switch {
+		case runnerCtx.Err() != nil:
+			// task runner cancelled (not considered a timeout, even if
+			// the runner context had a deadline)
+		case err == context.Canceled:
+			// happy path - cancelled or completed (not considered an error),
+			// unless resources failed to reconcile or timed out
+			result.Err = w.resultError()
 		}
 
 		// Update RESTMapper to pick up new custom resource types
 		w.updateRESTMapper(taskContext)
 
 		// Done here. signal completion to the task runner
-		taskContext.TaskChannel() <- TaskResult{}
+		taskContext.TaskChannel() <- result
 	}()
 }
 
@@ -153,6 +204,9 @@ func (w *WaitTask) startInner(taskContext *TaskContext) {
 			w.sendEvent(taskContext, id, event.ReconcileSkipped)
 		case w.reconciledByID(taskContext, id):
 			w.sendEvent(taskContext, id, event.Reconciled)
+		case w.failedByID(taskContext, id):
+			w.failed = append(w.failed, id)
+			w.sendEvent(taskContext, id, event.ReconcileFailed)
 		default:
 			pending = append(pending, id)
 			w.sendEvent(taskContext, id, event.ReconcilePending)
@@ -160,38 +214,159 @@ func (w *WaitTask) startInner(taskContext *TaskContext) {
 	}
 	w.pending = pending
 
-	if len(pending) == 0 {
+	switch {
+	case len(w.failed) > 0:
+		// failed - exit without waiting for the pending objects
+		klog.V(3).Infof("%d objects failed to reconcile (name: %q)", len(w.failed), w.name)
+		w.cancelFunc()
+	case len(pending) == 0:
 		// all reconciled - clear pending and exit
 		klog.V(3).Infof("all objects reconciled or skipped (name: %q)", w.name)
 		w.cancelFunc()
 	}
 }
 
-// sendTimeoutEvents sends a timeout event for every remaining pending object
-// The pending set is read locked during execution of sendTimeoutEvents.
-func (w *WaitTask) sendTimeoutEvents(taskContext *TaskContext) {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
+// runTimers times out the pending resources as their timeouts expire,
+// until the context is done. The task completes once no resources are
+// pending anymore.
+func (w *WaitTask) runTimers(ctx context.Context, taskContext *TaskContext) {
+	for _, timeout := range w.timeouts() {
+		timer := time.NewTimer(time.Until(w.started.Add(timeout)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			w.timeoutExpired(taskContext)
+		}
+	}
+}
 
+// timeouts returns the distinct timeouts of the resources, in ascending
+// order. Resources without a timeout are waited on until the task is
+// cancelled.
+func (w *WaitTask) timeouts() []time.Duration {
+	var timeouts []time.Duration
+	seen := map[time.Duration]bool{}
+	for _, id := range w.Ids {
+		timeout := w.timeout(id)
+		if timeout <= 0 || seen[timeout] {
+			continue
+		}
+		seen[timeout] = true
+		timeouts = append(timeouts, timeout)
+	}
+	sort.Slice(timeouts, func(i, j int) bool {
+		return timeouts[i] < timeouts[j]
+	})
+	return timeouts
+}
+
+// timeoutExpired sends a timeout event for every pending resource whose
+// timeout expired, and completes the task if no resources are pending
+// anymore.
+// The pending set is write locked during execution of timeoutExpired.
+func (w *WaitTask) timeoutExpired(taskContext *TaskContext) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	pending := object.ObjMetadataSet{}
 	for _, id := range w.pending {
-		w.sendEvent(taskContext, id, event.ReconcileTimeout)
+		if !w.expired(id) {
+			pending = append(pending, id)
+			continue
+		}
+		w.timeOut(taskContext, id)
+	}
+	w.pending = pending
+
+	if len(pending) == 0 {
+		klog.V(3).Infof("all objects reconciled, skipped or timed out (name: %q)", w.name)
+		w.cancelFunc()
 	}
 }
 
+// timeOut sends a timeout event for the resource and records it as timed
+// out. Pending external dependencies are recorded as errors of the
+// objects that depend on them instead.
+// The caller must hold the write lock of the pending set.
+func (w *WaitTask) timeOut(taskContext *TaskContext, id object.ObjMetadata) {
+	w.sendEvent(taskContext, id, event.ReconcileTimeout)
+	cached := taskContext.ResourceCache().Get(id)
+	if w.External {
+		taskContext.AddExternalDependencyError(id, &applyerror.ExternalDependencyError{
+			Dependency: id,
+			Status:     cached.Status,
+			Timeout:    w.Timeout,
+		})
+		return
+	}
+	resource := TimedOutResource{
+		Identifier: id,
+		Status:     cached.Status,
+		Message:    cached.StatusMessage,
+		Timeout:    w.ObjectTimeouts[id],
+	}
+	if cond, found := w.ObjectConditions[id]; found && cond != w.Condition {
+		resource.Condition = cond
+	}
+	w.timedOut = append(w.timedOut, resource)
+}
+
+// isTimedOut returns true if the resource timed out.
+// The caller must hold the lock of the pending set.
+func (w *WaitTask) isTimedOut(id object.ObjMetadata) bool {
+	for _, resource := range w.timedOut {
+		if resource.Identifier == id {
+			return true
+		}
+	}
+	return false
+}
+
+// expired returns true if the timeout of the resource expired.
+func (w *WaitTask) expired(id object.ObjMetadata) bool {
+	timeout := w.timeout(id)
+	return timeout > 0 && !time.Now().Before(w.started.Add(timeout))
+}
+
+// condition returns the condition the resource is waited on for.
+func (w *WaitTask) condition(id object.ObjMetadata) Condition {
+	if cond, found := w.ObjectConditions[id]; found {
+		return cond
+	}
+	return w.Condition
+}
+
+// timeout returns how long the resource is waited on for.
+func (w *WaitTask) timeout(id object.ObjMetadata) time.Duration {
+	if timeout, found := w.ObjectTimeouts[id]; found {
+		return timeout
+	}
+	return w.Timeout
+}
+
 // reconciledByID checks whether the condition set in the task is currently met
 // for the specified object given the status of resource in the cache.
 func (w *WaitTask) reconciledByID(taskContext *TaskContext, id object.ObjMetadata) bool {
-	return conditionMet(taskContext, object.ObjMetadataSet{id}, w.Condition)
+	return ObjectReconciled(taskContext, id, w.condition(id))
+}
+
+// failedByID checks whether the condition set in the task fails fast and
+// the specified object reached the Failed status, given the status of
+// resource in the cache. Resources in the cache older that the applied
+// generation are not considered failed.
+func (w *WaitTask) failedByID(taskContext *TaskContext, id object.ObjMetadata) bool {
+	return ObjectFailed(taskContext, id, w.condition(id))
 }
 
 // skipped returns true if the object failed or was skipped by a preceding
 // apply/delete/prune task.
 func (w *WaitTask) skipped(taskContext *TaskContext, id object.ObjMetadata) bool {
-	if w.Condition == AllCurrent &&
-		taskContext.IsFailedApply(id) || taskContext.IsSkippedApply(id) {
+	if taskContext.IsFailedApply(id) || taskContext.IsSkippedApply(id) {
 		return true
 	}
-	if w.Condition == AllNotFound &&
+	if w.condition(id) == AllNotFound &&
 		taskContext.IsFailedDelete(id) || taskContext.IsSkippedDelete(id) {
 		return true
 	}
@@ -216,13 +391,25 @@ func (w *WaitTask) StatusUpdate(taskContext *TaskContext, id object.ObjMetadata)
 	}
 
 	switch {
+	case w.failed.Contains(id):
+		// already failed - ignore
+		return
+	case w.isTimedOut(id):
+		// already timed out - ignore
+		return
 	case w.pending.Contains(id):
 		// pending - check if reconciled
-		if w.reconciledByID(taskContext, id) {
+		switch {
+		case w.reconciledByID(taskContext, id):
 			// reconciled - remove from pending & send event
 			w.pending = w.pending.Remove(id)
 			w.sendEvent(taskContext, id, event.Reconciled)
-		} else {
+		case w.failedByID(taskContext, id):
+			// failed - remove from pending, send event & exit
+			w.pending = w.pending.Remove(id)
+			w.fail(taskContext, id)
+			return
+		default:
 			// can't be all reconciled now, so don't bother checking
 			return
 		}
@@ -233,8 +420,18 @@ func (w *WaitTask) StatusUpdate(taskContext *TaskContext, id object.ObjMetadata)
 		// skipped - ignore
 		return
 	default:
-		// reconciled - check if unreconciled
+		// reconciled - check if failed or unreconciled
+		if w.failedByID(taskContext, id) {
+			// failed - send event & exit
+			w.fail(taskContext, id)
+			return
+		}
 		if !w.reconciledByID(taskContext, id) {
+			if w.expired(id) {
+				// unreconciled after its timeout - time out
+				w.timeOut(taskContext, id)
+				break
+			}
 			// unreconciled - add to pending & send event
 			w.pending = append(w.pending, id)
 			w.sendEvent(taskContext, id, event.ReconcilePending)
@@ -244,7 +441,7 @@ func (w *WaitTask) StatusUpdate(taskContext *TaskContext, id object.ObjMetadata)
 	}
 
 	// if all conditions are met, complete the wait task
-	if conditionMet(taskContext, w.pending, w.Condition) {
+	if w.allReconciled(taskContext, w.pending) {
 		// all reconciled - clear pending and exit
 		w.pending = object.ObjMetadataSet{}
 		klog.V(3).Infof("all objects reconciled or skipped (name: %q)", w.name)
@@ -252,6 +449,55 @@ func (w *WaitTask) StatusUpdate(taskContext *TaskContext, id object.ObjMetadata)
 	}
 }
 
+// fail records that the object reached the Failed status, sends the
+// ReconcileFailed event and ends the wait without waiting for the
+// remaining pending objects.
+// The caller must hold the write lock of the pending set.
+func (w *WaitTask) fail(taskContext *TaskContext, id object.ObjMetadata) {
+	w.failed = append(w.failed, id)
+	w.sendEvent(taskContext, id, event.ReconcileFailed)
+	klog.V(3).Infof("object failed to reconcile (name: %q, object: %q)", w.name, id)
+	w.cancelFunc()
+}
+
+// allReconciled returns true if all the passed resources meet their
+// condition.
+func (w *WaitTask) allReconciled(taskContext *TaskContext, ids object.ObjMetadataSet) bool {
+	for _, id := range ids {
+		if !w.reconciledByID(taskContext, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// resultError returns a ReconcileFailedError with the resources that
+// reached the Failed status. Otherwise, if the task fails on timeout, it
+// returns a TimeoutError with the resources that timed out. Returns nil
+// if neither happened.
+// The failed and timed out resources are read locked during execution
+// of resultError.
+func (w *WaitTask) resultError() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if len(w.failed) > 0 {
+		return &ReconcileFailedError{
+			Identifiers: w.failed,
+			Condition:   w.Condition,
+		}
+	}
+	if w.FailOnTimeout && len(w.timedOut) > 0 {
+		return &TimeoutError{
+			Identifiers:       w.Ids,
+			Timeout:           w.Timeout,
+			Condition:         w.Condition,
+			TimedOutResources: w.timedOut,
+		}
+	}
+	return nil
+}
+
 // updateRESTMapper resets the RESTMapper if CRDs were applied, so that new
 // resource types can be applied by subsequent tasks.
 // TODO: find a way to add/remove mappers without resetting the entire mapper
@@ -270,13 +516,23 @@ func (w *WaitTask) updateRESTMapper(taskContext *TaskContext) {
 	}
 
 	klog.V(5).Infof("resetting RESTMapper")
-	ddRESTMapper, err := extractDeferredDiscoveryRESTMapper(w.mapper)
-	if err != nil {
+	if err := ResetRESTMapper(w.mapper); err != nil {
 		if klog.V(4).Enabled() {
 			klog.Errorf("error resetting RESTMapper: %v", err)
 		}
 	}
+}
+
+// ResetRESTMapper resets the DeferredDiscoveryRESTMapper wrapped by the
+// passed RESTMapper, so that it picks up the resource types of CRDs that
+// have been applied.
+func ResetRESTMapper(mapper meta.RESTMapper) error {
+	ddRESTMapper, err := extractDeferredDiscoveryRESTMapper(mapper)
+	if err != nil {
+		return err
+	}
 	ddRESTMapper.Reset()
+	return nil
 }
 
 // extractDeferredDiscoveryRESTMapper unwraps the provided RESTMapper
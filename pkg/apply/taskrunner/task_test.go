@@ -4,11 +4,13 @@
 package taskrunner
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/cli-utils/pkg/apply/cache"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -83,7 +85,7 @@ func TestWaitTask_CompleteEventually(t *testing.T) {
 
 	eventChannel := make(chan event.Event)
 	resourceCache := cache.NewResourceCacheMap()
-	taskContext := NewTaskContext(eventChannel, resourceCache)
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
 	defer close(eventChannel)
 
 	// mark deployment 1 & 2 as applied
@@ -207,6 +209,97 @@ loop:
 		len(receivedEvents), len(expectedEvents))
 }
 
+func TestWaitTask_FailFast(t *testing.T) {
+	testDeployment1ID := testutil.ToIdentifier(t, testDeployment1YAML)
+	testDeployment1 := testutil.Unstructured(t, testDeployment1YAML)
+	testDeployment2ID := testutil.ToIdentifier(t, testDeployment2YAML)
+	ids := object.ObjMetadataSet{
+		testDeployment1ID,
+		testDeployment2ID,
+	}
+	// long enough that the test times out first, if the task doesn't fail fast
+	waitTimeout := 1 * time.Minute
+	taskName := "wait-1"
+	task := NewWaitTask(taskName, ids, AllCurrentOrAnyFailed,
+		waitTimeout, testutil.NewFakeRESTMapper())
+
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
+	defer close(eventChannel)
+
+	// mark deployment 1 & 2 as applied
+	taskContext.AddSuccessfulApply(testDeployment1ID, "unused", 1)
+	taskContext.AddSuccessfulApply(testDeployment2ID, "unused", 1)
+
+	// run task async, to let the test collect events
+	go func() {
+		// start the task
+		task.Start(taskContext)
+
+		// mark deployment1 as Failed
+		resourceCache.Put(testDeployment1ID, cache.ResourceStatus{
+			Resource: testDeployment1,
+			Status:   status.FailedStatus,
+		})
+		// tell the WaitTask deployment1 has new status
+		task.StatusUpdate(taskContext, testDeployment1ID)
+	}()
+
+	// wait for task result
+	timer := time.NewTimer(5 * time.Second)
+	receivedEvents := []event.Event{}
+loop:
+	for {
+		select {
+		case e := <-taskContext.EventChannel():
+			receivedEvents = append(receivedEvents, e)
+		case res := <-taskContext.TaskChannel():
+			timer.Stop()
+			assert.Equal(t, &ReconcileFailedError{
+				Identifiers: object.ObjMetadataSet{testDeployment1ID},
+				Condition:   AllCurrentOrAnyFailed,
+			}, res.Err)
+			break loop
+		case <-timer.C:
+			t.Fatalf("timed out waiting for TaskResult")
+		}
+	}
+
+	expectedEvents := []event.Event{
+		// deployment1 pending
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment1ID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+		// deployment2 pending
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment2ID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+		// deployment1 failed, ending the wait without deployment2
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment1ID,
+				Operation:  event.ReconcileFailed,
+			},
+		},
+	}
+	testutil.AssertEqual(t, expectedEvents, receivedEvents,
+		"Actual events (%d) do not match expected events (%d)",
+		len(receivedEvents), len(expectedEvents))
+}
+
 func TestWaitTask_Timeout(t *testing.T) {
 	testDeployment1ID := testutil.ToIdentifier(t, testDeployment1YAML)
 	testDeployment1 := testutil.Unstructured(t, testDeployment1YAML)
@@ -223,10 +316,15 @@ func TestWaitTask_Timeout(t *testing.T) {
 	taskName := "wait-2"
 	task := NewWaitTask(taskName, ids, AllCurrent,
 		waitTimeout, testutil.NewFakeRESTMapper())
+	// deployment 2 set its timeout with the reconcile-timeout annotation
+	task.ObjectTimeouts = map[object.ObjMetadata]time.Duration{
+		testDeployment2ID: waitTimeout,
+	}
+	task.FailOnTimeout = true
 
 	eventChannel := make(chan event.Event)
 	resourceCache := cache.NewResourceCacheMap()
-	taskContext := NewTaskContext(eventChannel, resourceCache)
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
 	defer close(eventChannel)
 
 	// mark deployment 1 & 2 as applied
@@ -262,7 +360,19 @@ loop:
 			receivedEvents = append(receivedEvents, e)
 		case res := <-taskContext.TaskChannel():
 			timer.Stop()
-			assert.NoError(t, res.Err)
+			assert.Equal(t, &TimeoutError{
+				Identifiers: ids,
+				Timeout:     waitTimeout,
+				Condition:   AllCurrent,
+				TimedOutResources: []TimedOutResource{
+					{
+						Identifier: testDeployment2ID,
+						Status:     status.UnknownStatus,
+						Message:    "resource not cached",
+						Timeout:    waitTimeout,
+					},
+				},
+			}, res.Err)
 			break loop
 		case <-timer.C:
 			t.Fatalf("timed out waiting for TaskResult")
@@ -334,6 +444,217 @@ loop:
 		len(receivedEvents), len(expectedEvents))
 }
 
+func TestWaitTask_ObjectTimeouts(t *testing.T) {
+	testDeployment1ID := testutil.ToIdentifier(t, testDeployment1YAML)
+	testDeployment2ID := testutil.ToIdentifier(t, testDeployment2YAML)
+	testDeployment2 := testutil.Unstructured(t, testDeployment2YAML)
+	testDeployment3ID := testutil.ToIdentifier(t, testDeployment3YAML)
+	ids := object.ObjMetadataSet{
+		testDeployment1ID,
+		testDeployment2ID,
+		testDeployment3ID,
+	}
+	taskName := "wait-0"
+	task := NewWaitTask(taskName, ids, AllCurrent,
+		time.Minute, testutil.NewFakeRESTMapper())
+	// deployment 1 times out long before the others, deployment 3 is
+	// waited on until it is deleted
+	task.ObjectTimeouts = map[object.ObjMetadata]time.Duration{
+		testDeployment1ID: 100 * time.Millisecond,
+	}
+	task.ObjectConditions = map[object.ObjMetadata]Condition{
+		testDeployment3ID: AllNotFound,
+	}
+
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
+	defer close(eventChannel)
+
+	taskContext.AddSuccessfulApply(testDeployment1ID, "unused", 1)
+	taskContext.AddSuccessfulApply(testDeployment2ID, "unused", 1)
+	taskContext.AddSuccessfulApply(testDeployment3ID, "unused", 1)
+
+	// run task async, to let the test collect events
+	go task.Start(taskContext)
+
+	// wait for task result
+	timer := time.NewTimer(5 * time.Second)
+	receivedEvents := []event.Event{}
+loop:
+	for {
+		select {
+		case e := <-taskContext.EventChannel():
+			receivedEvents = append(receivedEvents, e)
+			if e.WaitEvent.Operation != event.ReconcileTimeout {
+				continue
+			}
+			// deployment1 timed out, so the others reconcile
+			go func() {
+				resourceCache.Put(testDeployment2ID, cache.ResourceStatus{
+					Resource: testDeployment2,
+					Status:   status.CurrentStatus,
+				})
+				task.StatusUpdate(taskContext, testDeployment2ID)
+				resourceCache.Put(testDeployment3ID, cache.ResourceStatus{
+					Status: status.NotFoundStatus,
+				})
+				task.StatusUpdate(taskContext, testDeployment3ID)
+			}()
+		case res := <-taskContext.TaskChannel():
+			timer.Stop()
+			// timeouts are only reported as errors if the task fails on timeout
+			assert.NoError(t, res.Err)
+			break loop
+		case <-timer.C:
+			t.Fatalf("timed out waiting for TaskResult")
+		}
+	}
+
+	expectedEvents := []event.Event{
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment1ID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment2ID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment3ID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+		// deployment1 timed out without waiting for the task timeout
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment1ID,
+				Operation:  event.ReconcileTimeout,
+			},
+		},
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment2ID,
+				Operation:  event.Reconciled,
+			},
+		},
+		// deployment3 reconciled when it was deleted
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment3ID,
+				Operation:  event.Reconciled,
+			},
+		},
+	}
+	testutil.AssertEqual(t, expectedEvents, receivedEvents,
+		"Actual events (%d) do not match expected events (%d)",
+		len(receivedEvents), len(expectedEvents))
+}
+
+func TestWaitTask_ExternalTimeout(t *testing.T) {
+	testDeployment1ID := testutil.ToIdentifier(t, testDeployment1YAML)
+	testDeployment1 := testutil.Unstructured(t, testDeployment1YAML)
+	testDeployment2ID := testutil.ToIdentifier(t, testDeployment2YAML)
+	ids := object.ObjMetadataSet{
+		testDeployment1ID,
+		testDeployment2ID,
+	}
+	waitTimeout := 100 * time.Millisecond
+	taskName := "wait-external-0"
+	task := NewExternalWaitTask(taskName, ids, waitTimeout, testutil.NewFakeRESTMapper())
+
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
+	defer close(eventChannel)
+
+	// deployment1 is Current, deployment2 does not exist
+	resourceCache.Put(testDeployment1ID, cache.ResourceStatus{
+		Resource: testDeployment1,
+		Status:   status.CurrentStatus,
+	})
+	resourceCache.Put(testDeployment2ID, cache.ResourceStatus{
+		Status: status.NotFoundStatus,
+	})
+
+	go task.Start(taskContext)
+
+	// wait for task result
+	timer := time.NewTimer(5 * time.Second)
+	receivedEvents := []event.Event{}
+loop:
+	for {
+		select {
+		case e := <-taskContext.EventChannel():
+			receivedEvents = append(receivedEvents, e)
+		case res := <-taskContext.TaskChannel():
+			timer.Stop()
+			assert.NoError(t, res.Err)
+			break loop
+		case <-timer.C:
+			t.Fatalf("timed out waiting for TaskResult")
+		}
+	}
+
+	expectedEvents := []event.Event{
+		// deployment1 reconciled
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment1ID,
+				Operation:  event.Reconciled,
+			},
+		},
+		// deployment2 pending
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment2ID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+		// deployment2 timeout
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeployment2ID,
+				Operation:  event.ReconcileTimeout,
+			},
+		},
+	}
+	testutil.AssertEqual(t, expectedEvents, receivedEvents,
+		"Actual events (%d) do not match expected events (%d)",
+		len(receivedEvents), len(expectedEvents))
+
+	// Only the dependency that timed out has an error.
+	assert.NoError(t, taskContext.ExternalDependencyError(testDeployment1ID))
+	assert.Equal(t, &applyerror.ExternalDependencyError{
+		Dependency: testDeployment2ID,
+		Status:     status.NotFoundStatus,
+		Timeout:    waitTimeout,
+	}, taskContext.ExternalDependencyError(testDeployment2ID))
+}
+
 func TestWaitTask_StartAndComplete(t *testing.T) {
 	testDeploymentID := testutil.ToIdentifier(t, testDeployment1YAML)
 	testDeployment := testutil.Unstructured(t, testDeployment1YAML)
@@ -347,7 +668,7 @@ func TestWaitTask_StartAndComplete(t *testing.T) {
 
 	eventChannel := make(chan event.Event)
 	resourceCache := cache.NewResourceCacheMap()
-	taskContext := NewTaskContext(eventChannel, resourceCache)
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
 	defer close(eventChannel)
 
 	// mark the deployment as applied
@@ -411,7 +732,7 @@ func TestWaitTask_Cancel(t *testing.T) {
 
 	eventChannel := make(chan event.Event)
 	resourceCache := cache.NewResourceCacheMap()
-	taskContext := NewTaskContext(eventChannel, resourceCache)
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
 	defer close(eventChannel)
 
 	// run task async, to let the test collect events
@@ -461,6 +782,61 @@ loop:
 		len(receivedEvents), len(expectedEvents))
 }
 
+func TestWaitTask_RunnerContextDeadline(t *testing.T) {
+	testDeploymentID := testutil.ToIdentifier(t, testDeployment1YAML)
+	ids := object.ObjMetadataSet{
+		testDeploymentID,
+	}
+	waitTimeout := 5 * time.Second
+	taskName := "wait-5"
+	task := NewWaitTask(taskName, ids, AllCurrent,
+		waitTimeout, testutil.NewFakeRESTMapper())
+
+	// The runner context expires before the wait timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	eventChannel := make(chan event.Event)
+	resourceCache := cache.NewResourceCacheMap()
+	taskContext := NewTaskContext(ctx, eventChannel, resourceCache)
+	defer close(eventChannel)
+
+	// run task async, to let the test collect events
+	go task.Start(taskContext)
+
+	// wait for first task result
+	timer := time.NewTimer(10 * time.Second)
+	receivedEvents := []event.Event{}
+loop:
+	for {
+		select {
+		case e := <-taskContext.EventChannel():
+			receivedEvents = append(receivedEvents, e)
+		case res := <-taskContext.TaskChannel():
+			timer.Stop()
+			assert.NoError(t, res.Err)
+			break loop
+		case <-timer.C:
+			t.Fatalf("timed out waiting for TaskResult")
+		}
+	}
+
+	// no timeout events sent when the runner context is done
+	expectedEvents := []event.Event{
+		// deployment1 pending
+		{
+			Type: event.WaitType,
+			WaitEvent: event.WaitEvent{
+				GroupName:  taskName,
+				Identifier: testDeploymentID,
+				Operation:  event.ReconcilePending,
+			},
+		},
+	}
+	testutil.AssertEqual(t, expectedEvents, receivedEvents,
+		"Actual events (%d) do not match expected events (%d)",
+		len(receivedEvents), len(expectedEvents))
+}
+
 func TestWaitTask_SingleTaskResult(t *testing.T) {
 	testDeploymentID := testutil.ToIdentifier(t, testDeployment1YAML)
 	testDeployment := testutil.Unstructured(t, testDeployment1YAML)
@@ -475,7 +851,7 @@ func TestWaitTask_SingleTaskResult(t *testing.T) {
 	// buffer events, because they're sent by StatusUpdate
 	eventChannel := make(chan event.Event, 10)
 	resourceCache := cache.NewResourceCacheMap()
-	taskContext := NewTaskContext(eventChannel, resourceCache)
+	taskContext := NewTaskContext(context.TODO(), eventChannel, resourceCache)
 	defer close(eventChannel)
 
 	// mark the deployment as applied
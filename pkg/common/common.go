@@ -25,6 +25,37 @@ const (
 	// used as a suffix of the inventory object name. Example:
 	//   inventory-1e5824fb
 	InventoryHash = "cli-utils.sigs.k8s.io/inventory-hash"
+	// ForceConflictsAnnotation defines an annotation which, if set to
+	// "true", makes server-side apply take ownership of the fields of
+	// the object that conflict with other field managers, as if the
+	// ForceConflicts option were set for just that object.
+	ForceConflictsAnnotation = "cli-utils.sigs.k8s.io/force-conflicts"
+	// ApplyPolicyAnnotation defines an annotation which controls how an
+	// object is applied. Example:
+	//   cli-utils.sigs.k8s.io/apply-policy: create-only
+	ApplyPolicyAnnotation = "cli-utils.sigs.k8s.io/apply-policy"
+	// ApplyPolicyCreateOnly is the apply policy value to only create the
+	// object. Objects that already exist are not applied.
+	ApplyPolicyCreateOnly = "create-only"
+	// IgnoreFieldsAnnotation defines an annotation which holds a yaml
+	// list of JSONPath expressions. The matching fields are removed from
+	// the object before it is applied, so they are never changed by
+	// apply. It requires server-side apply. Fields previously applied and
+	// owned only by the applier are deleted from the live object instead.
+	// Example:
+	//   cli-utils.sigs.k8s.io/ignore-fields: |
+	//     - $.spec.replicas
+	IgnoreFieldsAnnotation = "cli-utils.sigs.k8s.io/ignore-fields"
+	// ReplaceOnImmutableAnnotation defines an annotation which, if set to
+	// "true", makes apply delete and re-create the object if applying it
+	// fails because an immutable field was changed.
+	ReplaceOnImmutableAnnotation = "cli-utils.sigs.k8s.io/replace-on-immutable"
+	// ApplyHashAnnotation defines an annotation which stores the hash of
+	// the content of an object at the time it was applied. Objects whose
+	// content hash matches the hash on the live object, and whose fields
+	// still have the applied values on the live object, are unchanged,
+	// and are not applied again.
+	ApplyHashAnnotation = "cli-utils.sigs.k8s.io/apply-hash"
 	// Resource lifecycle annotation key for "on-remove" operations.
 	OnRemoveAnnotation = "cli-utils.sigs.k8s.io/on-remove"
 	// Resource lifecycle annotation value to prevent deletion.
@@ -63,6 +94,12 @@ func NoDeletion(key, value string) bool {
 	return false
 }
 
+// CreateOnly returns true if the passed annotations contain the
+// create-only apply policy.
+func CreateOnly(annotations map[string]string) bool {
+	return annotations[ApplyPolicyAnnotation] == ApplyPolicyCreateOnly
+}
+
 var Strategies = []DryRunStrategy{DryRunClient, DryRunServer}
 
 //go:generate stringer -type=DryRunStrategy
@@ -127,4 +164,12 @@ type ServerSideOptions struct {
 
 	// FieldManager identifies the client "owner" of the applied fields (e.g. kubectl)
 	FieldManager string
+
+	// MigrateFromClientSide migrates objects that were previously applied
+	// with client-side apply. Before an object is applied, the fields
+	// owned by the client-side apply field managers are moved to
+	// FieldManager, and the last-applied-configuration annotation is
+	// removed, so fields removed from the manifests are pruned by
+	// server-side apply. Only used with ServerSideApply.
+	MigrateFromClientSide bool
 }
@@ -5,6 +5,7 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -17,6 +18,7 @@ import (
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/inventory/configmap"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/kio/filters"
 	"sigs.k8s.io/kustomize/kyaml/openapi"
@@ -24,6 +26,15 @@ import (
 
 const (
 	manifestFilename = "inventory-template.yaml"
+	// crdFilename is the file the ResourceGroup CRD is written to, when
+	// the inventory object is a ResourceGroup.
+	crdFilename = "resourcegroup-crd.yaml"
+)
+
+// Kinds of inventory objects which can be generated.
+const (
+	ConfigMapInventoryKind     = "ConfigMap"
+	ResourceGroupInventoryKind = "ResourceGroup"
 )
 
 // InitOptions contains the fields necessary to generate a
@@ -40,6 +51,8 @@ type InitOptions struct {
 	Namespace string
 	// Inventory object label value; must be a valid k8s label value.
 	InventoryID string
+	// Kind of the inventory object; ConfigMap (default) or ResourceGroup.
+	InventoryKind string
 }
 
 func NewInitOptions(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *InitOptions {
@@ -64,6 +77,15 @@ func (i *InitOptions) Complete(args []string) error {
 	i.Dir = dir
 	klog.V(4).Infof("init directory: %s", i.Dir)
 
+	switch i.InventoryKind {
+	case "", ConfigMapInventoryKind:
+	case ResourceGroupInventoryKind:
+		i.Template = resourcegroup.Template
+	default:
+		return fmt.Errorf("invalid inventory kind: %s (must be %s or %s)",
+			i.InventoryKind, ConfigMapInventoryKind, ResourceGroupInventoryKind)
+	}
+
 	ns, err := FindNamespace(i.factory.ToRawKubeConfigLoader(), i.Dir)
 	if err != nil {
 		return err
@@ -247,5 +269,28 @@ func (i *InitOptions) Run() error {
 		return fmt.Errorf("unable to write inventory object template file: %s", manifestFilePath)
 	}
 	fmt.Fprintf(i.ioStreams.Out, "Initialized: %s\n", manifestFilePath)
+	if i.InventoryKind == ResourceGroupInventoryKind {
+		return i.writeCRD()
+	}
+	return nil
+}
+
+// writeCRD writes the ResourceGroup CRD to the package directory, so it
+// can be installed before the inventory object is applied. The CRD is
+// marked as local config, so it isn't applied with the package: the
+// inventory object is applied before the other objects, so the CRD must
+// be installed beforehand.
+func (i *InitOptions) writeCRD() error {
+	crdFilePath := filepath.Join(i.Dir, crdFilename)
+	if fileExists(crdFilePath) {
+		klog.V(4).Infof("ResourceGroup CRD file already exists: %s", crdFilePath)
+	} else {
+		crd := strings.Replace(resourcegroup.CRD, "metadata:\n", fmt.Sprintf("metadata:\n  annotations:\n    %s: \"true\"\n",
+			filters.LocalConfigAnnotation), 1)
+		if err := ioutil.WriteFile(crdFilePath, []byte(crd), 0644); err != nil {
+			return fmt.Errorf("unable to write ResourceGroup CRD file: %s", err)
+		}
+	}
+	fmt.Fprintf(i.ioStreams.Out, "Install the ResourceGroup CRD before applying: kubectl apply -f %s\n", crdFilePath)
 	return nil
 }
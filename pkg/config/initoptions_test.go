@@ -13,8 +13,13 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"sigs.k8s.io/cli-utils/pkg/inventory/configmap"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/kustomize/kyaml/kio/filters"
+	"sigs.k8s.io/yaml"
 )
 
 // writeFile writes a file under the test directory
@@ -185,6 +190,54 @@ func TestComplete(t *testing.T) {
 	}
 }
 
+func TestCompleteInventoryKind(t *testing.T) {
+	tests := map[string]struct {
+		inventoryKind    string
+		isError          bool
+		expectedTemplate string
+	}{
+		"Empty inventory kind uses the ConfigMap template": {
+			inventoryKind:    "",
+			expectedTemplate: configmap.ConfigMapTemplate,
+		},
+		"ConfigMap inventory kind uses the ConfigMap template": {
+			inventoryKind:    "ConfigMap",
+			expectedTemplate: configmap.ConfigMapTemplate,
+		},
+		"ResourceGroup inventory kind uses the ResourceGroup template": {
+			inventoryKind:    "ResourceGroup",
+			expectedTemplate: resourcegroup.Template,
+		},
+		"Unknown inventory kind should fail": {
+			inventoryKind: "Secret",
+			isError:       true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "test-dir")
+			if !assert.NoError(t, err) {
+				assert.FailNow(t, err.Error())
+			}
+			defer os.RemoveAll(dir)
+			writeFile(t, filepath.Join(dir, "c_test.yaml"), readFileC)
+
+			tf := cmdtesting.NewTestFactory().WithNamespace("foo")
+			defer tf.Cleanup()
+			ioStreams, _, _, _ := genericclioptions.NewTestIOStreams()
+			io := NewInitOptions(tf, ioStreams)
+			io.InventoryKind = tc.inventoryKind
+			err = io.Complete([]string{dir})
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedTemplate, io.Template)
+		})
+	}
+}
+
 func TestFindNamespace(t *testing.T) {
 	testCases := map[string]struct {
 		namespace         string
@@ -372,3 +425,69 @@ func TestFillInValues(t *testing.T) {
 		})
 	}
 }
+
+func TestRun(t *testing.T) {
+	tests := map[string]struct {
+		inventoryKind string
+		expectedKind  string
+		expectCRD     bool
+	}{
+		"ConfigMap inventory kind writes the inventory template": {
+			inventoryKind: "ConfigMap",
+			expectedKind:  "ConfigMap",
+		},
+		"ResourceGroup inventory kind writes the inventory template and the CRD": {
+			inventoryKind: "ResourceGroup",
+			expectedKind:  "ResourceGroup",
+			expectCRD:     true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "test-dir")
+			if !assert.NoError(t, err) {
+				assert.FailNow(t, err.Error())
+			}
+			defer os.RemoveAll(dir)
+
+			tf := cmdtesting.NewTestFactory().WithNamespace("foo")
+			defer tf.Cleanup()
+			ioStreams, _, _, _ := genericclioptions.NewTestIOStreams()
+			io := NewInitOptions(tf, ioStreams)
+			io.InventoryKind = tc.inventoryKind
+			if !assert.NoError(t, io.Complete([]string{dir})) {
+				return
+			}
+			if !assert.NoError(t, io.Run()) {
+				return
+			}
+
+			inv := readObject(t, filepath.Join(dir, manifestFilename))
+			assert.Equal(t, tc.expectedKind, inv.GetKind())
+
+			crdFilePath := filepath.Join(dir, crdFilename)
+			if !tc.expectCRD {
+				assert.False(t, fileExists(crdFilePath))
+				return
+			}
+			crd := readObject(t, crdFilePath)
+			assert.Equal(t, "CustomResourceDefinition", crd.GetKind())
+			assert.Equal(t, "resourcegroups.cli-utils.sigs.k8s.io", crd.GetName())
+			// The CRD is installed before the package is applied, so it
+			// must not be applied with the package.
+			assert.Equal(t, "true", crd.GetAnnotations()[filters.LocalConfigAnnotation])
+		})
+	}
+}
+
+func readObject(t *testing.T, path string) *unstructured.Unstructured {
+	data, err := ioutil.ReadFile(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	obj := &unstructured.Unstructured{}
+	if !assert.NoError(t, yaml.Unmarshal(data, &obj.Object)) {
+		assert.FailNow(t, "invalid YAML")
+	}
+	return obj
+}
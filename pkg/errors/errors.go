@@ -13,14 +13,18 @@ import (
 	"text/template"
 
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/apply/prune"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
 	"sigs.k8s.io/cli-utils/pkg/manifestreader"
 )
 
 const (
-	DefaultErrorExitCode = 1
-	TimeoutErrorExitCode = 3
+	DefaultErrorExitCode            = 1
+	TimeoutErrorExitCode            = 3
+	PruneLimitExceededErrorExitCode = 4
+	DriftDetectedErrorExitCode      = 5
 )
 
 var errorMsgForType map[reflect.Type]string
@@ -48,7 +52,7 @@ The package should have one and only one inventory object template.
 Timeout after {{printf "%.0f" .err.Timeout.Seconds}} seconds waiting for {{printf "%d" (len .err.TimedOutResources)}} out of {{printf "%d" (len .err.Identifiers)}} resources to reach condition {{ .err.Condition}}:
 
 {{- range .err.TimedOutResources}}
-{{printf "%s/%s %s %s" .Identifier.GroupKind.Kind .Identifier.Name .Status .Message }}
+{{printf "%s/%s %s %s" .Identifier.GroupKind.Kind .Identifier.Name .Status .Message }}{{if .Condition}} (condition {{.Condition}}){{end}}{{if .Timeout}} (reconcile-timeout {{.Timeout}} exceeded){{end}}
 {{- end}}
 `
 
@@ -58,10 +62,48 @@ Unknown type(s) encountered. Every type must either be already installed in the
 {{- range .err.GroupKinds}}
 {{ printf "%s" . }}
 {{- end}}
+`
+
+	errorMsgForType[reflect.TypeOf(prune.PruneLimitExceededError{})] = `
+Refusing to prune {{printf "%d" (len .err.Objects)}} out of {{printf "%d" .err.InventorySize}} inventory objects, which exceeds the prune limit ({{ .err.Limit }}).
+Nothing has been applied or pruned. The objects that would have been pruned:
+
+{{- range .err.Objects}}
+{{printf "%s/%s" .GroupKind.Kind .Name }}{{if .Namespace}} (namespace {{ .Namespace }}){{end}}
+{{- end}}
+`
+
+	errorMsgForType[reflect.TypeOf(applyerror.DriftDetectedError{})] = `
+Drift detected in {{printf "%d" (len .err.Drifted)}} object(s).
+`
+
+	errorMsgForType[reflect.TypeOf(applyerror.ApplyConflictError{})] = `
+Server-side apply conflicts with other field managers:
+
+{{- range .err.Conflicts}}
+{{ .Field }}{{if .Manager}} is owned by {{ .Manager }}{{end}}
+{{- end}}
+
+Use --force-conflicts to take ownership of the fields.
+`
+
+	errorMsgForType[reflect.TypeOf(applyerror.ApplyConflictsError{})] = `
+Server-side apply conflicts with other field managers:
+
+{{- range .err.Objects}}
+{{printf "%s/%s" .Identifier.GroupKind.Kind .Identifier.Name }}{{if .Identifier.Namespace}} (namespace {{ .Identifier.Namespace }}){{end}}:
+{{- range .Err.Conflicts}}
+  {{ .Field }}{{if .Manager}} is owned by {{ .Manager }}{{end}}
+{{- end}}
+{{- end}}
+
+Use --force-conflicts to take ownership of the fields.
 `
 
 	statusCodeForType = make(map[reflect.Type]int)
 	statusCodeForType[reflect.TypeOf(taskrunner.TimeoutError{})] = TimeoutErrorExitCode
+	statusCodeForType[reflect.TypeOf(prune.PruneLimitExceededError{})] = PruneLimitExceededErrorExitCode
+	statusCodeForType[reflect.TypeOf(applyerror.DriftDetectedError{})] = DriftDetectedErrorExitCode
 }
 
 // CheckErr looks up the appropriate error message and exit status for known
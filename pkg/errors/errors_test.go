@@ -4,17 +4,29 @@
 package errors
 
 import (
+	"context"
+	goerrors "errors"
 	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/apply/cache"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
+	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	"sigs.k8s.io/cli-utils/pkg/apply/prune"
 	"sigs.k8s.io/cli-utils/pkg/apply/taskrunner"
 	"sigs.k8s.io/cli-utils/pkg/inventory"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
 )
 
 func TestTextForError(t *testing.T) {
@@ -83,6 +95,166 @@ func TestTextForError(t *testing.T) {
 			expectedErrText: `
 Timeout after 2 seconds waiting for 1 out of 1 resources to reach condition AllCurrent:
 Deployment/foo InProgress
+`,
+		},
+		"timeout error with per-object timeout": {
+			err: &taskrunner.TimeoutError{
+				Timeout: 20 * time.Minute,
+				Identifiers: object.ObjMetadataSet{
+					{
+						GroupKind: schema.GroupKind{
+							Kind:  "StatefulSet",
+							Group: "apps",
+						},
+						Name: "foo",
+					},
+				},
+				Condition: taskrunner.AllCurrent,
+				TimedOutResources: []taskrunner.TimedOutResource{
+					{
+						Identifier: object.ObjMetadata{
+							GroupKind: schema.GroupKind{
+								Kind:  "StatefulSet",
+								Group: "apps",
+							},
+							Name: "foo",
+						},
+						Status:  status.InProgressStatus,
+						Message: "Ready: 1/3",
+						Timeout: 20 * time.Minute,
+					},
+				},
+			},
+			cmdNameBase: "kapply",
+			expectFound: true,
+			expectedErrText: `
+Timeout after 1200 seconds waiting for 1 out of 1 resources to reach condition AllCurrent:
+StatefulSet/foo InProgress Ready: 1/3 (reconcile-timeout 20m0s exceeded)
+`,
+		},
+		"timeout error with per-object condition": {
+			err: &taskrunner.TimeoutError{
+				Timeout: time.Minute,
+				Identifiers: object.ObjMetadataSet{
+					{
+						GroupKind: schema.GroupKind{
+							Kind: "Job",
+						},
+						Name: "foo",
+					},
+				},
+				Condition: taskrunner.AllCurrent,
+				TimedOutResources: []taskrunner.TimedOutResource{
+					{
+						Identifier: object.ObjMetadata{
+							GroupKind: schema.GroupKind{
+								Kind: "Job",
+							},
+							Name: "foo",
+						},
+						Status:    status.CurrentStatus,
+						Condition: taskrunner.AllNotFound,
+					},
+				},
+			},
+			cmdNameBase: "kapply",
+			expectFound: true,
+			expectedErrText: `
+Timeout after 60 seconds waiting for 1 out of 1 resources to reach condition AllCurrent:
+Job/foo Current  (condition AllNotFound)
+`,
+		},
+		"prune limit exceeded error": {
+			err: &prune.PruneLimitExceededError{
+				Objects: object.ObjMetadataSet{
+					{
+						GroupKind: schema.GroupKind{
+							Kind:  "Deployment",
+							Group: "apps",
+						},
+						Name:      "foo",
+						Namespace: "default",
+					},
+					{
+						GroupKind: schema.GroupKind{
+							Kind: "Namespace",
+						},
+						Name: "default",
+					},
+				},
+				InventorySize: 3,
+				MaxPercent:    50,
+			},
+			cmdNameBase: "kapply",
+			expectFound: true,
+			expectedErrText: `
+Refusing to prune 2 out of 3 inventory objects, which exceeds the prune limit (max 50% of the inventory).
+Nothing has been applied or pruned. The objects that would have been pruned:
+Deployment/foo (namespace default)
+Namespace/default
+`,
+		},
+		"apply conflict error": {
+			err: func() error {
+				err, _ := applyerror.AsApplyConflictError(&apierrors.StatusError{ErrStatus: metav1.Status{
+					Code:   409,
+					Reason: metav1.StatusReasonConflict,
+					Details: &metav1.StatusDetails{
+						Causes: []metav1.StatusCause{
+							{
+								Type:    metav1.CauseTypeFieldManagerConflict,
+								Message: `conflict with "kube-controller-manager" using apps/v1`,
+								Field:   ".spec.replicas",
+							},
+						},
+					},
+				}})
+				return err
+			}(),
+			cmdNameBase: "kapply",
+			expectFound: true,
+			expectedErrText: `
+Server-side apply conflicts with other field managers:
+.spec.replicas is owned by kube-controller-manager
+
+Use --force-conflicts to take ownership of the fields.
+`,
+		},
+		"apply conflicts error": {
+			err: &applyerror.ApplyConflictsError{
+				Objects: []applyerror.ObjectApplyConflict{
+					{
+						Identifier: object.ObjMetadata{
+							GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+							Name:      "foo",
+							Namespace: "default",
+						},
+						Err: applyerror.NewApplyConflictError(fmt.Errorf("conflict"), []applyerror.FieldConflict{
+							{Manager: "kube-controller-manager", Field: ".spec.replicas"},
+						}),
+					},
+					{
+						Identifier: object.ObjMetadata{
+							GroupKind: schema.GroupKind{Kind: "ConfigMap"},
+							Name:      "bar",
+							Namespace: "default",
+						},
+						Err: applyerror.NewApplyConflictError(fmt.Errorf("conflict"), []applyerror.FieldConflict{
+							{Manager: "kubectl-edit", Field: ".data.key"},
+						}),
+					},
+				},
+			},
+			cmdNameBase: "kapply",
+			expectFound: true,
+			expectedErrText: `
+Server-side apply conflicts with other field managers:
+Deployment/foo (namespace default):
+  .spec.replicas is owned by kube-controller-manager
+ConfigMap/bar (namespace default):
+  .data.key is owned by kubectl-edit
+
+Use --force-conflicts to take ownership of the fields.
 `,
 		},
 	}
@@ -102,6 +274,105 @@ Deployment/foo InProgress
 	}
 }
 
+func TestFindErrExitCode(t *testing.T) {
+	testCases := map[string]struct {
+		err              error
+		expectedExitCode int
+	}{
+		"unknown error": {
+			err:              fmt.Errorf("this is a test"),
+			expectedExitCode: DefaultErrorExitCode,
+		},
+		"known error without exit code": {
+			err:              inventory.NoInventoryObjError{},
+			expectedExitCode: DefaultErrorExitCode,
+		},
+		"timeout error": {
+			err:              &taskrunner.TimeoutError{},
+			expectedExitCode: TimeoutErrorExitCode,
+		},
+		"prune limit exceeded error": {
+			err:              &prune.PruneLimitExceededError{},
+			expectedExitCode: PruneLimitExceededErrorExitCode,
+		},
+		"drift detected error": {
+			err:              &applyerror.DriftDetectedError{},
+			expectedExitCode: DriftDetectedErrorExitCode,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.expectedExitCode, findErrExitCode(tc.err))
+		})
+	}
+}
+
+// TestCheckErr_TimeoutError runs CheckErr with the error returned by
+// a WaitTask that timed out. CheckErr exits, so it runs in a subprocess.
+func TestCheckErr_TimeoutError(t *testing.T) {
+	err := waitTaskTimeoutError(t)
+	if os.Getenv("TEST_CHECK_ERR") == "1" {
+		CheckErr(os.Stdout, err, "kapply")
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCheckErr_TimeoutError$")
+	cmd.Env = append(os.Environ(), "TEST_CHECK_ERR=1")
+	out, runErr := cmd.Output()
+	var exitErr *exec.ExitError
+	require.True(t, goerrors.As(runErr, &exitErr), "expected exit error, got %v", runErr)
+	assert.Equal(t, TimeoutErrorExitCode, exitErr.ExitCode())
+	assert.Equal(t, `Timeout after 0 seconds waiting for 1 out of 2 resources to reach condition AllCurrent:
+Deployment/bar InProgress Ready: 1/3 (reconcile-timeout 10ms exceeded)
+`, string(out))
+}
+
+// waitTaskTimeoutError returns the error of a WaitTask that timed out
+// waiting for a deployment with the reconcile-timeout annotation.
+func waitTaskTimeoutError(t *testing.T) error {
+	fooID := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		Namespace: "default",
+		Name:      "foo",
+	}
+	barID := object.ObjMetadata{
+		GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		Namespace: "default",
+		Name:      "bar",
+	}
+	waitTimeout := 10 * time.Millisecond
+	task := taskrunner.NewWaitTask("wait-0", object.ObjMetadataSet{fooID, barID},
+		taskrunner.AllCurrent, waitTimeout, testutil.NewFakeRESTMapper())
+	task.ObjectTimeouts = map[object.ObjMetadata]time.Duration{barID: waitTimeout}
+	task.FailOnTimeout = true
+
+	eventChannel := make(chan event.Event)
+	defer close(eventChannel)
+	resourceCache := cache.NewResourceCacheMap()
+	resourceCache.Put(fooID, cache.ResourceStatus{Status: status.CurrentStatus})
+	resourceCache.Put(barID, cache.ResourceStatus{
+		Status:        status.InProgressStatus,
+		StatusMessage: "Ready: 1/3",
+	})
+	taskContext := taskrunner.NewTaskContext(context.Background(), eventChannel, resourceCache)
+	// run the task async, to let the test drain the events
+	go task.Start(taskContext)
+
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case <-taskContext.EventChannel():
+		case res := <-taskContext.TaskChannel():
+			require.Error(t, res.Err)
+			return res.Err
+		case <-timer.C:
+			t.Fatalf("timed out waiting for TaskResult")
+		}
+	}
+}
+
 type sliceError []string
 
 func (s sliceError) Error() string {
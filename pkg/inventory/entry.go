@@ -0,0 +1,123 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// Entry is the information stored in the inventory about an object,
+// in addition to its object metadata. The fields are empty if unknown,
+// e.g. for objects stored by inventories which only store the object
+// metadata.
+type Entry struct {
+	// UID is the UID of the object when it was last applied.
+	UID types.UID `json:"uid,omitempty"`
+	// Generation is the generation of the object when it was last applied.
+	Generation int64 `json:"generation,omitempty"`
+	// LastApplied is the time the object was last applied.
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+	// Status is the last known status of the object.
+	Status status.Status `json:"status,omitempty"`
+}
+
+// IsEmpty returns true if nothing is known about the object.
+func (e Entry) IsEmpty() bool {
+	return e == Entry{}
+}
+
+// Entries maps the objects stored in the inventory to their entries.
+type Entries map[object.ObjMetadata]Entry
+
+// NewEntries returns the Entries of the passed objects, with empty entries.
+func NewEntries(objs object.ObjMetadataSet) Entries {
+	entries := Entries{}
+	for _, obj := range objs {
+		entries[obj] = Entry{}
+	}
+	return entries
+}
+
+// ObjMetadataSet returns the objects of the entries, sorted by their
+// string representation.
+func (e Entries) ObjMetadataSet() object.ObjMetadataSet {
+	objs := object.ObjMetadataSet{}
+	for obj := range e {
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].String() < objs[j].String()
+	})
+	return objs
+}
+
+// Union returns the entries of the objects in either set. Objects in
+// both sets keep the entry of the passed set, unless it is empty.
+func (e Entries) Union(other Entries) Entries {
+	union := Entries{}
+	for obj, entry := range e {
+		union[obj] = entry
+	}
+	for obj, entry := range other {
+		if _, found := union[obj]; !found || !entry.IsEmpty() {
+			union[obj] = entry
+		}
+	}
+	return union
+}
+
+// Equal returns true if both sets have the same objects with the same
+// entries.
+func (e Entries) Equal(other Entries) bool {
+	if len(e) != len(other) {
+		return false
+	}
+	for obj, entry := range e {
+		otherEntry, found := other[obj]
+		if !found || !entry.equal(otherEntry) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e Entry) equal(other Entry) bool {
+	return e.UID == other.UID &&
+		e.Generation == other.Generation &&
+		e.LastApplied.Equal(other.LastApplied) &&
+		e.Status == other.Status
+}
+
+// encodeEntry returns the string stored as the value of the object in the
+// inventory ConfigMap. Empty entries are stored as an empty string, like
+// in inventories which only store the object metadata.
+func encodeEntry(e Entry) (string, error) {
+	if e.IsEmpty() {
+		return "", nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeEntry parses the value of an object in the inventory ConfigMap.
+func decodeEntry(s string) (Entry, error) {
+	var e Entry
+	if s == "" {
+		return e, nil
+	}
+	if err := json.Unmarshal([]byte(s), &e); err != nil {
+		return e, fmt.Errorf("error parsing inventory entry %q: %w", s, err)
+	}
+	return e, nil
+}
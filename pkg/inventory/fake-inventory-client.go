@@ -12,8 +12,9 @@ import (
 
 // FakeInventoryClient is a testing implementation of the InventoryClient interface.
 type FakeInventoryClient struct {
-	Objs object.ObjMetadataSet
-	Err  error
+	Objs    object.ObjMetadataSet
+	Entries Entries
+	Err     error
 }
 
 var (
@@ -30,8 +31,9 @@ func (f FakeInventoryClientFactory) NewInventoryClient(factory cmdutil.Factory)
 // NewFakeInventoryClient returns a FakeInventoryClient.
 func NewFakeInventoryClient(initObjs object.ObjMetadataSet) *FakeInventoryClient {
 	return &FakeInventoryClient{
-		Objs: initObjs,
-		Err:  nil,
+		Objs:    initObjs,
+		Entries: Entries{},
+		Err:     nil,
 	}
 }
 
@@ -66,6 +68,38 @@ func (fic *FakeInventoryClient) Replace(_ InventoryInfo, objs object.ObjMetadata
 	return nil
 }
 
+// GetClusterEntries returns the entries of the currently stored set of
+// objects, or an error if one is set up.
+func (fic *FakeInventoryClient) GetClusterEntries(InventoryInfo, common.DryRunStrategy) (Entries, error) {
+	if fic.Err != nil {
+		return Entries{}, fic.Err
+	}
+	entries := Entries{}
+	for _, obj := range fic.Objs {
+		entries[obj] = fic.Entries[obj]
+	}
+	return entries, nil
+}
+
+// ReplaceEntries replaces the stored cluster inventory objs with the objects
+// of the passed entries, and stores the non-empty entries, or an error if
+// one is set up.
+func (fic *FakeInventoryClient) ReplaceEntries(_ InventoryInfo, entries Entries, _ common.DryRunStrategy) error {
+	if fic.Err != nil {
+		return fic.Err
+	}
+	fic.Objs = entries.ObjMetadataSet()
+	if fic.Entries == nil {
+		fic.Entries = Entries{}
+	}
+	for obj, entry := range entries {
+		if !entry.IsEmpty() {
+			fic.Entries[obj] = entry
+		}
+	}
+	return nil
+}
+
 // DeleteInventoryObj returns an error if one is forced; does nothing otherwise.
 func (fic *FakeInventoryClient) DeleteInventoryObj(InventoryInfo, common.DryRunStrategy) error {
 	if fic.Err != nil {
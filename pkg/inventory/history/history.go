@@ -0,0 +1,332 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package history stores the revision history of an inventory. Every
+// revision holds the set of objects applied with the inventory, compressed,
+// in a companion Secret in the namespace of the inventory object, since the
+// applied objects may include Secrets. Only the most recent revisions are
+// kept.
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+const (
+	// InventoryLabel is the label on the revision Secrets with the
+	// id of the inventory they belong to. It must differ from the
+	// inventory label, so revisions are never mistaken for inventory
+	// objects.
+	InventoryLabel = "cli-utils.sigs.k8s.io/history-inventory-id"
+	// RevisionLabel is the label on the revision Secrets with the
+	// revision number.
+	RevisionLabel = "cli-utils.sigs.k8s.io/history-revision"
+	// TimestampAnnotation is the annotation on the revision Secrets
+	// with the time the revision was applied, in RFC 3339 format.
+	TimestampAnnotation = "cli-utils.sigs.k8s.io/history-timestamp"
+	// SecretType is the type of the revision Secrets.
+	SecretType = "cli-utils.sigs.k8s.io/history"
+
+	// objectsKey is the key in the data of the revision Secrets that
+	// holds the gzip compressed objects, as a JSON list.
+	objectsKey = "objects.json.gz"
+
+	// maxRecordAttempts is the number of revision numbers tried when
+	// revisions are recorded concurrently.
+	maxRecordAttempts = 5
+)
+
+var secretGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "secrets",
+}
+
+// Revision is a set of objects applied with an inventory.
+type Revision struct {
+	// Number is the revision number, starting at 1.
+	Number int
+	// Timestamp is the time the revision was recorded.
+	Timestamp time.Time
+	// Objects are the applied objects.
+	Objects object.UnstructuredSet
+}
+
+// RevisionNotFoundError is returned when the requested revision is not
+// in the history of the inventory.
+type RevisionNotFoundError struct {
+	Number int
+}
+
+func (e *RevisionNotFoundError) Error() string {
+	return fmt.Sprintf("revision %d not found in inventory history", e.Number)
+}
+
+// Client records and reads the revision history of inventories.
+type Client struct {
+	DynamicClient dynamic.Interface
+}
+
+// NewClient returns a new Client using the passed factory.
+func NewClient(factory cmdutil.Factory) (*Client, error) {
+	dynamicClient, err := factory.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		DynamicClient: dynamicClient,
+	}, nil
+}
+
+// Record stores the objects as a new revision in the history of the
+// inventory, and deletes the oldest revisions so at most limit revisions
+// are kept. If the cluster inventory object is passed, it is set as the
+// owner of the revision, so the history is garbage collected together
+// with the inventory. If another revision with the same number was
+// recorded concurrently, the next number is tried. Returns the number of
+// the new revision.
+func (c *Client) Record(ctx context.Context, inv inventory.InventoryInfo, clusterInv *unstructured.Unstructured,
+	objs object.UnstructuredSet, limit int) (int, error) {
+	if limit < 1 {
+		return 0, fmt.Errorf("history limit must be at least 1, got %d", limit)
+	}
+	revisions, err := c.list(ctx, inv)
+	if err != nil {
+		return 0, err
+	}
+	number := 1
+	if len(revisions) > 0 {
+		number = revisionNumber(revisions[len(revisions)-1]) + 1
+	}
+	data, err := encodeObjects(objs)
+	if err != nil {
+		return 0, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		secret, err := newRevisionSecret(inv, clusterInv, number, data)
+		if err != nil {
+			return 0, err
+		}
+		klog.V(4).Infof("recording revision %d of inventory %s/%s", number, inv.Namespace(), inv.Name())
+		_, err = c.DynamicClient.Resource(secretGVR).Namespace(inv.Namespace()).
+			Create(ctx, secret, metav1.CreateOptions{})
+		if err == nil {
+			break
+		}
+		if !apierrors.IsAlreadyExists(err) || attempt == maxRecordAttempts {
+			return 0, err
+		}
+		klog.V(4).Infof("revision %d of inventory %s/%s already exists", number, inv.Namespace(), inv.Name())
+		number++
+	}
+
+	// List the revisions again, since other revisions may have been
+	// recorded concurrently.
+	revisions, err = c.list(ctx, inv)
+	if err != nil {
+		return 0, err
+	}
+	for len(revisions) > limit {
+		old := revisions[0]
+		revisions = revisions[1:]
+		klog.V(4).Infof("deleting revision %d of inventory %s/%s", revisionNumber(old),
+			inv.Namespace(), inv.Name())
+		err := c.DynamicClient.Resource(secretGVR).Namespace(old.GetNamespace()).
+			Delete(ctx, old.GetName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return 0, err
+		}
+	}
+	return number, nil
+}
+
+// newRevisionSecret returns the Secret storing the revision with the
+// passed number and encoded objects.
+func newRevisionSecret(inv inventory.InventoryInfo, clusterInv *unstructured.Unstructured, number int,
+	data []byte) (*unstructured.Unstructured, error) {
+	secret := &unstructured.Unstructured{}
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	secret.SetName(fmt.Sprintf("%s-rev-%d", inv.Name(), number))
+	secret.SetNamespace(inv.Namespace())
+	secret.SetLabels(map[string]string{
+		InventoryLabel: inv.ID(),
+		RevisionLabel:  strconv.Itoa(number),
+	})
+	secret.SetAnnotations(map[string]string{
+		TimestampAnnotation: time.Now().UTC().Format(time.RFC3339),
+	})
+	if clusterInv != nil && clusterInv.GetUID() != "" {
+		secret.SetOwnerReferences([]metav1.OwnerReference{
+			{
+				APIVersion: clusterInv.GetAPIVersion(),
+				Kind:       clusterInv.GetKind(),
+				Name:       clusterInv.GetName(),
+				UID:        clusterInv.GetUID(),
+			},
+		})
+	}
+	if err := unstructured.SetNestedField(secret.Object, SecretType, "type"); err != nil {
+		return nil, err
+	}
+	if err := unstructured.SetNestedStringMap(secret.Object, map[string]string{
+		objectsKey: base64.StdEncoding.EncodeToString(data),
+	}, "data"); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// List returns the revisions in the history of the inventory, oldest
+// first.
+func (c *Client) List(ctx context.Context, inv inventory.InventoryInfo) ([]Revision, error) {
+	secrets, err := c.list(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, 0, len(secrets))
+	for i := range secrets {
+		rev, err := toRevision(&secrets[i])
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, nil
+}
+
+// Get returns the revision with the passed number from the history of the
+// inventory, or a RevisionNotFoundError if there is no such revision.
+func (c *Client) Get(ctx context.Context, inv inventory.InventoryInfo, number int) (*Revision, error) {
+	secrets, err := c.list(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	for i := range secrets {
+		if revisionNumber(secrets[i]) == number {
+			return toRevision(&secrets[i])
+		}
+	}
+	return nil, &RevisionNotFoundError{Number: number}
+}
+
+// list returns the revision Secrets of the inventory, sorted by
+// revision number.
+func (c *Client) list(ctx context.Context, inv inventory.InventoryInfo) ([]unstructured.Unstructured, error) {
+	if inv == nil {
+		return nil, fmt.Errorf("inventoryInfo must be specified")
+	}
+	if inv.ID() == "" {
+		return nil, fmt.Errorf("inventory %s/%s has no inventory id, which is required for history",
+			inv.Namespace(), inv.Name())
+	}
+	list, err := c.DynamicClient.Resource(secretGVR).Namespace(inv.Namespace()).
+		List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", InventoryLabel, inv.ID()),
+		})
+	if err != nil {
+		return nil, err
+	}
+	secrets := list.Items
+	sort.Slice(secrets, func(i, j int) bool {
+		return revisionNumber(secrets[i]) < revisionNumber(secrets[j])
+	})
+	return secrets, nil
+}
+
+func revisionNumber(secret unstructured.Unstructured) int {
+	number, err := strconv.Atoi(secret.GetLabels()[RevisionLabel])
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+func toRevision(secret *unstructured.Unstructured) (*Revision, error) {
+	rev := &Revision{
+		Number: revisionNumber(*secret),
+	}
+	if ts, found := secret.GetAnnotations()[TimestampAnnotation]; found {
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp in revision %d: %w", rev.Number, err)
+		}
+		rev.Timestamp = t
+	}
+	encoded, _, err := unstructured.NestedString(secret.Object, "data", objectsKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data in revision %d: %w", rev.Number, err)
+	}
+	rev.Objects, err = decodeObjects(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data in revision %d: %w", rev.Number, err)
+	}
+	return rev, nil
+}
+
+// encodeObjects returns the objects as a gzip compressed JSON list.
+func encodeObjects(objs object.UnstructuredSet) ([]byte, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion("v1")
+	list.SetKind("List")
+	for _, obj := range objs {
+		list.Items = append(list.Items, *obj)
+	}
+	data, err := list.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeObjects returns the objects from a gzip compressed JSON list.
+func decodeObjects(data []byte) (object.UnstructuredSet, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	if err := list.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	objs := make(object.UnstructuredSet, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+var inventoryYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: inventory
+  namespace: default
+  uid: inventory-uid
+  labels:
+    cli-utils.sigs.k8s.io/inventory-id: test
+`
+
+var deploymentYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment
+  namespace: default
+spec:
+  replicas: 3
+`
+
+var serviceYAML = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: service
+  namespace: default
+`
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	invObj := testutil.Unstructured(t, inventoryYAML)
+	inv := inventory.WrapInventoryInfoObj(invObj)
+	deployment := testutil.Unstructured(t, deploymentYAML)
+	service := testutil.Unstructured(t, serviceYAML)
+	dynamicClient := fake.NewSimpleDynamicClient(scheme.Scheme)
+	client := &Client{
+		DynamicClient: dynamicClient,
+	}
+
+	revisions := []object.UnstructuredSet{
+		{deployment},
+		{deployment, service},
+		{service},
+	}
+	for i, objs := range revisions {
+		number, err := client.Record(ctx, inv, invObj, objs, 2)
+		require.NoError(t, err)
+		assert.Equal(t, i+1, number)
+	}
+
+	list, err := client.List(ctx, inv)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, 2, list[0].Number)
+	assert.Equal(t, revisions[1], list[0].Objects)
+	assert.Equal(t, 3, list[1].Number)
+	assert.Equal(t, revisions[2], list[1].Objects)
+	assert.False(t, list[1].Timestamp.IsZero())
+
+	rev, err := client.Get(ctx, inv, 2)
+	require.NoError(t, err)
+	assert.Equal(t, revisions[1], rev.Objects)
+
+	_, err = client.Get(ctx, inv, 1)
+	assert.Equal(t, &RevisionNotFoundError{Number: 1}, err)
+
+	// The revisions are stored in Secrets, since they may hold Secrets.
+	secrets, err := dynamicClient.Resource(secretGVR).Namespace("default").
+		List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 2)
+	for _, secret := range secrets.Items {
+		assert.Equal(t, "Secret", secret.GetKind())
+		assert.Equal(t, SecretType, secret.Object["type"])
+		assert.Equal(t, "inventory", secret.GetOwnerReferences()[0].Name)
+	}
+	configMaps, err := dynamicClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace("default").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, configMaps.Items)
+}
+
+func TestClient_RecordConflict(t *testing.T) {
+	ctx := context.Background()
+	invObj := testutil.Unstructured(t, inventoryYAML)
+	inv := inventory.WrapInventoryInfoObj(invObj)
+	// A revision recorded concurrently, after the revisions were listed.
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "inventory-rev-1",
+			Namespace: "default",
+		},
+	}
+	client := &Client{
+		DynamicClient: fake.NewSimpleDynamicClient(scheme.Scheme, existing),
+	}
+
+	number, err := client.Record(ctx, inv, invObj, object.UnstructuredSet{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, number)
+
+	list, err := client.List(ctx, inv)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, 2, list[0].Number)
+}
+
+func TestClient_NoInventoryID(t *testing.T) {
+	invObj := testutil.Unstructured(t, inventoryYAML)
+	invObj.SetLabels(nil)
+	client := &Client{
+		DynamicClient: fake.NewSimpleDynamicClient(scheme.Scheme),
+	}
+	_, err := client.Record(context.Background(), inventory.WrapInventoryInfoObj(invObj), invObj,
+		object.UnstructuredSet{}, 1)
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"sort"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -40,6 +41,15 @@ type InventoryClient interface {
 	DeleteInventoryObj(inv InventoryInfo, dryRun common.DryRunStrategy) error
 	// ApplyInventoryNamespace applies the Namespace that the inventory object should be in.
 	ApplyInventoryNamespace(invNamespace *unstructured.Unstructured, dryRun common.DryRunStrategy) error
+	// GetClusterEntries returns the entries of the previously applied objects,
+	// or an error if one occurred. The entries are empty if the inventory
+	// object only stores the object metadata.
+	GetClusterEntries(inv InventoryInfo, dryRun common.DryRunStrategy) (Entries, error)
+	// ReplaceEntries replaces the set of objects stored in the inventory
+	// object with the objects of the passed entries, storing their entries
+	// as well. Objects with an empty entry keep their stored entry. Returns
+	// an error if one occurs.
+	ReplaceEntries(inv InventoryInfo, entries Entries, dryRun common.DryRunStrategy) error
 	// GetClusterInventoryInfo returns the cluster inventory object.
 	GetClusterInventoryInfo(inv InventoryInfo, dryRun common.DryRunStrategy) (*unstructured.Unstructured, error)
 	// GetInventoryObjs looks up the inventory objects from the cluster.
@@ -101,24 +111,21 @@ func (cic *ClusterInventoryClient) Merge(localInv InventoryInfo, objs object.Obj
 	}
 	if clusterInv == nil {
 		// Wrap inventory object and store the inventory in it.
-		inv := cic.InventoryFactoryFunc(invObj)
-		if err := inv.Store(objs); err != nil {
-			return nil, err
-		}
-		invInfo, err := inv.GetObject()
+		invInfo, shards, err := cic.replaceInventory(invObj, NewEntries(objs))
 		if err != nil {
 			return nil, err
 		}
 		klog.V(4).Infof("creating initial inventory object with %d objects", len(objs))
-		if err := cic.createInventoryObj(invInfo, dryRun); err != nil {
+		if err := cic.writeInventory(nil, invInfo, shards, dryRun); err != nil {
 			return nil, err
 		}
 	} else {
 		// Update existing cluster inventory with merged union of objects
-		clusterObjs, err := cic.GetClusterObjs(localInv, dryRun)
+		clusterEntries, err := cic.GetClusterEntries(localInv, dryRun)
 		if err != nil {
 			return pruneIds, err
 		}
+		clusterObjs := clusterEntries.ObjMetadataSet()
 		if objs.Equal(clusterObjs) {
 			klog.V(4).Infof("applied objects same as cluster inventory: do nothing")
 			return pruneIds, nil
@@ -127,17 +134,13 @@ func (cic *ClusterInventoryClient) Merge(localInv InventoryInfo, objs object.Obj
 		unionObjs := clusterObjs.Union(objs)
 		klog.V(4).Infof("num objects to prune: %d", len(pruneIds))
 		klog.V(4).Infof("num merged objects to store in inventory: %d", len(unionObjs))
-		wrappedInv := cic.InventoryFactoryFunc(clusterInv)
-		if err = wrappedInv.Store(unionObjs); err != nil {
+		invInfo, shards, err := cic.replaceInventory(clusterInv, clusterEntries.Union(NewEntries(objs)))
+		if err != nil {
 			return pruneIds, err
 		}
 		if !dryRun.ClientOrServerDryRun() {
-			clusterInv, err = wrappedInv.GetObject()
-			if err != nil {
-				return pruneIds, err
-			}
 			klog.V(4).Infof("update cluster inventory: %s/%s", clusterInv.GetNamespace(), clusterInv.GetName())
-			if err := cic.applyInventoryObj(clusterInv, dryRun); err != nil {
+			if err := cic.writeInventory(clusterInv, invInfo, shards, dryRun); err != nil {
 				return pruneIds, err
 			}
 		}
@@ -147,18 +150,34 @@ func (cic *ClusterInventoryClient) Merge(localInv InventoryInfo, objs object.Obj
 }
 
 // Replace stores the passed objects in the cluster inventory object, or
-// an error if one occurred.
+// an error if one occurred. The objects keep their stored entries.
 func (cic *ClusterInventoryClient) Replace(localInv InventoryInfo, objs object.ObjMetadataSet, dryRun common.DryRunStrategy) error {
+	return cic.ReplaceEntries(localInv, NewEntries(objs), dryRun)
+}
+
+// ReplaceEntries stores the passed entries in the cluster inventory object,
+// or an error if one occurred. Objects with an empty entry keep their
+// stored entry.
+func (cic *ClusterInventoryClient) ReplaceEntries(localInv InventoryInfo, entries Entries, dryRun common.DryRunStrategy) error {
 	// Skip entire function for dry-run.
 	if dryRun.ClientOrServerDryRun() {
 		klog.V(4).Infoln("dry-run replace inventory object: not applied")
 		return nil
 	}
-	clusterObjs, err := cic.GetClusterObjs(localInv, dryRun)
+	clusterEntries, err := cic.GetClusterEntries(localInv, dryRun)
 	if err != nil {
 		return err
 	}
-	if objs.Equal(clusterObjs) {
+	// Objects with an empty entry keep their stored entry.
+	merged := Entries{}
+	for obj, entry := range entries {
+		if entry.IsEmpty() {
+			entry = clusterEntries[obj]
+		}
+		merged[obj] = entry
+	}
+	entries = merged
+	if entries.Equal(clusterEntries) {
 		klog.V(4).Infof("applied objects same as cluster inventory: do nothing")
 		return nil
 	}
@@ -166,29 +185,77 @@ func (cic *ClusterInventoryClient) Replace(localInv InventoryInfo, objs object.O
 	if err != nil {
 		return err
 	}
-	clusterInv, err = cic.replaceInventory(clusterInv, objs)
+	invInfo, shards, err := cic.replaceInventory(clusterInv, entries)
 	if err != nil {
 		return err
 	}
 	klog.V(4).Infof("replace cluster inventory: %s/%s", clusterInv.GetNamespace(), clusterInv.GetName())
-	klog.V(4).Infof("replace cluster inventory %d objects", len(objs))
-	if err := cic.applyInventoryObj(clusterInv, dryRun); err != nil {
+	klog.V(4).Infof("replace cluster inventory %d objects", len(entries))
+	if err := cic.writeInventory(clusterInv, invInfo, shards, dryRun); err != nil {
 		return err
 	}
 	return nil
 }
 
-// replaceInventory stores the passed objects into the passed inventory object.
-func (cic *ClusterInventoryClient) replaceInventory(inv *unstructured.Unstructured, objs object.ObjMetadataSet) (*unstructured.Unstructured, error) {
+// replaceInventory stores the passed entries into the passed inventory object.
+// Returns the inventory object and the shards it references, if any.
+func (cic *ClusterInventoryClient) replaceInventory(inv *unstructured.Unstructured,
+	entries Entries) (*unstructured.Unstructured, object.UnstructuredSet, error) {
 	wrappedInv := cic.InventoryFactoryFunc(inv)
-	if err := wrappedInv.Store(objs); err != nil {
-		return nil, err
+	if err := storeEntries(wrappedInv, entries); err != nil {
+		return nil, nil, err
 	}
 	clusterInv, err := wrappedInv.GetObject()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return clusterInv, nil
+	var shards object.UnstructuredSet
+	if sharded, ok := wrappedInv.(ShardedInventory); ok {
+		shards, err = sharded.GetShards()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return clusterInv, shards, nil
+}
+
+// writeInventory writes the passed inventory object and its shards to the
+// APIServer, replacing the passed cluster inventory object, or creating the
+// inventory object if the cluster inventory object is nil. The shards are
+// written before the inventory object referencing them, and only if their
+// content changed. The shards of the cluster inventory object which are no
+// longer referenced are deleted after it. If writing the inventory object
+// fails, the entries may have moved between it and its shards, so the
+// inventory should be written again, e.g. by the next apply.
+func (cic *ClusterInventoryClient) writeInventory(clusterInv, obj *unstructured.Unstructured,
+	shards object.UnstructuredSet, dryRun common.DryRunStrategy) error {
+	if dryRun.ClientOrServerDryRun() {
+		klog.V(4).Infof("dry-run write inventory object: not written")
+		return nil
+	}
+	for _, shard := range shards {
+		if err := cic.writeShard(shard); err != nil {
+			return err
+		}
+	}
+	if clusterInv == nil {
+		return cic.createInventoryObj(obj, dryRun)
+	}
+	if err := cic.applyInventoryObj(obj, dryRun); err != nil {
+		return err
+	}
+	newShards := map[string]bool{}
+	for _, shard := range shards {
+		newShards[shard.GetName()] = true
+	}
+	for _, name := range cic.shardNames(clusterInv) {
+		if !newShards[name] {
+			if err := cic.deleteShard(clusterInv, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // DeleteInventoryObj deletes the inventory object from the cluster.
@@ -198,6 +265,14 @@ func (cic *ClusterInventoryClient) DeleteInventoryObj(localInv InventoryInfo, dr
 	}
 	switch localInv.Strategy() {
 	case NameStrategy:
+		// Delete the cluster inventory object, which references the shards.
+		clusterInvObjs, err := cic.getClusterInventoryObjsByName(localInv)
+		if err != nil {
+			return err
+		}
+		if len(clusterInvObjs) == 1 {
+			return cic.deleteInventoryObjByName(clusterInvObjs[0], dryRun)
+		}
 		return cic.deleteInventoryObjByName(cic.invToUnstructuredFunc(localInv), dryRun)
 	case LabelStrategy:
 		return cic.deleteInventoryObjsByLabel(localInv, dryRun)
@@ -231,10 +306,50 @@ func (cic *ClusterInventoryClient) GetClusterObjs(localInv InventoryInfo, dryRun
 	if clusterInv == nil {
 		return objs, nil
 	}
-	wrapped := cic.InventoryFactoryFunc(clusterInv)
+	wrapped, err := cic.loadInventory(clusterInv)
+	if err != nil {
+		return objs, err
+	}
 	return wrapped.Load()
 }
 
+// GetClusterEntries returns the entries stored in the cluster inventory
+// object, or an error if one occurred.
+func (cic *ClusterInventoryClient) GetClusterEntries(localInv InventoryInfo, dryRun common.DryRunStrategy) (Entries, error) {
+	entries := Entries{}
+	clusterInv, err := cic.GetClusterInventoryInfo(localInv, dryRun)
+	if err != nil {
+		return entries, err
+	}
+	// First time; no inventory obj yet.
+	if clusterInv == nil {
+		return entries, nil
+	}
+	wrapped, err := cic.loadInventory(clusterInv)
+	if err != nil {
+		return entries, err
+	}
+	return loadEntries(wrapped)
+}
+
+// loadInventory wraps the passed cluster inventory object, together with the
+// shards it references, to load the inventory. Returns an error if one occurred.
+func (cic *ClusterInventoryClient) loadInventory(clusterInv *unstructured.Unstructured) (Inventory, error) {
+	wrapped := cic.InventoryFactoryFunc(clusterInv)
+	if sharded, ok := wrapped.(ShardedInventory); ok {
+		var shards object.UnstructuredSet
+		for _, name := range sharded.ShardNames() {
+			shard, err := cic.getShard(clusterInv, name)
+			if err != nil {
+				return nil, err
+			}
+			shards = append(shards, shard)
+		}
+		sharded.SetShards(shards)
+	}
+	return wrapped, nil
+}
+
 // getClusterInventoryObj returns a pointer to the cluster inventory object, or
 // an error if one occurred. Returns the cached cluster inventory object if it
 // has been previously retrieved. Uses the ResourceBuilder to retrieve the
@@ -360,8 +475,11 @@ func (cic *ClusterInventoryClient) mergeClusterInventory(invObjs object.Unstruct
 	// choosing the first inventory object as the one to retain.
 	sort.Sort(ordering.SortableUnstructureds(invObjs))
 	retained := invObjs[0]
-	wrapRetained := cic.InventoryFactoryFunc(retained)
-	retainedObjs, err := wrapRetained.Load()
+	wrapRetained, err := cic.loadInventory(retained)
+	if err != nil {
+		return nil, err
+	}
+	retainedEntries, err := loadEntries(wrapRetained)
 	if err != nil {
 		return nil, err
 	}
@@ -369,17 +487,17 @@ func (cic *ClusterInventoryClient) mergeClusterInventory(invObjs object.Unstruct
 	// the retained objects.
 	for i := 1; i < len(invObjs); i++ {
 		merge := invObjs[i]
-		wrapMerge := cic.InventoryFactoryFunc(merge)
-		mergeObjs, err := wrapMerge.Load()
+		wrapMerge, err := cic.loadInventory(merge)
 		if err != nil {
 			return nil, err
 		}
-		retainedObjs = retainedObjs.Union(mergeObjs)
-	}
-	if err := wrapRetained.Store(retainedObjs); err != nil {
-		return nil, err
+		mergeEntries, err := loadEntries(wrapMerge)
+		if err != nil {
+			return nil, err
+		}
+		retainedEntries = retainedEntries.Union(mergeEntries)
 	}
-	retainInfo, err := wrapRetained.GetObject()
+	retainInfo, shards, err := cic.replaceInventory(retained, retainedEntries)
 	if err != nil {
 		return nil, err
 	}
@@ -389,7 +507,7 @@ func (cic *ClusterInventoryClient) mergeClusterInventory(invObjs object.Unstruct
 	// IMPORTANT: This must happen BEFORE deleting the other
 	// inventory objects, in order to ensure we always have
 	// access to the union of the inventory.
-	if err := cic.applyInventoryObj(retainInfo, dryRun); err != nil {
+	if err := cic.writeInventory(retained, retainInfo, shards, dryRun); err != nil {
 		return nil, err
 	}
 	// Finally, delete the other inventory objects.
@@ -472,10 +590,106 @@ func (cic *ClusterInventoryClient) deleteInventoryObjByName(obj *unstructured.Un
 	}
 	helper := cic.helperFromInfo(invInfo)
 	klog.V(4).Infof("deleting inventory object: %s/%s", invInfo.Namespace, invInfo.Name)
-	_, err = helper.Delete(invInfo.Namespace, invInfo.Name)
+	if _, err = helper.Delete(invInfo.Namespace, invInfo.Name); err != nil {
+		return err
+	}
+	// The shards are deleted after the inventory object, so they are
+	// at worst left behind.
+	for _, name := range cic.shardNames(obj) {
+		if err := cic.deleteShard(obj, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shardNames returns the names of the shards referenced by the passed
+// inventory object, if its inventory is sharded.
+func (cic *ClusterInventoryClient) shardNames(obj *unstructured.Unstructured) []string {
+	if sharded, ok := cic.InventoryFactoryFunc(obj).(ShardedInventory); ok {
+		return sharded.ShardNames()
+	}
+	return nil
+}
+
+// shardHelper returns the resource.Helper and namespace to talk to the
+// APIServer about the shards of the passed inventory object, which are of
+// the same type and in the same namespace as the inventory object.
+func (cic *ClusterInventoryClient) shardHelper(invObj *unstructured.Unstructured) (*resource.Helper, string, error) {
+	invInfo, err := cic.toInfo(invObj)
+	if err != nil {
+		return nil, "", err
+	}
+	return cic.helperFromInfo(invInfo), invInfo.Namespace, nil
+}
+
+// getShard retrieves the shard with the passed name of the passed cluster
+// inventory object, or an error if it does not exist.
+func (cic *ClusterInventoryClient) getShard(invObj *unstructured.Unstructured, name string) (*unstructured.Unstructured, error) {
+	helper, namespace, err := cic.shardHelper(invObj)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("inventory shard fetch by name (namespace: %q, name: %q)", namespace, name)
+	res, err := helper.Get(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving shard %s of inventory object %s/%s: %w",
+			name, invObj.GetNamespace(), invObj.GetName(), err)
+	}
+	shard, ok := res.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("retrieved inventory shard is not of type *Unstructured")
+	}
+	return shard, nil
+}
+
+// writeShard creates the passed shard, or updates it in place if it
+// already exists with another content.
+func (cic *ClusterInventoryClient) writeShard(shard *unstructured.Unstructured) error {
+	helper, namespace, err := cic.shardHelper(shard)
+	if err != nil {
+		return err
+	}
+	res, err := helper.Get(namespace, shard.GetName())
+	if apierrors.IsNotFound(err) {
+		klog.V(4).Infof("creating inventory shard: %s/%s", namespace, shard.GetName())
+		var clearResourceVersion = false
+		_, err = helper.Create(namespace, clearResourceVersion, shard)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	live, ok := res.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("retrieved inventory shard is not of type *Unstructured")
+	}
+	if equality.Semantic.DeepEqual(live.Object["data"], shard.Object["data"]) &&
+		equality.Semantic.DeepEqual(live.GetLabels(), shard.GetLabels()) {
+		klog.V(4).Infof("inventory shard unchanged: %s/%s", namespace, shard.GetName())
+		return nil
+	}
+	klog.V(4).Infof("updating inventory shard: %s/%s", namespace, shard.GetName())
+	shard = shard.DeepCopy()
+	shard.SetResourceVersion(live.GetResourceVersion())
+	_, err = helper.Replace(namespace, shard.GetName(), false, shard)
 	return err
 }
 
+// deleteShard deletes the shard with the passed name of the passed inventory
+// object from the APIServer. No error if the shard does not exist.
+func (cic *ClusterInventoryClient) deleteShard(invObj *unstructured.Unstructured, name string) error {
+	helper, namespace, err := cic.shardHelper(invObj)
+	if err != nil {
+		return err
+	}
+	klog.V(4).Infof("deleting inventory shard: %s/%s", namespace, name)
+	if _, err := helper.Delete(namespace, name); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // ApplyInventoryNamespace creates the passed namespace if it does not already
 // exist, or returns an error if one happened. NOTE: No error if already exists.
 func (cic *ClusterInventoryClient) ApplyInventoryNamespace(obj *unstructured.Unstructured, dryRun common.DryRunStrategy) error {
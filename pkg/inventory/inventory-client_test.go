@@ -333,7 +333,7 @@ func TestReplace(t *testing.T) {
 				t.Fatalf("unexpected error storing inventory objects: %s", err)
 			}
 			// Call replaceInventory with the new set of "localObjs"
-			inv, err = invClient.replaceInventory(inv, tc.localObjs)
+			inv, _, err = invClient.replaceInventory(inv, NewEntries(tc.localObjs))
 			if err != nil {
 				t.Fatalf("unexpected error received: %s", err)
 			}
@@ -589,3 +589,73 @@ func ignoreErrInfoToObjMeta(info *resource.Info) object.ObjMetadata {
 	objMeta, _ := object.InfoToObjMeta(info)
 	return objMeta
 }
+
+func TestWriteShard(t *testing.T) {
+	newShard := func(data map[string]string) *unstructured.Unstructured {
+		shard := &unstructured.Unstructured{}
+		shard.SetAPIVersion("v1")
+		shard.SetKind("ConfigMap")
+		shard.SetName(inventoryObjName + "-shard-0")
+		shard.SetNamespace(testNamespace)
+		shard.SetLabels(map[string]string{ShardLabel: testInventoryLabel})
+		require.NoError(t, unstructured.SetNestedStringMap(shard.Object, data, "data"))
+		return shard
+	}
+
+	tests := map[string]struct {
+		live            *unstructured.Unstructured
+		expectedMethods []string
+	}{
+		"missing shard is created": {
+			live:            nil,
+			expectedMethods: []string{"GET", "POST"},
+		},
+		"unchanged shard is not written": {
+			live:            newShard(map[string]string{"a": "1"}),
+			expectedMethods: []string{"GET"},
+		},
+		"changed shard is updated in place": {
+			live:            newShard(map[string]string{"a": "0"}),
+			expectedMethods: []string{"GET", "PUT"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tf := cmdtesting.NewTestFactory().WithNamespace(testNamespace)
+			defer tf.Cleanup()
+
+			var methods []string
+			tf.UnstructuredClient = &fake.RESTClient{
+				NegotiatedSerializer: resource.UnstructuredPlusDefaultContentConfig().NegotiatedSerializer,
+				Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+					methods = append(methods, req.Method)
+					if req.Method == "GET" {
+						if tc.live == nil {
+							return &http.Response{StatusCode: http.StatusNotFound, Header: cmdtesting.DefaultHeader(),
+								Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+						}
+						b, err := tc.live.MarshalJSON()
+						if err != nil {
+							return nil, err
+						}
+						return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(),
+							Body: ioutil.NopCloser(bytes.NewReader(b))}, nil
+					}
+					b, err := ioutil.ReadAll(req.Body)
+					if err != nil {
+						return nil, err
+					}
+					return &http.Response{StatusCode: http.StatusOK, Header: cmdtesting.DefaultHeader(),
+						Body: ioutil.NopCloser(bytes.NewReader(b))}, nil
+				}),
+			}
+			tf.ClientConfigVal = cmdtesting.DefaultClientConfig()
+
+			invClient, err := NewInventoryClient(tf, WrapInventoryObj, InvInfoToConfigMap)
+			require.NoError(t, err)
+			require.NoError(t, invClient.writeShard(newShard(map[string]string{"a": "1"})))
+			assert.Equal(t, tc.expectedMethods, methods)
+		})
+	}
+}
@@ -36,6 +36,56 @@ type Inventory interface {
 	GetObject() (*unstructured.Unstructured, error)
 }
 
+// ShardedInventory describes an Inventory which spills the object
+// metadata that doesn't fit into the inventory object into shard
+// objects. Shards are named by their index and updated in place. They
+// are written before the inventory object referencing them, and the
+// shards no longer referenced are deleted after it.
+type ShardedInventory interface {
+	Inventory
+	// ShardNames returns the names of the shards referenced by the
+	// wrapped inventory object.
+	ShardNames() []string
+	// SetShards sets the shards retrieved from the cluster, which must
+	// happen before the inventory is loaded.
+	SetShards(shards object.UnstructuredSet)
+	// GetShards returns the shards referenced by the object returned
+	// by GetObject.
+	GetShards() (object.UnstructuredSet, error)
+}
+
+// EntryInventory describes an Inventory which stores an Entry for
+// each object in addition to its object metadata.
+type EntryInventory interface {
+	Inventory
+	// LoadEntries retrieves the entries from the inventory object
+	LoadEntries() (Entries, error)
+	// StoreEntries stores the entries in the inventory object
+	StoreEntries(entries Entries) error
+}
+
+// loadEntries returns the entries of the passed inventory. The entries
+// are empty if the inventory only stores the object metadata.
+func loadEntries(inv Inventory) (Entries, error) {
+	if entryInv, ok := inv.(EntryInventory); ok {
+		return entryInv.LoadEntries()
+	}
+	objs, err := inv.Load()
+	if err != nil {
+		return nil, err
+	}
+	return NewEntries(objs), nil
+}
+
+// storeEntries stores the passed entries in the passed inventory, or
+// only their object metadata if the inventory doesn't store entries.
+func storeEntries(inv Inventory, entries Entries) error {
+	if entryInv, ok := inv.(EntryInventory); ok {
+		return entryInv.StoreEntries(entries)
+	}
+	return inv.Store(entries.ObjMetadataSet())
+}
+
 // InventoryFactoryFunc creates the object which implements the Inventory
 // interface from the passed info object.
 type InventoryFactoryFunc func(*unstructured.Unstructured) Inventory
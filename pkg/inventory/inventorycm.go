@@ -10,12 +10,30 @@ package inventory
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/object"
 )
 
+const (
+	// ShardsAnnotation is the annotation on a ConfigMap inventory object
+	// with the comma separated names of the shard ConfigMaps storing the
+	// object metadata that doesn't fit into the inventory object.
+	ShardsAnnotation = "cli-utils.sigs.k8s.io/inventory-shards"
+	// ShardLabel is the label on a shard ConfigMap with the id of its
+	// inventory. It differs from the inventory label, so shards are
+	// never mistaken for inventory objects.
+	ShardLabel = "cli-utils.sigs.k8s.io/inventory-shard-of"
+)
+
+// maxDataSize is the maximum size of the object metadata stored in
+// one ConfigMap, leaving room for the rest of the object below the
+// object size limit of the APIServer.
+var maxDataSize = 512 * 1024
+
 // WrapInventoryObj takes a passed ConfigMap (as a resource.Info),
 // wraps it with the InventoryConfigMap and upcasts the wrapper as
 // an the Inventory interface.
@@ -41,13 +59,18 @@ func InvInfoToConfigMap(inv InventoryInfo) *unstructured.Unstructured {
 // InventoryConfigMap wraps a ConfigMap resource and implements
 // the Inventory interface. This wrapper loads and stores the
 // object metadata (inventory) to and from the wrapped ConfigMap.
+// The object metadata is stored as the keys of the ConfigMap
+// "data" section, and the entries as their JSON encoded values.
 type InventoryConfigMap struct {
-	inv      *unstructured.Unstructured
-	objMetas object.ObjMetadataSet
+	inv     *unstructured.Unstructured
+	entries Entries
+	shards  object.UnstructuredSet
 }
 
 var _ InventoryInfo = &InventoryConfigMap{}
 var _ Inventory = &InventoryConfigMap{}
+var _ ShardedInventory = &InventoryConfigMap{}
+var _ EntryInventory = &InventoryConfigMap{}
 
 func (icm *InventoryConfigMap) Name() string {
 	return icm.inv.GetName()
@@ -71,54 +94,233 @@ func (icm *InventoryConfigMap) UnstructuredInventory() *unstructured.Unstructure
 }
 
 // Load is an Inventory interface function returning the set of
-// object metadata from the wrapped ConfigMap, or an error.
+// object metadata from the wrapped ConfigMap and its shards, or an
+// error. Returns an error if a shard of the ConfigMap has not been set.
 func (icm *InventoryConfigMap) Load() (object.ObjMetadataSet, error) {
 	objs := object.ObjMetadataSet{}
-	objMap, exists, err := unstructured.NestedStringMap(icm.inv.Object, "data")
+	objMap, err := icm.loadObjMap()
 	if err != nil {
-		err := fmt.Errorf("error retrieving object metadata from inventory object")
 		return objs, err
 	}
-	if exists {
-		for objStr := range objMap {
-			obj, err := object.ParseObjMetadata(objStr)
-			if err != nil {
-				return objs, err
-			}
-			objs = append(objs, obj)
+	for objStr := range objMap {
+		obj, err := object.ParseObjMetadata(objStr)
+		if err != nil {
+			return objs, err
 		}
+		objs = append(objs, obj)
 	}
 	return objs, nil
 }
 
+// LoadEntries is an EntryInventory interface function returning the
+// entries from the wrapped ConfigMap and its shards, or an error.
+// Objects stored without an entry have an empty entry.
+func (icm *InventoryConfigMap) LoadEntries() (Entries, error) {
+	entries := Entries{}
+	objMap, err := icm.loadObjMap()
+	if err != nil {
+		return entries, err
+	}
+	for objStr, entryStr := range objMap {
+		obj, err := object.ParseObjMetadata(objStr)
+		if err != nil {
+			return entries, err
+		}
+		entry, err := decodeEntry(entryStr)
+		if err != nil {
+			return entries, err
+		}
+		entries[obj] = entry
+	}
+	return entries, nil
+}
+
+// loadObjMap returns the union of the "data" sections of the wrapped
+// ConfigMap and its shards.
+func (icm *InventoryConfigMap) loadObjMap() (map[string]string, error) {
+	objMap, err := dataOf(icm.inv)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range icm.ShardNames() {
+		shard := icm.findShard(name)
+		if shard == nil {
+			return nil, fmt.Errorf("shard %s of inventory object %s/%s not loaded",
+				name, icm.inv.GetNamespace(), icm.inv.GetName())
+		}
+		shardMap, err := dataOf(shard)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range shardMap {
+			objMap[key] = value
+		}
+	}
+	return objMap, nil
+}
+
+func (icm *InventoryConfigMap) findShard(name string) *unstructured.Unstructured {
+	for _, shard := range icm.shards {
+		if shard.GetName() == name {
+			return shard
+		}
+	}
+	return nil
+}
+
+// dataOf returns the "data" section of the passed ConfigMap.
+func dataOf(cm *unstructured.Unstructured) (map[string]string, error) {
+	objMap, exists, err := unstructured.NestedStringMap(cm.Object, "data")
+	if err != nil {
+		err := fmt.Errorf("error retrieving object metadata from inventory object")
+		return nil, err
+	}
+	if !exists {
+		objMap = map[string]string{}
+	}
+	return objMap, nil
+}
+
 // Store is an Inventory interface function implemented to store
 // the object metadata in the wrapped ConfigMap. Actual storing
 // happens in "GetObject".
 func (icm *InventoryConfigMap) Store(objMetas object.ObjMetadataSet) error {
-	icm.objMetas = objMetas
+	icm.entries = NewEntries(objMetas)
+	return nil
+}
+
+// StoreEntries is an EntryInventory interface function implemented to
+// store the entries in the wrapped ConfigMap. Actual storing happens in
+// "GetObject".
+func (icm *InventoryConfigMap) StoreEntries(entries Entries) error {
+	icm.entries = entries
 	return nil
 }
 
 // GetObject returns the wrapped object (ConfigMap) as a resource.Info
-// or an error if one occurs.
+// or an error if one occurs. The object metadata which doesn't fit
+// into the ConfigMap is left to the shards returned by GetShards.
 func (icm *InventoryConfigMap) GetObject() (*unstructured.Unstructured, error) {
 	// Create the objMap of all the resources, and compute the hash.
-	objMap := buildObjMap(icm.objMetas)
+	allObjMap, err := buildObjMap(icm.entries)
+	if err != nil {
+		return nil, err
+	}
+	objMap, shardMaps := splitObjMap(allObjMap)
 	// Create the inventory object by copying the template.
 	invCopy := icm.inv.DeepCopy()
 	// Adds the inventory map to the ConfigMap "data" section.
-	err := unstructured.SetNestedStringMap(invCopy.UnstructuredContent(),
+	err = unstructured.SetNestedStringMap(invCopy.UnstructuredContent(),
 		objMap, "data")
 	if err != nil {
 		return nil, err
 	}
+	annotations := invCopy.GetAnnotations()
+	delete(annotations, ShardsAnnotation)
+	if len(shardMaps) > 0 {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		var names []string
+		for i := range shardMaps {
+			names = append(names, icm.shardName(i))
+		}
+		annotations[ShardsAnnotation] = strings.Join(names, ",")
+	}
+	invCopy.SetAnnotations(annotations)
 	return invCopy, nil
 }
 
-func buildObjMap(objMetas object.ObjMetadataSet) map[string]string {
+// ShardNames is a ShardedInventory interface function returning the
+// names of the shards referenced by the wrapped ConfigMap.
+func (icm *InventoryConfigMap) ShardNames() []string {
+	var names []string
+	for _, name := range strings.Split(icm.inv.GetAnnotations()[ShardsAnnotation], ",") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SetShards is a ShardedInventory interface function setting the shard
+// ConfigMaps read from the cluster, which are loaded by "Load".
+func (icm *InventoryConfigMap) SetShards(shards object.UnstructuredSet) {
+	icm.shards = shards
+}
+
+// GetShards is a ShardedInventory interface function returning the
+// shard ConfigMaps storing the object metadata which doesn't fit into
+// the ConfigMap returned by "GetObject".
+func (icm *InventoryConfigMap) GetShards() (object.UnstructuredSet, error) {
+	allObjMap, err := buildObjMap(icm.entries)
+	if err != nil {
+		return nil, err
+	}
+	_, shardMaps := splitObjMap(allObjMap)
+	var shards object.UnstructuredSet
+	for i, shardMap := range shardMaps {
+		shard := &unstructured.Unstructured{}
+		shard.SetAPIVersion(icm.inv.GetAPIVersion())
+		shard.SetKind(icm.inv.GetKind())
+		shard.SetName(icm.shardName(i))
+		shard.SetNamespace(icm.inv.GetNamespace())
+		shard.SetLabels(map[string]string{ShardLabel: icm.ID()})
+		err := unstructured.SetNestedStringMap(shard.UnstructuredContent(),
+			shardMap, "data")
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+// shardName returns the name of the shard with the passed index. The
+// names are stable, so the shards are updated in place, and a shard whose
+// content didn't change isn't written again.
+func (icm *InventoryConfigMap) shardName(i int) string {
+	return fmt.Sprintf("%s-shard-%d", icm.inv.GetName(), i)
+}
+
+func buildObjMap(entries Entries) (map[string]string, error) {
 	objMap := map[string]string{}
-	for _, objMetadata := range objMetas {
-		objMap[objMetadata.String()] = ""
+	for objMetadata, entry := range entries {
+		entryStr, err := encodeEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		objMap[objMetadata.String()] = entryStr
+	}
+	return objMap, nil
+}
+
+// splitObjMap splits the passed objMap into the objMap stored in the
+// inventory object and the objMaps stored in its shards, each of at most
+// maxDataSize. The split only depends on the content of the objMap.
+func splitObjMap(objMap map[string]string) (map[string]string, []map[string]string) {
+	objMaps := []map[string]string{{}}
+	size := 0
+	for _, key := range sortedKeys(objMap) {
+		current := objMaps[len(objMaps)-1]
+		// Each entry is stored as "<key>":"<value>", in the JSON encoding.
+		entrySize := len(key) + len(objMap[key]) + 6
+		if size+entrySize > maxDataSize && len(current) > 0 {
+			current = map[string]string{}
+			objMaps = append(objMaps, current)
+			size = 0
+		}
+		current[key] = objMap[key]
+		size += entrySize
+	}
+	return objMaps[0], objMaps[1:]
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
-	return objMap
+	sort.Strings(keys)
+	return keys
 }
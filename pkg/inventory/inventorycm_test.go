@@ -0,0 +1,169 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// newObjMetas returns n pod object metadata.
+func newObjMetas(n int) object.ObjMetadataSet {
+	var objMetas object.ObjMetadataSet
+	for i := 0; i < n; i++ {
+		objMetas = append(objMetas, object.ObjMetadata{
+			GroupKind: schema.GroupKind{Kind: "Pod"},
+			Namespace: testNamespace,
+			Name:      fmt.Sprintf("pod-%03d", i),
+		})
+	}
+	return objMetas
+}
+
+func TestInventoryConfigMap_Shards(t *testing.T) {
+	oldMaxDataSize := maxDataSize
+	maxDataSize = 200
+	defer func() { maxDataSize = oldMaxDataSize }()
+
+	testCases := map[string]struct {
+		objMetas       object.ObjMetadataSet
+		expectedShards int
+	}{
+		"no objects": {
+			objMetas:       object.ObjMetadataSet{},
+			expectedShards: 0,
+		},
+		"objects fit into the inventory object": {
+			objMetas:       newObjMetas(2),
+			expectedShards: 0,
+		},
+		"objects spill into shards": {
+			objMetas:       newObjMetas(20),
+			expectedShards: 4,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			inv := WrapInventoryObj(inventoryObj).(*InventoryConfigMap)
+			require.NoError(t, inv.Store(tc.objMetas))
+			invObj, err := inv.GetObject()
+			require.NoError(t, err)
+			shards, err := inv.GetShards()
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedShards, len(shards))
+
+			var names []string
+			for _, shard := range shards {
+				assert.True(t, strings.HasPrefix(shard.GetName(), inventoryObj.GetName()+"-shard-"))
+				assert.Equal(t, inventoryObj.GetNamespace(), shard.GetNamespace())
+				assert.Equal(t, testInventoryLabel, shard.GetLabels()[ShardLabel])
+				assert.False(t, IsInventoryObject(shard))
+				names = append(names, shard.GetName())
+			}
+
+			clusterInv := WrapInventoryObj(invObj).(*InventoryConfigMap)
+			assert.Equal(t, names, clusterInv.ShardNames())
+			clusterInv.SetShards(shards)
+			objMetas, err := clusterInv.Load()
+			require.NoError(t, err)
+			assert.True(t, tc.objMetas.Equal(objMetas), "expected %s, got %s", tc.objMetas, objMetas)
+		})
+	}
+}
+
+func TestInventoryConfigMap_ShardNames(t *testing.T) {
+	oldMaxDataSize := maxDataSize
+	maxDataSize = 200
+	defer func() { maxDataSize = oldMaxDataSize }()
+
+	shardNames := func(objMetas object.ObjMetadataSet) []string {
+		inv := WrapInventoryObj(inventoryObj).(*InventoryConfigMap)
+		require.NoError(t, inv.Store(objMetas))
+		invObj, err := inv.GetObject()
+		require.NoError(t, err)
+		return WrapInventoryObj(invObj).(*InventoryConfigMap).ShardNames()
+	}
+
+	// The shards are named by their index, regardless of the order of
+	// the objects.
+	objMetas := newObjMetas(19)
+	names := shardNames(objMetas)
+	assert.Equal(t, []string{"test-inventory-obj-shard-0", "test-inventory-obj-shard-1",
+		"test-inventory-obj-shard-2", "test-inventory-obj-shard-3"}, names)
+	reversed := object.ObjMetadataSet{}
+	for i := len(objMetas) - 1; i >= 0; i-- {
+		reversed = append(reversed, objMetas[i])
+	}
+	assert.Equal(t, names, shardNames(reversed))
+
+	// Adding an object keeps the names of the shards.
+	assert.Equal(t, names, shardNames(newObjMetas(20)))
+}
+
+func TestInventoryConfigMap_LoadMissingShard(t *testing.T) {
+	oldMaxDataSize := maxDataSize
+	maxDataSize = 200
+	defer func() { maxDataSize = oldMaxDataSize }()
+
+	inv := WrapInventoryObj(inventoryObj)
+	require.NoError(t, inv.Store(newObjMetas(20)))
+	invObj, err := inv.GetObject()
+	require.NoError(t, err)
+
+	// Loading the inventory without its shards would lose entries.
+	_, err = WrapInventoryObj(invObj).Load()
+	assert.Error(t, err)
+}
+
+func TestInventoryConfigMap_Entries(t *testing.T) {
+	objMetas := newObjMetas(2)
+	lastApplied := metav1.NewTime(time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC))
+	entries := Entries{
+		objMetas[0]: {
+			UID:         "uid-0",
+			Generation:  2,
+			LastApplied: &lastApplied,
+			Status:      status.CurrentStatus,
+		},
+		objMetas[1]: {},
+	}
+
+	inv := WrapInventoryObj(inventoryObj).(*InventoryConfigMap)
+	require.NoError(t, inv.StoreEntries(entries))
+	invObj, err := inv.GetObject()
+	require.NoError(t, err)
+
+	// Objects without an entry are stored like by inventories which only
+	// store the object metadata.
+	data, _, err := unstructured.NestedStringMap(invObj.Object, "data")
+	require.NoError(t, err)
+	assert.Equal(t, "", data[objMetas[1].String()])
+
+	clusterInv := WrapInventoryObj(invObj).(*InventoryConfigMap)
+	actualEntries, err := clusterInv.LoadEntries()
+	require.NoError(t, err)
+	assert.True(t, entries.Equal(actualEntries), "expected %v, got %v", entries, actualEntries)
+	actualObjs, err := clusterInv.Load()
+	require.NoError(t, err)
+	assert.True(t, objMetas.Equal(actualObjs), "expected %s, got %s", objMetas, actualObjs)
+
+	// Storing only the object metadata drops the entries.
+	require.NoError(t, clusterInv.Store(objMetas))
+	invObj, err = clusterInv.GetObject()
+	require.NoError(t, err)
+	actualEntries, err = WrapInventoryObj(invObj).(*InventoryConfigMap).LoadEntries()
+	require.NoError(t, err)
+	assert.True(t, NewEntries(objMetas).Equal(actualEntries), "expected empty entries, got %v", actualEntries)
+}
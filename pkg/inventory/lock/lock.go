@@ -0,0 +1,525 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lock locks inventories, so that concurrent applies and destroys
+// of the same inventory don't race. The lock is a coordination.k8s.io
+// Lease in the namespace of the inventory object, with the holder of the
+// lock and a TTL. The holder renews the Lease while it holds the lock, so
+// a lock left behind by a holder that is gone expires after the TTL.
+//
+// The Lease is named after the id of the inventory, so every inventory
+// object with the same id shares the lock, whatever its name.
+//
+// An inventory in a namespace that doesn't exist yet is not locked, since
+// the Lease can't be created before the namespace. This only happens on
+// the first apply of the inventory, before it is stored in the cluster.
+// An inventory is not locked either if the caller is not allowed to
+// manage Leases in its namespace. A warning is logged in that case.
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+)
+
+const (
+	// InventoryLabel is the label on the Leases with the id of the
+	// inventory they lock.
+	InventoryLabel = "cli-utils.sigs.k8s.io/lock-inventory-id"
+
+	// DefaultTTL is the default time a lock is held without being
+	// renewed.
+	DefaultTTL = time.Minute
+)
+
+var leaseGVR = schema.GroupVersionResource{
+	Group:    "coordination.k8s.io",
+	Version:  "v1",
+	Resource: "leases",
+}
+
+// errNamespaceNotFound is returned when the Lease can't be created
+// because the namespace of the inventory doesn't exist.
+var errNamespaceNotFound = errors.New("namespace not found")
+
+// errLockLost is returned when the lock was taken over by another holder,
+// force unlocked, or expired before it could be renewed.
+var errLockLost = errors.New("lock lost")
+
+// retryInterval is how often a lock held by another holder is checked
+// while waiting for it.
+var retryInterval = time.Second
+
+// Options define how the lock of an inventory is taken.
+type Options struct {
+	// Holder identifies the holder of the lock. Defaults to a unique
+	// id made of the host name and a random suffix.
+	Holder string
+
+	// TTL is how long the lock is held without being renewed. The lock
+	// is renewed every third of the TTL while it is held. Defaults to
+	// DefaultTTL.
+	TTL time.Duration
+
+	// Wait is how long to wait for a lock held by another holder to be
+	// released or to expire. If zero, taking the lock fails immediately
+	// when it is held by another holder.
+	Wait time.Duration
+
+	// ForceUnlock releases the lock before taking it, even if it is held
+	// by another holder. It is meant for locks left behind by a holder
+	// that is gone, when waiting for them to expire is not an option.
+	ForceUnlock bool
+}
+
+// LockHeldError is returned when the lock of an inventory is held by
+// another holder.
+type LockHeldError struct {
+	Namespace string
+	Name      string
+	Holder    string
+	Expires   time.Time
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("inventory %s/%s is locked by %q until %s: another apply or destroy is in progress, "+
+		"or the lock was left behind and must be force unlocked",
+		e.Namespace, e.Name, e.Holder, e.Expires.Format(time.RFC3339))
+}
+
+// LockLostError is the error of the context returned by Lock.Context
+// when it is cancelled because the lock was lost while it was held.
+type LockLostError struct {
+	Namespace string
+	Name      string
+	Err       error
+}
+
+func (e *LockLostError) Error() string {
+	return fmt.Sprintf("lost the lock of inventory %s/%s: %v: another apply or destroy may be in progress",
+		e.Namespace, e.Name, e.Err)
+}
+
+func (e *LockLostError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports the error as context.Canceled, so callers checking for a
+// cancelled context keep working when the lock is lost.
+func (e *LockLostError) Is(target error) bool {
+	return target == context.Canceled
+}
+
+// Client takes and releases the locks of inventories.
+type Client struct {
+	DynamicClient dynamic.Interface
+}
+
+// NewClient returns a new Client using the passed factory.
+func NewClient(factory cmdutil.Factory) (*Client, error) {
+	dynamicClient, err := factory.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		DynamicClient: dynamicClient,
+	}, nil
+}
+
+// Lock is a lock on an inventory. It is renewed in the background until
+// it is released.
+type Lock struct {
+	client *Client
+	inv    inventory.InventoryInfo
+	holder string
+	ttl    time.Duration
+	// noLease is true if the namespace of the inventory didn't exist, so
+	// no Lease was created, and there is nothing to renew or release.
+	noLease bool
+
+	stop chan struct{}
+	lost chan struct{}
+	// lostErr is why the lock was lost. It is set before lost is closed.
+	lostErr error
+	wg      sync.WaitGroup
+}
+
+// Holder returns the holder of the lock.
+func (l *Lock) Holder() string {
+	return l.holder
+}
+
+// Lost returns a channel that is closed if the lock is lost while it is
+// held, because it was taken over by another holder, force unlocked, or
+// expired before it could be renewed. The work protected by the lock
+// should be stopped when that happens.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Err returns a LockLostError if the lock was lost, and nil otherwise.
+func (l *Lock) Err() error {
+	select {
+	case <-l.lost:
+		return &LockLostError{
+			Namespace: l.inv.Namespace(),
+			Name:      l.inv.Name(),
+			Err:       l.lostErr,
+		}
+	default:
+		return nil
+	}
+}
+
+// Context returns a copy of ctx which is cancelled when the lock is lost,
+// or when the returned cancel function is called. The Err method of the
+// returned context returns a LockLostError if the lock was lost.
+func (l *Lock) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	lockCtx := &lockContext{Context: ctx}
+	go func() {
+		select {
+		case <-l.lost:
+			lockCtx.mu.Lock()
+			lockCtx.lostErr = l.Err()
+			lockCtx.mu.Unlock()
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return lockCtx, cancel
+}
+
+// lockContext is a context that is cancelled when a lock is lost.
+type lockContext struct {
+	context.Context
+
+	mu      sync.Mutex
+	lostErr error
+}
+
+// Err returns the LockLostError if the context was cancelled because the
+// lock was lost, and the error of the wrapped context otherwise.
+func (c *lockContext) Err() error {
+	err := c.Context.Err()
+	if err == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lostErr != nil {
+		return c.lostErr
+	}
+	return err
+}
+
+// Acquire takes the lock of the inventory. If the lock is held by another
+// holder, it waits for the lock for up to Options.Wait, and returns a
+// LockHeldError if the lock is still held afterwards.
+func (c *Client) Acquire(ctx context.Context, inv inventory.InventoryInfo, o Options) (*Lock, error) {
+	if inv == nil {
+		return nil, fmt.Errorf("inventoryInfo must be specified")
+	}
+	if o.Holder == "" {
+		o.Holder = DefaultHolder()
+	}
+	if o.TTL <= 0 {
+		o.TTL = DefaultTTL
+	}
+	if o.ForceUnlock {
+		// Forbidden is handled below, when taking the lock fails for
+		// the same reason.
+		if err := c.ForceUnlock(ctx, inv); err != nil && !apierrors.IsForbidden(err) {
+			return nil, err
+		}
+	}
+
+	l := &Lock{
+		client: c,
+		inv:    inv,
+		holder: o.Holder,
+		ttl:    o.TTL,
+		stop:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	deadline := time.Now().Add(o.Wait)
+	for {
+		err := c.tryAcquire(ctx, inv, o)
+		if err == nil {
+			break
+		}
+		var heldErr *LockHeldError
+		switch {
+		case errors.Is(err, errNamespaceNotFound):
+			klog.V(2).Infof("namespace of inventory %s/%s not found: the inventory is not locked",
+				inv.Namespace(), inv.Name())
+			l.noLease = true
+			return l, nil
+		case apierrors.IsForbidden(err):
+			klog.Warningf("not allowed to lock inventory %s/%s: the inventory is not locked, "+
+				"so concurrent applies and destroys of it are not prevented: %v",
+				inv.Namespace(), inv.Name(), err)
+			l.noLease = true
+			return l, nil
+		case errors.As(err, &heldErr):
+			klog.V(2).Infof("waiting for the lock of inventory %s/%s held by %q",
+				inv.Namespace(), inv.Name(), heldErr.Holder)
+		case apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err):
+			// Another holder updated the lock at the same time.
+			klog.V(2).Infof("lock of inventory %s/%s changed while taking it: %v",
+				inv.Namespace(), inv.Name(), err)
+		default:
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(retryInterval):
+		}
+	}
+	klog.V(4).Infof("locked inventory %s/%s (holder: %q)", inv.Namespace(), inv.Name(), o.Holder)
+
+	l.wg.Add(1)
+	go l.renew()
+	return l, nil
+}
+
+// tryAcquire takes the lock of the inventory if it is free, expired or
+// already held by the holder, and returns a LockHeldError otherwise.
+func (c *Client) tryAcquire(ctx context.Context, inv inventory.InventoryInfo, o Options) error {
+	now := time.Now()
+	leases := c.DynamicClient.Resource(leaseGVR).Namespace(inv.Namespace())
+	lease, err := leases.Get(ctx, leaseName(inv), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &unstructured.Unstructured{}
+		lease.SetAPIVersion("coordination.k8s.io/v1")
+		lease.SetKind("Lease")
+		lease.SetName(leaseName(inv))
+		lease.SetNamespace(inv.Namespace())
+		if inv.ID() != "" {
+			lease.SetLabels(map[string]string{
+				InventoryLabel: inv.ID(),
+			})
+		}
+		if err := setHolder(lease, o.Holder, o.TTL, now, true); err != nil {
+			return err
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsNotFound(err) {
+			return errNamespaceNotFound
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	holder, expires, err := leaseHolder(lease)
+	if err != nil {
+		return err
+	}
+	if holder != "" && holder != o.Holder && now.Before(expires) {
+		return &LockHeldError{
+			Namespace: inv.Namespace(),
+			Name:      inv.Name(),
+			Holder:    holder,
+			Expires:   expires,
+		}
+	}
+	if err := setHolder(lease, o.Holder, o.TTL, now, true); err != nil {
+		return err
+	}
+	// The update fails with a conflict if the Lease changed since it
+	// was read.
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// renew renews the lock every third of the TTL until it is released. The
+// Lost channel is closed, and the lock isn't renewed anymore, if the lock
+// was lost or couldn't be renewed before it expired.
+func (l *Lock) renew() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			err := l.renewOnce(context.Background())
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			if errors.Is(err, errLockLost) || time.Since(renewed) >= l.ttl {
+				klog.Warningf("lost the lock of inventory %s/%s: %v", l.inv.Namespace(), l.inv.Name(), err)
+				l.lostErr = err
+				close(l.lost)
+				return
+			}
+			klog.Warningf("failed to renew the lock of inventory %s/%s: %v", l.inv.Namespace(), l.inv.Name(), err)
+		}
+	}
+}
+
+func (l *Lock) renewOnce(ctx context.Context) error {
+	leases := l.client.DynamicClient.Resource(leaseGVR).Namespace(l.inv.Namespace())
+	lease, err := leases.Get(ctx, leaseName(l.inv), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: lease was deleted", errLockLost)
+	}
+	if err != nil {
+		return err
+	}
+	holder, _, err := leaseHolder(lease)
+	if err != nil {
+		return err
+	}
+	if holder != l.holder {
+		return fmt.Errorf("%w: taken over by %q", errLockLost, holder)
+	}
+	if err := setHolder(lease, l.holder, l.ttl, time.Now(), false); err != nil {
+		return err
+	}
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// Release stops renewing the lock and releases it. The lock is left
+// alone if it was taken over by another holder in the meantime.
+func (l *Lock) Release(ctx context.Context) error {
+	if l.noLease {
+		return nil
+	}
+	close(l.stop)
+	l.wg.Wait()
+
+	leases := l.client.DynamicClient.Resource(leaseGVR).Namespace(l.inv.Namespace())
+	lease, err := leases.Get(ctx, leaseName(l.inv), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	holder, _, err := leaseHolder(lease)
+	if err != nil {
+		return err
+	}
+	if holder != l.holder {
+		klog.V(2).Infof("lock of inventory %s/%s was taken over by %q", l.inv.Namespace(), l.inv.Name(), holder)
+		return nil
+	}
+	klog.V(4).Infof("unlocking inventory %s/%s (holder: %q)", l.inv.Namespace(), l.inv.Name(), l.holder)
+	resourceVersion := lease.GetResourceVersion()
+	err = leases.Delete(ctx, lease.GetName(), metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{
+			ResourceVersion: &resourceVersion,
+		},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// ForceUnlock releases the lock of the inventory, whoever holds it.
+func (c *Client) ForceUnlock(ctx context.Context, inv inventory.InventoryInfo) error {
+	if inv == nil {
+		return fmt.Errorf("inventoryInfo must be specified")
+	}
+	klog.V(2).Infof("force unlocking inventory %s/%s", inv.Namespace(), inv.Name())
+	err := c.DynamicClient.Resource(leaseGVR).Namespace(inv.Namespace()).
+		Delete(ctx, leaseName(inv), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// DefaultHolder returns a unique holder id made of the host name and a
+// random suffix, so concurrent runs on the same host don't share a lock.
+func DefaultHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s_%s", hostname, uuid.NewUUID())
+}
+
+// leaseName returns the name of the Lease that locks the inventory. It is
+// derived from the id of the inventory, which isn't always a valid name,
+// so it is hashed. Inventories without an id are locked by name.
+func leaseName(inv inventory.InventoryInfo) string {
+	if inv.ID() == "" {
+		return fmt.Sprintf("%s-lock", inv.Name())
+	}
+	sum := sha256.Sum256([]byte(inv.ID()))
+	return fmt.Sprintf("inventory-lock-%s", hex.EncodeToString(sum[:16]))
+}
+
+// leaseHolder returns the holder of the Lease and the time the lock
+// expires.
+func leaseHolder(lease *unstructured.Unstructured) (string, time.Time, error) {
+	holder, _, err := unstructured.NestedString(lease.Object, "spec", "holderIdentity")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	seconds, _, err := unstructured.NestedInt64(lease.Object, "spec", "leaseDurationSeconds")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	renewTime, found, err := unstructured.NestedString(lease.Object, "spec", "renewTime")
+	if err != nil || !found {
+		return holder, time.Time{}, err
+	}
+	renewed, err := time.Parse(metav1.RFC3339Micro, renewTime)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid renew time of lease %s/%s: %w",
+			lease.GetNamespace(), lease.GetName(), err)
+	}
+	return holder, renewed.Add(time.Duration(seconds) * time.Second), nil
+}
+
+// setHolder sets the holder of the Lease and renews it. The acquire time
+// is set as well if acquired is true.
+func setHolder(lease *unstructured.Unstructured, holder string, ttl time.Duration, now time.Time, acquired bool) error {
+	timestamp := now.UTC().Format(metav1.RFC3339Micro)
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	if err := unstructured.SetNestedField(lease.Object, holder, "spec", "holderIdentity"); err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(lease.Object, seconds, "spec", "leaseDurationSeconds"); err != nil {
+		return err
+	}
+	if acquired {
+		if err := unstructured.SetNestedField(lease.Object, timestamp, "spec", "acquireTime"); err != nil {
+			return err
+		}
+	}
+	return unstructured.SetNestedField(lease.Object, timestamp, "spec", "renewTime")
+}
@@ -0,0 +1,314 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+var inventoryYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: inventory
+  namespace: default
+  labels:
+    cli-utils.sigs.k8s.io/inventory-id: test
+`
+
+func newTestClient(t *testing.T) (*Client, inventory.InventoryInfo) {
+	oldInterval := retryInterval
+	retryInterval = 10 * time.Millisecond
+	t.Cleanup(func() { retryInterval = oldInterval })
+	client := &Client{
+		DynamicClient: fake.NewSimpleDynamicClient(scheme.Scheme),
+	}
+	return client, inventory.WrapInventoryInfoObj(testutil.Unstructured(t, inventoryYAML))
+}
+
+// holder returns the holder of the lock of the inventory, or an empty
+// string if the inventory is not locked.
+func holder(t *testing.T, client *Client, inv inventory.InventoryInfo) string {
+	lease, err := client.DynamicClient.Resource(leaseGVR).Namespace(inv.Namespace()).
+		Get(context.Background(), leaseName(inv), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return ""
+	}
+	require.NoError(t, err)
+	assert.Equal(t, "test", lease.GetLabels()[InventoryLabel])
+	h, _, err := leaseHolder(lease)
+	require.NoError(t, err)
+	return h
+}
+
+func TestClient_AcquireRelease(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+
+	lock, err := client.Acquire(ctx, inv, Options{Holder: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "a", holder(t, client, inv))
+
+	// The lock is held by a, so b fails immediately.
+	_, err = client.Acquire(ctx, inv, Options{Holder: "b"})
+	var heldErr *LockHeldError
+	require.True(t, errors.As(err, &heldErr), "expected LockHeldError, got %v", err)
+	assert.Equal(t, "a", heldErr.Holder)
+
+	require.NoError(t, lock.Release(ctx))
+	assert.Equal(t, "", holder(t, client, inv))
+
+	lock, err = client.Acquire(ctx, inv, Options{Holder: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder(t, client, inv))
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestClient_AcquireExpired(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+
+	// a takes the lock and is gone without renewing or releasing it.
+	require.NoError(t, client.tryAcquire(ctx, inv, Options{Holder: "a", TTL: time.Second}))
+	lease, err := client.DynamicClient.Resource(leaseGVR).Namespace(inv.Namespace()).
+		Get(ctx, leaseName(inv), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, setHolder(lease, "a", time.Second, time.Now().Add(-time.Minute), false))
+	_, err = client.DynamicClient.Resource(leaseGVR).Namespace(inv.Namespace()).
+		Update(ctx, lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	lock, err := client.Acquire(ctx, inv, Options{Holder: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder(t, client, inv))
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestClient_AcquireWait(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+
+	lockA, err := client.Acquire(ctx, inv, Options{Holder: "a"})
+	require.NoError(t, err)
+
+	// b waits until a releases the lock.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, lockA.Release(ctx))
+	}()
+	lockB, err := client.Acquire(ctx, inv, Options{Holder: "b", Wait: 5 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder(t, client, inv))
+	require.NoError(t, lockB.Release(ctx))
+}
+
+func TestClient_AcquireForceUnlock(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+
+	lockA, err := client.Acquire(ctx, inv, Options{Holder: "a"})
+	require.NoError(t, err)
+
+	lockB, err := client.Acquire(ctx, inv, Options{Holder: "b", ForceUnlock: true})
+	require.NoError(t, err)
+	assert.Equal(t, "b", holder(t, client, inv))
+
+	// Releasing the lock that was taken over leaves the new lock alone.
+	require.NoError(t, lockA.Release(ctx))
+	assert.Equal(t, "b", holder(t, client, inv))
+	require.NoError(t, lockB.Release(ctx))
+	assert.Equal(t, "", holder(t, client, inv))
+}
+
+func TestClient_AcquireNamespaceNotFound(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+	client.DynamicClient.(*fake.FakeDynamicClient).PrependReactor("create", "leases",
+		func(clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "default")
+		})
+
+	lock, err := client.Acquire(ctx, inv, Options{Holder: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "", holder(t, client, inv))
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestClient_AcquireForbidden(t *testing.T) {
+	testCases := map[string]struct {
+		verb        string
+		forceUnlock bool
+	}{
+		"get forbidden": {
+			verb: "get",
+		},
+		"create forbidden": {
+			verb: "create",
+		},
+		"delete forbidden on force unlock": {
+			verb:        "*",
+			forceUnlock: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			client, inv := newTestClient(t)
+			client.DynamicClient.(*fake.FakeDynamicClient).PrependReactor(tc.verb, "leases",
+				func(clienttesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(leaseGVR.GroupResource(), leaseName(inv), errors.New("denied"))
+				})
+
+			lock, err := client.Acquire(ctx, inv, Options{Holder: "a", ForceUnlock: tc.forceUnlock})
+			require.NoError(t, err)
+			assert.True(t, lock.noLease)
+			require.NoError(t, lock.Release(ctx))
+		})
+	}
+}
+
+func TestClient_AcquireConflict(t *testing.T) {
+	testCases := map[string]struct {
+		wait      time.Duration
+		conflicts int
+		expectErr bool
+	}{
+		"conflict is retried until it succeeds": {
+			wait:      5 * time.Second,
+			conflicts: 2,
+		},
+		"conflict fails after the wait": {
+			wait:      0,
+			conflicts: 1,
+			expectErr: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ctx := context.Background()
+			client, inv := newTestClient(t)
+			conflicts := 0
+			client.DynamicClient.(*fake.FakeDynamicClient).PrependReactor("create", "leases",
+				func(clienttesting.Action) (bool, runtime.Object, error) {
+					if conflicts == tc.conflicts {
+						return false, nil, nil
+					}
+					conflicts++
+					return true, nil, apierrors.NewAlreadyExists(leaseGVR.GroupResource(), leaseName(inv))
+				})
+
+			lock, err := client.Acquire(ctx, inv, Options{Holder: "a", Wait: tc.wait})
+			if tc.expectErr {
+				require.Error(t, err)
+				assert.True(t, apierrors.IsAlreadyExists(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.conflicts, conflicts)
+			assert.Equal(t, "a", holder(t, client, inv))
+			require.NoError(t, lock.Release(ctx))
+		})
+	}
+}
+
+func TestClient_AcquireConflictCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client, inv := newTestClient(t)
+	client.DynamicClient.(*fake.FakeDynamicClient).PrependReactor("create", "leases",
+		func(clienttesting.Action) (bool, runtime.Object, error) {
+			cancel()
+			return true, nil, apierrors.NewAlreadyExists(leaseGVR.GroupResource(), leaseName(inv))
+		})
+
+	_, err := client.Acquire(ctx, inv, Options{Holder: "a", Wait: time.Minute})
+	require.Error(t, err)
+	assert.True(t, apierrors.IsAlreadyExists(err))
+}
+
+func TestLock_Lost(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+
+	lockA, err := client.Acquire(ctx, inv, Options{Holder: "a", TTL: 30 * time.Millisecond})
+	require.NoError(t, err)
+	lockB, err := client.Acquire(ctx, inv, Options{Holder: "b", ForceUnlock: true})
+	require.NoError(t, err)
+
+	lockCtx, cancel := lockA.Context(ctx)
+	defer cancel()
+	select {
+	case <-lockCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the lock to be lost")
+	}
+	select {
+	case <-lockA.Lost():
+	default:
+		t.Fatalf("expected the lock to be lost")
+	}
+	var lostErr *LockLostError
+	require.True(t, errors.As(lockCtx.Err(), &lostErr))
+	assert.Equal(t, "inventory", lostErr.Name)
+	assert.True(t, errors.Is(lockCtx.Err(), context.Canceled))
+	assert.Equal(t, lockCtx.Err(), lockA.Err())
+	require.NoError(t, lockA.Release(ctx))
+	assert.Equal(t, "b", holder(t, client, inv))
+	require.NoError(t, lockB.Release(ctx))
+}
+
+func TestLock_ContextCancelled(t *testing.T) {
+	ctx := context.Background()
+	client, inv := newTestClient(t)
+
+	lock, err := client.Acquire(ctx, inv, Options{Holder: "a"})
+	require.NoError(t, err)
+	lockCtx, cancel := lock.Context(ctx)
+	cancel()
+	<-lockCtx.Done()
+	assert.Equal(t, context.Canceled, lockCtx.Err())
+	assert.NoError(t, lock.Err())
+	require.NoError(t, lock.Release(ctx))
+}
+
+func TestLeaseName(t *testing.T) {
+	inv := func(name, id string) inventory.InventoryInfo {
+		obj := testutil.Unstructured(t, inventoryYAML)
+		obj.SetName(name)
+		if id == "" {
+			obj.SetLabels(nil)
+		} else {
+			obj.SetLabels(map[string]string{common.InventoryLabel: id})
+		}
+		return inventory.WrapInventoryInfoObj(obj)
+	}
+
+	// Inventories with the same id share the lock, whatever their name.
+	assert.Equal(t, leaseName(inv("a", "test")), leaseName(inv("b", "test")))
+	// Inventories with the same name and different ids don't.
+	assert.NotEqual(t, leaseName(inv("a", "test")), leaseName(inv("a", "other")))
+	// Inventories without an id are locked by name.
+	assert.Equal(t, "a-lock", leaseName(inv("a", "")))
+}
+
+func TestDefaultHolder(t *testing.T) {
+	assert.NotEqual(t, DefaultHolder(), DefaultHolder())
+}
@@ -0,0 +1,381 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package migrate moves the inventory of a package from one inventory
+// object to another, e.g. from a ConfigMap inventory to a ResourceGroup
+// inventory, or to an inventory with another inventory id.
+//
+// A migration stores the objects of the old inventory in the new one,
+// annotates the live objects with the id of the new inventory, and deletes
+// the old inventory only after everything else succeeded. Every step is
+// idempotent, so a migration which failed part way can be resumed by
+// running it again.
+//
+// If the old and new inventory are the same object, only the inventory id
+// changes: the live objects are annotated with the new id, and the
+// inventory object is relabeled with it last.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// Options define how an inventory is migrated.
+type Options struct {
+	// DryRunStrategy defines whether the migration is only reported,
+	// without changing anything in the cluster.
+	DryRunStrategy common.DryRunStrategy
+
+	// InventoryLock defines how the old and new inventory are locked
+	// during the migration, so it doesn't race with an apply or destroy.
+	// If nil, the inventories are not locked.
+	InventoryLock *lock.Options
+}
+
+// Result describes what a migration did, or would do in dry-run.
+type Result struct {
+	// Objects are the objects stored in the new inventory.
+	Objects object.ObjMetadataSet
+	// Annotated are the live objects annotated with the id of the new
+	// inventory.
+	Annotated object.ObjMetadataSet
+	// Skipped are the objects whose live object was not annotated, because
+	// it is not owned by the old inventory, or because it doesn't exist.
+	// Objects which don't exist are not stored in the new inventory.
+	Skipped object.ObjMetadataSet
+	// Relabeled is true if the inventory object was relabeled with the
+	// new inventory id, when the old and new inventory are the same object.
+	Relabeled bool
+	// Deleted is true if the old inventory was deleted.
+	Deleted bool
+}
+
+// errObjectNotFound is returned by annotate when the live object doesn't
+// exist.
+var errObjectNotFound = errors.New("object not found in the cluster")
+
+// Migrator migrates inventories.
+type Migrator struct {
+	// InvClient reads and writes the inventories. It must not migrate
+	// inventories by itself, like the resourcegroup.InventoryClient does.
+	InvClient     inventory.InventoryClient
+	DynamicClient dynamic.Interface
+	Mapper        meta.RESTMapper
+}
+
+// NewMigrator returns a new Migrator using the passed factory and
+// inventory client.
+func NewMigrator(factory cmdutil.Factory, invClient inventory.InventoryClient) (*Migrator, error) {
+	// The resourcegroup.InventoryClient moves a ConfigMap inventory to the
+	// ResourceGroup with the same name by itself, and deletes the ConfigMap
+	// before the live objects are annotated, so the client it wraps is
+	// used instead.
+	if rgClient, ok := invClient.(*resourcegroup.InventoryClient); ok {
+		invClient = rgClient.ClusterInventoryClient
+	}
+	dynamicClient, err := factory.DynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := factory.ToRESTMapper()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		InvClient:     invClient,
+		DynamicClient: dynamicClient,
+		Mapper:        mapper,
+	}, nil
+}
+
+// Migrate moves the objects and entries stored in the old inventory to the
+// new inventory, which is created if it doesn't exist, annotates the live
+// objects owned by the old inventory with the id of the new inventory, and
+// finally deletes the old inventory. If the old and new inventory are the
+// same object with another inventory id, the inventory object is relabeled
+// with the new id instead of being deleted. If the old inventory doesn't
+// exist but the new one does, the migration is considered complete.
+// Returns an error if one occurred, in which case the migration can be
+// resumed by calling Migrate again.
+func (m *Migrator) Migrate(ctx context.Context, from, to inventory.InventoryInfo, o Options) (*Result, error) {
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("inventoryInfo must be specified")
+	}
+	sameObject := from.Name() == to.Name() && from.Namespace() == to.Namespace() &&
+		reflect.TypeOf(from) == reflect.TypeOf(to)
+	if sameObject && from.ID() == to.ID() {
+		return nil, fmt.Errorf("old and new inventory are the same inventory %s/%s", to.Namespace(), to.Name())
+	}
+	dryRun := o.DryRunStrategy
+	result := &Result{}
+
+	if o.InventoryLock != nil && !dryRun.ClientOrServerDryRun() {
+		lockCtx, unlock, err := m.lock(ctx, from, to, *o.InventoryLock)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		ctx = lockCtx
+	}
+
+	fromExists, err := m.exists(from)
+	if err != nil {
+		return nil, err
+	}
+	toExists, err := m.exists(to)
+	if err != nil {
+		return nil, err
+	}
+	if !fromExists {
+		if !toExists {
+			return nil, fmt.Errorf("inventory %s/%s not found", from.Namespace(), from.Name())
+		}
+		klog.V(4).Infof("old inventory %s/%s not found: migration already complete", from.Namespace(), from.Name())
+		result.Objects, err = m.InvClient.GetClusterObjs(to, dryRun)
+		return result, err
+	}
+
+	entries, err := m.InvClient.GetClusterEntries(from, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	result.Objects = entries.ObjMetadataSet()
+
+	// Store the objects in the new inventory, unless it is the old one. If
+	// it already exists, e.g. when resuming a migration, it must not hold
+	// other objects.
+	if sameObject {
+		klog.V(4).Infof("changing the id of inventory %s/%s from %q to %q", to.Namespace(), to.Name(), from.ID(), to.ID())
+	} else if err := m.store(from, to, toExists, entries, dryRun); err != nil {
+		return nil, err
+	}
+
+	// Annotate the live objects with the id of the new inventory. Objects
+	// which don't exist anymore are dropped from the new inventory, since
+	// there is nothing left to migrate or prune.
+	if from.ID() != to.ID() {
+		var missing object.ObjMetadataSet
+		for _, id := range result.Objects {
+			annotated, err := m.annotate(ctx, id, from, to, dryRun)
+			if errors.Is(err, errObjectNotFound) {
+				klog.V(4).Infof("object not found in the cluster, removed from the inventory (object: %q)", id)
+				missing = append(missing, id)
+				result.Skipped = append(result.Skipped, id)
+				continue
+			}
+			if err != nil {
+				return result, err
+			}
+			if annotated {
+				result.Annotated = append(result.Annotated, id)
+			} else {
+				result.Skipped = append(result.Skipped, id)
+			}
+		}
+		if len(missing) > 0 {
+			var objs object.ObjMetadataSet
+			for _, id := range result.Objects {
+				if missing.Contains(id) {
+					delete(entries, id)
+				} else {
+					objs = append(objs, id)
+				}
+			}
+			result.Objects = objs
+			// The objects of the new inventory are stored in the old one
+			// when they are the same object.
+			inv := to
+			if sameObject {
+				inv = from
+			}
+			if err := m.InvClient.ReplaceEntries(inv, entries, dryRun); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	// The old inventory is relabeled or deleted last, so it's left behind
+	// if any of the previous steps failed.
+	if sameObject {
+		klog.V(4).Infof("relabeling inventory %s/%s", from.Namespace(), from.Name())
+		if err := m.relabel(ctx, from, to, dryRun); err != nil {
+			return result, err
+		}
+		result.Relabeled = true
+		return result, nil
+	}
+	klog.V(4).Infof("deleting inventory %s/%s", from.Namespace(), from.Name())
+	if err := m.InvClient.DeleteInventoryObj(from, dryRun); err != nil {
+		return result, err
+	}
+	result.Deleted = true
+	return result, nil
+}
+
+// store stores the passed entries of the old inventory in the new
+// inventory. If the new inventory already exists, it must not hold other
+// objects.
+func (m *Migrator) store(from, to inventory.InventoryInfo, toExists bool, entries inventory.Entries,
+	dryRun common.DryRunStrategy) error {
+	objs := entries.ObjMetadataSet()
+	if toExists {
+		toObjs, err := m.InvClient.GetClusterObjs(to, dryRun)
+		if err != nil {
+			return err
+		}
+		if extra := toObjs.Diff(objs); len(extra) > 0 {
+			return fmt.Errorf("inventory %s/%s already stores %d objects not in inventory %s/%s",
+				to.Namespace(), to.Name(), len(extra), from.Namespace(), from.Name())
+		}
+	}
+	klog.V(4).Infof("storing %d objects in inventory %s/%s", len(entries), to.Namespace(), to.Name())
+	if _, err := m.InvClient.Merge(to, objs, dryRun); err != nil {
+		return err
+	}
+	return m.InvClient.ReplaceEntries(to, entries, dryRun)
+}
+
+// lock takes the locks of the old and new inventory, which share a lock
+// if they have the same name and namespace. Returns a context which is
+// cancelled if a lock is lost, and a function that releases the locks.
+func (m *Migrator) lock(ctx context.Context, from, to inventory.InventoryInfo,
+	o lock.Options) (context.Context, func(), error) {
+	invs := []inventory.InventoryInfo{from}
+	if from.Name() != to.Name() || from.Namespace() != to.Namespace() {
+		invs = append(invs, to)
+	}
+	lockClient := &lock.Client{DynamicClient: m.DynamicClient}
+	var cancels []func()
+	unlock := func() {
+		for i := len(cancels) - 1; i >= 0; i-- {
+			cancels[i]()
+		}
+	}
+	for _, inv := range invs {
+		l, err := lockClient.Acquire(ctx, inv, o)
+		if err != nil {
+			unlock()
+			return nil, nil, err
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = l.Context(ctx)
+		inv := inv
+		cancels = append(cancels, func() {
+			cancel()
+			// The lock is released even if the context was cancelled. If
+			// releasing fails, the lock expires after its TTL.
+			if err := l.Release(context.Background()); err != nil {
+				klog.Warningf("failed to release the lock of inventory %s/%s: %v", inv.Namespace(), inv.Name(), err)
+			}
+		})
+	}
+	return ctx, unlock, nil
+}
+
+// exists returns true if the passed inventory object exists in the cluster.
+func (m *Migrator) exists(inv inventory.InventoryInfo) (bool, error) {
+	objs, err := m.InvClient.GetClusterInventoryObjs(inv)
+	if err != nil {
+		return false, err
+	}
+	return len(objs) > 0, nil
+}
+
+// annotate sets the inventory id annotation of the live object to the id
+// of the new inventory, if the object is owned by the old inventory.
+// Returns true if the object is annotated, or already was, and false if
+// the object is owned by another inventory or by none. Returns
+// errObjectNotFound if the object doesn't exist, and an error if the type
+// of the object is unknown, so the old inventory isn't deleted while it
+// stores objects which were not annotated.
+func (m *Migrator) annotate(ctx context.Context, id object.ObjMetadata, from, to inventory.InventoryInfo,
+	dryRun common.DryRunStrategy) (bool, error) {
+	mapping, err := m.Mapper.RESTMapping(id.GroupKind)
+	if err != nil {
+		return false, fmt.Errorf("failed to annotate %s: %w", id, err)
+	}
+	client := m.DynamicClient.Resource(mapping.Resource).Namespace(id.Namespace)
+	obj, err := client.Get(ctx, id.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to annotate %s: %w (resource: %s)", id, errObjectNotFound, mapping.Resource)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to annotate %s: %w", id, err)
+	}
+	switch inventory.InventoryIDMatch(to, obj) {
+	case inventory.Match:
+		return true, nil
+	case inventory.Empty:
+		klog.V(4).Infof("object not owned by any inventory, not annotated (object: %q)", id)
+		return false, nil
+	}
+	if inventory.InventoryIDMatch(from, obj) != inventory.Match {
+		klog.V(4).Infof("object owned by another inventory, not annotated (object: %q)", id)
+		return false, nil
+	}
+	if dryRun.ClientOrServerDryRun() {
+		return true, nil
+	}
+	klog.V(4).Infof("annotating object (object: %q, annotation: %q)", id, inventory.OwningInventoryKey)
+	inventory.AddInventoryIDAnnotation(obj, to)
+	if _, err := client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// relabel sets the inventory id label of the old inventory object to the
+// id of the new inventory, when they are the same object.
+func (m *Migrator) relabel(ctx context.Context, from, to inventory.InventoryInfo, dryRun common.DryRunStrategy) error {
+	invObjs, err := m.InvClient.GetClusterInventoryObjs(from)
+	if err != nil {
+		return err
+	}
+	for _, invObj := range invObjs {
+		gvk := invObj.GroupVersionKind()
+		mapping, err := m.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return err
+		}
+		client := m.DynamicClient.Resource(mapping.Resource).Namespace(invObj.GetNamespace())
+		obj, err := client.Get(ctx, invObj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		setInventoryLabel(obj, to.ID())
+		if dryRun.ClientOrServerDryRun() {
+			continue
+		}
+		klog.V(4).Infof("relabeling inventory object %s/%s with inventory id %q", obj.GetNamespace(), obj.GetName(), to.ID())
+		if _, err := client.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setInventoryLabel sets the inventory id label of the inventory object.
+func setInventoryLabel(obj *unstructured.Unstructured, id string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[common.InventoryLabel] = id
+	obj.SetLabels(labels)
+}
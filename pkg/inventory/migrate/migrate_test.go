@@ -0,0 +1,434 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/inventory/lock"
+	"sigs.k8s.io/cli-utils/pkg/inventory/resourcegroup"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+var oldInventoryYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old-inventory
+  namespace: default
+  labels:
+    cli-utils.sigs.k8s.io/inventory-id: old-id
+`
+
+var newInventoryYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new-inventory
+  namespace: default
+  labels:
+    cli-utils.sigs.k8s.io/inventory-id: new-id
+`
+
+var relabeledInventoryYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old-inventory
+  namespace: default
+  labels:
+    cli-utils.sigs.k8s.io/inventory-id: new-id
+`
+
+var deploymentYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment
+  namespace: default
+`
+
+var serviceYAML = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: service
+  namespace: default
+`
+
+var podYAML = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+  namespace: default
+`
+
+var missingYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: missing
+  namespace: default
+`
+
+var deploymentGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "deployments",
+}
+
+var serviceGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "services",
+}
+
+var configMapGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "configmaps",
+}
+
+// fakeInvClient is an InventoryClient storing multiple inventories, by
+// inventory id, each in a FakeInventoryClient.
+type fakeInvClient struct {
+	*inventory.FakeInventoryClient
+	invs map[string]*inventory.FakeInventoryClient
+}
+
+func (c *fakeInvClient) GetClusterObjs(inv inventory.InventoryInfo, dryRun common.DryRunStrategy) (object.ObjMetadataSet, error) {
+	if fic, found := c.invs[inv.ID()]; found {
+		return fic.GetClusterObjs(inv, dryRun)
+	}
+	return object.ObjMetadataSet{}, nil
+}
+
+func (c *fakeInvClient) GetClusterEntries(inv inventory.InventoryInfo, dryRun common.DryRunStrategy) (inventory.Entries, error) {
+	if fic, found := c.invs[inv.ID()]; found {
+		return fic.GetClusterEntries(inv, dryRun)
+	}
+	return inventory.Entries{}, nil
+}
+
+func (c *fakeInvClient) Merge(inv inventory.InventoryInfo, objs object.ObjMetadataSet, dryRun common.DryRunStrategy) (object.ObjMetadataSet, error) {
+	if dryRun.ClientOrServerDryRun() {
+		return object.ObjMetadataSet{}, nil
+	}
+	if _, found := c.invs[inv.ID()]; !found {
+		c.invs[inv.ID()] = inventory.NewFakeInventoryClient(object.ObjMetadataSet{})
+	}
+	return c.invs[inv.ID()].Merge(inv, objs, dryRun)
+}
+
+func (c *fakeInvClient) ReplaceEntries(inv inventory.InventoryInfo, entries inventory.Entries, dryRun common.DryRunStrategy) error {
+	if dryRun.ClientOrServerDryRun() {
+		return nil
+	}
+	return c.invs[inv.ID()].ReplaceEntries(inv, entries, dryRun)
+}
+
+func (c *fakeInvClient) DeleteInventoryObj(inv inventory.InventoryInfo, dryRun common.DryRunStrategy) error {
+	if !dryRun.ClientOrServerDryRun() {
+		delete(c.invs, inv.ID())
+	}
+	return nil
+}
+
+func (c *fakeInvClient) GetClusterInventoryObjs(inv inventory.InventoryInfo) (object.UnstructuredSet, error) {
+	if _, found := c.invs[inv.ID()]; found {
+		return object.UnstructuredSet{inventory.InvInfoToConfigMap(inv)}, nil
+	}
+	return object.UnstructuredSet{}, nil
+}
+
+func TestMigrate(t *testing.T) {
+	deployment := testutil.Unstructured(t, deploymentYAML, testutil.AddOwningInv(t, "old-id"))
+	service := testutil.Unstructured(t, serviceYAML, testutil.AddOwningInv(t, "other-id"))
+	pod := testutil.Unstructured(t, podYAML)
+	oldInventory := testutil.Unstructured(t, oldInventoryYAML)
+	deploymentID := testutil.ToIdentifier(t, deploymentYAML)
+	serviceID := testutil.ToIdentifier(t, serviceYAML)
+	podID := testutil.ToIdentifier(t, podYAML)
+	missingID := testutil.ToIdentifier(t, missingYAML)
+	allIDs := object.ObjMetadataSet{deploymentID, serviceID, podID}
+	entries := inventory.Entries{
+		deploymentID: {UID: "deployment-uid", Generation: 2},
+		serviceID:    {UID: "service-uid"},
+		podID:        {},
+	}
+
+	tests := map[string]struct {
+		oldInventory       object.ObjMetadataSet
+		newInventory       object.ObjMetadataSet
+		sameInventory      bool
+		relabel            bool
+		lockedBy           string
+		inventoryLock      *lock.Options
+		dryRun             common.DryRunStrategy
+		expectedError      bool
+		expectedResult     *Result
+		expectedInventory  object.ObjMetadataSet
+		expectedOld        bool
+		expectedAnnotation string
+		expectedLabel      string
+	}{
+		"migrates the objects and annotations to the new inventory": {
+			oldInventory: allIDs,
+			expectedResult: &Result{
+				Objects:   entries.ObjMetadataSet(),
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{podID, serviceID},
+				Deleted:   true,
+			},
+			expectedInventory:  allIDs,
+			expectedAnnotation: "new-id",
+		},
+		"resumes a migration with the new inventory already created": {
+			oldInventory: allIDs,
+			newInventory: object.ObjMetadataSet{deploymentID},
+			expectedResult: &Result{
+				Objects:   entries.ObjMetadataSet(),
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{podID, serviceID},
+				Deleted:   true,
+			},
+			expectedInventory:  allIDs,
+			expectedAnnotation: "new-id",
+		},
+		"migration with the old inventory deleted is complete": {
+			newInventory: allIDs,
+			expectedResult: &Result{
+				Objects: allIDs,
+			},
+			expectedInventory:  allIDs,
+			expectedAnnotation: "old-id",
+		},
+		"dry-run does not change anything": {
+			oldInventory: allIDs,
+			dryRun:       common.DryRunClient,
+			expectedResult: &Result{
+				Objects:   entries.ObjMetadataSet(),
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{podID, serviceID},
+				Deleted:   true,
+			},
+			expectedOld:        true,
+			expectedAnnotation: "old-id",
+		},
+		"missing inventories are an error": {
+			expectedError:      true,
+			expectedAnnotation: "old-id",
+		},
+		"new inventory with other objects is an error": {
+			oldInventory:       object.ObjMetadataSet{deploymentID},
+			newInventory:       object.ObjMetadataSet{serviceID},
+			expectedError:      true,
+			expectedInventory:  object.ObjMetadataSet{serviceID},
+			expectedOld:        true,
+			expectedAnnotation: "old-id",
+		},
+		"changes the inventory id in place": {
+			oldInventory: allIDs,
+			relabel:      true,
+			expectedResult: &Result{
+				Objects:   entries.ObjMetadataSet(),
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{podID, serviceID},
+				Relabeled: true,
+			},
+			expectedOld:        true,
+			expectedAnnotation: "new-id",
+			expectedLabel:      "new-id",
+		},
+		"dry-run does not change the inventory id in place": {
+			oldInventory: allIDs,
+			relabel:      true,
+			dryRun:       common.DryRunClient,
+			expectedResult: &Result{
+				Objects:   entries.ObjMetadataSet(),
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{podID, serviceID},
+				Relabeled: true,
+			},
+			expectedOld:        true,
+			expectedAnnotation: "old-id",
+			expectedLabel:      "old-id",
+		},
+		"object missing in the cluster is dropped from the new inventory": {
+			oldInventory: object.ObjMetadataSet{deploymentID, missingID},
+			expectedResult: &Result{
+				Objects:   object.ObjMetadataSet{deploymentID},
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{missingID},
+				Deleted:   true,
+			},
+			expectedInventory:  object.ObjMetadataSet{deploymentID},
+			expectedAnnotation: "new-id",
+		},
+		"object missing in the cluster is dropped when changing the inventory id in place": {
+			oldInventory: object.ObjMetadataSet{deploymentID, missingID},
+			relabel:      true,
+			expectedResult: &Result{
+				Objects:   object.ObjMetadataSet{deploymentID},
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{missingID},
+				Relabeled: true,
+			},
+			expectedOld:        true,
+			expectedAnnotation: "new-id",
+			expectedLabel:      "new-id",
+		},
+		"migrates with the inventories locked": {
+			oldInventory:  allIDs,
+			inventoryLock: &lock.Options{},
+			expectedResult: &Result{
+				Objects:   entries.ObjMetadataSet(),
+				Annotated: object.ObjMetadataSet{deploymentID},
+				Skipped:   object.ObjMetadataSet{podID, serviceID},
+				Deleted:   true,
+			},
+			expectedInventory:  allIDs,
+			expectedAnnotation: "new-id",
+		},
+		"inventory locked by another holder is an error": {
+			oldInventory:       allIDs,
+			lockedBy:           "other",
+			inventoryLock:      &lock.Options{},
+			expectedError:      true,
+			expectedOld:        true,
+			expectedAnnotation: "old-id",
+		},
+		"same old and new inventory is an error": {
+			oldInventory:       allIDs,
+			sameInventory:      true,
+			expectedError:      true,
+			expectedOld:        true,
+			expectedAnnotation: "old-id",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			from := inventory.WrapInventoryInfoObj(testutil.Unstructured(t, oldInventoryYAML))
+			to := inventory.WrapInventoryInfoObj(testutil.Unstructured(t, newInventoryYAML))
+			if tc.sameInventory {
+				to = from
+			}
+			if tc.relabel {
+				to = inventory.WrapInventoryInfoObj(testutil.Unstructured(t, relabeledInventoryYAML))
+			}
+			invClient := &fakeInvClient{
+				FakeInventoryClient: inventory.NewFakeInventoryClient(object.ObjMetadataSet{}),
+				invs:                map[string]*inventory.FakeInventoryClient{},
+			}
+			if tc.oldInventory != nil {
+				old := inventory.NewFakeInventoryClient(tc.oldInventory)
+				old.Entries = entries
+				invClient.invs[from.ID()] = old
+			}
+			if tc.newInventory != nil {
+				invClient.invs[to.ID()] = inventory.NewFakeInventoryClient(tc.newInventory)
+			}
+			dynamicClient := fake.NewSimpleDynamicClient(scheme.Scheme,
+				deployment.DeepCopy(), service.DeepCopy(), pod.DeepCopy(), oldInventory.DeepCopy())
+			migrator := &Migrator{
+				InvClient:     invClient,
+				DynamicClient: dynamicClient,
+				Mapper: testutil.NewFakeRESTMapper(
+					schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+					schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+					schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+				),
+			}
+			lockClient := &lock.Client{DynamicClient: dynamicClient}
+			if tc.lockedBy != "" {
+				_, err := lockClient.Acquire(context.Background(), from, lock.Options{Holder: tc.lockedBy})
+				require.NoError(t, err)
+			}
+
+			result, err := migrator.Migrate(context.Background(), from, to, Options{
+				DryRunStrategy: tc.dryRun,
+				InventoryLock:  tc.inventoryLock,
+			})
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedResult, result)
+			}
+
+			if tc.expectedInventory != nil {
+				newInv, found := invClient.invs[to.ID()]
+				require.True(t, found)
+				assert.True(t, tc.expectedInventory.Equal(newInv.Objs))
+				if tc.oldInventory != nil && !tc.expectedError {
+					assert.Equal(t, entries[deploymentID], newInv.Entries[deploymentID])
+				}
+			} else if !tc.sameInventory {
+				_, found := invClient.invs[to.ID()]
+				assert.False(t, found)
+			}
+			oldInv, found := invClient.invs[from.ID()]
+			assert.Equal(t, tc.expectedOld, found)
+			if tc.relabel && !tc.dryRun.ClientOrServerDryRun() {
+				// The relabeled inventory only stores the migrated objects.
+				require.True(t, found)
+				assert.True(t, tc.expectedResult.Objects.Equal(oldInv.Objs))
+			}
+
+			// The locks are released, unless held by another holder.
+			leases, err := dynamicClient.Resource(schema.GroupVersionResource{
+				Group:    "coordination.k8s.io",
+				Version:  "v1",
+				Resource: "leases",
+			}).Namespace("default").List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			if tc.lockedBy != "" {
+				assert.Len(t, leases.Items, 1)
+			} else {
+				assert.Empty(t, leases.Items)
+			}
+
+			if tc.expectedLabel != "" {
+				live, err := dynamicClient.Resource(configMapGVR).Namespace("default").
+					Get(context.Background(), "old-inventory", metav1.GetOptions{})
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedLabel, live.GetLabels()[common.InventoryLabel])
+			}
+
+			live, err := dynamicClient.Resource(deploymentGVR).Namespace("default").
+				Get(context.Background(), "deployment", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedAnnotation, live.GetAnnotations()[inventory.OwningInventoryKey])
+			live, err = dynamicClient.Resource(serviceGVR).Namespace("default").
+				Get(context.Background(), "service", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "other-id", live.GetAnnotations()[inventory.OwningInventoryKey])
+		})
+	}
+}
+
+func TestNewMigrator(t *testing.T) {
+	tf := cmdtesting.NewTestFactory().WithNamespace("default")
+	defer tf.Cleanup()
+
+	invClient, err := resourcegroup.NewInventoryClient(tf)
+	require.NoError(t, err)
+	migrator, err := NewMigrator(tf, invClient)
+	require.NoError(t, err)
+	// The migrator must not use the ResourceGroup client, which deletes the
+	// ConfigMap inventory with the same name by itself.
+	assert.Equal(t, invClient.ClusterInventoryClient, migrator.InvClient)
+}
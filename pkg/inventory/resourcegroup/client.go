@@ -0,0 +1,193 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+var (
+	_ inventory.InventoryClientFactory = ClusterInventoryClientFactory{}
+	_ inventory.InventoryClient        = &InventoryClient{}
+)
+
+// ClusterInventoryClientFactory is a factory that creates instances of
+// InventoryClient, which handles both ResourceGroup and ConfigMap
+// inventory objects.
+type ClusterInventoryClientFactory struct {
+}
+
+func (ClusterInventoryClientFactory) NewInventoryClient(factory cmdutil.Factory) (inventory.InventoryClient, error) {
+	return NewInventoryClient(factory)
+}
+
+// InventoryClient is an InventoryClient which handles both ResourceGroup
+// and ConfigMap inventory objects. It migrates the ConfigMap inventory with
+// the same namespace and inventory id as a ResourceGroup inventory to the
+// ResourceGroup: until the ResourceGroup is created, the inventory is read
+// from and written to the ConfigMap, and the ConfigMap is deleted once
+// its objects are merged into the newly created ResourceGroup.
+type InventoryClient struct {
+	*inventory.ClusterInventoryClient
+}
+
+// NewInventoryClient returns an InventoryClient or an error.
+func NewInventoryClient(factory cmdutil.Factory) (*InventoryClient, error) {
+	cic, err := inventory.NewInventoryClient(factory, WrapInventoryObj, InvInfoToUnstructured)
+	if err != nil {
+		return nil, err
+	}
+	return &InventoryClient{ClusterInventoryClient: cic}, nil
+}
+
+// GetClusterObjs returns the objects stored in the cluster inventory object,
+// or in the ConfigMap inventory it migrates from, or an error if one occurred.
+func (ic *InventoryClient) GetClusterObjs(localInv inventory.InventoryInfo, dryRun common.DryRunStrategy) (object.ObjMetadataSet, error) {
+	inv, err := ic.target(localInv)
+	if err != nil {
+		return nil, err
+	}
+	return ic.ClusterInventoryClient.GetClusterObjs(inv, dryRun)
+}
+
+// GetClusterEntries returns the entries stored in the cluster inventory
+// object, or in the ConfigMap inventory it migrates from, or an error if
+// one occurred.
+func (ic *InventoryClient) GetClusterEntries(localInv inventory.InventoryInfo, dryRun common.DryRunStrategy) (inventory.Entries, error) {
+	inv, err := ic.target(localInv)
+	if err != nil {
+		return nil, err
+	}
+	return ic.ClusterInventoryClient.GetClusterEntries(inv, dryRun)
+}
+
+// GetClusterInventoryInfo returns the cluster inventory object, or the
+// ConfigMap inventory object it migrates from.
+func (ic *InventoryClient) GetClusterInventoryInfo(localInv inventory.InventoryInfo,
+	dryRun common.DryRunStrategy) (*unstructured.Unstructured, error) {
+	inv, err := ic.target(localInv)
+	if err != nil {
+		return nil, err
+	}
+	return ic.ClusterInventoryClient.GetClusterInventoryInfo(inv, dryRun)
+}
+
+// Replace stores the passed objects in the cluster inventory object, or in
+// the ConfigMap inventory it migrates from, or returns an error if one
+// occurred.
+func (ic *InventoryClient) Replace(localInv inventory.InventoryInfo, objs object.ObjMetadataSet, dryRun common.DryRunStrategy) error {
+	return ic.ReplaceEntries(localInv, inventory.NewEntries(objs), dryRun)
+}
+
+// ReplaceEntries stores the passed entries in the cluster inventory object,
+// or in the ConfigMap inventory it migrates from, or returns an error if
+// one occurred.
+func (ic *InventoryClient) ReplaceEntries(localInv inventory.InventoryInfo, entries inventory.Entries, dryRun common.DryRunStrategy) error {
+	inv, err := ic.target(localInv)
+	if err != nil {
+		return err
+	}
+	return ic.ClusterInventoryClient.ReplaceEntries(inv, entries, dryRun)
+}
+
+// Merge stores the union of the passed objects with the objects currently
+// stored in the cluster inventory object, and returns the objects to prune,
+// like the ClusterInventoryClient. If the ResourceGroup inventory object
+// does not exist yet, the objects and entries of the ConfigMap inventory it
+// migrates from are stored in the newly created ResourceGroup, and the
+// ConfigMap is deleted afterwards.
+func (ic *InventoryClient) Merge(localInv inventory.InventoryInfo, objs object.ObjMetadataSet, dryRun common.DryRunStrategy) (object.ObjMetadataSet, error) {
+	cmInv, err := ic.migrateFrom(localInv)
+	if err != nil {
+		return object.ObjMetadataSet{}, err
+	}
+	if cmInv == nil {
+		return ic.ClusterInventoryClient.Merge(localInv, objs, dryRun)
+	}
+	cmEntries, err := ic.ClusterInventoryClient.GetClusterEntries(cmInv, dryRun)
+	if err != nil {
+		return object.ObjMetadataSet{}, err
+	}
+	pruneIds := cmEntries.ObjMetadataSet().Diff(objs)
+	entries := cmEntries.Union(inventory.NewEntries(objs))
+	klog.V(4).Infof("migrating %d objects from ConfigMap inventory %s/%s to ResourceGroup inventory %s/%s",
+		len(cmEntries), cmInv.Namespace(), cmInv.Name(), localInv.Namespace(), localInv.Name())
+	if _, err := ic.ClusterInventoryClient.Merge(localInv, entries.ObjMetadataSet(), dryRun); err != nil {
+		return pruneIds, err
+	}
+	if err := ic.ClusterInventoryClient.ReplaceEntries(localInv, entries, dryRun); err != nil {
+		return pruneIds, err
+	}
+	// The ConfigMap inventory is deleted only once the ResourceGroup stores
+	// all its objects, so a partial failure never loses objects.
+	if err := ic.ClusterInventoryClient.DeleteInventoryObj(cmInv, dryRun); err != nil {
+		return pruneIds, err
+	}
+	return pruneIds, nil
+}
+
+// DeleteInventoryObj deletes the inventory object from the cluster, or the
+// ConfigMap inventory it migrates from, or returns an error if one occurred.
+func (ic *InventoryClient) DeleteInventoryObj(localInv inventory.InventoryInfo, dryRun common.DryRunStrategy) error {
+	inv, err := ic.target(localInv)
+	if err != nil {
+		return err
+	}
+	return ic.ClusterInventoryClient.DeleteInventoryObj(inv, dryRun)
+}
+
+// target returns the inventory to read from and write to: the ConfigMap
+// inventory the passed inventory migrates from, if any, or else the passed
+// inventory. Returns an error if one occurred.
+func (ic *InventoryClient) target(localInv inventory.InventoryInfo) (inventory.InventoryInfo, error) {
+	cmInv, err := ic.migrateFrom(localInv)
+	if err != nil {
+		return nil, err
+	}
+	if cmInv != nil {
+		return cmInv, nil
+	}
+	return localInv, nil
+}
+
+// migrateFrom returns the ConfigMap inventory to migrate to the passed
+// ResourceGroup inventory: the one with the same namespace and inventory
+// id, if it exists and the ResourceGroup does not. Returns nil otherwise,
+// or an error if one occurred.
+func (ic *InventoryClient) migrateFrom(localInv inventory.InventoryInfo) (inventory.InventoryInfo, error) {
+	cmInv := configMapInventory(localInv)
+	if cmInv == nil {
+		return nil, nil
+	}
+	rgObjs, err := ic.GetClusterInventoryObjs(localInv)
+	if err != nil || len(rgObjs) > 0 {
+		return nil, err
+	}
+	cmObjs, err := ic.GetClusterInventoryObjs(cmInv)
+	if err != nil || len(cmObjs) == 0 {
+		return nil, err
+	}
+	return cmInv, nil
+}
+
+// configMapInventory returns the ConfigMap inventory with the same name,
+// namespace and inventory id as the passed inventory, if it is a
+// ResourceGroup inventory, or nil otherwise.
+func configMapInventory(localInv inventory.InventoryInfo) inventory.InventoryInfo {
+	if _, ok := localInv.(*InventoryResourceGroup); !ok {
+		return nil
+	}
+	cm := &unstructured.Unstructured{}
+	cm.SetAPIVersion("v1")
+	cm.SetKind("ConfigMap")
+	cm.SetName(localInv.Name())
+	cm.SetNamespace(localInv.Namespace())
+	cm.SetLabels(map[string]string{common.InventoryLabel: localInv.ID()})
+	return inventory.WrapInventoryInfoObj(cm)
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	_ "embed"
+)
+
+// CRD is the CustomResourceDefinition of the ResourceGroup, which must be
+// installed before a ResourceGroup inventory is applied. The status is
+// not a subresource, so it is written together with the spec.
+//
+//go:embed crd.yaml
+var CRD string
+
+// Template for ResourceGroup inventory object. The following fields
+// must be filled in for this to be valid:
+//
+//  <DATETIME>: The time this is auto-generated
+//  <NAMESPACE>: The namespace to place this inventory object
+//  <RANDOMSUFFIX>: The random suffix added to the end of the name
+//  <INVENTORYID>: The label value to retrieve this inventory object
+//
+const Template = `# NOTE: auto-generated. Some fields should NOT be modified.
+# Date: <DATETIME>
+#
+# Contains the "inventory object" template ResourceGroup.
+# When this object is applied, it is handled specially,
+# storing the metadata of all the other objects applied.
+# This object and its stored inventory is subsequently
+# used to calculate the set of objects to automatically
+# delete (prune), when an object is omitted from further
+# applies. When applied, this "inventory object" is also
+# used to identify the entire set of objects to delete.
+#
+# NOTE: The ResourceGroup CustomResourceDefinition must be
+# installed in the cluster before this object is applied
+# (see resourcegroup-crd.yaml).
+#
+apiVersion: cli-utils.sigs.k8s.io/v1alpha1
+kind: ResourceGroup
+metadata:
+  # DANGER: Do not change the inventory object namespace
+  # or name. Changing them will cause a loss of continuity
+  # with previously applied grouped objects. Set deletion
+  # and pruning functionality will be impaired.
+  namespace: <NAMESPACE>
+  name: inventory-<RANDOMSUFFIX>
+  labels:
+    # DANGER: Do not change the value of this label.
+    # Changing this value will cause a loss of continuity
+    # with previously applied grouped objects. Set deletion
+    # and pruning functionality will be impaired.
+    cli-utils.sigs.k8s.io/inventory-id: <INVENTORYID>
+`
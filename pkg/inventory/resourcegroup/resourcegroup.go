@@ -0,0 +1,265 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Introduces the InventoryResourceGroup struct which implements
+// the Inventory interface. The InventoryResourceGroup wraps a
+// ResourceGroup custom resource which stores the set of inventory
+// (object metadata) in its spec, and their entries in its status.
+
+package resourcegroup
+
+import (
+	"fmt"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+// IsResourceGroup returns true if the passed object is a ResourceGroup.
+func IsResourceGroup(obj *unstructured.Unstructured) bool {
+	return obj != nil && obj.GroupVersionKind().GroupKind() == GroupVersionKind.GroupKind()
+}
+
+// WrapInventoryObj takes a passed inventory object and wraps it as an
+// Inventory: a ResourceGroup with the InventoryResourceGroup, and any
+// other object with the InventoryConfigMap. This lets a single inventory
+// client handle both kinds of inventory objects.
+func WrapInventoryObj(obj *unstructured.Unstructured) inventory.Inventory {
+	if IsResourceGroup(obj) {
+		return &InventoryResourceGroup{inv: obj}
+	}
+	return inventory.WrapInventoryObj(obj)
+}
+
+// WrapInventoryInfoObj takes a passed inventory object and wraps it as
+// an InventoryInfo, like WrapInventoryObj.
+func WrapInventoryInfoObj(obj *unstructured.Unstructured) inventory.InventoryInfo {
+	if IsResourceGroup(obj) {
+		return &InventoryResourceGroup{inv: obj}
+	}
+	return inventory.WrapInventoryInfoObj(obj)
+}
+
+// InvInfoToUnstructured returns the inventory object wrapped by the passed
+// InventoryInfo, or nil if it is neither a ResourceGroup nor a ConfigMap
+// inventory.
+func InvInfoToUnstructured(inv inventory.InventoryInfo) *unstructured.Unstructured {
+	if irg, ok := inv.(*InventoryResourceGroup); ok {
+		return irg.inv
+	}
+	return inventory.InvInfoToConfigMap(inv)
+}
+
+// InventoryResourceGroup wraps a ResourceGroup resource and implements
+// the Inventory interface. This wrapper loads and stores the
+// object metadata (inventory) and their entries to and from the
+// wrapped ResourceGroup.
+type InventoryResourceGroup struct {
+	inv     *unstructured.Unstructured
+	entries inventory.Entries
+}
+
+var _ inventory.InventoryInfo = &InventoryResourceGroup{}
+var _ inventory.EntryInventory = &InventoryResourceGroup{}
+
+func (irg *InventoryResourceGroup) Name() string {
+	return irg.inv.GetName()
+}
+
+func (irg *InventoryResourceGroup) Namespace() string {
+	return irg.inv.GetNamespace()
+}
+
+func (irg *InventoryResourceGroup) ID() string {
+	// Empty string if not set.
+	return irg.inv.GetLabels()[common.InventoryLabel]
+}
+
+// Strategy returns NameStrategy: a ResourceGroup is retrieved by name,
+// and its inventory id must match the inventory id of the local one.
+func (irg *InventoryResourceGroup) Strategy() inventory.InventoryStrategy {
+	return inventory.NameStrategy
+}
+
+// Load is an Inventory interface function returning the set of
+// object metadata from the spec of the wrapped ResourceGroup, or an error.
+func (irg *InventoryResourceGroup) Load() (object.ObjMetadataSet, error) {
+	spec, err := irg.spec()
+	if err != nil {
+		return nil, err
+	}
+	objs := object.ObjMetadataSet{}
+	for _, res := range spec.Resources {
+		obj, err := res.toObjMetadata()
+		if err != nil {
+			return objs, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// LoadEntries is an EntryInventory interface function returning the
+// entries of the objects in the spec of the wrapped ResourceGroup, from
+// its status. Objects without a resource status have an empty entry.
+func (irg *InventoryResourceGroup) LoadEntries() (inventory.Entries, error) {
+	objs, err := irg.Load()
+	if err != nil {
+		return nil, err
+	}
+	entries := inventory.NewEntries(objs)
+	st, err := irg.status()
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range st.ResourceStatuses {
+		obj, err := rs.toObjMetadata()
+		if err != nil {
+			return nil, err
+		}
+		if _, found := entries[obj]; found {
+			entries[obj] = inventory.Entry{
+				UID:         rs.UID,
+				Generation:  rs.Generation,
+				LastApplied: rs.LastApplied,
+				Status:      rs.Status,
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Store is an Inventory interface function implemented to store
+// the object metadata in the wrapped ResourceGroup. Actual storing
+// happens in "GetObject".
+func (irg *InventoryResourceGroup) Store(objs object.ObjMetadataSet) error {
+	irg.entries = inventory.NewEntries(objs)
+	return nil
+}
+
+// StoreEntries is an EntryInventory interface function implemented to
+// store the entries in the wrapped ResourceGroup. Actual storing happens
+// in "GetObject".
+func (irg *InventoryResourceGroup) StoreEntries(entries inventory.Entries) error {
+	irg.entries = entries
+	return nil
+}
+
+// GetObject returns a copy of the wrapped ResourceGroup, with the stored
+// objects in its spec, and their entries and the Reconciled condition in
+// its status, or an error if one occurs.
+func (irg *InventoryResourceGroup) GetObject() (*unstructured.Unstructured, error) {
+	st, err := irg.status()
+	if err != nil {
+		return nil, err
+	}
+	spec := ResourceGroupSpec{}
+	st.ResourceStatuses = nil
+	notCurrent := 0
+	for _, obj := range irg.entries.ObjMetadataSet() {
+		res := fromObjMetadata(obj)
+		spec.Resources = append(spec.Resources, res)
+		entry := irg.entries[obj]
+		if entry.Status != status.CurrentStatus {
+			notCurrent++
+		}
+		if entry.IsEmpty() {
+			continue
+		}
+		st.ResourceStatuses = append(st.ResourceStatuses, ResourceStatus{
+			ObjMetadata: res,
+			UID:         entry.UID,
+			Generation:  entry.Generation,
+			LastApplied: entry.LastApplied,
+			Status:      entry.Status,
+		})
+	}
+	apimeta.SetStatusCondition(&st.Conditions, reconciledCondition(notCurrent, len(irg.entries)))
+
+	invCopy := irg.inv.DeepCopy()
+	if err := setField(invCopy, &spec, "spec"); err != nil {
+		return nil, err
+	}
+	if err := setField(invCopy, &st, "status"); err != nil {
+		return nil, err
+	}
+	return invCopy, nil
+}
+
+// reconciledCondition returns the Reconciled condition of an inventory
+// with total objects, of which notCurrent are not known to be Current.
+func reconciledCondition(notCurrent, total int) metav1.Condition {
+	if notCurrent == 0 {
+		return metav1.Condition{
+			Type:    ReconciledCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  "AllCurrent",
+			Message: fmt.Sprintf("all %d resources are Current", total),
+		}
+	}
+	return metav1.Condition{
+		Type:    ReconciledCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NotCurrent",
+		Message: fmt.Sprintf("%d of %d resources are not Current", notCurrent, total),
+	}
+}
+
+func (irg *InventoryResourceGroup) spec() (ResourceGroupSpec, error) {
+	spec := ResourceGroupSpec{}
+	err := getField(irg.inv, &spec, "spec")
+	return spec, err
+}
+
+func (irg *InventoryResourceGroup) status() (ResourceGroupStatus, error) {
+	st := ResourceGroupStatus{}
+	err := getField(irg.inv, &st, "status")
+	return st, err
+}
+
+// getField converts the passed field of the passed object into the
+// passed typed value. The value is left alone if the field doesn't exist.
+func getField(obj *unstructured.Unstructured, into interface{}, field string) error {
+	m, found, err := unstructured.NestedMap(obj.Object, field)
+	if err != nil || !found {
+		return err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, into); err != nil {
+		return fmt.Errorf("error retrieving %s of inventory object %s/%s: %w",
+			field, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// setField sets the passed field of the passed object to the passed
+// typed value.
+func setField(obj *unstructured.Unstructured, from interface{}, field string) error {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(from)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedMap(obj.Object, m, field)
+}
+
+func fromObjMetadata(obj object.ObjMetadata) ObjMetadata {
+	return ObjMetadata{
+		Group:     obj.GroupKind.Group,
+		Kind:      obj.GroupKind.Kind,
+		Namespace: obj.Namespace,
+		Name:      obj.Name,
+	}
+}
+
+func (o ObjMetadata) toObjMetadata() (object.ObjMetadata, error) {
+	return object.CreateObjMetadata(o.Namespace, o.Name, schema.GroupKind{
+		Group: o.Group,
+		Kind:  o.Kind,
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/common"
+	"sigs.k8s.io/cli-utils/pkg/inventory"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/object"
+)
+
+const (
+	testNamespace      = "test-inventory-namespace"
+	inventoryObjName   = "test-inventory-obj"
+	testInventoryLabel = "test-app-label"
+)
+
+var inventoryObj = &unstructured.Unstructured{
+	Object: map[string]interface{}{
+		"apiVersion": "cli-utils.sigs.k8s.io/v1alpha1",
+		"kind":       "ResourceGroup",
+		"metadata": map[string]interface{}{
+			"name":      inventoryObjName,
+			"namespace": testNamespace,
+			"labels": map[string]interface{}{
+				common.InventoryLabel: testInventoryLabel,
+			},
+		},
+	},
+}
+
+var configMapObj = &unstructured.Unstructured{
+	Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      inventoryObjName,
+			"namespace": testNamespace,
+			"labels": map[string]interface{}{
+				common.InventoryLabel: testInventoryLabel,
+			},
+		},
+	},
+}
+
+var pod1 = object.ObjMetadata{
+	Namespace: testNamespace,
+	Name:      "pod-1",
+	GroupKind: schema.GroupKind{Kind: "Pod"},
+}
+
+var deployment1 = object.ObjMetadata{
+	Namespace: testNamespace,
+	Name:      "deployment-1",
+	GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"},
+}
+
+func TestWrapInventoryObj(t *testing.T) {
+	assert.True(t, IsResourceGroup(inventoryObj))
+	assert.False(t, IsResourceGroup(configMapObj))
+	assert.False(t, IsResourceGroup(nil))
+
+	irg, ok := WrapInventoryObj(inventoryObj).(*InventoryResourceGroup)
+	require.True(t, ok)
+	assert.Equal(t, inventoryObjName, irg.Name())
+	assert.Equal(t, testNamespace, irg.Namespace())
+	assert.Equal(t, testInventoryLabel, irg.ID())
+	assert.Equal(t, inventory.NameStrategy, irg.Strategy())
+	assert.Equal(t, inventoryObj, InvInfoToUnstructured(WrapInventoryInfoObj(inventoryObj)))
+
+	_, ok = WrapInventoryObj(configMapObj).(*inventory.InventoryConfigMap)
+	assert.True(t, ok)
+	assert.Equal(t, configMapObj, InvInfoToUnstructured(WrapInventoryInfoObj(configMapObj)))
+}
+
+func TestInventoryResourceGroup_Objs(t *testing.T) {
+	objs := object.ObjMetadataSet{pod1, deployment1}
+	wrapped := WrapInventoryObj(inventoryObj)
+	require.NoError(t, wrapped.Store(objs))
+	obj, err := wrapped.GetObject()
+	require.NoError(t, err)
+
+	resources, found, err := unstructured.NestedSlice(obj.Object, "spec", "resources")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Len(t, resources, 2)
+	_, found, err = unstructured.NestedSlice(obj.Object, "status", "resourceStatuses")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	loaded, err := WrapInventoryObj(obj).Load()
+	require.NoError(t, err)
+	assert.True(t, objs.Equal(loaded))
+
+	// The wrapped object is not modified.
+	_, found, err = unstructured.NestedMap(inventoryObj.Object, "spec")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestInventoryResourceGroup_Entries(t *testing.T) {
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	tests := map[string]struct {
+		entries         inventory.Entries
+		expectedStatus  metav1.ConditionStatus
+		expectedMessage string
+	}{
+		"no objects are reconciled": {
+			entries:         inventory.Entries{},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "all 0 resources are Current",
+		},
+		"all objects Current are reconciled": {
+			entries: inventory.Entries{
+				pod1: {UID: "uid-1", LastApplied: &now, Status: status.CurrentStatus},
+				deployment1: {
+					UID:         "uid-2",
+					Generation:  3,
+					LastApplied: &now,
+					Status:      status.CurrentStatus,
+				},
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "all 2 resources are Current",
+		},
+		"objects not Current are not reconciled": {
+			entries: inventory.Entries{
+				pod1:        {UID: "uid-1", LastApplied: &now, Status: status.InProgressStatus},
+				deployment1: {},
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedMessage: "2 of 2 resources are not Current",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			wrapped := WrapInventoryObj(inventoryObj).(*InventoryResourceGroup)
+			require.NoError(t, wrapped.StoreEntries(tc.entries))
+			obj, err := wrapped.GetObject()
+			require.NoError(t, err)
+
+			loaded, err := WrapInventoryObj(obj).(*InventoryResourceGroup).LoadEntries()
+			require.NoError(t, err)
+			assert.True(t, tc.entries.Equal(loaded), "expected %v, got %v", tc.entries, loaded)
+
+			st, err := WrapInventoryObj(obj).(*InventoryResourceGroup).status()
+			require.NoError(t, err)
+			cond := apimeta.FindStatusCondition(st.Conditions, ReconciledCondition)
+			require.NotNil(t, cond)
+			assert.Equal(t, tc.expectedStatus, cond.Status)
+			assert.Equal(t, tc.expectedMessage, cond.Message)
+		})
+	}
+}
+
+func TestConfigMapInventory(t *testing.T) {
+	cmInv := configMapInventory(WrapInventoryInfoObj(inventoryObj))
+	require.NotNil(t, cmInv)
+	assert.Equal(t, inventory.LabelStrategy, cmInv.Strategy())
+	assert.Equal(t, inventoryObjName, cmInv.Name())
+	assert.Equal(t, testNamespace, cmInv.Namespace())
+	assert.Equal(t, testInventoryLabel, cmInv.ID())
+
+	assert.Nil(t, configMapInventory(WrapInventoryInfoObj(configMapObj)))
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package resourcegroup
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+)
+
+// GroupVersionKind is the type of the ResourceGroup inventory object.
+var GroupVersionKind = schema.GroupVersionKind{
+	Group:   "cli-utils.sigs.k8s.io",
+	Version: "v1alpha1",
+	Kind:    "ResourceGroup",
+}
+
+// ReconciledCondition is the type of the condition written after each run,
+// which is True if all the objects in the inventory are Current.
+const ReconciledCondition = "Reconciled"
+
+// ResourceGroup is the inventory object of the ResourceGroup inventory. The
+// spec stores the objects in the inventory, and the status stores their
+// entries and the conditions of the inventory.
+type ResourceGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ResourceGroupSpec   `json:"spec,omitempty"`
+	Status ResourceGroupStatus `json:"status,omitempty"`
+}
+
+// ResourceGroupSpec is the spec of a ResourceGroup.
+type ResourceGroupSpec struct {
+	// Resources are the objects stored in the inventory.
+	Resources []ObjMetadata `json:"resources,omitempty"`
+}
+
+// ResourceGroupStatus is the status of a ResourceGroup.
+type ResourceGroupStatus struct {
+	// ResourceStatuses are the entries of the objects stored in the
+	// inventory which have one.
+	ResourceStatuses []ResourceStatus `json:"resourceStatuses,omitempty"`
+	// Conditions are the conditions of the inventory.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ObjMetadata identifies an object stored in the inventory.
+type ObjMetadata struct {
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// ResourceStatus is the entry of an object stored in the inventory.
+type ResourceStatus struct {
+	ObjMetadata `json:",inline"`
+	// UID is the UID of the object when it was last applied.
+	UID types.UID `json:"uid,omitempty"`
+	// Generation is the generation of the object when it was last applied.
+	Generation int64 `json:"generation,omitempty"`
+	// LastApplied is the time the object was last applied.
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+	// Status is the last known status of the object.
+	Status status.Status `json:"status,omitempty"`
+}
@@ -6,6 +6,8 @@ package jsonpath
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	// Using gopkg.in/yaml.v3 instead of sigs.k8s.io/yaml on purpose.
 	// yaml.v3 correctly parses ints:
@@ -147,6 +149,128 @@ func Set(obj map[string]interface{}, expression string, value interface{}) (int,
 	return len(nodes), nil
 }
 
+// Delete evaluates the jsonpath expression to delete values from the input map.
+// Returns the number of matching nodes that were deleted, or an error.
+func Delete(obj map[string]interface{}, expression string) (int, error) {
+	// format input object as json for input into jsonpath library
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal input to json: %w", err)
+	}
+
+	klog.V(7).Infof("jsonpath.Delete input as json:\n%s", jsonBytes)
+
+	// parse json into an ajson node
+	root, err := ajson.Unmarshal(jsonBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal input json: %w", err)
+	}
+
+	// retrieve nodes that match the expression
+	nodes, err := root.JSONPath(expression)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate jsonpath expression (%s): %w", expression, err)
+	}
+	if len(nodes) == 0 {
+		// zero nodes found, none deleted
+		return 0, nil
+	}
+
+	// Delete the matching nodes from the input map, instead of parsing
+	// the json back into it, so the types of the remaining values are
+	// preserved. The paths are sorted in reverse order, so deleting an
+	// array element doesn't change the index of the following matches,
+	// regardless of the order the expression matched them in.
+	paths := make([][]interface{}, len(nodes))
+	for i, node := range nodes {
+		paths[i] = nodePath(node)
+		if len(paths[i]) == 0 {
+			return 0, fmt.Errorf("failed to delete root node (%s)", expression)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return comparePaths(paths[i], paths[j]) > 0
+	})
+	for _, path := range paths {
+		deleteAtPath(obj, path)
+	}
+
+	klog.V(7).Infof("jsonpath.Delete output:\n%v", obj)
+
+	return len(nodes), nil
+}
+
+// nodePath returns the keys and indexes from the root to the node.
+func nodePath(node *ajson.Node) []interface{} {
+	var path []interface{}
+	for ; node.Parent() != nil; node = node.Parent() {
+		if node.Parent().IsArray() {
+			path = append([]interface{}{node.Index()}, path...)
+		} else {
+			path = append([]interface{}{node.Key()}, path...)
+		}
+	}
+	return path
+}
+
+// comparePaths compares two paths step by step, comparing indexes as
+// numbers and keys as strings. A path sorts after its own prefix.
+func comparePaths(a, b []interface{}) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		aIndex, aIsIndex := a[i].(int)
+		bIndex, bIsIndex := b[i].(int)
+		switch {
+		case aIsIndex && bIsIndex:
+			if aIndex != bIndex {
+				return aIndex - bIndex
+			}
+		case aIsIndex != bIsIndex:
+			// Both steps have the same parent, so this doesn't happen.
+			if aIsIndex {
+				return -1
+			}
+			return 1
+		default:
+			if c := strings.Compare(a[i].(string), b[i].(string)); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(a) - len(b)
+}
+
+// deleteAtPath deletes the value at the path from the map.
+func deleteAtPath(obj map[string]interface{}, path []interface{}) {
+	var parent interface{} = obj
+	var set func(interface{})
+	for i, step := range path {
+		last := i == len(path)-1
+		switch typedParent := parent.(type) {
+		case map[string]interface{}:
+			key := step.(string)
+			if last {
+				delete(typedParent, key)
+				return
+			}
+			set = func(value interface{}) { typedParent[key] = value }
+			parent = typedParent[key]
+		case []interface{}:
+			index := step.(int)
+			if index >= len(typedParent) {
+				return
+			}
+			if last {
+				set(append(typedParent[:index:index], typedParent[index+1:]...))
+				return
+			}
+			set = func(value interface{}) { typedParent[index] = value }
+			parent = typedParent[index]
+		default:
+			return
+		}
+	}
+}
+
 func toArrayOfNodes(obj []interface{}) ([]*ajson.Node, error) {
 	out := make([]*ajson.Node, len(obj))
 	for index, value := range obj {
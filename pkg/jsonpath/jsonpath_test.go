@@ -249,6 +249,77 @@ func TestSet(t *testing.T) {
 	}
 }
 
+func TestDelete(t *testing.T) {
+	testCases := map[string]struct {
+		obj   *unstructured.Unstructured
+		path  string
+		found int
+		err   error
+	}{
+		"string in map": {
+			obj:   ktestutil.YamlToUnstructured(t, o1y),
+			path:  "$.metadata.namespace",
+			found: 1,
+		},
+		"array in map": {
+			obj:   ktestutil.YamlToUnstructured(t, o1y),
+			path:  "$.map.c",
+			found: 1,
+		},
+		"field selector": {
+			obj:   ktestutil.YamlToUnstructured(t, o1y),
+			path:  `$.entries[?(@.name=="b")].value`,
+			found: 1,
+		},
+		"multi-field selector": {
+			obj:   ktestutil.YamlToUnstructured(t, o1y),
+			path:  `$.entries[?(@.name=="a" || @.name=="c")].value`,
+			found: 2,
+		},
+		"missing field": {
+			obj:   ktestutil.YamlToUnstructured(t, o1y),
+			path:  "$.spec.replicas",
+			found: 0,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			found, err := Delete(tc.obj.Object, tc.path)
+			testCtx := []interface{}{"path: %s\nobject (mutated):\n%s", tc.path, toYaml(t, tc.obj.Object)}
+			require.Equal(t, tc.err, err, testCtx...)
+			require.Equal(t, tc.found, found, testCtx...)
+
+			values, err := Get(tc.obj.Object, tc.path)
+			require.NoError(t, err, testCtx...)
+			require.Empty(t, values, testCtx...)
+		})
+	}
+}
+
+func TestDelete_ArrayElements(t *testing.T) {
+	obj := ktestutil.YamlToUnstructured(t, o1y)
+
+	found, err := Delete(obj.Object, `$.entries[?(@.name=="a" || @.name=="c")]`)
+	require.NoError(t, err)
+	require.Equal(t, 2, found)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"name": "b", "value": "y"},
+	}, obj.Object["entries"])
+
+	found, err = Delete(obj.Object, "$.list[1]")
+	require.NoError(t, err)
+	require.Equal(t, 1, found)
+	require.Equal(t, []interface{}{int64(1), false}, obj.Object["list"])
+
+	// Indexes are deleted in reverse, regardless of the match order.
+	obj = ktestutil.YamlToUnstructured(t, o1y)
+	found, err = Delete(obj.Object, "$.list[2,0]")
+	require.NoError(t, err)
+	require.Equal(t, 2, found)
+	require.Equal(t, []interface{}{"b"}, obj.Object["list"])
+}
+
 func toYaml(t *testing.T, in interface{}) string {
 	yamlBytes, err := yaml.Marshal(in)
 	require.NoError(t, err)
@@ -0,0 +1,318 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package clusterreader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewWatchingClusterReader returns a new instance of the WatchingClusterReader.
+// The set of identifiers is used to figure out which GroupKind and namespace
+// combinations need to be watched, the same way as for the
+// CachingClusterReader.
+func NewWatchingClusterReader(dynamicClient dynamic.Interface, mapper meta.RESTMapper,
+	identifiers object.ObjMetadataSet) (*WatchingClusterReader, error) {
+	gvkNamespaceSet := newGnSet()
+	for _, id := range identifiers {
+		err := buildGvkNamespaceSet([]schema.GroupKind{id.GroupKind}, id.Namespace, gvkNamespaceSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &WatchingClusterReader{
+		client:  dynamicClient,
+		mapper:  mapper,
+		gns:     gvkNamespaceSet.gvkNamespaces,
+		cache:   make(map[gkNamespace]*watchCacheEntry),
+		polled:  make(map[gkNamespace]bool),
+		changes: make(chan struct{}, 1),
+	}, nil
+}
+
+// WatchingClusterReader is an implementation of the ClusterReader interface
+// that keeps a live cache of all resources needed, using a LIST followed by
+// a WATCH for every combination of GroupKind and namespace. Whenever a
+// watched resource changes, a notification is sent on the Changes channel,
+// so the PollerEngine can compute the new status right away instead of
+// waiting for the next polling cycle.
+//
+// If a resource type can't be watched, for example because the RBAC rules
+// don't allow it, the WatchingClusterReader falls back to fetching the
+// resources with a LIST call on every Sync, like the CachingClusterReader.
+type WatchingClusterReader struct {
+	mx sync.RWMutex
+
+	// client is used to list and watch resources in the cluster.
+	client dynamic.Interface
+
+	// mapper is the client-side representation of the server-side scheme. It is used
+	// to resolve GroupVersionKind from GroupKind.
+	mapper meta.RESTMapper
+
+	// gns contains the slice of all the GVK and namespace combinations that
+	// should be included in the cache.
+	gns []gkNamespace
+
+	// cache contains the resources found in the cluster for the given
+	// combination of GVK and namespace.
+	cache map[gkNamespace]*watchCacheEntry
+
+	// polled contains the GVK and namespace combinations that can't be
+	// watched, and are instead listed on every Sync.
+	polled map[gkNamespace]bool
+
+	// changes receives a notification whenever the cache is updated by a
+	// watch event.
+	changes chan struct{}
+}
+
+type watchCacheEntry struct {
+	resources map[string]unstructured.Unstructured
+	err       error
+	// watching is true if the entry is kept up to date by a watch. If
+	// false, the entry will be refreshed on the next Sync.
+	watching bool
+}
+
+// Get looks up the resource identified by the key and the object GVK in the cache. If the needed combination
+// of GVK and namespace is not part of the cache, that is considered an error.
+func (c *WatchingClusterReader) Get(_ context.Context, key client.ObjectKey, obj *unstructured.Unstructured) error {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind())
+	if err != nil {
+		return err
+	}
+	gn := gkNamespace{
+		GroupKind: gvk.GroupKind(),
+		Namespace: key.Namespace,
+	}
+	cacheEntry, found := c.cache[gn]
+	if !found {
+		return fmt.Errorf("GVK %s and Namespace %s not found in cache", gvk.String(), gn.Namespace)
+	}
+
+	if cacheEntry.err != nil {
+		return cacheEntry.err
+	}
+	u, found := cacheEntry.resources[key.Name]
+	if !found {
+		return errors.NewNotFound(mapping.Resource.GroupResource(), key.Name)
+	}
+	obj.Object = u.DeepCopy().Object
+	return nil
+}
+
+// ListNamespaceScoped lists all resource identifier by the GVK of the list, the namespace and the selector
+// from the cache. If the needed combination of GVK and namespace is not part of the cache, that is considered an error.
+func (c *WatchingClusterReader) ListNamespaceScoped(_ context.Context, list *unstructured.UnstructuredList,
+	namespace string, selector labels.Selector) error {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	gvk := list.GroupVersionKind()
+	gn := gkNamespace{
+		GroupKind: gvk.GroupKind(),
+		Namespace: namespace,
+	}
+
+	cacheEntry, found := c.cache[gn]
+	if !found {
+		return fmt.Errorf("GVK %s and Namespace %s not found in cache", gvk.String(), gn.Namespace)
+	}
+
+	if cacheEntry.err != nil {
+		return cacheEntry.err
+	}
+
+	var items []unstructured.Unstructured
+	for _, u := range cacheEntry.resources {
+		if selector.Matches(labels.Set(u.GetLabels())) {
+			items = append(items, *u.DeepCopy())
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].GetName() < items[j].GetName()
+	})
+	list.Items = items
+	return nil
+}
+
+// ListClusterScoped lists all resource identifier by the GVK of the list and selector
+// from the cache. If the needed combination of GVK and namespace (which for clusterscoped resources
+// will always be the empty string) is not part of the cache, that is considered an error.
+func (c *WatchingClusterReader) ListClusterScoped(ctx context.Context, list *unstructured.UnstructuredList, selector labels.Selector) error {
+	return c.ListNamespaceScoped(ctx, list, "", selector)
+}
+
+// Changes returns a channel that receives a notification whenever a watched
+// resource changes. Notifications are coalesced, so a single notification
+// can cover several changes.
+func (c *WatchingClusterReader) Changes() <-chan struct{} {
+	return c.changes
+}
+
+// Sync populates the cache for all the GVK and namespace combinations that
+// are not currently being watched. For each of them, the resources are
+// fetched with a LIST call, and a WATCH is started from the returned
+// resourceVersion. The watches run until the context is cancelled, or until
+// the watch is closed by the server, in which case the combination is
+// listed again on the next Sync.
+func (c *WatchingClusterReader) Sync(ctx context.Context) error {
+	for _, gn := range c.gns {
+		c.mx.RLock()
+		entry, found := c.cache[gn]
+		watching := found && entry.watching
+		c.mx.RUnlock()
+		if watching {
+			continue
+		}
+		if err := c.syncGroupKindNamespace(ctx, gn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *WatchingClusterReader) syncGroupKindNamespace(ctx context.Context, gn gkNamespace) error {
+	mapping, err := c.mapper.RESTMapping(gn.GroupKind)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			// The type doesn't exist yet. Presumably the CRD is being
+			// applied, so try again on the next Sync.
+			c.setEntry(gn, &watchCacheEntry{
+				err: err,
+			})
+			return nil
+		}
+		return err
+	}
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = c.client.Resource(mapping.Resource).Namespace(gn.Namespace)
+	} else {
+		ri = c.client.Resource(mapping.Resource)
+	}
+
+	list, err := ri.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		// We continue even if there is an error. Whenever any pollers
+		// request a resource covered by this gns, we just return the
+		// error.
+		c.setEntry(gn, &watchCacheEntry{
+			err: err,
+		})
+		return nil
+	}
+	entry := &watchCacheEntry{
+		resources: make(map[string]unstructured.Unstructured, len(list.Items)),
+	}
+	for _, u := range list.Items {
+		entry.resources[u.GetName()] = u
+	}
+
+	c.mx.RLock()
+	polled := c.polled[gn]
+	c.mx.RUnlock()
+	if polled {
+		c.setEntry(gn, entry)
+		return nil
+	}
+
+	w, err := ri.Watch(ctx, metav1.ListOptions{
+		ResourceVersion: list.GetResourceVersion(),
+	})
+	if err != nil {
+		if errors.IsForbidden(err) || errors.IsMethodNotSupported(err) {
+			klog.V(4).Infof("unable to watch %s in namespace %q, falling back to polling: %v",
+				gn.GroupKind, gn.Namespace, err)
+			c.mx.Lock()
+			c.polled[gn] = true
+			c.mx.Unlock()
+		} else {
+			klog.V(4).Infof("failed to watch %s in namespace %q, retrying on next sync: %v",
+				gn.GroupKind, gn.Namespace, err)
+		}
+		c.setEntry(gn, entry)
+		return nil
+	}
+	entry.watching = true
+	c.setEntry(gn, entry)
+	go c.watch(ctx, gn, w)
+	return nil
+}
+
+// watch updates the cache entry for the GVK and namespace combination with
+// the events from the watch, until the context is cancelled or the watch
+// is closed.
+func (c *WatchingClusterReader) watch(ctx context.Context, gn gkNamespace, w watch.Interface) {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-w.ResultChan():
+			if !ok || e.Type == watch.Error {
+				klog.V(4).Infof("watch of %s in namespace %q closed", gn.GroupKind, gn.Namespace)
+				c.stopWatching(gn)
+				c.notify()
+				return
+			}
+			u, ok := e.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				c.mx.Lock()
+				c.cache[gn].resources[u.GetName()] = *u
+				c.mx.Unlock()
+			case watch.Deleted:
+				c.mx.Lock()
+				delete(c.cache[gn].resources, u.GetName())
+				c.mx.Unlock()
+			default:
+				continue
+			}
+			c.notify()
+		}
+	}
+}
+
+func (c *WatchingClusterReader) setEntry(gn gkNamespace, entry *watchCacheEntry) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.cache[gn] = entry
+}
+
+func (c *WatchingClusterReader) stopWatching(gn gkNamespace) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.cache[gn].watching = false
+}
+
+// notify sends a notification on the changes channel, unless there is
+// already one pending.
+func (c *WatchingClusterReader) notify() {
+	select {
+	case c.changes <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package clusterreader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kubectl/pkg/scheme"
+	"sigs.k8s.io/cli-utils/pkg/object"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var deploymentYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment
+  namespace: default
+`
+
+var deploymentGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "deployments",
+}
+
+func TestWatchingClusterReader(t *testing.T) {
+	testCases := map[string]struct {
+		watchError    error
+		expectWatched bool
+	}{
+		"watch allowed": {
+			expectWatched: true,
+		},
+		"watch forbidden falls back to polling": {
+			watchError: errors.NewForbidden(deploymentGVR.GroupResource(), "",
+				assert.AnError),
+			expectWatched: false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			deployment := testutil.Unstructured(t, deploymentYAML)
+			fakeClient := fake.NewSimpleDynamicClient(scheme.Scheme, deployment)
+			if tc.watchError != nil {
+				fakeClient.PrependWatchReactor("*", func(clienttesting.Action) (bool, watch.Interface, error) {
+					return true, nil, tc.watchError
+				})
+			}
+			mapper := testutil.NewFakeRESTMapper(deploymentGVK, rsGVK, podGVK)
+			identifiers := object.ObjMetadataSet{
+				object.UnstructuredToObjMetaOrDie(deployment),
+			}
+
+			clusterReader, err := NewWatchingClusterReader(fakeClient, mapper, identifiers)
+			require.NoError(t, err)
+			require.NoError(t, clusterReader.Sync(ctx))
+
+			gn := gkNamespace{
+				GroupKind: deploymentGVK.GroupKind(),
+				Namespace: "default",
+			}
+			assert.Equal(t, tc.expectWatched, clusterReader.cache[gn].watching)
+			assert.Equal(t, !tc.expectWatched, clusterReader.polled[gn])
+
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(deploymentGVK)
+			err = clusterReader.Get(ctx, client.ObjectKey{Namespace: "default", Name: "deployment"}, u)
+			require.NoError(t, err)
+			assert.Equal(t, "deployment", u.GetName())
+
+			// Create a new deployment and check that the cache picks it up,
+			// either from the watch or from the next Sync.
+			deployment2 := deployment.DeepCopy()
+			deployment2.SetName("deployment2")
+			_, err = fakeClient.Resource(deploymentGVR).Namespace("default").
+				Create(ctx, deployment2, metav1.CreateOptions{})
+			require.NoError(t, err)
+			if tc.expectWatched {
+				select {
+				case <-clusterReader.Changes():
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for change notification")
+				}
+			} else {
+				require.NoError(t, clusterReader.Sync(ctx))
+			}
+
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(deploymentGVK)
+			err = clusterReader.ListNamespaceScoped(ctx, list, "default", labels.Everything())
+			require.NoError(t, err)
+			var names []string
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
+			}
+			assert.Equal(t, []string{"deployment", "deployment2"}, names)
+		})
+	}
+}
+
+func TestWatchingClusterReader_WatchClosed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deployment := testutil.Unstructured(t, deploymentYAML)
+	fakeClient := fake.NewSimpleDynamicClient(scheme.Scheme, deployment)
+	fakeWatcher := watch.NewFake()
+	fakeClient.PrependWatchReactor("deployments", func(clienttesting.Action) (bool, watch.Interface, error) {
+		return true, fakeWatcher, nil
+	})
+	mapper := testutil.NewFakeRESTMapper(deploymentGVK, rsGVK, podGVK)
+
+	clusterReader, err := NewWatchingClusterReader(fakeClient, mapper, object.ObjMetadataSet{
+		object.UnstructuredToObjMetaOrDie(deployment),
+	})
+	require.NoError(t, err)
+	require.NoError(t, clusterReader.Sync(ctx))
+
+	gn := gkNamespace{
+		GroupKind: deploymentGVK.GroupKind(),
+		Namespace: "default",
+	}
+	fakeWatcher.Delete(deployment.DeepCopy())
+	<-clusterReader.Changes()
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(deploymentGVK)
+	err = clusterReader.Get(ctx, client.ObjectKey{Namespace: "default", Name: "deployment"}, u)
+	assert.True(t, errors.IsNotFound(err))
+
+	fakeWatcher.Stop()
+	<-clusterReader.Changes()
+	clusterReader.mx.RLock()
+	watching := clusterReader.cache[gn].watching
+	clusterReader.mx.RUnlock()
+	assert.False(t, watching)
+}
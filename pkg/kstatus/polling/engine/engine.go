@@ -142,8 +142,9 @@ type Options struct {
 // by a single goroutine, meaning we don't need synchronization.
 // The statusPollerRunner uses an implementation of the ClusterReader interface to talk to the
 // kubernetes cluster. Currently this can be either the cached ClusterReader that syncs all needed resources
-// with LIST calls before each polling loop, or the normal ClusterReader that just forwards each call
-// to the client.Reader from controller-runtime.
+// with LIST calls before each polling loop, the watching ClusterReader that keeps the resources up to date
+// with WATCH calls, or the normal ClusterReader that just forwards each call to the client.Reader from
+// controller-runtime.
 type statusPollerRunner struct {
 	// ctx is the context for the runner. It will be used by the caller of Poll to cancel
 	// polling resources.
@@ -197,20 +198,28 @@ func (r *statusPollerRunner) Run() {
 		return
 	}
 
+	// If the ClusterReader can notify about changes, the status is also
+	// computed whenever a resource changes. The nil channel blocks forever.
+	var changes <-chan struct{}
+	if notifier, ok := r.clusterReader.(ChangeNotifier); ok {
+		changes = notifier.Changes()
+	}
+
 	for {
 		select {
 		case <-r.ctx.Done():
 			return
 		case <-ticker.C:
-			// First sync and then compute status for all resources.
-			err := r.syncAndPoll()
-			if err != nil {
-				r.eventChannel <- event.Event{
-					EventType: event.ErrorEvent,
-					Error:     err,
-				}
-				return
+		case <-changes:
+		}
+		// First sync and then compute status for all resources.
+		err := r.syncAndPoll()
+		if err != nil {
+			r.eventChannel <- event.Event{
+				EventType: event.ErrorEvent,
+				Error:     err,
 			}
+			return
 		}
 	}
 }
@@ -30,3 +30,12 @@ type ClusterReader interface {
 	// to sync caches.
 	Sync(ctx context.Context) error
 }
+
+// ChangeNotifier can be implemented by a ClusterReader that is able to
+// detect changes to resources as they happen, for example by using watches.
+// The PollerEngine computes the status of all resources whenever a
+// notification is received on the Changes channel, in addition to the
+// regular polling cycle.
+type ChangeNotifier interface {
+	Changes() <-chan struct{}
+}
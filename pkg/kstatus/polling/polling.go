@@ -10,6 +10,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/clusterreader"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
 	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
@@ -30,9 +31,27 @@ func NewStatusPoller(reader client.Reader, mapper meta.RESTMapper) *StatusPoller
 	}
 }
 
+// NewWatchingStatusPoller creates a new StatusPoller that uses watches to
+// keep a live cache of the resources, and computes their status as soon as
+// they change. Resource types that can't be watched are polled instead.
+// The UseCache option is ignored by the returned StatusPoller.
+func NewWatchingStatusPoller(reader client.Reader, dynamicClient dynamic.Interface, mapper meta.RESTMapper) *StatusPoller {
+	return &StatusPoller{
+		engine: &engine.PollerEngine{
+			Reader: reader,
+			Mapper: mapper,
+		},
+		dynamicClient: dynamicClient,
+	}
+}
+
 // StatusPoller provides functionality for polling a cluster for status for a set of resources.
 type StatusPoller struct {
 	engine *engine.PollerEngine
+
+	// dynamicClient is used to watch resources. If nil, resources are
+	// polled.
+	dynamicClient dynamic.Interface
 }
 
 // Poll will create a new statusPollerRunner that will poll all the resources provided and report their status
@@ -49,9 +68,13 @@ func (s *StatusPoller) Poll(ctx context.Context, identifiers object.ObjMetadataS
 			return readers, defaultReader
 		}
 	}
+	clusterReaderFactory := clusterReaderFactoryFunc(options.UseCache)
+	if s.dynamicClient != nil {
+		clusterReaderFactory = watchingClusterReaderFactoryFunc(s.dynamicClient)
+	}
 	return s.engine.Poll(ctx, identifiers, engine.Options{
 		PollInterval:             options.PollInterval,
-		ClusterReaderFactoryFunc: clusterReaderFactoryFunc(options.UseCache),
+		ClusterReaderFactoryFunc: clusterReaderFactory,
 		StatusReadersFactoryFunc: statusReaderFactory,
 	})
 }
@@ -109,3 +132,12 @@ func clusterReaderFactoryFunc(useCache bool) engine.ClusterReaderFactoryFunc {
 		return &clusterreader.DirectClusterReader{Reader: r}, nil
 	}
 }
+
+// watchingClusterReaderFactoryFunc returns a factory function for creating
+// an instance of the WatchingClusterReader, which uses the dynamic client
+// rather than the client.Reader passed to the factory function.
+func watchingClusterReaderFactoryFunc(dynamicClient dynamic.Interface) engine.ClusterReaderFactoryFunc {
+	return func(_ client.Reader, mapper meta.RESTMapper, identifiers object.ObjMetadataSet) (engine.ClusterReader, error) {
+		return clusterreader.NewWatchingClusterReader(dynamicClient, mapper, identifiers)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package rules computes the status of resources from declarative
+// readiness rules, for resource types that don't follow the standard
+// kstatus conventions. A RuleSet maps a GroupKind to JSONPath
+// expressions that define when a resource is Current or Failed.
+//
+// A RuleSet is usually read from a YAML or JSON file:
+//
+//	rules:
+//	- group: example.com
+//	  kind: Database
+//	  current:
+//	  - path: $.status.conditions[?(@.type=="Synced")].status
+//	    value: "True"
+//	  failed:
+//	  - path: $.status.phase
+//	    value: Failed
+//
+// The rules are used by the StatusPoller through the StatusReaders
+// returned by RuleSet.StatusReadersFactoryFunc.
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/jsonpath"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/engine"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/polling/statusreaders"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/yaml"
+)
+
+// RuleSet is a set of readiness rules, at most one per GroupKind.
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule defines how the status of resources of a single GroupKind is
+// computed. A resource is Failed if any of the Failed expressions
+// matches, Current if all of the Current expressions match, and
+// InProgress otherwise.
+type Rule struct {
+	Group   string       `json:"group,omitempty"`
+	Kind    string       `json:"kind"`
+	Current []Expression `json:"current"`
+	Failed  []Expression `json:"failed,omitempty"`
+}
+
+// Expression matches a resource if the JSONPath expression in Path
+// finds a value equal to Value. If Value is empty, the expression
+// matches if Path finds any value.
+type Expression struct {
+	Path  string `json:"path"`
+	Value string `json:"value,omitempty"`
+}
+
+// ReadFile reads a RuleSet from a YAML or JSON file.
+func ReadFile(path string) (*RuleSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status rules %q: %w", path, err)
+	}
+	return rs, nil
+}
+
+// Parse parses and validates a RuleSet from YAML or JSON.
+func Parse(data []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := yaml.UnmarshalStrict(data, rs); err != nil {
+		return nil, err
+	}
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Validate returns an error if a rule has no kind or current
+// expressions, if an expression has no path, or if there is more
+// than one rule for a GroupKind.
+func (rs *RuleSet) Validate() error {
+	seen := map[schema.GroupKind]bool{}
+	for i, r := range rs.Rules {
+		if r.Kind == "" {
+			return fmt.Errorf("rule %d: kind is required", i)
+		}
+		gk := r.GroupKind()
+		if seen[gk] {
+			return fmt.Errorf("rule %d: duplicate rule for %s", i, gk)
+		}
+		seen[gk] = true
+		if len(r.Current) == 0 {
+			return fmt.Errorf("rule %d (%s): at least one current expression is required", i, gk)
+		}
+		for _, e := range append(append([]Expression{}, r.Current...), r.Failed...) {
+			if e.Path == "" {
+				return fmt.Errorf("rule %d (%s): expression path is required", i, gk)
+			}
+		}
+	}
+	return nil
+}
+
+// StatusReadersFactoryFunc returns a function that creates a generic
+// StatusReader for every rule in the set. It can be used as the
+// CustomStatusReadersFactoryFunc in the polling.Options.
+func (rs *RuleSet) StatusReadersFactoryFunc() func(engine.ClusterReader, meta.RESTMapper) map[schema.GroupKind]engine.StatusReader {
+	return func(reader engine.ClusterReader, mapper meta.RESTMapper) map[schema.GroupKind]engine.StatusReader {
+		readers := make(map[schema.GroupKind]engine.StatusReader, len(rs.Rules))
+		for _, r := range rs.Rules {
+			readers[r.GroupKind()] = statusreaders.NewGenericStatusReader(reader, mapper, r.StatusFunc())
+		}
+		return readers
+	}
+}
+
+// GroupKind returns the GroupKind the rule applies to.
+func (r Rule) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: r.Group, Kind: r.Kind}
+}
+
+// StatusFunc returns a function that computes the status of a resource
+// using the rule. Resources that are scheduled for deletion are
+// Terminating, and resources whose latest generation is not yet
+// observed are InProgress, like with status.Compute.
+func (r Rule) StatusFunc() statusreaders.StatusFunc {
+	return func(u *unstructured.Unstructured) (*status.Result, error) {
+		if u.GetDeletionTimestamp() != nil {
+			return &status.Result{
+				Status:     status.TerminatingStatus,
+				Message:    "Resource scheduled for deletion",
+				Conditions: []status.Condition{},
+			}, nil
+		}
+		observedGeneration, found, err := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+		if err != nil {
+			return nil, fmt.Errorf("looking up status.observedGeneration from resource: %w", err)
+		}
+		if found && observedGeneration != u.GetGeneration() {
+			return &status.Result{
+				Status: status.InProgressStatus,
+				Message: fmt.Sprintf("%s generation is %d, but latest observed generation is %d",
+					u.GetKind(), u.GetGeneration(), observedGeneration),
+				Conditions: []status.Condition{},
+			}, nil
+		}
+
+		for _, e := range r.Failed {
+			matched, err := e.Matches(u)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				return &status.Result{
+					Status:     status.FailedStatus,
+					Message:    fmt.Sprintf("Failed rule matched: %s", e),
+					Conditions: []status.Condition{},
+				}, nil
+			}
+		}
+		for _, e := range r.Current {
+			matched, err := e.Matches(u)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				return &status.Result{
+					Status:     status.InProgressStatus,
+					Message:    fmt.Sprintf("Current rule not matched: %s", e),
+					Conditions: []status.Condition{},
+				}, nil
+			}
+		}
+		return &status.Result{
+			Status:     status.CurrentStatus,
+			Message:    "Resource is current",
+			Conditions: []status.Condition{},
+		}, nil
+	}
+}
+
+// Matches returns true if the expression matches the resource.
+func (e Expression) Matches(u *unstructured.Unstructured) (bool, error) {
+	values, err := jsonpath.Get(u.Object, e.Path)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range values {
+		if e.Value == "" || fmt.Sprint(v) == e.Value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// String returns the expression in the form "path == value".
+func (e Expression) String() string {
+	if e.Value == "" {
+		return e.Path
+	}
+	return fmt.Sprintf("%s == %q", e.Path, e.Value)
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/cli-utils/pkg/kstatus/status"
+	"sigs.k8s.io/cli-utils/pkg/testutil"
+)
+
+var rulesYAML = `
+rules:
+- group: example.com
+  kind: Database
+  current:
+  - path: $.status.conditions[?(@.type=="Synced")].status
+    value: "True"
+  - path: $.status.endpoint
+  failed:
+  - path: $.status.phase
+    value: Failed
+`
+
+var databaseYAML = `
+apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: db
+  namespace: default
+  generation: 2
+status:
+  observedGeneration: 2
+`
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		data     string
+		expected *RuleSet
+		isError  bool
+	}{
+		"valid rules": {
+			data: rulesYAML,
+			expected: &RuleSet{
+				Rules: []Rule{
+					{
+						Group: "example.com",
+						Kind:  "Database",
+						Current: []Expression{
+							{Path: `$.status.conditions[?(@.type=="Synced")].status`, Value: "True"},
+							{Path: "$.status.endpoint"},
+						},
+						Failed: []Expression{
+							{Path: "$.status.phase", Value: "Failed"},
+						},
+					},
+				},
+			},
+		},
+		"missing kind": {
+			data: `
+rules:
+- group: example.com
+  current:
+  - path: $.status.ready
+`,
+			isError: true,
+		},
+		"missing current expressions": {
+			data: `
+rules:
+- group: example.com
+  kind: Database
+`,
+			isError: true,
+		},
+		"missing path": {
+			data: `
+rules:
+- kind: Database
+  current:
+  - value: "True"
+`,
+			isError: true,
+		},
+		"duplicate group kind": {
+			data: `
+rules:
+- kind: Database
+  current:
+  - path: $.status.ready
+- kind: Database
+  current:
+  - path: $.status.synced
+`,
+			isError: true,
+		},
+		"unknown field": {
+			data: `
+rules:
+- kind: Database
+  ready:
+  - path: $.status.ready
+`,
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			rs, err := Parse([]byte(tc.data))
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rs)
+		})
+	}
+}
+
+func TestRule_StatusFunc(t *testing.T) {
+	rs, err := Parse([]byte(rulesYAML))
+	require.NoError(t, err)
+	rule := rs.Rules[0]
+	assert.Equal(t, schema.GroupKind{Group: "example.com", Kind: "Database"}, rule.GroupKind())
+
+	testCases := map[string]struct {
+		status         map[string]interface{}
+		generation     int64
+		expectedStatus status.Status
+	}{
+		"no status is in progress": {
+			expectedStatus: status.InProgressStatus,
+		},
+		"partially matched is in progress": {
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Synced", "status": "True"},
+				},
+			},
+			expectedStatus: status.InProgressStatus,
+		},
+		"all current expressions matched is current": {
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"endpoint":           "db.example.com",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Synced", "status": "True"},
+				},
+			},
+			expectedStatus: status.CurrentStatus,
+		},
+		"failed expression matched is failed": {
+			status: map[string]interface{}{
+				"observedGeneration": int64(2),
+				"phase":              "Failed",
+			},
+			expectedStatus: status.FailedStatus,
+		},
+		"old observed generation is in progress": {
+			status: map[string]interface{}{
+				"observedGeneration": int64(1),
+				"endpoint":           "db.example.com",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Synced", "status": "True"},
+				},
+			},
+			expectedStatus: status.InProgressStatus,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			u := testutil.Unstructured(t, databaseYAML)
+			if tc.status != nil {
+				u.Object["status"] = tc.status
+			}
+			res, err := rule.StatusFunc()(u)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, res.Status)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package applyhash computes and stores the hash of the objects applied
+// by the applier, which is used to skip objects that didn't change since
+// they were last applied.
+package applyhash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/common"
+)
+
+// Compute returns a hash of the content of the object, ignoring the
+// apply hash annotation itself.
+func Compute(obj *unstructured.Unstructured) (string, error) {
+	u := obj.DeepCopy()
+	annotations := u.GetAnnotations()
+	if _, found := annotations[common.ApplyHashAnnotation]; found {
+		delete(annotations, common.ApplyHashAnnotation)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		u.SetAnnotations(annotations)
+	}
+	data, err := json.Marshal(u.Object)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Set computes the apply hash of the object and returns a copy of the
+// object with the hash stored in the apply hash annotation, and the hash.
+// The passed object is not modified.
+func Set(obj *unstructured.Unstructured) (*unstructured.Unstructured, string, error) {
+	hash, err := Compute(obj)
+	if err != nil {
+		return nil, "", err
+	}
+	obj = obj.DeepCopy()
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[common.ApplyHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+	return obj, hash, nil
+}
+
+// LiveMatches returns true if every field set in the object has the same
+// value in the live object, i.e. the fields of the object were not
+// changed on the live object since it was applied. Fields set on the live
+// object only, e.g. by defaulting or by other field managers, are not
+// compared. Null fields of the object match any value.
+func LiveMatches(obj, live *unstructured.Unstructured) bool {
+	return fieldsMatch(obj.Object, live.Object)
+}
+
+func fieldsMatch(local, live interface{}) bool {
+	switch l := local.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		m, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range l {
+			liveValue, found := m[key]
+			if !found && value != nil {
+				return false
+			}
+			if !fieldsMatch(value, liveValue) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		s, ok := live.([]interface{})
+		if !ok || len(l) != len(s) {
+			return false
+		}
+		for i := range l {
+			if !fieldsMatch(l[i], s[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(local, live)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+package applyhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/cli-utils/pkg/common"
+)
+
+func TestSet(t *testing.T) {
+	newObj := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "cm",
+					"namespace": "default",
+				},
+				"data": map[string]interface{}{
+					"key": "value",
+				},
+			},
+		}
+	}
+
+	orig := newObj()
+	obj, hash, err := Set(orig)
+	require.NoError(t, err)
+	assert.Equal(t, hash, obj.GetAnnotations()[common.ApplyHashAnnotation])
+	// The passed object is not modified.
+	assert.Equal(t, newObj(), orig)
+
+	// The hash annotation itself is ignored.
+	rehash, err := Compute(obj)
+	require.NoError(t, err)
+	assert.Equal(t, hash, rehash)
+	sameHash, err := Compute(newObj())
+	require.NoError(t, err)
+	assert.Equal(t, hash, sameHash)
+
+	changed := newObj()
+	changed.Object["data"] = map[string]interface{}{
+		"key": "other",
+	}
+	changedHash, err := Compute(changed)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changedHash)
+}
+
+func TestLiveMatches(t *testing.T) {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":              "dep",
+				"creationTimestamp": nil,
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64(1),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  "app",
+								"image": "app:1",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		mutate  func(live map[string]interface{})
+		matches bool
+	}{
+		"same fields match": {
+			mutate:  func(map[string]interface{}) {},
+			matches: true,
+		},
+		"other fields on the live object match": {
+			mutate: func(live map[string]interface{}) {
+				live["status"] = map[string]interface{}{"replicas": int64(1)}
+				container := live["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0]
+				container.(map[string]interface{})["imagePullPolicy"] = "IfNotPresent"
+			},
+			matches: true,
+		},
+		"changed field does not match": {
+			mutate: func(live map[string]interface{}) {
+				live["spec"].(map[string]interface{})["replicas"] = int64(3)
+			},
+			matches: false,
+		},
+		"changed list element does not match": {
+			mutate: func(live map[string]interface{}) {
+				container := live["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0]
+				container.(map[string]interface{})["image"] = "app:2"
+			},
+			matches: false,
+		},
+		"removed field does not match": {
+			mutate: func(live map[string]interface{}) {
+				delete(live["spec"].(map[string]interface{}), "replicas")
+			},
+			matches: false,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			live := obj.DeepCopy()
+			unstructured.RemoveNestedField(live.Object, "metadata", "creationTimestamp")
+			tc.mutate(live.Object)
+			assert.Equal(t, tc.matches, LiveMatches(obj, live))
+		})
+	}
+}
@@ -26,17 +26,12 @@ func SortObjs(objs object.UnstructuredSet) ([]object.UnstructuredSet, error) {
 		return []object.UnstructuredSet{}, nil
 	}
 	// Create the graph, and build a map of object metadata to the object (Unstructured).
-	g := New()
+	g := buildGraph(objs)
 	objToUnstructured := map[object.ObjMetadata]*unstructured.Unstructured{}
 	for _, obj := range objs {
 		id := object.UnstructuredToObjMetaOrDie(obj)
 		objToUnstructured[id] = obj
 	}
-	// Add object vertices and dependency edges to graph.
-	addApplyTimeMutationEdges(g, objs)
-	addDependsOnEdges(g, objs)
-	addNamespaceEdges(g, objs)
-	addCRDEdges(g, objs)
 	// Run topological sort on the graph.
 	objSets := []object.UnstructuredSet{}
 	sortedObjSets, err := g.Sort()
@@ -53,6 +48,11 @@ func SortObjs(objs object.UnstructuredSet) ([]object.UnstructuredSet, error) {
 				currentSet = append(currentSet, obj)
 			}
 		}
+		// External dependencies are not applied, so a set of only
+		// external dependencies is dropped.
+		if len(currentSet) == 0 {
+			continue
+		}
 		// Sort each set in apply order
 		sort.Sort(ordering.SortableUnstructureds(currentSet))
 		objSets = append(objSets, currentSet)
@@ -80,6 +80,69 @@ func ReverseSortObjs(objs object.UnstructuredSet) ([]object.UnstructuredSet, err
 	return s, nil
 }
 
+// Dependencies returns the dependencies of each of the objects, using the
+// same edges as SortObjs. Unlike SortObjs, the objects are not grouped
+// into sets, so each object can be applied as soon as the objects it
+// depends on are reconciled. Dependencies on objects that are not in the
+// passed set are not returned. Objects without dependencies are mapped
+// to an empty set. Returns a CyclicDependencyError if the dependencies
+// contain a cycle.
+func Dependencies(objs object.UnstructuredSet) (map[object.ObjMetadata]object.ObjMetadataSet, error) {
+	ids := object.ObjMetadataSet(object.UnstructuredsToObjMetasOrDie(objs))
+	deps := make(map[object.ObjMetadata]object.ObjMetadataSet, len(ids))
+	for _, id := range ids {
+		deps[id] = object.ObjMetadataSet{}
+	}
+	g := buildGraph(objs)
+	for _, id := range ids {
+		for _, dep := range g.edges[id] {
+			if ids.Contains(dep) {
+				deps[id] = append(deps[id], dep)
+			}
+		}
+	}
+	// Sorting removes the vertices from the graph, so it is done after
+	// the edges have been read.
+	if _, err := g.Sort(); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// ExternalDependencies returns the objects that the passed objects depend
+// on with the "depends-on" annotation, but that are not in the passed set.
+// These are managed by another inventory or not managed at all, so they
+// are waited on instead of applied. Objects without external dependencies
+// are not in the returned map.
+func ExternalDependencies(objs object.UnstructuredSet) map[object.ObjMetadata]object.ObjMetadataSet {
+	ids := object.ObjMetadataSet(object.UnstructuredsToObjMetasOrDie(objs))
+	external := map[object.ObjMetadata]object.ObjMetadataSet{}
+	for i, obj := range objs {
+		deps, err := dependson.ReadAnnotation(obj)
+		if err != nil {
+			klog.V(3).Infof("failed to read dependencies of: %s: %s", ids[i], err)
+			continue
+		}
+		for _, dep := range deps {
+			if !ids.Contains(dep) && !external[ids[i]].Contains(dep) {
+				external[ids[i]] = append(external[ids[i]], dep)
+			}
+		}
+	}
+	return external
+}
+
+// buildGraph returns a graph with the passed objects as vertices and
+// their dependencies as edges.
+func buildGraph(objs object.UnstructuredSet) *Graph {
+	g := New()
+	addApplyTimeMutationEdges(g, objs)
+	addDependsOnEdges(g, objs)
+	addNamespaceEdges(g, objs)
+	addCRDEdges(g, objs)
+	return g
+}
+
 // addApplyTimeMutationEdges updates the graph with edges from objects
 // with an explicit "apply-time-mutation" annotation.
 func addApplyTimeMutationEdges(g *Graph, objs object.UnstructuredSet) {
@@ -248,6 +248,19 @@ func TestSortObjs(t *testing.T) {
 			expected: []object.UnstructuredSet{},
 			isError:  true,
 		},
+		"external dependency is not in the object sets": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+			},
+			expected: []object.UnstructuredSet{
+				{
+					testutil.Unstructured(t, resources["deployment"],
+						testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+				},
+			},
+			isError: false,
+		},
 		"three objects in cyclic dependency": {
 			objs: []*unstructured.Unstructured{
 				testutil.Unstructured(t, resources["deployment"],
@@ -369,6 +382,133 @@ func TestReverseSortObjs(t *testing.T) {
 	}
 }
 
+func TestDependencies(t *testing.T) {
+	testCases := map[string]struct {
+		objs     []*unstructured.Unstructured
+		expected map[object.ObjMetadata]object.ObjMetadataSet
+		isError  bool
+	}{
+		"no objects returns no dependencies": {
+			objs:     []*unstructured.Unstructured{},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{},
+		},
+		"unrelated objects have no dependencies": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"]),
+				testutil.Unstructured(t, resources["default-pod"]),
+			},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{
+				testutil.ToIdentifier(t, resources["deployment"]):  {},
+				testutil.ToIdentifier(t, resources["default-pod"]): {},
+			},
+		},
+		"depends-on, namespace and CRD dependencies": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+				testutil.Unstructured(t, resources["secret"]),
+				testutil.Unstructured(t, resources["namespace"]),
+				testutil.Unstructured(t, resources["crd"]),
+				testutil.Unstructured(t, resources["crontab1"]),
+			},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{
+				testutil.ToIdentifier(t, resources["deployment"]): {
+					testutil.ToIdentifier(t, resources["secret"]),
+					testutil.ToIdentifier(t, resources["namespace"]),
+				},
+				testutil.ToIdentifier(t, resources["secret"]): {
+					testutil.ToIdentifier(t, resources["namespace"]),
+				},
+				testutil.ToIdentifier(t, resources["namespace"]): {},
+				testutil.ToIdentifier(t, resources["crd"]):       {},
+				testutil.ToIdentifier(t, resources["crontab1"]): {
+					testutil.ToIdentifier(t, resources["namespace"]),
+					testutil.ToIdentifier(t, resources["crd"]),
+				},
+			},
+		},
+		"dependencies on objects not in the set are ignored": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+			},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{
+				testutil.ToIdentifier(t, resources["deployment"]): {},
+			},
+		},
+		"cyclic dependency returns an error": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+				testutil.Unstructured(t, resources["secret"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["deployment"]))),
+			},
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := Dependencies(tc.objs)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(tc.expected), len(actual))
+			for id, deps := range tc.expected {
+				assert.ElementsMatch(t, deps, actual[id], "dependencies of %s", id)
+			}
+		})
+	}
+}
+
+func TestExternalDependencies(t *testing.T) {
+	testCases := map[string]struct {
+		objs     []*unstructured.Unstructured
+		expected map[object.ObjMetadata]object.ObjMetadataSet
+	}{
+		"no objects returns no external dependencies": {
+			objs:     []*unstructured.Unstructured{},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{},
+		},
+		"dependencies in the set are not external": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+				testutil.Unstructured(t, resources["secret"]),
+			},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{},
+		},
+		"dependencies not in the set are external": {
+			objs: []*unstructured.Unstructured{
+				testutil.Unstructured(t, resources["deployment"],
+					testutil.AddDependsOn(t,
+						testutil.ToIdentifier(t, resources["secret"]),
+						testutil.ToIdentifier(t, resources["default-pod"]))),
+				testutil.Unstructured(t, resources["default-pod"]),
+				testutil.Unstructured(t, resources["pod"],
+					testutil.AddDependsOn(t, testutil.ToIdentifier(t, resources["secret"]))),
+			},
+			expected: map[object.ObjMetadata]object.ObjMetadataSet{
+				testutil.ToIdentifier(t, resources["deployment"]): {
+					testutil.ToIdentifier(t, resources["secret"]),
+				},
+				testutil.ToIdentifier(t, resources["pod"]): {
+					testutil.ToIdentifier(t, resources["secret"]),
+				},
+			},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			actual := ExternalDependencies(tc.objs)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
 func TestApplyTimeMutationEdges(t *testing.T) {
 	testCases := map[string]struct {
 		objs     []*unstructured.Unstructured
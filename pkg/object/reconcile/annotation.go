@@ -0,0 +1,86 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+//
+
+// Package reconcile reads the annotations that control how the applier
+// waits for an object to be reconciled.
+package reconcile
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// TimeoutAnnotation overrides the reconcile timeout of the applier
+	// for a single object. The value is a duration, e.g. "20m".
+	TimeoutAnnotation = "config.kubernetes.io/reconcile-timeout"
+
+	// WaitForAnnotation defines what the applier waits for after the
+	// object has been applied. See WaitFor for the supported values.
+	WaitForAnnotation = "config.kubernetes.io/wait-for"
+)
+
+// WaitFor is the value of the wait-for annotation.
+type WaitFor string
+
+const (
+	// WaitForCurrent waits for the object to reach the Current status.
+	// This is the default.
+	WaitForCurrent WaitFor = "current"
+
+	// WaitForNone doesn't wait for the object at all.
+	WaitForNone WaitFor = "none"
+
+	// WaitForDeleted waits for the object to be deleted from the
+	// cluster, e.g. by a controller or by its own TTL.
+	WaitForDeleted WaitFor = "deleted"
+)
+
+// ReadTimeoutAnnotation reads the reconcile-timeout annotation. The
+// second return value is false if the annotation is not present.
+func ReadTimeoutAnnotation(u *unstructured.Unstructured) (time.Duration, bool, error) {
+	if u == nil {
+		return 0, false, nil
+	}
+	timeoutStr, found := u.GetAnnotations()[TimeoutAnnotation]
+	if !found {
+		return 0, false, nil
+	}
+	klog.V(5).Infof("reconcile-timeout annotation found for %s/%s: %q",
+		u.GetNamespace(), u.GetName(), timeoutStr)
+
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse %s annotation: %w", TimeoutAnnotation, err)
+	}
+	if timeout < 0 {
+		return 0, false, fmt.Errorf("invalid %s annotation: negative timeout %q", TimeoutAnnotation, timeoutStr)
+	}
+	return timeout, true, nil
+}
+
+// ReadWaitForAnnotation reads the wait-for annotation. Returns
+// WaitForCurrent if the annotation is not present.
+func ReadWaitForAnnotation(u *unstructured.Unstructured) (WaitFor, error) {
+	if u == nil {
+		return WaitForCurrent, nil
+	}
+	waitForStr, found := u.GetAnnotations()[WaitForAnnotation]
+	if !found {
+		return WaitForCurrent, nil
+	}
+	klog.V(5).Infof("wait-for annotation found for %s/%s: %q",
+		u.GetNamespace(), u.GetName(), waitForStr)
+
+	switch waitFor := WaitFor(waitForStr); waitFor {
+	case WaitForCurrent, WaitForNone, WaitForDeleted:
+		return waitFor, nil
+	default:
+		return "", fmt.Errorf("invalid %s annotation: %q (must be one of %q, %q or %q)",
+			WaitForAnnotation, waitForStr, WaitForCurrent, WaitForNone, WaitForDeleted)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The Kubernetes Authors.
+// SPDX-License-Identifier: Apache-2.0
+//
+
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func withAnnotations(annotations map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      "unused",
+				"namespace": "unused",
+			},
+		},
+	}
+	if annotations != nil {
+		u.Object["metadata"].(map[string]interface{})["annotations"] = annotations
+	}
+	return u
+}
+
+func TestReadTimeoutAnnotation(t *testing.T) {
+	testCases := map[string]struct {
+		obj           *unstructured.Unstructured
+		expected      time.Duration
+		expectedFound bool
+		isError       bool
+	}{
+		"nil object is not found": {
+			obj: nil,
+		},
+		"object with no annotations is not found": {
+			obj: withAnnotations(nil),
+		},
+		"valid timeout": {
+			obj: withAnnotations(map[string]interface{}{
+				TimeoutAnnotation: "20m",
+			}),
+			expected:      20 * time.Minute,
+			expectedFound: true,
+		},
+		"unparseable timeout": {
+			obj: withAnnotations(map[string]interface{}{
+				TimeoutAnnotation: "twenty minutes",
+			}),
+			isError: true,
+		},
+		"negative timeout": {
+			obj: withAnnotations(map[string]interface{}{
+				TimeoutAnnotation: "-1s",
+			}),
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			actual, found, err := ReadTimeoutAnnotation(tc.obj)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReadWaitForAnnotation(t *testing.T) {
+	testCases := map[string]struct {
+		obj      *unstructured.Unstructured
+		expected WaitFor
+		isError  bool
+	}{
+		"nil object defaults to current": {
+			obj:      nil,
+			expected: WaitForCurrent,
+		},
+		"object with no annotations defaults to current": {
+			obj:      withAnnotations(nil),
+			expected: WaitForCurrent,
+		},
+		"current": {
+			obj: withAnnotations(map[string]interface{}{
+				WaitForAnnotation: "current",
+			}),
+			expected: WaitForCurrent,
+		},
+		"none": {
+			obj: withAnnotations(map[string]interface{}{
+				WaitForAnnotation: "none",
+			}),
+			expected: WaitForNone,
+		},
+		"deleted": {
+			obj: withAnnotations(map[string]interface{}{
+				WaitForAnnotation: "deleted",
+			}),
+			expected: WaitForDeleted,
+		},
+		"unknown value": {
+			obj: withAnnotations(map[string]interface{}{
+				WaitForAnnotation: "ready",
+			}),
+			isError: true,
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := ReadWaitForAnnotation(tc.obj)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
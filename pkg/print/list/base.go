@@ -4,7 +4,9 @@
 package list
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
@@ -17,6 +19,7 @@ type Formatter interface {
 	FormatPruneEvent(pe event.PruneEvent) error
 	FormatDeleteEvent(de event.DeleteEvent) error
 	FormatWaitEvent(we event.WaitEvent) error
+	FormatRetryEvent(re event.RetryEvent) error
 	FormatErrorEvent(ee event.ErrorEvent) error
 	FormatActionGroupEvent(
 		age event.ActionGroupEvent,
@@ -40,6 +43,8 @@ type ApplyStats struct {
 	Created           int
 	Unchanged         int
 	Configured        int
+	Replaced          int
+	Skipped           int
 	Failed            int
 }
 
@@ -54,6 +59,10 @@ func (a *ApplyStats) inc(op event.ApplyEventOperation) {
 		a.Unchanged++
 	case event.Configured:
 		a.Configured++
+	case event.Replaced:
+		a.Replaced++
+	case event.ApplySkipped:
+		a.Skipped++
 	default:
 		panic(fmt.Errorf("unknown apply operation %s", op.String()))
 	}
@@ -64,7 +73,7 @@ func (a *ApplyStats) incFailed() {
 }
 
 func (a *ApplyStats) Sum() int {
-	return a.ServersideApplied + a.Configured + a.Unchanged + a.Created + a.Failed
+	return a.ServersideApplied + a.Configured + a.Unchanged + a.Created + a.Replaced + a.Skipped + a.Failed
 }
 
 type PruneStats struct {
@@ -107,6 +116,7 @@ type WaitStats struct {
 	Reconciled int
 	Timeout    int
 	Skipped    int
+	Failed     int
 }
 
 func (w *WaitStats) incReconciled() {
@@ -121,6 +131,10 @@ func (w *WaitStats) incSkipped() {
 	w.Skipped++
 }
 
+func (w *WaitStats) incFailed() {
+	w.Failed++
+}
+
 type Collector interface {
 	LatestStatus() map[object.ObjMetadata]event.StatusEvent
 }
@@ -208,10 +222,16 @@ func (b *BaseListPrinter) Print(ch <-chan event.Event, previewStrategy common.Dr
 				waitStats.incSkipped()
 			case event.ReconcileTimeout:
 				waitStats.incTimeout()
+			case event.ReconcileFailed:
+				waitStats.incFailed()
 			}
 			if err := formatter.FormatWaitEvent(e.WaitEvent); err != nil {
 				return err
 			}
+		case event.RetryType:
+			if err := formatter.FormatRetryEvent(e.RetryEvent); err != nil {
+				return err
+			}
 		case event.ActionGroupType:
 			if err := formatter.FormatActionGroupEvent(
 				e.ActionGroupEvent,
@@ -227,18 +247,21 @@ func (b *BaseListPrinter) Print(ch <-chan event.Event, previewStrategy common.Dr
 		}
 	}
 	failedSum := applyStats.Failed + pruneStats.Failed + deleteStats.Failed
-	switch {
-	case failedSum > 0 && waitStats.Timeout > 0:
-		return fmt.Errorf("%d resources failed, %d resources failed to reconcile before timeout",
-			failedSum, waitStats.Timeout)
-	case failedSum > 0:
-		return fmt.Errorf("%d resources failed", failedSum)
-	case waitStats.Timeout > 0:
-		return fmt.Errorf("%d resources failed to reconcile before timeout",
-			waitStats.Timeout)
-	default:
-		return nil
+	var msgs []string
+	if failedSum > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d resources failed", failedSum))
+	}
+	if waitStats.Failed > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d resources failed to reconcile", waitStats.Failed))
+	}
+	if waitStats.Timeout > 0 {
+		msgs = append(msgs, fmt.Sprintf("%d resources failed to reconcile before timeout",
+			waitStats.Timeout))
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, ", "))
 	}
+	return nil
 }
 
 func ActionGroupByName(name string, ags []event.ActionGroup) (event.ActionGroup, bool) {
@@ -4,12 +4,14 @@
 package events
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -30,10 +32,20 @@ type formatter struct {
 func (ef *formatter) FormatApplyEvent(ae event.ApplyEvent) error {
 	gk := ae.Identifier.GroupKind
 	name := ae.Identifier.Name
-	if ae.Error != nil {
+	var conflictErr *applyerror.ApplyConflictError
+	switch {
+	case errors.As(ae.Error, &conflictErr):
+		ef.print("%s apply failed: conflicts with other field managers: %s", resourceIDToString(gk, name),
+			conflictErr.Summary())
+	case ae.Error != nil:
 		ef.print("%s apply failed: %s", resourceIDToString(gk, name),
 			ae.Error.Error())
-	} else {
+	case ae.Operation == event.ApplySkipped:
+		ef.print("%s apply skipped", resourceIDToString(gk, name))
+	case ae.Migration != nil:
+		ef.print("%s %s (migrated from client-side apply: %s)", resourceIDToString(gk, name),
+			strings.ToLower(ae.Operation.String()), migrationToString(ae.Migration))
+	default:
 		ef.print("%s %s", resourceIDToString(gk, name),
 			strings.ToLower(ae.Operation.String()))
 	}
@@ -95,10 +107,32 @@ func (ef *formatter) FormatWaitEvent(we event.WaitEvent) error {
 		ef.print("%s reconcile skipped", resourceIDToString(gk, name))
 	case event.ReconcileTimeout:
 		ef.print("%s reconcile timeout", resourceIDToString(gk, name))
+	case event.ReconcileFailed:
+		ef.print("%s reconcile failed", resourceIDToString(gk, name))
 	}
 	return nil
 }
 
+func (ef *formatter) FormatRetryEvent(re event.RetryEvent) error {
+	gk := re.Identifier.GroupKind
+	name := re.Identifier.Name
+
+	var operation string
+	switch re.Action {
+	case event.ApplyAction:
+		operation = "apply"
+	case event.PruneAction:
+		operation = "prune"
+	case event.DeleteAction:
+		operation = "deletion"
+	default:
+		operation = strings.ToLower(re.Action.String())
+	}
+	ef.print("%s %s failed, retrying (%d/%d): %s", resourceIDToString(gk, name),
+		operation, re.Attempt, re.MaxAttempts, re.Error.Error())
+	return nil
+}
+
 func (ef *formatter) FormatErrorEvent(_ event.ErrorEvent) error {
 	return nil
 }
@@ -115,13 +149,18 @@ func (ef *formatter) FormatActionGroupEvent(
 	if age.Action == event.ApplyAction &&
 		age.Type == event.Finished &&
 		list.IsLastActionGroup(age, ags) {
-		output := fmt.Sprintf("%d resource(s) applied. %d created, %d unchanged, %d configured, %d failed",
-			as.Sum(), as.Created, as.Unchanged, as.Configured, as.Failed)
+		output := fmt.Sprintf("%d resource(s) applied. %d created, %d unchanged, %d configured, %d replaced, %d failed",
+			as.Sum(), as.Created, as.Unchanged, as.Configured, as.Replaced, as.Failed)
 		// Only print information about serverside apply if some of the
 		// resources actually were applied serverside.
 		if as.ServersideApplied > 0 {
 			output += fmt.Sprintf(", %d serverside applied", as.ServersideApplied)
 		}
+		// Only print information about skipped resources if the apply
+		// was interrupted.
+		if as.Skipped > 0 {
+			output += fmt.Sprintf(", %d skipped", as.Skipped)
+		}
 		ef.print(output)
 	}
 
@@ -154,7 +193,13 @@ func (ef *formatter) FormatActionGroupEvent(
 	if age.Action == event.WaitAction &&
 		age.Type == event.Finished &&
 		list.IsLastActionGroup(age, ags) {
-		ef.print("%d resource(s) reconciled, %d skipped", ws.Reconciled, ds.Skipped)
+		output := fmt.Sprintf("%d resource(s) reconciled, %d skipped", ws.Reconciled, ds.Skipped)
+		// Only print information about failed resources if waiting
+		// ended early because of them.
+		if ws.Failed > 0 {
+			output += fmt.Sprintf(", %d failed to reconcile", ws.Failed)
+		}
+		ef.print(output)
 	}
 	return nil
 }
@@ -181,3 +226,16 @@ func getPrintFunc(w io.Writer, previewStrategy common.DryRunStrategy) printFunc
 		fmt.Fprintf(w, format+"\n", a...)
 	}
 }
+
+// migrationToString returns a description of what was migrated from
+// client-side apply.
+func migrationToString(m *event.ClientSideMigration) string {
+	var parts []string
+	if len(m.FieldManagers) > 0 {
+		parts = append(parts, "field managers "+strings.Join(m.FieldManagers, ", "))
+	}
+	if m.LastAppliedRemoved {
+		parts = append(parts, "last-applied-configuration removed")
+	}
+	return strings.Join(parts, "; ")
+}
@@ -12,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	pollevent "sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
@@ -60,6 +61,30 @@ func TestFormatter_FormatApplyEvent(t *testing.T) {
 			},
 			expected: "deployment.apps/my-dep apply failed: this is a test error (preview-server)",
 		},
+		"apply event with conflict error should display the conflicts": {
+			previewStrategy: common.DryRunNone,
+			event: event.ApplyEvent{
+				Identifier: createIdentifier("apps", "Deployment", "", "my-dep"),
+				Error: applyerror.NewApplyConflictError(fmt.Errorf("this is a test error"), []applyerror.FieldConflict{
+					{Manager: "kube-controller-manager", Field: ".spec.replicas"},
+				}),
+			},
+			expected: "deployment.apps/my-dep apply failed: conflicts with other field managers: " +
+				".spec.replicas (kube-controller-manager)",
+		},
+		"apply event with migration should display what was migrated": {
+			previewStrategy: common.DryRunNone,
+			event: event.ApplyEvent{
+				Operation:  event.ServersideApplied,
+				Identifier: createIdentifier("apps", "Deployment", "", "my-dep"),
+				Migration: &event.ClientSideMigration{
+					FieldManagers:      []string{"kubectl", "kubectl-client-side-apply"},
+					LastAppliedRemoved: true,
+				},
+			},
+			expected: "deployment.apps/my-dep serversideapplied (migrated from client-side apply: " +
+				"field managers kubectl, kubectl-client-side-apply; last-applied-configuration removed)",
+		},
 	}
 
 	for tn, tc := range testCases {
@@ -277,6 +302,15 @@ func TestFormatter_FormatWaitEvent(t *testing.T) {
 			},
 			expected: "deployment.apps/my-dep reconcile timeout (preview-server)",
 		},
+		"resource reconcile failed": {
+			previewStrategy: common.DryRunNone,
+			event: event.WaitEvent{
+				GroupName:  "wait-1",
+				Identifier: createIdentifier("apps", "Deployment", "default", "my-dep"),
+				Operation:  event.ReconcileFailed,
+			},
+			expected: "deployment.apps/my-dep reconcile failed",
+		},
 		"resource reconcile skipped": {
 			previewStrategy: common.DryRunNone,
 			event: event.WaitEvent{
@@ -318,6 +352,62 @@ func TestFormatter_FormatWaitEvent(t *testing.T) {
 	}
 }
 
+func TestFormatter_FormatRetryEvent(t *testing.T) {
+	testCases := map[string]struct {
+		previewStrategy common.DryRunStrategy
+		event           event.RetryEvent
+		expected        string
+	}{
+		"apply retried": {
+			previewStrategy: common.DryRunNone,
+			event: event.RetryEvent{
+				GroupName:   "apply-1",
+				Action:      event.ApplyAction,
+				Identifier:  createIdentifier("apps", "Deployment", "default", "my-dep"),
+				Attempt:     2,
+				MaxAttempts: 5,
+				Error:       fmt.Errorf("etcdserver: leader changed"),
+			},
+			expected: "deployment.apps/my-dep apply failed, retrying (2/5): etcdserver: leader changed",
+		},
+		"prune retried": {
+			previewStrategy: common.DryRunNone,
+			event: event.RetryEvent{
+				GroupName:   "prune-1",
+				Action:      event.PruneAction,
+				Identifier:  createIdentifier("apps", "Deployment", "default", "my-dep"),
+				Attempt:     3,
+				MaxAttempts: 3,
+				Error:       fmt.Errorf("conflict"),
+			},
+			expected: "deployment.apps/my-dep prune failed, retrying (3/3): conflict",
+		},
+		"delete retried": {
+			previewStrategy: common.DryRunNone,
+			event: event.RetryEvent{
+				GroupName:   "delete-1",
+				Action:      event.DeleteAction,
+				Identifier:  createIdentifier("apps", "Deployment", "default", "my-dep"),
+				Attempt:     2,
+				MaxAttempts: 3,
+				Error:       fmt.Errorf("conflict"),
+			},
+			expected: "deployment.apps/my-dep deletion failed, retrying (2/3): conflict",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ioStreams, _, out, _ := genericclioptions.NewTestIOStreams() //nolint:dogsled
+			formatter := NewFormatter(ioStreams, tc.previewStrategy)
+			err := formatter.FormatRetryEvent(tc.event)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expected, strings.TrimSpace(out.String()))
+		})
+	}
+}
+
 func createObject(group, kind, namespace, name string) *unstructured.Unstructured {
 	return &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -19,16 +19,23 @@
 // pertains to a particular resource, the fields group, kind, name and namespace
 // will always be present.
 //
-// Events of type apply can have two different values for eventType, each which comes
+// Events of type apply can have three different values for eventType, each which comes
 // with a specific set of fields:
 //  * resourceApplied: A resource has been applied to the cluster.
 //    * fields identifying the resource.
 //    * operation: The operation that was performed on the resource. Must be one of
-//      created, configured, unchanged and serversideApplied.
+//      created, configured, unchanged, serversideApplied and replaced.
+//  * resourceRetrying: Applying a resource failed with a transient error and
+//    will be retried.
+//    * fields identifying the resource.
+//    * attempt: The number of the next attempt.
+//    * maxAttempts: The maximum number of attempts.
+//    * error: The error message of the failed attempt.
 //  * completed: All resources have been applied.
 //    * count: Total number of resources applied
 //    * createdCount: Number of resources created.
 //    * configuredCount: Number of resources configured.
+//    * replacedCount: Number of resources deleted and created again.
 //    * unchangedCount: Number of resources unchanged.
 //    * serversideAppliedCount: Number of resources applied serverside.
 //
@@ -45,7 +52,8 @@
 //    * error: The error message.
 //
 // Events of type prune can have two different values for eventType, each which comes
-// with a specific set of fields:
+// with a specific set of fields. Like apply, prune and delete events can also have
+// the eventType resourceRetrying, with the same fields:
 //  * resourcePruned: A resource has been pruned or was intended to be pruned but has been
 //    skipped due to the presence of a lifecycle directive.
 //    * fields identifying the resource.
@@ -5,10 +5,12 @@ package json
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/object"
@@ -32,9 +34,26 @@ func (jf *formatter) FormatApplyEvent(ae event.ApplyEvent) error {
 	eventInfo := jf.baseResourceEvent(ae.Identifier)
 	if ae.Error != nil {
 		eventInfo["error"] = ae.Error.Error()
+		var conflictErr *applyerror.ApplyConflictError
+		if errors.As(ae.Error, &conflictErr) {
+			conflicts := make([]map[string]interface{}, 0, len(conflictErr.Conflicts))
+			for _, c := range conflictErr.Conflicts {
+				conflicts = append(conflicts, map[string]interface{}{
+					"field":   c.Field,
+					"manager": c.Manager,
+				})
+			}
+			eventInfo["conflicts"] = conflicts
+		}
 		return jf.printEvent("apply", "resourceFailed", eventInfo)
 	}
 	eventInfo["operation"] = ae.Operation.String()
+	if ae.Migration != nil {
+		eventInfo["migration"] = map[string]interface{}{
+			"fieldManagers":      ae.Migration.FieldManagers,
+			"lastAppliedRemoved": ae.Migration.LastAppliedRemoved,
+		}
+	}
 	return jf.printEvent("apply", "resourceApplied", eventInfo)
 }
 
@@ -75,6 +94,23 @@ func (jf *formatter) FormatWaitEvent(we event.WaitEvent) error {
 	return jf.printEvent("wait", "resourceReconciled", eventInfo)
 }
 
+func (jf *formatter) FormatRetryEvent(re event.RetryEvent) error {
+	eventInfo := jf.baseResourceEvent(re.Identifier)
+	eventInfo["attempt"] = re.Attempt
+	eventInfo["maxAttempts"] = re.MaxAttempts
+	eventInfo["error"] = re.Error.Error()
+	var eventType string
+	switch re.Action {
+	case event.PruneAction:
+		eventType = "prune"
+	case event.DeleteAction:
+		eventType = "delete"
+	default:
+		eventType = "apply"
+	}
+	return jf.printEvent(eventType, "resourceRetrying", eventInfo)
+}
+
 func (jf *formatter) FormatErrorEvent(ee event.ErrorEvent) error {
 	return jf.printEvent("error", "error", map[string]interface{}{
 		"error": ee.Err.Error(),
@@ -96,7 +132,9 @@ func (jf *formatter) FormatActionGroupEvent(
 			"createdCount":    as.Created,
 			"unchangedCount":  as.Unchanged,
 			"configuredCount": as.Configured,
+			"replacedCount":   as.Replaced,
 			"serverSideCount": as.ServersideApplied,
+			"skippedCount":    as.Skipped,
 			"failedCount":     as.Failed,
 		}); err != nil {
 			return err
@@ -138,6 +176,7 @@ func (jf *formatter) FormatActionGroupEvent(
 			"reconciled": ws.Reconciled,
 			"skipped":    ws.Skipped,
 			"timeout":    ws.Timeout,
+			"failed":     ws.Failed,
 		})
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	pollevent "sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
@@ -102,6 +103,60 @@ func TestFormatter_FormatApplyEvent(t *testing.T) {
 				},
 			},
 		},
+		"resource apply conflict error": {
+			previewStrategy: common.DryRunNone,
+			event: event.ApplyEvent{
+				Identifier: createIdentifier("apps", "Deployment", "", "my-dep"),
+				Error: applyerror.NewApplyConflictError(errors.New("example error"), []applyerror.FieldConflict{
+					{Manager: "kube-controller-manager", Field: ".spec.replicas"},
+				}),
+			},
+			expected: []map[string]interface{}{
+				{
+					"eventType": "resourceFailed",
+					"group":     "apps",
+					"kind":      "Deployment",
+					"name":      "my-dep",
+					"namespace": "",
+					"timestamp": "",
+					"type":      "apply",
+					"error":     "example error",
+					"conflicts": []interface{}{
+						map[string]interface{}{
+							"field":   ".spec.replicas",
+							"manager": "kube-controller-manager",
+						},
+					},
+				},
+			},
+		},
+		"resource migrated from client-side apply": {
+			previewStrategy: common.DryRunNone,
+			event: event.ApplyEvent{
+				Operation:  event.ServersideApplied,
+				Identifier: createIdentifier("apps", "Deployment", "", "my-dep"),
+				Migration: &event.ClientSideMigration{
+					FieldManagers:      []string{"kubectl-client-side-apply"},
+					LastAppliedRemoved: true,
+				},
+			},
+			expected: []map[string]interface{}{
+				{
+					"eventType": "resourceApplied",
+					"group":     "apps",
+					"kind":      "Deployment",
+					"name":      "my-dep",
+					"namespace": "",
+					"operation": "ServersideApplied",
+					"timestamp": "",
+					"type":      "apply",
+					"migration": map[string]interface{}{
+						"fieldManagers":      []interface{}{"kubectl-client-side-apply"},
+						"lastAppliedRemoved": true,
+					},
+				},
+			},
+		},
 	}
 
 	for tn, tc := range testCases {
@@ -453,6 +508,72 @@ func TestFormatter_FormatWaitEvent(t *testing.T) {
 	}
 }
 
+func TestFormatter_FormatRetryEvent(t *testing.T) {
+	testCases := map[string]struct {
+		previewStrategy common.DryRunStrategy
+		event           event.RetryEvent
+		expected        map[string]interface{}
+	}{
+		"apply retried": {
+			previewStrategy: common.DryRunNone,
+			event: event.RetryEvent{
+				GroupName:   "apply-1",
+				Action:      event.ApplyAction,
+				Identifier:  createIdentifier("apps", "Deployment", "default", "my-dep"),
+				Attempt:     2,
+				MaxAttempts: 5,
+				Error:       errors.New("etcdserver: leader changed"),
+			},
+			expected: map[string]interface{}{
+				"attempt":     2,
+				"error":       "etcdserver: leader changed",
+				"eventType":   "resourceRetrying",
+				"group":       "apps",
+				"kind":        "Deployment",
+				"maxAttempts": 5,
+				"name":        "my-dep",
+				"namespace":   "default",
+				"timestamp":   "",
+				"type":        "apply",
+			},
+		},
+		"delete retried": {
+			previewStrategy: common.DryRunNone,
+			event: event.RetryEvent{
+				GroupName:   "delete-1",
+				Action:      event.DeleteAction,
+				Identifier:  createIdentifier("apps", "Deployment", "default", "my-dep"),
+				Attempt:     3,
+				MaxAttempts: 3,
+				Error:       errors.New("conflict"),
+			},
+			expected: map[string]interface{}{
+				"attempt":     3,
+				"error":       "conflict",
+				"eventType":   "resourceRetrying",
+				"group":       "apps",
+				"kind":        "Deployment",
+				"maxAttempts": 3,
+				"name":        "my-dep",
+				"namespace":   "default",
+				"timestamp":   "",
+				"type":        "delete",
+			},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			ioStreams, _, out, _ := genericclioptions.NewTestIOStreams() //nolint:dogsled
+			formatter := NewFormatter(ioStreams, tc.previewStrategy)
+			err := formatter.FormatRetryEvent(tc.event)
+			assert.NoError(t, err)
+
+			assertOutput(t, tc.expected, out.String())
+		})
+	}
+}
+
 // nolint:unparam
 func assertOutput(t *testing.T, expectedMap map[string]interface{}, actual string) bool {
 	var m map[string]interface{}
@@ -72,6 +72,10 @@ type ResourceInfo struct {
 	// a resource has been applied to the cluster.
 	ApplyOpResult event.ApplyEventOperation
 
+	// ApplyError contains the error if the resource
+	// failed to be applied to the cluster.
+	ApplyError error
+
 	// PruneOpResult contains the result after
 	// a prune operation on a resource
 	PruneOpResult event.PruneEventOperation
@@ -83,6 +87,15 @@ type ResourceInfo struct {
 	// WaitOpResult contains the result after
 	// a wait operation on a resource
 	WaitOpResult event.WaitEventOperation
+
+	// Waited is true if a wait event was received for the
+	// resource, so WaitOpResult is set. Resources applied with
+	// DAG scheduling are waited on by their apply group.
+	Waited bool
+
+	// Retry contains the latest retry of an operation
+	// on the resource, until the operation completes.
+	Retry *event.RetryEvent
 }
 
 // Identifier returns the identifier for the given resource.
@@ -179,6 +192,8 @@ func (r *ResourceStateCollector) processEvent(ev event.Event) error {
 		r.processPruneEvent(ev.PruneEvent)
 	case event.WaitType:
 		r.processWaitEvent(ev.WaitEvent)
+	case event.RetryType:
+		r.processRetryEvent(ev.RetryEvent)
 	case event.ErrorType:
 		return ev.ErrorEvent.Err
 	}
@@ -207,6 +222,8 @@ func (r *ResourceStateCollector) processApplyEvent(e event.ApplyEvent) {
 		return
 	}
 	previous.ApplyOpResult = e.Operation
+	previous.ApplyError = e.Error
+	previous.Retry = nil
 }
 
 // processPruneEvent handles event related to prune operations.
@@ -219,6 +236,7 @@ func (r *ResourceStateCollector) processPruneEvent(e event.PruneEvent) {
 		return
 	}
 	previous.PruneOpResult = e.Operation
+	previous.Retry = nil
 }
 
 // processPruneEvent handles event related to prune operations.
@@ -231,6 +249,20 @@ func (r *ResourceStateCollector) processWaitEvent(e event.WaitEvent) {
 		return
 	}
 	previous.WaitOpResult = e.Operation
+	previous.Waited = true
+}
+
+// processRetryEvent handles events related to retried operations.
+func (r *ResourceStateCollector) processRetryEvent(e event.RetryEvent) {
+	identifier := e.Identifier
+	klog.V(7).Infof("processing retry event for %s", identifier)
+	previous, found := r.resourceInfos[identifier]
+	if !found {
+		klog.V(4).Infof("%s retry event not found in ResourceInfos; no processing", identifier)
+		return
+	}
+	retry := e
+	previous.Retry = &retry
 }
 
 // ResourceState contains the latest state for all the resources.
@@ -267,9 +299,12 @@ func (r *ResourceStateCollector) LatestState() *ResourceState {
 			resourceStatus: ri.resourceStatus,
 			ResourceAction: ri.ResourceAction,
 			ApplyOpResult:  ri.ApplyOpResult,
+			ApplyError:     ri.ApplyError,
 			PruneOpResult:  ri.PruneOpResult,
 			DeleteOpResult: ri.DeleteOpResult,
 			WaitOpResult:   ri.WaitOpResult,
+			Waited:         ri.Waited,
+			Retry:          ri.Retry,
 		})
 	}
 	sort.Sort(resourceInfos)
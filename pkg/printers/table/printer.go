@@ -4,11 +4,13 @@
 package table
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"time"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
 	"sigs.k8s.io/cli-utils/pkg/common"
 	"sigs.k8s.io/cli-utils/pkg/print/table"
@@ -89,7 +91,9 @@ var (
 			var text string
 			switch resInfo.ResourceAction {
 			case event.ApplyAction:
-				if resInfo.ApplyOpResult != event.ApplyUnspecified {
+				if resInfo.ApplyError != nil {
+					text = "Failed"
+				} else if resInfo.ApplyOpResult != event.ApplyUnspecified {
 					text = resInfo.ApplyOpResult.String()
 				}
 			case event.PruneAction:
@@ -124,8 +128,7 @@ var (
 			}
 
 			var text string
-			switch resInfo.ResourceAction {
-			case event.WaitAction:
+			if resInfo.ResourceAction == event.WaitAction || resInfo.Waited {
 				text = resInfo.WaitOpResult.String()
 			}
 
@@ -137,6 +140,40 @@ var (
 		},
 	}
 
+	messageColumnDef = table.ColumnDef{
+		// Column containing the apply error if the resource failed to
+		// be applied, with the conflicting fields for server-side apply
+		// conflicts, or the attempt if the operation is retried.
+		// Otherwise the message from the resource status.
+		ColumnName:   "message",
+		ColumnHeader: "MESSAGE",
+		ColumnWidth:  40,
+		PrintResourceFunc: func(w io.Writer, width int, r table.Resource) (int,
+			error) {
+			resInfo, ok := r.(*ResourceInfo)
+			if !ok || (resInfo.ApplyError == nil && resInfo.Retry == nil) {
+				return table.MustColumn("message").PrintResource(w, width, r)
+			}
+
+			var text string
+			var conflictErr *applyerror.ApplyConflictError
+			switch {
+			case errors.As(resInfo.ApplyError, &conflictErr):
+				text = "conflicts: " + conflictErr.Summary()
+			case resInfo.ApplyError != nil:
+				text = resInfo.ApplyError.Error()
+			default:
+				text = fmt.Sprintf("retrying (%d/%d): %s", resInfo.Retry.Attempt,
+					resInfo.Retry.MaxAttempts, resInfo.Retry.Error)
+			}
+			if len(text) > width {
+				text = text[:width]
+			}
+			_, err := fmt.Fprint(w, text)
+			return len(text), err
+		},
+	}
+
 	columns = []table.ColumnDefinition{
 		table.MustColumn("namespace"),
 		table.MustColumn("resource"),
@@ -145,7 +182,7 @@ var (
 		reconciledColumnDef,
 		table.MustColumn("conditions"),
 		table.MustColumn("age"),
-		table.MustColumn("message"),
+		messageColumnDef,
 	}
 )
 
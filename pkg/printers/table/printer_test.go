@@ -5,9 +5,12 @@ package table
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
+	applyerror "sigs.k8s.io/cli-utils/pkg/apply/error"
 	"sigs.k8s.io/cli-utils/pkg/apply/event"
+	pe "sigs.k8s.io/cli-utils/pkg/kstatus/polling/event"
 	"sigs.k8s.io/cli-utils/pkg/print/table"
 )
 
@@ -40,6 +43,14 @@ func TestActionColumnDef(t *testing.T) {
 			columnWidth:    15,
 			expectedOutput: "Created",
 		},
+		"apply failed": {
+			resource: &ResourceInfo{
+				ResourceAction: event.ApplyAction,
+				ApplyError:     fmt.Errorf("this is a test"),
+			},
+			columnWidth:    15,
+			expectedOutput: "Failed",
+		},
 		"pruned": {
 			resource: &ResourceInfo{
 				ResourceAction: event.PruneAction,
@@ -72,3 +83,126 @@ func TestActionColumnDef(t *testing.T) {
 		})
 	}
 }
+
+func TestReconciledColumnDef(t *testing.T) {
+	testCases := map[string]struct {
+		resource       table.Resource
+		columnWidth    int
+		expectedOutput string
+	}{
+		"applied, not waited on": {
+			resource: &ResourceInfo{
+				ResourceAction: event.ApplyAction,
+				ApplyOpResult:  createdOpResult,
+			},
+			columnWidth:    10,
+			expectedOutput: "",
+		},
+		"waited on by a wait task": {
+			resource: &ResourceInfo{
+				ResourceAction: event.WaitAction,
+				WaitOpResult:   event.Reconciled,
+				Waited:         true,
+			},
+			columnWidth:    10,
+			expectedOutput: "Reconciled",
+		},
+		"waited on by a DAG apply task": {
+			resource: &ResourceInfo{
+				ResourceAction: event.ApplyAction,
+				ApplyOpResult:  createdOpResult,
+				WaitOpResult:   event.ReconcileTimeout,
+				Waited:         true,
+			},
+			columnWidth:    10,
+			expectedOutput: "Timeout",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := reconciledColumnDef.PrintResource(&buf, tc.columnWidth, tc.resource)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if want, got := tc.expectedOutput, buf.String(); want != got {
+				t.Errorf("expected %q, but got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestMessageColumnDef(t *testing.T) {
+	testCases := map[string]struct {
+		resource       table.Resource
+		columnWidth    int
+		expectedOutput string
+	}{
+		"no status and no error": {
+			resource:       &ResourceInfo{},
+			columnWidth:    40,
+			expectedOutput: "",
+		},
+		"status message": {
+			resource: &ResourceInfo{
+				resourceStatus: &pe.ResourceStatus{
+					Message: "Deployment is available",
+				},
+			},
+			columnWidth:    40,
+			expectedOutput: "Deployment is available",
+		},
+		"apply error": {
+			resource: &ResourceInfo{
+				ApplyError: fmt.Errorf("this is a test"),
+			},
+			columnWidth:    40,
+			expectedOutput: "this is a test",
+		},
+		"apply conflict error": {
+			resource: &ResourceInfo{
+				ApplyError: applyerror.NewApplyConflictError(fmt.Errorf("this is a test"),
+					[]applyerror.FieldConflict{
+						{Manager: "kube-controller-manager", Field: ".spec.replicas"},
+					}),
+			},
+			columnWidth:    60,
+			expectedOutput: "conflicts: .spec.replicas (kube-controller-manager)",
+		},
+		"retrying": {
+			resource: &ResourceInfo{
+				Retry: &event.RetryEvent{
+					Action:      event.ApplyAction,
+					Attempt:     2,
+					MaxAttempts: 5,
+					Error:       fmt.Errorf("this is a test"),
+				},
+			},
+			columnWidth:    40,
+			expectedOutput: "retrying (2/5): this is a test",
+		},
+		"trimmed output": {
+			resource: &ResourceInfo{
+				ApplyError: fmt.Errorf("this is a test"),
+			},
+			columnWidth:    4,
+			expectedOutput: "this",
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := messageColumnDef.PrintResource(&buf, tc.columnWidth, tc.resource)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if want, got := tc.expectedOutput, buf.String(); want != got {
+				t.Errorf("expected %q, but got %q", want, got)
+			}
+		})
+	}
+}
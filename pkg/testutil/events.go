@@ -24,6 +24,7 @@ type ExpEvent struct {
 	PruneEvent       *ExpPruneEvent
 	DeleteEvent      *ExpDeleteEvent
 	WaitEvent        *ExpWaitEvent
+	RetryEvent       *ExpRetryEvent
 }
 
 type ExpInitEvent struct {
@@ -74,6 +75,14 @@ type ExpWaitEvent struct {
 	Identifier object.ObjMetadata
 }
 
+type ExpRetryEvent struct {
+	GroupName  string
+	Action     event.ResourceAction
+	Identifier object.ObjMetadata
+	Attempt    int
+	Error      error
+}
+
 func VerifyEvents(expEvents []ExpEvent, events []event.Event) error {
 	if len(expEvents) == 0 && len(events) == 0 {
 		return nil
@@ -270,6 +279,40 @@ func isMatch(ee ExpEvent, e event.Event) bool {
 		}
 		return true
 
+	case event.RetryType:
+		ree := ee.RetryEvent
+		if ree == nil {
+			return true
+		}
+		re := e.RetryEvent
+
+		if ree.Identifier != object.NilObjMetadata {
+			if ree.Identifier != re.Identifier {
+				return false
+			}
+		}
+
+		if ree.GroupName != "" {
+			if ree.GroupName != re.GroupName {
+				return false
+			}
+		}
+
+		if ree.Action != re.Action {
+			return false
+		}
+
+		if ree.Attempt != 0 {
+			if ree.Attempt != re.Attempt {
+				return false
+			}
+		}
+
+		if ree.Error != nil {
+			return re.Error != nil
+		}
+		return re.Error == nil
+
 	default:
 		return true
 	}
@@ -364,6 +407,18 @@ func EventToExpEvent(e event.Event) ExpEvent {
 				Operation:  e.WaitEvent.Operation,
 			},
 		}
+
+	case event.RetryType:
+		return ExpEvent{
+			EventType: event.RetryType,
+			RetryEvent: &ExpRetryEvent{
+				GroupName:  e.RetryEvent.GroupName,
+				Action:     e.RetryEvent.Action,
+				Identifier: e.RetryEvent.Identifier,
+				Attempt:    e.RetryEvent.Attempt,
+				Error:      e.RetryEvent.Error,
+			},
+		}
 	}
 	return ExpEvent{}
 }
@@ -381,6 +436,19 @@ func RemoveEqualEvents(in []ExpEvent, expected ExpEvent) ([]ExpEvent, int) {
 	return in, matches
 }
 
+// SortExpEvents sorts the events with GroupedEventsByID, only ever swapping
+// adjacent events, so events of different types or task groups keep their
+// order. GroupedEventsByID is not a strict weak ordering, so sort.Sort and
+// sort.Stable may reorder unrelated events.
+func SortExpEvents(events []ExpEvent) {
+	ape := GroupedEventsByID(events)
+	for i := 1; i < ape.Len(); i++ {
+		for j := i; j > 0 && ape.Less(j, j-1); j-- {
+			ape.Swap(j, j-1)
+		}
+	}
+}
+
 // GroupedEventsByID implements sort.Interface for []ExpEvent based on
 // the serialized ObjMetadata of Apply, Prune, and Delete events within the same
 // task group.
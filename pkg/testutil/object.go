@@ -110,3 +110,37 @@ func (d dependsOnMutator) Mutate(u *unstructured.Unstructured) {
 		d.t.FailNow()
 	}
 }
+
+// AddAnnotation returns a testutil.Mutator which adds the passed
+// annotation to the object which is mutated.
+func AddAnnotation(t *testing.T, key, value string) Mutator {
+	return annotationMutator{
+		t:     t,
+		key:   key,
+		value: value,
+	}
+}
+
+// annotationMutator encapsulates fields for adding an annotation to
+// a test object. Implements the Mutator interface.
+type annotationMutator struct {
+	t     *testing.T
+	key   string
+	value string
+}
+
+// Mutate adds the annotation to the supplied object.
+func (a annotationMutator) Mutate(u *unstructured.Unstructured) {
+	annos, found, err := unstructured.NestedStringMap(u.Object, "metadata", "annotations")
+	if !assert.NoError(a.t, err) {
+		a.t.FailNow()
+	}
+	if !found {
+		annos = make(map[string]string)
+	}
+	annos[a.key] = a.value
+	err = unstructured.SetNestedStringMap(u.Object, annos, "metadata", "annotations")
+	if !assert.NoError(a.t, err) {
+		a.t.FailNow()
+	}
+}
@@ -32,3 +32,30 @@ func NewStatusPoller(f cmdutil.Factory) (*polling.StatusPoller, error) {
 
 	return polling.NewStatusPoller(c, mapper), nil
 }
+
+// NewWatchingStatusPoller creates a new StatusPoller instance from the
+// passed in factory, that uses watches rather than polling to keep track
+// of the resources.
+func NewWatchingStatusPoller(f cmdutil.Factory) (*polling.StatusPoller, error) {
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error getting RESTConfig: %w", err)
+	}
+
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, fmt.Errorf("error getting RESTMapper: %w", err)
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+	if err != nil {
+		return nil, fmt.Errorf("error creating client: %w", err)
+	}
+
+	dynamicClient, err := f.DynamicClient()
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %w", err)
+	}
+
+	return polling.NewWatchingStatusPoller(c, dynamicClient, mapper), nil
+}